@@ -0,0 +1,43 @@
+package handlers
+
+import (
+	"strconv"
+	"tukem-backend/db"
+	"tukem-backend/models"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// requestScope returns the access scope stored by RequirePermission. Without one nothing is in
+// scope, so a route registered without a permission fails closed.
+func requestScope(c echo.Context) *models.AccessScope {
+	if scope, ok := c.Get("access_scope").(*models.AccessScope); ok {
+		return scope
+	}
+	return &models.AccessScope{}
+}
+
+// accessScopeCondition returns the SQL condition limiting a query to children in the access scope
+// of the request and its argument, or an empty condition for a global scope. column is the child
+// facility column, e.g. c.facility_id.
+func accessScopeCondition(c echo.Context, column string, argIndex int) (string, interface{}) {
+	scope := requestScope(c)
+	if scope.Global {
+		return "", nil
+	}
+	return ` AND ` + column + ` = ANY($` + strconv.Itoa(argIndex) + `)`, pq.Array(scope.FacilityIDs)
+}
+
+// childInScope reports whether the child is in the access scope of the request
+func childInScope(c echo.Context, childID string) (bool, error) {
+	scope := requestScope(c)
+	if scope.Global {
+		return true, nil
+	}
+
+	var inScope bool
+	err := db.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM children WHERE id = $1 AND facility_id = ANY($2))`,
+		childID, pq.Array(scope.FacilityIDs)).Scan(&inScope)
+	return inScope, err
+}
@@ -2,12 +2,12 @@ package handlers
 
 import (
 	"database/sql"
+	"encoding/json"
 	"net/http"
-	"os"
-	"strings"
 	"time"
 	"tukem-backend/db"
 	"tukem-backend/models"
+	"tukem-backend/services"
 	"tukem-backend/utils"
 
 	"github.com/golang-jwt/jwt/v5"
@@ -59,68 +59,17 @@ func AdminRequestOTP(c echo.Context) error {
 		})
 	}
 
-	// Check rate limiting (max 3 requests per 15 minutes)
-	canRequest, retryAfter, err := checkAdminRateLimit(phoneNumber)
+	// Issue OTP with purpose 'admin_login' (request limits and lockout are enforced by the OTP service)
+	otpCode, expiresAt, err := otpService().Issue(phoneNumber, services.OTPPurposeAdminLogin, c.RealIP(), c.Request().UserAgent())
 	if err != nil {
-		c.Logger().Errorf("Rate limit check error: %v", err)
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Database error",
-		})
-	}
-
-	if !canRequest {
-		return c.JSON(http.StatusTooManyRequests, models.OTPResponse{
-			Success:    false,
-			Error:      "Terlalu banyak permintaan. Silakan coba lagi nanti",
-			RetryAfter: retryAfter,
-		})
-	}
-
-	// Generate OTP
-	otpCode, err := utils.GenerateOTP()
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Failed to generate OTP",
-		})
+		return otpErrorResponse(c, err)
 	}
 
-	// Store OTP in database with purpose 'admin_login'
-	expiresAt := utils.GetOTPExpiration()
-	ipAddress := c.RealIP()
-	userAgent := c.Request().UserAgent()
-
-	query := `INSERT INTO otp_codes (phone_number, otp_code, purpose, expires_at, ip_address, user_agent, max_attempts)
-	          VALUES ($1, $2, 'admin_login', $3, $4, $5, 3)
-	          RETURNING id`
-	var otpID string
-	err = db.DB.QueryRow(query, phoneNumber, otpCode, expiresAt, ipAddress, userAgent).Scan(&otpID)
+	// Queue OTP for delivery via WhatsApp (sent by the outbox workers)
+	err = notificationService().SendOTP(phoneNumber, otpCode, resolveLanguage(c), expiresAt)
 	if err != nil {
-		c.Logger().Errorf("Failed to store OTP: %v", err)
+		c.Logger().Errorf("Failed to queue WhatsApp OTP for admin: %v", err)
 		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Failed to store OTP",
-		})
-	}
-
-	// Update rate limit
-	_ = updateAdminRateLimit(phoneNumber)
-
-	// Send OTP via WhatsApp
-	err = whatsappService.SendOTP(phoneNumber, otpCode)
-	if err != nil {
-		c.Logger().Errorf("Failed to send WhatsApp OTP for admin: %v", err)
-		errorMsg := err.Error()
-		if strings.Contains(errorMsg, "Lid is missing in chat table") ||
-			strings.Contains(errorMsg, "chat table") ||
-			strings.Contains(errorMsg, "belum pernah") {
-			return c.JSON(http.StatusBadRequest, models.OTPResponse{
-				Success: false,
-				Error:   "Nomor WhatsApp belum pernah mengirim pesan ke bot. Silakan kirim pesan ke nomor bot terlebih dahulu.",
-			})
-		}
-		return c.JSON(http.StatusBadGateway, models.OTPResponse{
 			Success: false,
 			Error:   "Gagal mengirim OTP melalui WhatsApp. Silakan coba lagi nanti.",
 		})
@@ -129,7 +78,7 @@ func AdminRequestOTP(c echo.Context) error {
 	return c.JSON(http.StatusOK, models.OTPResponse{
 		Success:   true,
 		Message:   "OTP telah dikirim ke WhatsApp Anda",
-		ExpiresIn: 300, // 5 minutes
+		ExpiresIn: otpExpiresIn(expiresAt),
 	})
 }
 
@@ -152,58 +101,9 @@ func AdminVerifyOTP(c echo.Context) error {
 		})
 	}
 
-	// Find OTP record with purpose 'admin_login'
-	var otp models.OTPCode
-	query := `SELECT id, phone_number, otp_code, purpose, expires_at, used_at, is_used, attempt_count, max_attempts
-	          FROM otp_codes
-	          WHERE phone_number = $1 AND otp_code = $2 AND purpose = 'admin_login' AND is_used = false
-	          ORDER BY created_at DESC
-	          LIMIT 1`
-
-	var usedAt sql.NullTime
-	err = db.DB.QueryRow(query, phoneNumber, req.OTP).Scan(
-		&otp.ID, &otp.PhoneNumber, &otp.OTPCode, &otp.Purpose,
-		&otp.ExpiresAt, &usedAt, &otp.IsUsed, &otp.AttemptCount, &otp.MaxAttempts)
-
-	if err == sql.ErrNoRows {
-		incrementAdminOTPAttempt(phoneNumber)
-		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
-			Success: false,
-			Error:   "OTP tidak valid",
-		})
-	} else if err != nil {
-		c.Logger().Errorf("Admin OTP verification query error: %v", err)
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Database error",
-		})
-	}
-
-	// Check if OTP is expired
-	if utils.IsOTPExpired(otp.ExpiresAt) {
-		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
-			Success: false,
-			Error:   "OTP sudah kadaluarsa",
-		})
-	}
-
-	// Check if max attempts exceeded
-	if otp.AttemptCount >= otp.MaxAttempts {
-		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
-			Success: false,
-			Error:   "Terlalu banyak percobaan. Silakan request OTP baru",
-		})
-	}
-
-	// Mark OTP as used
-	now := time.Now()
-	updateQuery := `UPDATE otp_codes SET is_used = true, used_at = $1 WHERE id = $2`
-	_, err = db.DB.Exec(updateQuery, now, otp.ID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Failed to update OTP",
-		})
+	// Verify OTP with purpose 'admin_login' (attempt limits and lockout are enforced by the OTP service)
+	if err := otpService().Verify(phoneNumber, services.OTPPurposeAdminLogin, req.OTP, c.RealIP()); err != nil {
+		return otpErrorResponse(c, err)
 	}
 
 	// Find admin user
@@ -216,21 +116,26 @@ func AdminVerifyOTP(c echo.Context) error {
 		})
 	}
 
-	// Generate JWT
-	token, err := generateAdminJWT(user)
+	// Start session, or ask for the second factor
+	tokens, challenge, err := startLogin(c, user, true, loginMethodAdminOTP)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
 			Success: false,
 			Error:   "Failed to generate token",
 		})
 	}
+	if challenge != nil {
+		return c.JSON(http.StatusOK, challenge)
+	}
 
 	// Log admin login for audit
-	logAdminLogin(user.ID, c.RealIP(), c.Request().UserAgent())
+	logAdminLogin(user.ID, loginMethodAdminOTP, "", c.RealIP(), c.Request().UserAgent())
 
 	return c.JSON(http.StatusOK, models.AuthResponse{
-		Token: token,
-		User:  *user,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         *user,
 	})
 }
 
@@ -280,67 +185,28 @@ func findAdminByPhone(phoneNumber string) (*models.User, error) {
 	return &user, nil
 }
 
-func checkAdminRateLimit(phoneNumber string) (bool, int, error) {
-	windowStart := time.Now().Add(-15 * time.Minute)
-	windowEnd := time.Now()
-
-	var count int
-	query := `SELECT COALESCE(SUM(request_count), 0) 
-	          FROM otp_rate_limits 
-	          WHERE phone_number = $1 AND window_start >= $2 AND window_end <= $3`
-	err := db.DB.QueryRow(query, phoneNumber, windowStart, windowEnd).Scan(&count)
-	if err != nil && err != sql.ErrNoRows {
-		return false, 0, err
-	}
-
-	if count >= 3 {
-		retryAfter := int(time.Until(windowEnd.Add(15 * time.Minute)).Seconds())
-		return false, retryAfter, nil
-	}
-
-	return true, 0, nil
-}
-
-func updateAdminRateLimit(phoneNumber string) error {
-	windowStart := time.Now().Truncate(15 * time.Minute)
-	windowEnd := windowStart.Add(15 * time.Minute)
-
-	query := `INSERT INTO otp_rate_limits (phone_number, request_count, window_start, window_end, updated_at)
-	          VALUES ($1, 1, $2, $3, $4)
-	          ON CONFLICT (phone_number, window_start) 
-	          DO UPDATE SET request_count = otp_rate_limits.request_count + 1, updated_at = $4`
-	_, err := db.DB.Exec(query, phoneNumber, windowStart, windowEnd, time.Now())
-	return err
-}
-
-func incrementAdminOTPAttempt(phoneNumber string) {
-	query := `UPDATE otp_codes 
-	          SET attempt_count = attempt_count + 1 
-	          WHERE phone_number = $1 AND purpose = 'admin_login' AND is_used = false 
-	          ORDER BY created_at DESC 
-	          LIMIT 1`
-	db.DB.Exec(query, phoneNumber)
-}
-
-func generateAdminJWT(user *models.User) (string, error) {
+func generateAdminJWT(user *models.User, sessionID string) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
 	claims["user_id"] = user.ID
 	claims["role"] = user.Role
 	claims["is_admin"] = true
-	claims["exp"] = time.Now().Add(time.Hour * 24).Unix() // 24 hours for admin
+	claims["sid"] = sessionID
+	claims["exp"] = time.Now().Add(accessTokenTTL).Unix()
 
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "secret"
-	}
-
-	return token.SignedString([]byte(jwtSecret))
+	return token.SignedString(utils.SecretKey(utils.SecretJWT))
 }
 
-func logAdminLogin(userID, ipAddress, userAgent string) {
+// logAdminLogin records an admin login with its method and second factor (empty if none)
+func logAdminLogin(userID, method, secondFactor, ipAddress, userAgent string) {
+	details := map[string]string{"method": method}
+	if secondFactor != "" {
+		details["second_factor"] = secondFactor
+	}
+	detailsJSON, _ := json.Marshal(details)
+
 	query := `INSERT INTO audit_logs (user_id, action, resource_type, ip_address, user_agent, details)
-	          VALUES ($1, 'admin_login', 'auth', $2, $3, '{"method": "otp"}')`
-	db.DB.Exec(query, userID, ipAddress, userAgent)
+	          VALUES ($1, 'admin_login', 'auth', $2, $3, $4)`
+	db.DB.Exec(query, userID, ipAddress, userAgent, string(detailsJSON))
 }
 
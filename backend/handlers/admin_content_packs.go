@@ -0,0 +1,492 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+const contentPackColumns = `id, source, version, name, notes, status, milestone_count, content_count, imported_by, activated_at, created_at`
+
+// GetAdminContentPacks lists content pack versions, optionally filtered by source
+func GetAdminContentPacks(c echo.Context) error {
+	query := `SELECT ` + contentPackColumns + ` FROM content_packs WHERE 1=1`
+	args := []interface{}{}
+
+	if source := c.QueryParam("source"); source != "" {
+		query += ` AND source = $1`
+		args = append(args, strings.ToUpper(source))
+	}
+	query += ` ORDER BY source ASC, created_at DESC`
+
+	packs := []models.ContentPack{}
+	if err := db.DB.Select(&packs, query, args...); err != nil {
+		c.Logger().Errorf("GetAdminContentPacks query error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"packs": packs,
+		"total": len(packs),
+	})
+}
+
+// PreviewAdminContentPack validates a content pack and returns its diff against the active version
+// without importing it
+func PreviewAdminContentPack(c echo.Context) error {
+	var pack models.ContentPackFile
+	if err := c.Bind(&pack); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid content pack"})
+	}
+
+	utils.NormalizeContentPack(&pack)
+	if err := utils.ValidateContentPack(pack); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	current, err := loadActiveContentPackFile(pack.Source)
+	if err != nil {
+		c.Logger().Errorf("PreviewAdminContentPack load active pack error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, utils.DiffContentPacks(current, pack))
+}
+
+// ImportAdminContentPack imports a content pack as a draft version.
+// The pack's milestones are inactive until the version is activated.
+func ImportAdminContentPack(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	var pack models.ContentPackFile
+	if err := c.Bind(&pack); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid content pack"})
+	}
+
+	utils.NormalizeContentPack(&pack)
+	if err := utils.ValidateContentPack(pack); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	var exists bool
+	err := db.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM content_packs WHERE source = $1 AND version = $2)`,
+		pack.Source, pack.Version).Scan(&exists)
+	if err != nil {
+		c.Logger().Errorf("ImportAdminContentPack check version error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if exists {
+		return c.JSON(http.StatusConflict, map[string]string{"error": fmt.Sprintf("Version %s of %s already exists", pack.Version, pack.Source)})
+	}
+
+	current, err := loadActiveContentPackFile(pack.Source)
+	if err != nil {
+		c.Logger().Errorf("ImportAdminContentPack load active pack error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	diff := utils.DiffContentPacks(current, pack)
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to start transaction"})
+	}
+	defer tx.Rollback()
+
+	var packID string
+	err = tx.QueryRow(
+		`INSERT INTO content_packs (source, version, name, notes, status, imported_by)
+		 VALUES ($1, $2, $3, $4, 'draft', $5)
+		 RETURNING id`,
+		pack.Source, pack.Version, pack.Name, pack.Notes, adminUserID,
+	).Scan(&packID)
+	if err != nil {
+		c.Logger().Errorf("ImportAdminContentPack insert pack error: %v", err)
+		sanitizedErr := utils.SanitizeError(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
+	}
+
+	contentCount := 0
+	for _, m := range pack.Milestones {
+		var packKey *string
+		if m.Key != "" {
+			packKey = &m.Key
+		}
+
+		var milestoneID string
+		err := tx.QueryRow(
+			`INSERT INTO milestones (age_months, min_age_range, max_age_range, category, question, question_en,
+				source, is_red_flag, pyramid_level, denver_domain, content_pack_id, pack_key, is_active)
+			 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, false)
+			 RETURNING id`,
+			m.AgeMonths, m.MinAgeRange, m.MaxAgeRange, m.Category, m.Question, m.QuestionEn,
+			pack.Source, m.IsRedFlag, m.PyramidLevel, m.DenverDomain, packID, packKey,
+		).Scan(&milestoneID)
+		if err != nil {
+			c.Logger().Errorf("ImportAdminContentPack insert milestone error: %v", err)
+			sanitizedErr := utils.SanitizeError(err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
+		}
+
+		for _, s := range m.Stimulation {
+			_, err := tx.Exec(
+				`INSERT INTO stimulation_content (milestone_id, category, title, description, content_type, url,
+					thumbnail_url, age_min_months, age_max_months, is_active, content_pack_id)
+				 VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, false, $10)`,
+				milestoneID, s.Category, s.Title, s.Description, s.ContentType, s.URL,
+				s.ThumbnailURL, s.AgeMinMonths, s.AgeMaxMonths, packID,
+			)
+			if err != nil {
+				c.Logger().Errorf("ImportAdminContentPack insert stimulation content error: %v", err)
+				sanitizedErr := utils.SanitizeError(err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
+			}
+			contentCount++
+		}
+	}
+
+	_, err = tx.Exec(`UPDATE content_packs SET milestone_count = $1, content_count = $2 WHERE id = $3`,
+		len(pack.Milestones), contentCount, packID)
+	if err != nil {
+		c.Logger().Errorf("ImportAdminContentPack update counts error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to commit transaction"})
+	}
+
+	// Log audit
+	packData := map[string]interface{}{
+		"source":          pack.Source,
+		"version":         pack.Version,
+		"milestone_count": len(pack.Milestones),
+		"content_count":   contentCount,
+	}
+	utils.LogAudit(adminUserID, "import", "content_pack", &packID, nil, packData, ipAddress, userAgent)
+
+	var created models.ContentPack
+	if err := db.DB.Get(&created, `SELECT `+contentPackColumns+` FROM content_packs WHERE id = $1`, packID); err != nil {
+		c.Logger().Errorf("ImportAdminContentPack fetch created pack error: %v", err)
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"pack": created,
+		"diff": diff,
+	})
+}
+
+// ActivateAdminContentPack makes a pack version the active one for its source.
+// Activating an archived version rolls the source back to it.
+func ActivateAdminContentPack(c echo.Context) error {
+	packID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	// Validate UUID format
+	if err := utils.ValidateUUID(packID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid content pack ID format"})
+	}
+
+	var pack models.ContentPack
+	err := db.DB.Get(&pack, `SELECT `+contentPackColumns+` FROM content_packs WHERE id = $1`, packID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Content pack not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("ActivateAdminContentPack get pack error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if pack.Status == "active" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Content pack is already active"})
+	}
+
+	previousVersion, err := activateContentPack(pack)
+	if err != nil {
+		c.Logger().Errorf("ActivateAdminContentPack error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to activate content pack"})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "activate", "content_pack", &packID,
+		map[string]interface{}{"source": pack.Source, "version": previousVersion},
+		map[string]interface{}{"source": pack.Source, "version": pack.Version},
+		ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, map[string]string{"message": fmt.Sprintf("%s %s is now active", pack.Source, pack.Version)})
+}
+
+// RollbackAdminContentPack re-activates the previously active version of a source
+func RollbackAdminContentPack(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	var req struct {
+		Source string `json:"source" validate:"required"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	source := strings.ToUpper(strings.TrimSpace(req.Source))
+
+	// The previous version is the most recently activated archived pack
+	var pack models.ContentPack
+	err := db.DB.Get(&pack, `SELECT `+contentPackColumns+` FROM content_packs
+		WHERE source = $1 AND status = 'archived' AND activated_at IS NOT NULL
+		ORDER BY activated_at DESC LIMIT 1`, source)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "No previous version to roll back to"})
+	}
+	if err != nil {
+		c.Logger().Errorf("RollbackAdminContentPack get previous pack error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	previousVersion, err := activateContentPack(pack)
+	if err != nil {
+		c.Logger().Errorf("RollbackAdminContentPack error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to roll back content pack"})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "rollback", "content_pack", &pack.ID,
+		map[string]interface{}{"source": source, "version": previousVersion},
+		map[string]interface{}{"source": source, "version": pack.Version},
+		ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, map[string]string{"message": fmt.Sprintf("%s rolled back to %s", source, pack.Version)})
+}
+
+// ExportAdminContentPack exports a pack version (by ID) or the active version of a source (?source=)
+// in the content pack file format
+func ExportAdminContentPack(c echo.Context) error {
+	var pack *models.ContentPackFile
+	var err error
+
+	if packID := c.Param("id"); packID != "" {
+		if err := utils.ValidateUUID(packID); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid content pack ID format"})
+		}
+		pack, err = loadContentPackFile(packID)
+	} else {
+		source := strings.ToUpper(c.QueryParam("source"))
+		if source == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "source is required"})
+		}
+		pack, err = loadActiveContentPackFile(source)
+	}
+	if err != nil {
+		c.Logger().Errorf("ExportAdminContentPack error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if pack == nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Content pack not found"})
+	}
+
+	filename := fmt.Sprintf("%s_%s.json", strings.ToLower(pack.Source), pack.Version)
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	return c.JSON(http.StatusOK, pack)
+}
+
+// DeleteAdminContentPack deletes a draft pack version together with its milestones and content
+func DeleteAdminContentPack(c echo.Context) error {
+	packID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	// Validate UUID format
+	if err := utils.ValidateUUID(packID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid content pack ID format"})
+	}
+
+	var pack models.ContentPack
+	err := db.DB.Get(&pack, `SELECT `+contentPackColumns+` FROM content_packs WHERE id = $1`, packID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Content pack not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("DeleteAdminContentPack get pack error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	// Versions that have been active may have assessments attached and are kept for history
+	if pack.Status != "draft" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Only draft content packs can be deleted"})
+	}
+
+	if _, err := db.DB.Exec("DELETE FROM content_packs WHERE id = $1", packID); err != nil {
+		c.Logger().Errorf("DeleteAdminContentPack error: %v", err)
+		sanitizedErr := utils.SanitizeError(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "delete", "content_pack", &packID, pack, nil, ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Content pack deleted successfully"})
+}
+
+// activateContentPack switches the active version of the pack's source to this pack.
+// Milestones of other versions are deactivated but kept, so existing assessments stay attached.
+// Returns the previously active version (empty if none).
+func activateContentPack(pack models.ContentPack) (string, error) {
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return "", err
+	}
+	defer tx.Rollback()
+
+	var previousVersion string
+	err = tx.QueryRow(`SELECT version FROM content_packs WHERE source = $1 AND status = 'active'`, pack.Source).
+		Scan(&previousVersion)
+	if err != nil && err != sql.ErrNoRows {
+		return "", err
+	}
+
+	statements := []struct {
+		query string
+		args  []interface{}
+	}{
+		{`UPDATE content_packs SET status = 'archived' WHERE source = $1 AND status = 'active'`,
+			[]interface{}{pack.Source}},
+		{`UPDATE content_packs SET status = 'active', activated_at = NOW() WHERE id = $1 AND source = $2`,
+			[]interface{}{pack.ID, pack.Source}},
+		{`UPDATE milestones SET is_active = COALESCE(content_pack_id = $1, false) WHERE source = $2`,
+			[]interface{}{pack.ID, pack.Source}},
+		{`UPDATE stimulation_content SET is_active = (content_pack_id = $1), updated_at = NOW()
+		 WHERE content_pack_id IN (SELECT id FROM content_packs WHERE source = $2)`,
+			[]interface{}{pack.ID, pack.Source}},
+	}
+	for _, stmt := range statements {
+		if _, err := tx.Exec(stmt.query, stmt.args...); err != nil {
+			return "", err
+		}
+	}
+
+	return previousVersion, tx.Commit()
+}
+
+// loadActiveContentPackFile builds the content pack file of the active milestones of a source.
+// Returns nil if the source has no milestones.
+func loadActiveContentPackFile(source string) (*models.ContentPackFile, error) {
+	pack := &models.ContentPackFile{Source: source}
+
+	var meta models.ContentPack
+	err := db.DB.Get(&meta, `SELECT `+contentPackColumns+` FROM content_packs WHERE source = $1 AND status = 'active'`, source)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	if err == nil {
+		pack.Version = meta.Version
+		pack.Name = meta.Name
+		pack.Notes = meta.Notes
+	}
+
+	milestones, err := loadContentPackMilestones(`source = $1 AND is_active = true`, source)
+	if err != nil {
+		return nil, err
+	}
+	if len(milestones) == 0 && meta.ID == "" {
+		return nil, nil
+	}
+	pack.Milestones = milestones
+	return pack, nil
+}
+
+// loadContentPackFile builds the content pack file of a specific pack version.
+// Returns nil if the pack does not exist.
+func loadContentPackFile(packID string) (*models.ContentPackFile, error) {
+	var meta models.ContentPack
+	err := db.DB.Get(&meta, `SELECT `+contentPackColumns+` FROM content_packs WHERE id = $1`, packID)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	milestones, err := loadContentPackMilestones(`content_pack_id = $1`, packID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.ContentPackFile{
+		Source:     meta.Source,
+		Version:    meta.Version,
+		Name:       meta.Name,
+		Notes:      meta.Notes,
+		Milestones: milestones,
+	}, nil
+}
+
+// loadContentPackMilestones loads the milestones matching condition with their linked stimulation content
+func loadContentPackMilestones(condition string, arg interface{}) ([]models.ContentPackMilestone, error) {
+	var milestones []models.Milestone
+	err := db.DB.Select(&milestones, `SELECT * FROM milestones WHERE `+condition+`
+		ORDER BY age_months ASC, pyramid_level ASC, created_at ASC`, arg)
+	if err != nil {
+		return nil, err
+	}
+	if len(milestones) == 0 {
+		return []models.ContentPackMilestone{}, nil
+	}
+
+	ids := make([]string, len(milestones))
+	for i, m := range milestones {
+		ids[i] = m.ID
+	}
+
+	var contents []models.StimulationContent
+	err = db.DB.Select(&contents, `SELECT * FROM stimulation_content
+		WHERE milestone_id = ANY($1) ORDER BY created_at ASC`, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	contentByMilestone := make(map[string][]models.ContentPackStimulation)
+	for _, s := range contents {
+		contentByMilestone[*s.MilestoneID] = append(contentByMilestone[*s.MilestoneID], models.ContentPackStimulation{
+			Category:     s.Category,
+			Title:        s.Title,
+			Description:  s.Description,
+			ContentType:  s.ContentType,
+			URL:          s.URL,
+			ThumbnailURL: s.ThumbnailURL,
+			AgeMinMonths: s.AgeMinMonths,
+			AgeMaxMonths: s.AgeMaxMonths,
+		})
+	}
+
+	result := make([]models.ContentPackMilestone, 0, len(milestones))
+	for _, m := range milestones {
+		// Milestones created before content packs have no pack key; their ID is a stable key
+		key := m.ID
+		if m.PackKey != nil {
+			key = *m.PackKey
+		}
+		result = append(result, models.ContentPackMilestone{
+			Key:          key,
+			AgeMonths:    m.AgeMonths,
+			MinAgeRange:  m.MinAgeRange,
+			MaxAgeRange:  m.MaxAgeRange,
+			Category:     m.Category,
+			Question:     m.Question,
+			QuestionEn:   m.QuestionEn,
+			IsRedFlag:    m.IsRedFlag,
+			PyramidLevel: m.PyramidLevel,
+			DenverDomain: m.DenverDomain,
+			Stimulation:  contentByMilestone[m.ID],
+		})
+	}
+	return result, nil
+}
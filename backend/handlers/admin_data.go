@@ -62,6 +62,12 @@ func GetAdminChildren(c echo.Context) error {
 		argIndex += 2
 	}
 
+	if cond, arg := accessScopeCondition(c, "c.facility_id", argIndex); cond != "" {
+		query += cond
+		args = append(args, arg)
+		argIndex++
+	}
+
 	query += ` ORDER BY c.created_at DESC LIMIT $` + strconv.Itoa(argIndex) + ` OFFSET $` + strconv.Itoa(argIndex+1)
 	args = append(args, limit, offset)
 
@@ -131,6 +137,12 @@ func GetAdminChildren(c echo.Context) error {
 		countArgIndex += 2
 	}
 
+	if cond, arg := accessScopeCondition(c, "c.facility_id", countArgIndex); cond != "" {
+		countQuery += cond
+		countArgs = append(countArgs, arg)
+		countArgIndex++
+	}
+
 	var total int
 	err = db.DB.QueryRow(countQuery, countArgs...).Scan(&total)
 	if err != nil {
@@ -156,13 +168,20 @@ func GetAdminChild(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid child ID format"})
 	}
 
+	if inScope, err := childInScope(c, childID); err != nil {
+		c.Logger().Errorf("GetAdminChild error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	} else if !inScope {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
+	}
+
 	var child models.Child
 	var parentName sql.NullString
 	var parentEmail sql.NullString
 	var parentPhone sql.NullString
 
 	query := `SELECT c.id, c.parent_id, c.name, c.dob, c.gender, c.birth_weight, 
-	          c.birth_height, c.is_premature, c.gestational_age, c.created_at,
+	          c.birth_height, c.is_premature, c.gestational_age, c.facility_id, c.created_at,
 	          u.full_name, u.email, u.phone_number
 	          FROM children c
 	          JOIN users u ON u.id = c.parent_id
@@ -170,7 +189,7 @@ func GetAdminChild(c echo.Context) error {
 	err := db.DB.QueryRow(query, childID).Scan(
 		&child.ID, &child.ParentID, &child.Name, &child.DOB, &child.Gender,
 		&child.BirthWeight, &child.BirthHeight, &child.IsPremature, &child.GestationalAge,
-		&child.CreatedAt, &parentName, &parentEmail, &parentPhone)
+		&child.FacilityID, &child.CreatedAt, &parentName, &parentEmail, &parentPhone)
 
 	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
@@ -262,6 +281,12 @@ func GetAdminMeasurements(c echo.Context) error {
 		argIndex++
 	}
 
+	if cond, arg := accessScopeCondition(c, "c.facility_id", argIndex); cond != "" {
+		query += cond
+		args = append(args, arg)
+		argIndex++
+	}
+
 	query += ` ORDER BY m.measurement_date DESC LIMIT $` + strconv.Itoa(argIndex) + ` OFFSET $` + strconv.Itoa(argIndex+1)
 	args = append(args, limit, offset)
 
@@ -345,6 +370,12 @@ func GetAdminMeasurements(c echo.Context) error {
 		countArgIndex++
 	}
 
+	if cond, arg := accessScopeCondition(c, "c.facility_id", countArgIndex); cond != "" {
+		countQuery += cond
+		countArgs = append(countArgs, arg)
+		countArgIndex++
+	}
+
 	var total int
 	err = db.DB.QueryRow(countQuery, countArgs...).Scan(&total)
 	if err != nil {
@@ -416,6 +447,12 @@ func GetAdminAssessments(c echo.Context) error {
 		argIndex++
 	}
 
+	if cond, arg := accessScopeCondition(c, "c.facility_id", argIndex); cond != "" {
+		query += cond
+		args = append(args, arg)
+		argIndex++
+	}
+
 	query += ` GROUP BY a.id, a.child_id, a.assessment_date, a.created_at,
 	          c.name, c.dob, c.gender, u.full_name, u.email
 	          ORDER BY a.assessment_date DESC LIMIT $` + strconv.Itoa(argIndex) + ` OFFSET $` + strconv.Itoa(argIndex+1)
@@ -493,6 +530,12 @@ func GetAdminAssessments(c echo.Context) error {
 		countArgIndex++
 	}
 
+	if cond, arg := accessScopeCondition(c, "c.facility_id", countArgIndex); cond != "" {
+		countQuery += cond
+		countArgs = append(countArgs, arg)
+		countArgIndex++
+	}
+
 	var total int
 	err = db.DB.QueryRow(countQuery, countArgs...).Scan(&total)
 	if err != nil {
@@ -558,6 +601,12 @@ func GetAdminImmunizations(c echo.Context) error {
 		argIndex++
 	}
 
+	if cond, arg := accessScopeCondition(c, "c.facility_id", argIndex); cond != "" {
+		query += cond
+		args = append(args, arg)
+		argIndex++
+	}
+
 	query += ` ORDER BY ci.immunization_date DESC LIMIT $` + strconv.Itoa(argIndex) + ` OFFSET $` + strconv.Itoa(argIndex+1)
 	args = append(args, limit, offset)
 
@@ -636,6 +685,12 @@ func GetAdminImmunizations(c echo.Context) error {
 		countArgIndex++
 	}
 
+	if cond, arg := accessScopeCondition(c, "c.facility_id", countArgIndex); cond != "" {
+		countQuery += cond
+		countArgs = append(countArgs, arg)
+		countArgIndex++
+	}
+
 	var total int
 	err = db.DB.QueryRow(countQuery, countArgs...).Scan(&total)
 	if err != nil {
@@ -652,6 +707,54 @@ func GetAdminImmunizations(c echo.Context) error {
 	})
 }
 
+// CreateAdminChildMeasurement records a measurement of a child in scope, e.g. by a kader at the posyandu
+func CreateAdminChildMeasurement(c echo.Context) error {
+	childID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+
+	if err := utils.ValidateUUID(childID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid child ID format"})
+	}
+
+	if inScope, err := childInScope(c, childID); err != nil {
+		c.Logger().Errorf("CreateAdminChildMeasurement error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	} else if !inScope {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
+	}
+
+	var child models.Child
+	err := db.DB.QueryRow("SELECT id, dob, gender, is_premature, gestational_age FROM children WHERE id = $1", childID).
+		Scan(&child.ID, &child.DOB, &child.Gender, &child.IsPremature, &child.GestationalAge)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
+	} else if err != nil {
+		c.Logger().Errorf("CreateAdminChildMeasurement error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	req := new(models.CreateMeasurementRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if msg := validateMeasurementRequest(child, req); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	response, err := createMeasurement(c, child, req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error":   "Failed to create measurement",
+			"details": err.Error(),
+		})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "create", "measurement", &response.ID, nil, response, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusCreated, response)
+}
+
 // Helper function to get float64 from sql.NullFloat64
 func getFloat64FromNull(nf sql.NullFloat64) *float64 {
 	if nf.Valid {
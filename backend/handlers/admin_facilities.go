@@ -0,0 +1,258 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetAdminFacilities lists all facilities, including inactive ones
+func GetAdminFacilities(c echo.Context) error {
+	facilities := []models.Facility{}
+	err := db.DB.Select(&facilities, `SELECT * FROM facilities ORDER BY type ASC, name ASC`)
+	if err != nil {
+		c.Logger().Errorf("GetAdminFacilities error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"facilities": facilities,
+		"total":      len(facilities),
+	})
+}
+
+// CreateAdminFacility creates a facility
+func CreateAdminFacility(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+
+	var req models.FacilityRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if msg := validateFacilityRequest(&req, ""); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var facility models.Facility
+	err := db.DB.Get(&facility, `INSERT INTO facilities (name, type, area_id, parent_id, address, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING *`,
+		req.Name, req.Type, req.AreaID, req.ParentID, req.Address, isActive)
+	if err != nil {
+		c.Logger().Errorf("CreateAdminFacility error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "create", "facility", &facility.ID, nil, facility, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message":  "Facility created successfully",
+		"facility": facility,
+	})
+}
+
+// UpdateAdminFacility updates a facility
+func UpdateAdminFacility(c echo.Context) error {
+	facilityID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+
+	if err := utils.ValidateUUID(facilityID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid facility ID format"})
+	}
+
+	var before models.Facility
+	err := db.DB.Get(&before, `SELECT * FROM facilities WHERE id = $1`, facilityID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Facility not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("UpdateAdminFacility get error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	var req models.FacilityRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if msg := validateFacilityRequest(&req, facilityID); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+	isActive := before.IsActive
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var facility models.Facility
+	err = db.DB.Get(&facility, `UPDATE facilities SET
+			name = $1, type = $2, area_id = $3, parent_id = $4, address = $5, is_active = $6,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $7
+		RETURNING *`,
+		req.Name, req.Type, req.AreaID, req.ParentID, req.Address, isActive, facilityID)
+	if err != nil {
+		c.Logger().Errorf("UpdateAdminFacility error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "update", "facility", &facilityID, before, facility, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":  "Facility updated successfully",
+		"facility": facility,
+	})
+}
+
+// GetAdminAreas lists all areas
+func GetAdminAreas(c echo.Context) error {
+	areas := []models.Area{}
+	err := db.DB.Select(&areas, `SELECT * FROM areas ORDER BY name ASC`)
+	if err != nil {
+		c.Logger().Errorf("GetAdminAreas error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"areas": areas,
+		"total": len(areas),
+	})
+}
+
+// CreateAdminArea creates an area
+func CreateAdminArea(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+
+	var req models.AreaRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.Name == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "name is required"})
+	}
+	switch req.Level {
+	case "province", "district", "subdistrict", "village":
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "level must be province, district, subdistrict or village"})
+	}
+	if req.ParentID != nil {
+		if msg := validateReference("areas", *req.ParentID, "parent_id"); msg != "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+		}
+	}
+
+	var area models.Area
+	err := db.DB.Get(&area, `INSERT INTO areas (name, level, parent_id, code) VALUES ($1, $2, $3, $4) RETURNING *`,
+		req.Name, req.Level, req.ParentID, req.Code)
+	if err != nil {
+		c.Logger().Errorf("CreateAdminArea error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "create", "area", &area.ID, nil, area, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message": "Area created successfully",
+		"area":    area,
+	})
+}
+
+// UpdateAdminChildFacility sets or clears the facility where a child is followed up
+func UpdateAdminChildFacility(c echo.Context) error {
+	childID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+
+	if err := utils.ValidateUUID(childID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid child ID format"})
+	}
+
+	var req struct {
+		FacilityID *string `json:"facility_id"`
+	}
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.FacilityID != nil {
+		if msg := validateReference("facilities", *req.FacilityID, "facility_id"); msg != "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+		}
+	}
+
+	var before *string
+	err := db.DB.QueryRow(`SELECT facility_id FROM children WHERE id = $1`, childID).Scan(&before)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
+	} else if err != nil {
+		c.Logger().Errorf("UpdateAdminChildFacility error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	if _, err := db.DB.Exec(`UPDATE children SET facility_id = $1 WHERE id = $2`, req.FacilityID, childID); err != nil {
+		c.Logger().Errorf("UpdateAdminChildFacility error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "update", "child", &childID,
+		map[string]interface{}{"facility_id": before}, map[string]interface{}{"facility_id": req.FacilityID},
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Child facility updated successfully"})
+}
+
+// validateFacilityRequest validates a facility; facilityID is empty when creating
+func validateFacilityRequest(req *models.FacilityRequest, facilityID string) string {
+	if req.Name == "" {
+		return "name is required"
+	}
+	switch req.Type {
+	case "posyandu", "puskesmas", "clinic", "hospital":
+	default:
+		return "type must be posyandu, puskesmas, clinic or hospital"
+	}
+	if req.AreaID != nil {
+		if msg := validateReference("areas", *req.AreaID, "area_id"); msg != "" {
+			return msg
+		}
+	}
+	if req.ParentID != nil {
+		if msg := validateReference("facilities", *req.ParentID, "parent_id"); msg != "" {
+			return msg
+		}
+		if facilityID != "" {
+			// The parent can't be the facility itself or one of the facilities under it
+			var cycle bool
+			err := db.DB.QueryRow(`WITH RECURSIVE below AS (
+				SELECT id FROM facilities WHERE id = $1
+				UNION
+				SELECT f.id FROM facilities f JOIN below b ON f.parent_id = b.id
+			) SELECT EXISTS(SELECT 1 FROM below WHERE id = $2)`, facilityID, *req.ParentID).Scan(&cycle)
+			if err != nil || cycle {
+				return "parent_id can't be the facility itself or a facility under it"
+			}
+		}
+	}
+	return ""
+}
+
+// validateReference checks that id is a UUID of an existing row of table
+func validateReference(table, id, field string) string {
+	if err := utils.ValidateUUID(id); err != nil {
+		return "Invalid " + field + " format"
+	}
+	var exists bool
+	err := db.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE id = $1)`, id).Scan(&exists)
+	if err != nil || !exists {
+		return field + " not found"
+	}
+	return ""
+}
@@ -0,0 +1,343 @@
+package handlers
+
+import (
+	"database/sql"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetAdminHealthProgrammes lists all health programmes, including inactive ones
+func GetAdminHealthProgrammes(c echo.Context) error {
+	programmes := []models.HealthProgramme{}
+	err := db.DB.Select(&programmes, `SELECT * FROM health_programmes ORDER BY sort_order ASC, age_min_months ASC, name ASC`)
+	if err != nil {
+		c.Logger().Errorf("GetAdminHealthProgrammes error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"programmes": programmes,
+		"total":      len(programmes),
+	})
+}
+
+// CreateAdminHealthProgramme creates a health programme
+func CreateAdminHealthProgramme(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	var req models.HealthProgrammeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if msg := validateHealthProgrammeRequest(&req); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var programme models.HealthProgramme
+	err := db.DB.Get(&programme, `INSERT INTO health_programmes (code, name, name_id, description, programme_type,
+		schedule_type, age_min_months, age_max_months, campaign_months, interval_months, dosage, source, sort_order, is_active)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, COALESCE($12, 'Kemenkes'), $13, $14)
+		RETURNING *`,
+		req.Code, req.Name, req.NameID, req.Description, req.ProgrammeType,
+		req.ScheduleType, req.AgeMinMonths, req.AgeMaxMonths, campaignMonthsParam(req), req.IntervalMonths, req.Dosage, req.Source, req.SortOrder, isActive)
+	if err != nil {
+		c.Logger().Errorf("CreateAdminHealthProgramme error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "create", "health_programme", &programme.ID, nil, programme, ipAddress, userAgent)
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message":   "Health programme created successfully",
+		"programme": programme,
+	})
+}
+
+// UpdateAdminHealthProgramme replaces a health programme's definition. Recorded doses are kept;
+// they are matched against the new schedule the next time it is calculated.
+func UpdateAdminHealthProgramme(c echo.Context) error {
+	programmeID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	if err := utils.ValidateUUID(programmeID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid programme ID format"})
+	}
+
+	var before models.HealthProgramme
+	err := db.DB.Get(&before, `SELECT * FROM health_programmes WHERE id = $1`, programmeID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Health programme not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("UpdateAdminHealthProgramme get error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	var req models.HealthProgrammeRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if msg := validateHealthProgrammeRequest(&req); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+	isActive := before.IsActive
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var programme models.HealthProgramme
+	err = db.DB.Get(&programme, `UPDATE health_programmes SET
+			code = $1, name = $2, name_id = $3, description = $4, programme_type = $5, schedule_type = $6,
+			age_min_months = $7, age_max_months = $8, campaign_months = $9, interval_months = $10,
+			dosage = $11, source = COALESCE($12, source), sort_order = $13, is_active = $14,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $15
+		RETURNING *`,
+		req.Code, req.Name, req.NameID, req.Description, req.ProgrammeType, req.ScheduleType,
+		req.AgeMinMonths, req.AgeMaxMonths, campaignMonthsParam(req), req.IntervalMonths,
+		req.Dosage, req.Source, req.SortOrder, isActive, programmeID)
+	if err != nil {
+		c.Logger().Errorf("UpdateAdminHealthProgramme error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "update", "health_programme", &programmeID, before, programme, ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":   "Health programme updated successfully",
+		"programme": programme,
+	})
+}
+
+// DeleteAdminHealthProgramme deletes a health programme that has no recorded doses.
+// Programmes with doses should be deactivated instead.
+func DeleteAdminHealthProgramme(c echo.Context) error {
+	programmeID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	if err := utils.ValidateUUID(programmeID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid programme ID format"})
+	}
+
+	var doseCount int
+	err := db.DB.QueryRow("SELECT COUNT(*) FROM child_health_programme_doses WHERE programme_id = $1", programmeID).Scan(&doseCount)
+	if err != nil {
+		c.Logger().Errorf("DeleteAdminHealthProgramme check doses error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if doseCount > 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error":      "Cannot delete health programme: it has recorded doses. Deactivate it instead",
+			"dose_count": strconv.Itoa(doseCount),
+		})
+	}
+
+	var before models.HealthProgramme
+	err = db.DB.Get(&before, `SELECT * FROM health_programmes WHERE id = $1`, programmeID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Health programme not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("DeleteAdminHealthProgramme get error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	if _, err := db.DB.Exec("DELETE FROM health_programmes WHERE id = $1", programmeID); err != nil {
+		c.Logger().Errorf("DeleteAdminHealthProgramme error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "delete", "health_programme", &programmeID, before, nil, ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Health programme deleted successfully"})
+}
+
+// GetAdminHealthProgrammesAnalytics returns the coverage of each active programme and the doses
+// recorded per month. Coverage counts, per child, the latest round that was given or has closed
+// in the last 12 months (see utils.CoverageProgrammeSlot).
+func GetAdminHealthProgrammesAnalytics(c echo.Context) error {
+	stats := make(map[string]interface{})
+
+	programmes, err := getActiveHealthProgrammes()
+	if err != nil {
+		c.Logger().Errorf("Failed to get health programmes: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	// Children young enough to be eligible for any programme
+	maxAgeMonths := 0
+	for _, programme := range programmes {
+		if programme.AgeMaxMonths > maxAgeMonths {
+			maxAgeMonths = programme.AgeMaxMonths
+		}
+	}
+	var children []struct {
+		ID  string `db:"id"`
+		DOB string `db:"dob"`
+	}
+	err = db.DB.Select(&children, `SELECT id, dob FROM children
+		WHERE dob > CURRENT_DATE - make_interval(months => $1)`, maxAgeMonths+13)
+	if err != nil {
+		c.Logger().Errorf("Failed to get children for coverage: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	var doses []struct {
+		ChildID     string `db:"child_id"`
+		ProgrammeID string `db:"programme_id"`
+		GivenDate   string `db:"given_date"`
+	}
+	err = db.DB.Select(&doses, `SELECT child_id, programme_id, given_date FROM child_health_programme_doses
+		ORDER BY given_date ASC`)
+	if err != nil {
+		c.Logger().Errorf("Failed to get health programme doses: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	today, _ := time.Parse("2006-01-02", time.Now().Format("2006-01-02"))
+	yearAgo := today.AddDate(-1, 0, 0)
+
+	given := make(map[string][]time.Time) // child_id|programme_id -> dates
+	recentDoses := make(map[string]int)   // programme_id -> doses in the last 12 months
+	for _, dose := range doses {
+		date, err := utils.ParseDate(dose.GivenDate)
+		if err != nil {
+			continue
+		}
+		key := dose.ChildID + "|" + dose.ProgrammeID
+		given[key] = append(given[key], date)
+		if !date.Before(yearAgo) {
+			recentDoses[dose.ProgrammeID]++
+		}
+	}
+
+	coverage := make([]models.HealthProgrammeCoverage, 0, len(programmes))
+	for _, programme := range programmes {
+		item := models.HealthProgrammeCoverage{
+			ProgrammeID:   programme.ID,
+			Code:          programme.Code,
+			Name:          programme.Name,
+			DosesLast12Mo: recentDoses[programme.ID],
+		}
+		for _, child := range children {
+			dob, err := utils.ParseDate(child.DOB)
+			if err != nil {
+				continue
+			}
+			slots := utils.ProgrammeSlots(programme, dob)
+			utils.EvaluateProgrammeSlots(slots, given[child.ID+"|"+programme.ID], today)
+			slot := utils.CoverageProgrammeSlot(slots, today)
+			if slot == nil {
+				continue
+			}
+			item.Eligible++
+			if slot.Status == utils.ProgrammeSlotCompleted {
+				item.Covered++
+			}
+		}
+		if item.Eligible > 0 {
+			item.CoverageRate = math.Round(float64(item.Covered)/float64(item.Eligible)*1000) / 10
+		}
+		coverage = append(coverage, item)
+	}
+
+	stats["coverage"] = coverage
+
+	// Doses by month and programme (last 12 months)
+	type MonthlyDoses struct {
+		Month       string `json:"month" db:"month"`
+		ProgrammeID string `json:"programme_id" db:"programme_id"`
+		Count       int    `json:"count" db:"count"`
+	}
+	monthlyDoses := []MonthlyDoses{}
+	err = db.DB.Select(&monthlyDoses, `
+		SELECT TO_CHAR(given_date, 'YYYY-MM') as month, programme_id, COUNT(*) as count
+		FROM child_health_programme_doses
+		WHERE given_date >= CURRENT_DATE - INTERVAL '12 months'
+		GROUP BY TO_CHAR(given_date, 'YYYY-MM'), programme_id
+		ORDER BY month`)
+	if err != nil {
+		c.Logger().Errorf("Failed to get health programme doses by month: %v", err)
+	}
+	stats["doses_by_month"] = monthlyDoses
+
+	return c.JSON(http.StatusOK, stats)
+}
+
+// validateHealthProgrammeRequest checks a health programme definition and returns an error message,
+// or "" when it is valid
+func validateHealthProgrammeRequest(req *models.HealthProgrammeRequest) string {
+	req.Code = strings.TrimSpace(req.Code)
+	req.Name = strings.TrimSpace(req.Name)
+	if req.Code == "" || req.Name == "" {
+		return "code and name are required"
+	}
+	if err := utils.ValidateStringLength(req.Code, 1, 50, "code"); err != nil {
+		return err.Error()
+	}
+
+	validType := false
+	for _, t := range utils.ValidProgrammeTypes {
+		if req.ProgrammeType == t {
+			validType = true
+		}
+	}
+	if !validType {
+		return "programme_type must be one of: " + strings.Join(utils.ValidProgrammeTypes, ", ")
+	}
+
+	if req.AgeMinMonths < 0 || req.AgeMaxMonths < req.AgeMinMonths {
+		return "age_min_months must be >= 0 and age_max_months must not be below age_min_months"
+	}
+
+	switch req.ScheduleType {
+	case utils.ProgrammeScheduleCampaign:
+		if len(req.CampaignMonths) == 0 {
+			return "campaign_months is required for campaign programmes"
+		}
+		for _, month := range req.CampaignMonths {
+			if month < 1 || month > 12 {
+				return "campaign_months must be between 1 and 12"
+			}
+		}
+		req.IntervalMonths = nil
+	case utils.ProgrammeScheduleAge:
+		if req.IntervalMonths != nil && *req.IntervalMonths < 1 {
+			return "interval_months must be at least 1"
+		}
+		req.CampaignMonths = nil
+	default:
+		return "schedule_type must be campaign or age"
+	}
+
+	return ""
+}
+
+// campaignMonthsParam converts the campaign months of a request to a SQL parameter (NULL when empty)
+func campaignMonthsParam(req models.HealthProgrammeRequest) interface{} {
+	if len(req.CampaignMonths) == 0 {
+		return nil
+	}
+	return pq.Array(req.CampaignMonths)
+}
@@ -675,6 +675,9 @@ func DeleteAdminImmunizationSchedule(c echo.Context) error {
 		sanitizedErr := utils.SanitizeError(err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
 	}
+	if err := deleteTranslations("immunization_schedule", scheduleID); err != nil {
+		c.Logger().Warnf("DeleteAdminImmunizationSchedule delete translations error: %v", err)
+	}
 
 	// Log audit
 	scheduleData := map[string]interface{}{
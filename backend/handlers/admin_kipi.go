@@ -0,0 +1,218 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// adminKIPIReport is a KIPI report with its child and vaccine for the admin view
+type adminKIPIReport struct {
+	models.KIPIReport
+	ChildName   string `json:"child_name" db:"child_name"`
+	VaccineName string `json:"vaccine_name" db:"vaccine_name"`
+	DoseNumber  int    `json:"dose_number" db:"dose_number"`
+	GivenDate   string `json:"given_date" db:"given_date"`
+}
+
+// GetAdminKIPIReports lists KIPI reports with pagination, filtered by vaccine, batch number,
+// severity, seriousness and child
+func GetAdminKIPIReports(c echo.Context) error {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 20
+	}
+	offset := (page - 1) * limit
+
+	where := ` WHERE 1=1`
+	args := []interface{}{}
+	argIndex := 1
+
+	if vaccine := c.QueryParam("vaccine"); vaccine != "" {
+		where += ` AND s.name = $` + strconv.Itoa(argIndex)
+		args = append(args, vaccine)
+		argIndex++
+	}
+	if batch := c.QueryParam("batch_number"); batch != "" {
+		where += ` AND kr.vaccine_batch_number = $` + strconv.Itoa(argIndex)
+		args = append(args, batch)
+		argIndex++
+	}
+	if severity := c.QueryParam("severity"); severity != "" {
+		where += ` AND kr.severity = $` + strconv.Itoa(argIndex)
+		args = append(args, severity)
+		argIndex++
+	}
+	if childID := c.QueryParam("child_id"); childID != "" {
+		if err := utils.ValidateUUID(childID); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid child ID format"})
+		}
+		where += ` AND kr.child_id = $` + strconv.Itoa(argIndex)
+		args = append(args, childID)
+		argIndex++
+	}
+	if isSerious := c.QueryParam("is_serious"); isSerious == "true" {
+		where += ` AND kr.is_serious = true`
+	} else if isSerious == "false" {
+		where += ` AND kr.is_serious = false`
+	}
+
+	from := ` FROM kipi_reports kr
+		JOIN children ch ON ch.id = kr.child_id
+		JOIN immunization_schedule s ON s.id = kr.immunization_schedule_id
+		JOIN child_immunizations ci ON ci.id = kr.child_immunization_id`
+
+	var total int
+	if err := db.DB.Get(&total, `SELECT COUNT(*)`+from+where, args...); err != nil {
+		c.Logger().Errorf("GetAdminKIPIReports count error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	query := `SELECT kr.id, kr.child_immunization_id, kr.child_id, kr.immunization_schedule_id, kr.reported_by,
+		kr.onset_at, kr.symptoms, kr.severity, kr.outcome, kr.vaccine_batch_number, kr.is_serious, kr.notes,
+		kr.created_at, kr.updated_at,
+		ch.name AS child_name, s.name AS vaccine_name, s.dose_number, ci.given_date` +
+		from + where +
+		` ORDER BY kr.onset_at DESC LIMIT $` + strconv.Itoa(argIndex) + ` OFFSET $` + strconv.Itoa(argIndex+1)
+	args = append(args, limit, offset)
+
+	reports := []adminKIPIReport{}
+	if err := db.DB.Select(&reports, query, args...); err != nil {
+		c.Logger().Errorf("GetAdminKIPIReports query error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"reports": reports,
+		"pagination": map[string]interface{}{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// GetAdminKIPIClusters groups KIPI reports by vaccine and batch number so clusters stand out.
+// Optional filters: days (onset within the last N days) and min_reports (default 2).
+func GetAdminKIPIClusters(c echo.Context) error {
+	minReports, _ := strconv.Atoi(c.QueryParam("min_reports"))
+	if minReports < 1 {
+		minReports = 2
+	}
+
+	query := `SELECT s.name AS vaccine_name, kr.vaccine_batch_number,
+		COUNT(*) AS report_count,
+		COUNT(*) FILTER (WHERE kr.is_serious) AS serious_count,
+		COUNT(DISTINCT kr.child_id) AS child_count,
+		MIN(kr.onset_at) AS first_onset_at,
+		MAX(kr.onset_at) AS last_onset_at
+		FROM kipi_reports kr
+		JOIN immunization_schedule s ON s.id = kr.immunization_schedule_id
+		WHERE 1=1`
+	args := []interface{}{}
+	argIndex := 1
+
+	if days, _ := strconv.Atoi(c.QueryParam("days")); days > 0 {
+		query += ` AND kr.onset_at >= $` + strconv.Itoa(argIndex)
+		args = append(args, time.Now().AddDate(0, 0, -days))
+		argIndex++
+	}
+
+	query += ` GROUP BY s.name, kr.vaccine_batch_number
+		HAVING COUNT(*) >= $` + strconv.Itoa(argIndex) + `
+		ORDER BY serious_count DESC, report_count DESC, last_onset_at DESC`
+	args = append(args, minReports)
+
+	clusters := []models.KIPICluster{}
+	if err := db.DB.Select(&clusters, query, args...); err != nil {
+		c.Logger().Errorf("GetAdminKIPIClusters query error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"clusters":    clusters,
+		"total":       len(clusters),
+		"min_reports": minReports,
+	})
+}
+
+// GetAdminImmunizationReviewFlags lists doses flagged for clinician review (default: pending)
+func GetAdminImmunizationReviewFlags(c echo.Context) error {
+	status := c.QueryParam("status")
+	if status == "" {
+		status = "pending"
+	}
+
+	flags := []models.ImmunizationReviewFlag{}
+	err := db.DB.Select(&flags, `SELECT `+reviewFlagColumns+` FROM immunization_review_flags
+		WHERE status = $1 ORDER BY created_at DESC`, status)
+	if err != nil {
+		c.Logger().Errorf("GetAdminImmunizationReviewFlags query error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"review_flags": flags,
+		"total":        len(flags),
+	})
+}
+
+// ReviewAdminImmunizationReviewFlag records the clinician decision on a flagged dose:
+// cleared (the dose can be given) or contraindicated
+func ReviewAdminImmunizationReviewFlag(c echo.Context) error {
+	flagID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	// Validate UUID format
+	if err := utils.ValidateUUID(flagID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid review flag ID format"})
+	}
+
+	var req models.ReviewImmunizationFlagRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.Status != "cleared" && req.Status != "contraindicated" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "status must be cleared or contraindicated"})
+	}
+
+	var before models.ImmunizationReviewFlag
+	err := db.DB.Get(&before, `SELECT `+reviewFlagColumns+` FROM immunization_review_flags WHERE id = $1`, flagID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Review flag not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("ReviewAdminImmunizationReviewFlag get error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	var flag models.ImmunizationReviewFlag
+	err = db.DB.Get(&flag, `
+		UPDATE immunization_review_flags
+		SET status = $1, review_notes = $2, reviewed_by = $3, reviewed_at = NOW()
+		WHERE id = $4
+		RETURNING `+reviewFlagColumns,
+		req.Status, req.ReviewNotes, adminUserID, flagID)
+	if err != nil {
+		c.Logger().Errorf("ReviewAdminImmunizationReviewFlag update error: %v", err)
+		sanitizedErr := utils.SanitizeError(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "update", "immunization_review_flag", &flagID, before, flag, ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, flag)
+}
@@ -556,6 +556,9 @@ func DeleteAdminMilestone(c echo.Context) error {
 		sanitizedErr := utils.SanitizeError(err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
 	}
+	if err := deleteTranslations("milestone", milestoneID); err != nil {
+		c.Logger().Warnf("DeleteAdminMilestone delete translations error: %v", err)
+	}
 
 	// Log audit
 	milestoneData := map[string]interface{}{
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/services"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+var notificationChannels = []string{services.ChannelWhatsApp, services.ChannelEmail, services.ChannelSMS}
+
+// GetAdminNotificationTemplates lists notification templates, filtered by key, channel and language
+func GetAdminNotificationTemplates(c echo.Context) error {
+	query := `SELECT * FROM notification_templates WHERE 1=1`
+	args := []interface{}{}
+	argIndex := 1
+
+	for _, filter := range []string{"key", "channel", "language"} {
+		if value := c.QueryParam(filter); value != "" {
+			query += ` AND ` + filter + ` = $` + strconv.Itoa(argIndex)
+			args = append(args, value)
+			argIndex++
+		}
+	}
+	query += ` ORDER BY key, channel, language`
+
+	templates := []models.NotificationTemplate{}
+	if err := db.DB.Select(&templates, query, args...); err != nil {
+		c.Logger().Errorf("GetAdminNotificationTemplates error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"templates": templates,
+		"total":     len(templates),
+	})
+}
+
+// UpsertAdminNotificationTemplate creates or replaces the template for a key, channel and language
+func UpsertAdminNotificationTemplate(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	var req models.NotificationTemplateRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	req.Key = strings.TrimSpace(req.Key)
+	if err := utils.ValidateStringLength(req.Key, 1, 100, "key"); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if !isNotificationChannel(req.Channel) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "channel must be one of: " + strings.Join(notificationChannels, ", ")})
+	}
+	if req.Language = utils.NormalizeLanguage(req.Language); req.Language == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unsupported language"})
+	}
+	if strings.TrimSpace(req.Body) == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "body is required"})
+	}
+	if req.Channel == services.ChannelEmail && (req.Subject == nil || strings.TrimSpace(*req.Subject) == "") {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "subject is required for email templates"})
+	}
+	if req.Channel != services.ChannelEmail {
+		req.Subject = nil
+	}
+
+	// Reject templates that do not parse
+	if _, err := services.ParseTemplateText(req.Body); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid body template: " + err.Error()})
+	}
+	if req.Subject != nil {
+		if _, err := services.ParseTemplateText(*req.Subject); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid subject template: " + err.Error()})
+		}
+	}
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	var before interface{}
+	var existing models.NotificationTemplate
+	if err := db.DB.Get(&existing, `SELECT * FROM notification_templates WHERE key = $1 AND channel = $2 AND language = $3`,
+		req.Key, req.Channel, req.Language); err == nil {
+		before = existing
+	}
+
+	var template models.NotificationTemplate
+	err := db.DB.Get(&template, `INSERT INTO notification_templates (key, channel, language, subject, body, is_active, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		ON CONFLICT (key, channel, language) DO UPDATE SET
+			subject = EXCLUDED.subject, body = EXCLUDED.body, is_active = EXCLUDED.is_active,
+			updated_by = EXCLUDED.updated_by, updated_at = CURRENT_TIMESTAMP
+		RETURNING *`,
+		req.Key, req.Channel, req.Language, req.Subject, req.Body, isActive, adminUserID)
+	if err != nil {
+		c.Logger().Errorf("UpsertAdminNotificationTemplate error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	// Log audit
+	action := "create"
+	if before != nil {
+		action = "update"
+	}
+	utils.LogAudit(adminUserID, action, "notification_template", &template.ID, before, template, ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":  "Notification template saved successfully",
+		"template": template,
+	})
+}
+
+// SendAdminTestNotification renders a template and sends it to a recipient through the configured
+// provider, so admins can check templates and credentials. Disabled channels are still sent.
+func SendAdminTestNotification(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	var req models.TestNotificationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if !isNotificationChannel(req.Channel) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "channel must be one of: " + strings.Join(notificationChannels, ", ")})
+	}
+	if strings.TrimSpace(req.To) == "" || req.Key == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "to and key are required"})
+	}
+	lang := utils.NormalizeLanguage(req.Language)
+	if lang == "" {
+		lang = utils.DefaultLanguage
+	}
+
+	service := notificationService()
+	msg, err := service.Render(req.Key, req.Channel, lang, req.Data)
+	if errors.Is(err, services.ErrTemplateNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Notification template not found"})
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	msg.To = strings.TrimSpace(req.To)
+
+	notifier, err := service.Notifier(req.Channel)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := notifier.Send(msg); err != nil {
+		c.Logger().Errorf("SendAdminTestNotification error: %v", err)
+		return c.JSON(http.StatusBadGateway, map[string]string{"error": "Failed to send notification: " + err.Error()})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "test_send", "notification_template", nil, nil, map[string]string{
+		"channel": req.Channel,
+		"key":     req.Key,
+		"to":      msg.To,
+	}, ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":      "Notification sent",
+		"notification": msg,
+	})
+}
+
+func isNotificationChannel(channel string) bool {
+	for _, ch := range notificationChannels {
+		if channel == ch {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,156 @@
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/services"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// sensitiveBodyMask replaces the body of sensitive messages (OTP codes) in admin responses
+const sensitiveBodyMask = "[redacted]"
+
+// GetAdminOutboundMessages lists the outbox, filtered by status, channel, recipient and template
+// key, with counts per status
+func GetAdminOutboundMessages(c echo.Context) error {
+	page, _ := strconv.Atoi(c.QueryParam("page"))
+	if page < 1 {
+		page = 1
+	}
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	if limit < 1 || limit > 100 {
+		limit = 50
+	}
+	offset := (page - 1) * limit
+
+	where := ` WHERE 1=1`
+	args := []interface{}{}
+	argIndex := 1
+	for _, filter := range []struct{ param, column string }{
+		{"status", "status"},
+		{"channel", "channel"},
+		{"recipient", "recipient"},
+		{"template_key", "template_key"},
+	} {
+		if value := c.QueryParam(filter.param); value != "" {
+			where += ` AND ` + filter.column + ` = $` + strconv.Itoa(argIndex)
+			args = append(args, value)
+			argIndex++
+		}
+	}
+
+	var total int
+	if err := db.DB.Get(&total, `SELECT COUNT(*) FROM outbound_messages`+where, args...); err != nil {
+		c.Logger().Errorf("GetAdminOutboundMessages count error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	messages := []models.OutboundMessage{}
+	query := `SELECT * FROM outbound_messages` + where +
+		` ORDER BY created_at DESC LIMIT $` + strconv.Itoa(argIndex) + ` OFFSET $` + strconv.Itoa(argIndex+1)
+	if err := db.DB.Select(&messages, query, append(args, limit, offset)...); err != nil {
+		c.Logger().Errorf("GetAdminOutboundMessages error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	for i := range messages {
+		redactOutboundMessage(&messages[i])
+	}
+
+	// Counts per status across the whole outbox, for the queue overview
+	var statusRows []struct {
+		Status string `db:"status"`
+		Count  int    `db:"count"`
+	}
+	if err := db.DB.Select(&statusRows, `SELECT status, COUNT(*) AS count FROM outbound_messages GROUP BY status`); err != nil {
+		c.Logger().Errorf("GetAdminOutboundMessages status count error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	statusCounts := map[string]int{}
+	for _, row := range statusRows {
+		statusCounts[row.Status] = row.Count
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"messages":      messages,
+		"status_counts": statusCounts,
+		"pagination": map[string]interface{}{
+			"page":  page,
+			"limit": limit,
+			"total": total,
+		},
+	})
+}
+
+// GetAdminOutboundMessage returns a message with its delivery history
+func GetAdminOutboundMessage(c echo.Context) error {
+	id := c.Param("id")
+	if err := utils.ValidateUUID(id); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid message ID format"})
+	}
+
+	var message models.OutboundMessage
+	if err := db.DB.Get(&message, `SELECT * FROM outbound_messages WHERE id = $1`, id); err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Outbound message not found"})
+	}
+	redactOutboundMessage(&message)
+
+	events := []models.OutboundMessageEvent{}
+	if err := db.DB.Select(&events, `SELECT * FROM outbound_message_events WHERE message_id = $1 ORDER BY created_at`, id); err != nil {
+		c.Logger().Errorf("GetAdminOutboundMessage events error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": message,
+		"events":  events,
+	})
+}
+
+// RetryAdminOutboundMessage queues a failed or dead message again with a fresh set of attempts
+func RetryAdminOutboundMessage(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	id := c.Param("id")
+	if err := utils.ValidateUUID(id); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid message ID format"})
+	}
+
+	message, err := notificationService().RetryOutboundMessage(id)
+	if errors.Is(err, services.ErrOutboundMessageNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Outbound message not found"})
+	}
+	if errors.Is(err, services.ErrOutboundMessageNotRetryable) {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Only failed or dead messages that have not expired can be retried"})
+	}
+	if err != nil {
+		c.Logger().Errorf("RetryAdminOutboundMessage error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+	redactOutboundMessage(message)
+
+	// Log audit
+	utils.LogAudit(adminUserID, "retry", "outbound_message", &message.ID, nil, map[string]string{
+		"channel":   message.Channel,
+		"recipient": message.Recipient,
+	}, ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":          "Outbound message queued for retry",
+		"outbound_message": message,
+	})
+}
+
+// redactOutboundMessage hides the body of sensitive messages that are still pending
+func redactOutboundMessage(message *models.OutboundMessage) {
+	if message.IsSensitive && message.Body != nil {
+		mask := sensitiveBodyMask
+		message.Body = &mask
+	}
+}
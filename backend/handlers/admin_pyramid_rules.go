@@ -0,0 +1,323 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetAdminPyramidRules returns all pyramid warning rules
+func GetAdminPyramidRules(c echo.Context) error {
+	query := `SELECT ` + pyramidRuleColumns + ` FROM pyramid_warning_rules WHERE 1=1`
+
+	isActive := c.QueryParam("is_active")
+	if isActive == "true" {
+		query += ` AND is_active = true`
+	} else if isActive == "false" {
+		query += ` AND is_active = false`
+	}
+
+	query += ` ORDER BY sort_order ASC, created_at ASC`
+
+	rows, err := db.DB.Query(query)
+	if err != nil {
+		c.Logger().Errorf("GetAdminPyramidRules query error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	defer rows.Close()
+
+	rules := []models.PyramidWarningRule{}
+	for rows.Next() {
+		rule, err := scanPyramidRule(rows)
+		if err != nil {
+			c.Logger().Errorf("Failed to scan pyramid rule row: %v", err)
+			continue
+		}
+		rules = append(rules, rule)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"rules": rules,
+		"total": len(rules),
+	})
+}
+
+// GetAdminPyramidRule returns a single pyramid warning rule
+func GetAdminPyramidRule(c echo.Context) error {
+	ruleID := c.Param("id")
+
+	// Validate UUID format
+	if err := utils.ValidateUUID(ruleID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid rule ID format"})
+	}
+
+	rule, err := scanPyramidRule(db.DB.QueryRow(`SELECT `+pyramidRuleColumns+` FROM pyramid_warning_rules WHERE id = $1`, ruleID))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Pyramid rule not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("GetAdminPyramidRule error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, rule)
+}
+
+// CreateAdminPyramidRule creates a new pyramid warning rule
+func CreateAdminPyramidRule(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	var req struct {
+		Name        string                        `json:"name" validate:"required"`
+		Description *string                       `json:"description"`
+		Conditions  []models.PyramidRuleCondition `json:"conditions" validate:"required,min=1"`
+		Severity    string                        `json:"severity"`
+		Message     string                        `json:"message" validate:"required"`
+		MessageEn   *string                       `json:"message_en"`
+		IsActive    *bool                         `json:"is_active"`
+		SortOrder   int                           `json:"sort_order"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	if err := utils.ValidateStringLength(req.Name, 1, 255, "name"); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := utils.ValidateStringLength(req.Message, 1, 2000, "message"); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if err := utils.ValidatePyramidRuleConditions(req.Conditions); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if req.Severity == "" {
+		req.Severity = "warning"
+	}
+	if err := utils.ValidatePyramidRuleSeverity(req.Severity); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	isActive := true
+	if req.IsActive != nil {
+		isActive = *req.IsActive
+	}
+
+	conditionsJSON, err := json.Marshal(req.Conditions)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid conditions"})
+	}
+
+	var ruleID string
+	err = db.DB.QueryRow(
+		`INSERT INTO pyramid_warning_rules (name, description, conditions, severity, message, message_en, is_active, sort_order)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 RETURNING id`,
+		req.Name, req.Description, string(conditionsJSON), req.Severity, req.Message, req.MessageEn, isActive, req.SortOrder,
+	).Scan(&ruleID)
+
+	if err != nil {
+		c.Logger().Errorf("CreateAdminPyramidRule error: %v", err)
+		sanitizedErr := utils.SanitizeError(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
+	}
+
+	// Log audit
+	ruleData := map[string]interface{}{
+		"name":       req.Name,
+		"conditions": req.Conditions,
+		"severity":   req.Severity,
+		"is_active":  isActive,
+	}
+	utils.LogAudit(adminUserID, "create", "pyramid_rule", &ruleID, nil, ruleData, ipAddress, userAgent)
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"id":      ruleID,
+		"message": "Pyramid rule created successfully",
+	})
+}
+
+// UpdateAdminPyramidRule updates a pyramid warning rule
+func UpdateAdminPyramidRule(c echo.Context) error {
+	ruleID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	// Validate UUID format
+	if err := utils.ValidateUUID(ruleID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid rule ID format"})
+	}
+
+	// Get existing rule for audit log
+	existing, err := scanPyramidRule(db.DB.QueryRow(`SELECT `+pyramidRuleColumns+` FROM pyramid_warning_rules WHERE id = $1`, ruleID))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Pyramid rule not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("UpdateAdminPyramidRule get existing error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	var req struct {
+		Name        *string                        `json:"name"`
+		Description *string                        `json:"description"`
+		Conditions  *[]models.PyramidRuleCondition `json:"conditions"`
+		Severity    *string                        `json:"severity"`
+		Message     *string                        `json:"message"`
+		MessageEn   *string                        `json:"message_en"`
+		IsActive    *bool                          `json:"is_active"`
+		SortOrder   *int                           `json:"sort_order"`
+	}
+
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	// Build update query dynamically
+	updateFields := []string{}
+	args := []interface{}{}
+	argIndex := 1
+	afterData := map[string]interface{}{}
+
+	if req.Name != nil {
+		if err := utils.ValidateStringLength(*req.Name, 1, 255, "name"); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		updateFields = append(updateFields, "name = $"+strconv.Itoa(argIndex))
+		args = append(args, *req.Name)
+		argIndex++
+		afterData["name"] = *req.Name
+	}
+	if req.Description != nil {
+		updateFields = append(updateFields, "description = $"+strconv.Itoa(argIndex))
+		args = append(args, *req.Description)
+		argIndex++
+	}
+	if req.Conditions != nil {
+		if err := utils.ValidatePyramidRuleConditions(*req.Conditions); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		conditionsJSON, err := json.Marshal(*req.Conditions)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid conditions"})
+		}
+		updateFields = append(updateFields, "conditions = $"+strconv.Itoa(argIndex))
+		args = append(args, string(conditionsJSON))
+		argIndex++
+		afterData["conditions"] = *req.Conditions
+	}
+	if req.Severity != nil {
+		if err := utils.ValidatePyramidRuleSeverity(*req.Severity); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		updateFields = append(updateFields, "severity = $"+strconv.Itoa(argIndex))
+		args = append(args, *req.Severity)
+		argIndex++
+		afterData["severity"] = *req.Severity
+	}
+	if req.Message != nil {
+		if err := utils.ValidateStringLength(*req.Message, 1, 2000, "message"); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		updateFields = append(updateFields, "message = $"+strconv.Itoa(argIndex))
+		args = append(args, *req.Message)
+		argIndex++
+	}
+	if req.MessageEn != nil {
+		updateFields = append(updateFields, "message_en = $"+strconv.Itoa(argIndex))
+		args = append(args, *req.MessageEn)
+		argIndex++
+	}
+	if req.IsActive != nil {
+		updateFields = append(updateFields, "is_active = $"+strconv.Itoa(argIndex))
+		args = append(args, *req.IsActive)
+		argIndex++
+		afterData["is_active"] = *req.IsActive
+	}
+	if req.SortOrder != nil {
+		updateFields = append(updateFields, "sort_order = $"+strconv.Itoa(argIndex))
+		args = append(args, *req.SortOrder)
+		argIndex++
+	}
+
+	if len(updateFields) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "No fields to update"})
+	}
+
+	args = append(args, ruleID)
+	query := `UPDATE pyramid_warning_rules SET ` + updateFields[0]
+	for i := 1; i < len(updateFields); i++ {
+		query += `, ` + updateFields[i]
+	}
+	query += `, updated_at = NOW() WHERE id = $` + strconv.Itoa(argIndex)
+
+	_, err = db.DB.Exec(query, args...)
+	if err != nil {
+		c.Logger().Errorf("UpdateAdminPyramidRule error: %v", err)
+		sanitizedErr := utils.SanitizeError(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
+	}
+
+	// Log audit
+	beforeData := map[string]interface{}{
+		"name":       existing.Name,
+		"conditions": existing.Conditions,
+		"severity":   existing.Severity,
+		"is_active":  existing.IsActive,
+	}
+	utils.LogAudit(adminUserID, "update", "pyramid_rule", &ruleID, beforeData, afterData, ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Pyramid rule updated successfully"})
+}
+
+// DeleteAdminPyramidRule deletes a pyramid warning rule
+func DeleteAdminPyramidRule(c echo.Context) error {
+	ruleID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	// Validate UUID format
+	if err := utils.ValidateUUID(ruleID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid rule ID format"})
+	}
+
+	// Get rule data for audit log
+	rule, err := scanPyramidRule(db.DB.QueryRow(`SELECT `+pyramidRuleColumns+` FROM pyramid_warning_rules WHERE id = $1`, ruleID))
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Pyramid rule not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("DeleteAdminPyramidRule get rule error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	_, err = db.DB.Exec("DELETE FROM pyramid_warning_rules WHERE id = $1", ruleID)
+	if err != nil {
+		c.Logger().Errorf("DeleteAdminPyramidRule error: %v", err)
+		sanitizedErr := utils.SanitizeError(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
+	}
+	if err := deleteTranslations("pyramid_rule", ruleID); err != nil {
+		c.Logger().Warnf("DeleteAdminPyramidRule delete translations error: %v", err)
+	}
+
+	// Log audit
+	ruleData := map[string]interface{}{
+		"name":       rule.Name,
+		"conditions": rule.Conditions,
+		"severity":   rule.Severity,
+	}
+	utils.LogAudit(adminUserID, "delete", "pyramid_rule", &ruleID, ruleData, nil, ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Pyramid rule deleted successfully"})
+}
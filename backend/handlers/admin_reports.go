@@ -238,6 +238,12 @@ func GetChildrenReport(c echo.Context) error {
 		argIndex++
 	}
 
+	if cond, arg := accessScopeCondition(c, "c.facility_id", argIndex); cond != "" {
+		query += cond
+		args = append(args, arg)
+		argIndex++
+	}
+
 	query += ` GROUP BY c.id, c.name, c.dob, c.gender, c.birth_weight, c.birth_height, c.is_premature, c.gestational_age, c.created_at, u.full_name, u.email, u.phone_number ORDER BY c.created_at DESC`
 
 	rows, err := db.DB.Query(query, args...)
@@ -343,6 +349,12 @@ func GetGrowthReport(c echo.Context) error {
 		argIndex++
 	}
 
+	if cond, arg := accessScopeCondition(c, "c.facility_id", argIndex); cond != "" {
+		query += cond
+		args = append(args, arg)
+		argIndex++
+	}
+
 	query += ` ORDER BY m.measurement_date DESC`
 
 	rows, err := db.DB.Query(query, args...)
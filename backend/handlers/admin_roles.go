@@ -0,0 +1,272 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"sort"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetAdminRoles lists the health-worker roles with their permissions, and every permission with
+// whether it can be held for a facility or area
+func GetAdminRoles(c echo.Context) error {
+	var rows []struct {
+		Role       string `db:"role"`
+		Permission string `db:"permission"`
+	}
+	err := db.DB.Select(&rows, `SELECT role, permission FROM role_permissions ORDER BY role, permission`)
+	if err != nil {
+		c.Logger().Errorf("GetAdminRoles error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	byRole := map[string][]string{}
+	for _, r := range rows {
+		byRole[r.Role] = append(byRole[r.Role], r.Permission)
+	}
+	roles := []models.RolePermissions{}
+	for _, role := range utils.HealthWorkerRoles {
+		permissions := byRole[role]
+		if permissions == nil {
+			permissions = []string{}
+		}
+		roles = append(roles, models.RolePermissions{Role: role, Permissions: permissions})
+	}
+
+	permissions := []map[string]interface{}{}
+	for _, permission := range sortedPermissions() {
+		permissions = append(permissions, map[string]interface{}{
+			"permission": permission,
+			"scoped":     utils.Permissions[permission],
+		})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"roles":       roles,
+		"permissions": permissions,
+	})
+}
+
+// UpdateAdminRolePermissions replaces the permissions of a health-worker role
+func UpdateAdminRolePermissions(c echo.Context) error {
+	role := c.Param("role")
+	adminUserID := c.Get("user_id").(string)
+
+	if !utils.IsHealthWorkerRole(role) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid role"})
+	}
+
+	var req models.UpdateRolePermissionsRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	for _, permission := range req.Permissions {
+		if _, ok := utils.Permissions[permission]; !ok {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Unknown permission: " + permission})
+		}
+	}
+
+	before := []string{}
+	if err := db.DB.Select(&before, `SELECT permission FROM role_permissions WHERE role = $1 ORDER BY permission`, role); err != nil {
+		c.Logger().Errorf("UpdateAdminRolePermissions error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`DELETE FROM role_permissions WHERE role = $1`, role); err != nil {
+		c.Logger().Errorf("UpdateAdminRolePermissions error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+	for _, permission := range req.Permissions {
+		_, err := tx.Exec(`INSERT INTO role_permissions (role, permission) VALUES ($1, $2)
+		                   ON CONFLICT (role, permission) DO NOTHING`, role, permission)
+		if err != nil {
+			c.Logger().Errorf("UpdateAdminRolePermissions error: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	after := models.RolePermissions{Role: role, Permissions: req.Permissions}
+	utils.LogAudit(adminUserID, "update", "role_permissions", nil,
+		models.RolePermissions{Role: role, Permissions: before}, after, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Role permissions updated successfully",
+		"role":    after,
+	})
+}
+
+// GetAdminUserRoles lists the role assignments of a user
+func GetAdminUserRoles(c echo.Context) error {
+	userID := c.Param("id")
+	if err := utils.ValidateUUID(userID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	assignments, err := getRoleAssignments(`ra.user_id = $1`, userID)
+	if err != nil {
+		c.Logger().Errorf("GetAdminUserRoles error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"assignments": assignments,
+	})
+}
+
+// CreateAdminUserRole assigns a health-worker role to a user for a facility, an area, or
+// everywhere. A parent becomes a user of that role.
+func CreateAdminUserRole(c echo.Context) error {
+	userID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+
+	if err := utils.ValidateUUID(userID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	var req models.CreateRoleAssignmentRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if !utils.IsHealthWorkerRole(req.Role) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "role must be kader, bidan, nutritionist or district_viewer"})
+	}
+	if req.FacilityID != nil && req.AreaID != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Set either facility_id or area_id, not both"})
+	}
+	if req.FacilityID != nil {
+		if msg := validateReference("facilities", *req.FacilityID, "facility_id"); msg != "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+		}
+	}
+	if req.AreaID != nil {
+		if msg := validateReference("areas", *req.AreaID, "area_id"); msg != "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+		}
+	}
+
+	var userRole string
+	err := db.DB.QueryRow(`SELECT role FROM users WHERE id = $1`, userID).Scan(&userRole)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+	} else if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	var duplicate bool
+	err = db.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM role_assignments
+	                      WHERE user_id = $1 AND role = $2
+	                      AND facility_id IS NOT DISTINCT FROM $3 AND area_id IS NOT DISTINCT FROM $4)`,
+		userID, req.Role, req.FacilityID, req.AreaID).Scan(&duplicate)
+	if err != nil {
+		c.Logger().Errorf("CreateAdminUserRole error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if duplicate {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "User already has this role assignment"})
+	}
+
+	var assignmentID string
+	err = db.DB.QueryRow(`INSERT INTO role_assignments (user_id, role, facility_id, area_id, assigned_by)
+	                      VALUES ($1, $2, $3, $4, $5) RETURNING id`,
+		userID, req.Role, req.FacilityID, req.AreaID, adminUserID).Scan(&assignmentID)
+	if err != nil {
+		c.Logger().Errorf("CreateAdminUserRole error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	if userRole == "parent" {
+		if _, err := db.DB.Exec(`UPDATE users SET role = $1 WHERE id = $2`, req.Role, userID); err != nil {
+			c.Logger().Errorf("CreateAdminUserRole error: %v", err)
+		}
+	}
+
+	assignments, err := getRoleAssignments(`ra.id = $1`, assignmentID)
+	if err != nil || len(assignments) == 0 {
+		c.Logger().Errorf("CreateAdminUserRole error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "create", "role_assignment", &assignmentID, nil, assignments[0], c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message":    "Role assigned successfully",
+		"assignment": assignments[0],
+	})
+}
+
+// DeleteAdminUserRole removes a role assignment. A user left without health-worker assignments
+// becomes a parent again.
+func DeleteAdminUserRole(c echo.Context) error {
+	userID := c.Param("id")
+	assignmentID := c.Param("assignment_id")
+	adminUserID := c.Get("user_id").(string)
+
+	if err := utils.ValidateUUID(userID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+	if err := utils.ValidateUUID(assignmentID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid assignment ID format"})
+	}
+
+	assignments, err := getRoleAssignments(`ra.id = $1 AND ra.user_id = $2`, assignmentID, userID)
+	if err != nil {
+		c.Logger().Errorf("DeleteAdminUserRole error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if len(assignments) == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Role assignment not found"})
+	}
+
+	if _, err := db.DB.Exec(`DELETE FROM role_assignments WHERE id = $1`, assignmentID); err != nil {
+		c.Logger().Errorf("DeleteAdminUserRole error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	_, err = db.DB.Exec(`UPDATE users SET role = 'parent'
+	                     WHERE id = $1 AND role IN ('kader', 'bidan', 'nutritionist', 'district_viewer')
+	                     AND NOT EXISTS (SELECT 1 FROM role_assignments WHERE user_id = $1)`, userID)
+	if err != nil {
+		c.Logger().Errorf("DeleteAdminUserRole error: %v", err)
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "delete", "role_assignment", &assignmentID, assignments[0], nil, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Role assignment removed successfully"})
+}
+
+// getRoleAssignments returns the role assignments matching the condition on ra (role_assignments)
+func getRoleAssignments(condition string, args ...interface{}) ([]models.RoleAssignment, error) {
+	assignments := []models.RoleAssignment{}
+	err := db.DB.Select(&assignments, `SELECT ra.id, ra.user_id, ra.role, ra.facility_id, f.name AS facility_name,
+	                                   ra.area_id, a.name AS area_name, ra.assigned_by, ra.created_at
+	                                   FROM role_assignments ra
+	                                   LEFT JOIN facilities f ON f.id = ra.facility_id
+	                                   LEFT JOIN areas a ON a.id = ra.area_id
+	                                   WHERE `+condition+`
+	                                   ORDER BY ra.created_at ASC`, args...)
+	return assignments, err
+}
+
+func sortedPermissions() []string {
+	permissions := make([]string, 0, len(utils.Permissions))
+	for permission := range utils.Permissions {
+		permissions = append(permissions, permission)
+	}
+	sort.Strings(permissions)
+	return permissions
+}
@@ -64,6 +64,7 @@ func GetSystemSettings(c echo.Context) error {
 		if updatedAt.Valid {
 			s.UpdatedAt = updatedAt.Time.Format("2006-01-02T15:04:05Z07:00")
 		}
+		s.Value = maskSecretSetting(s.Type, s.Value)
 
 		settings = append(settings, s)
 	}
@@ -117,6 +118,7 @@ func GetSystemSetting(c echo.Context) error {
 	if updatedAt.Valid {
 		setting.UpdatedAt = updatedAt.Time.Format("2006-01-02T15:04:05Z07:00")
 	}
+	setting.Value = maskSecretSetting(setting.Type, setting.Value)
 
 	return c.JSON(http.StatusOK, setting)
 }
@@ -163,8 +165,8 @@ func UpdateSystemSetting(c echo.Context) error {
 	}
 
 	// Get existing setting for audit log
-	var oldValue string
-	err := db.DB.QueryRow("SELECT value FROM system_settings WHERE key = $1", key).Scan(&oldValue)
+	var oldValue, settingType string
+	err := db.DB.QueryRow("SELECT COALESCE(value, ''), type FROM system_settings WHERE key = $1", key).Scan(&oldValue, &settingType)
 	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Setting not found"})
 	}
@@ -172,6 +174,10 @@ func UpdateSystemSetting(c echo.Context) error {
 		c.Logger().Errorf("UpdateSystemSetting get old value error: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
 	}
+	// The masked value sent back unchanged keeps the stored secret
+	if settingType == "secret" && valueStr == secretSettingMask {
+		return c.JSON(http.StatusOK, map[string]string{"message": "Setting updated successfully"})
+	}
 
 	// Update setting
 	_, err = db.DB.Exec(
@@ -186,8 +192,8 @@ func UpdateSystemSetting(c echo.Context) error {
 	}
 
 	// Log audit
-	beforeData := map[string]string{"value": oldValue}
-	afterData := map[string]string{"value": valueStr}
+	beforeData := map[string]string{"value": maskSecretSetting(settingType, oldValue)}
+	afterData := map[string]string{"value": maskSecretSetting(settingType, valueStr)}
 	utils.LogAudit(adminUserID, "update", "system_setting", &key, beforeData, afterData, ipAddress, userAgent)
 
 	return c.JSON(http.StatusOK, map[string]string{"message": "Setting updated successfully"})
@@ -210,12 +216,15 @@ func UpdateSystemSettingsBatch(c echo.Context) error {
 	// Update each setting
 	for key, value := range req.Settings {
 		// Get old value for audit
-		var oldValue string
-		err := db.DB.QueryRow("SELECT value FROM system_settings WHERE key = $1", key).Scan(&oldValue)
+		var oldValue, settingType string
+		err := db.DB.QueryRow("SELECT COALESCE(value, ''), type FROM system_settings WHERE key = $1", key).Scan(&oldValue, &settingType)
 		if err != nil {
 			c.Logger().Errorf("UpdateSystemSettingsBatch get old value for %s error: %v", key, err)
 			continue
 		}
+		if settingType == "secret" && value == secretSettingMask {
+			continue
+		}
 
 		// Update
 		_, err = db.DB.Exec(
@@ -230,11 +239,20 @@ func UpdateSystemSettingsBatch(c echo.Context) error {
 		}
 
 		// Log audit
-		beforeData := map[string]string{"value": oldValue}
-		afterData := map[string]string{"value": value}
+		beforeData := map[string]string{"value": maskSecretSetting(settingType, oldValue)}
+		afterData := map[string]string{"value": maskSecretSetting(settingType, value)}
 		utils.LogAudit(adminUserID, "update", "system_setting", &key, beforeData, afterData, ipAddress, userAgent)
 	}
 
 	return c.JSON(http.StatusOK, map[string]string{"message": "Settings updated successfully"})
 }
 
+// secretSettingMask replaces the value of secret settings (API keys, passwords) in responses and audit logs
+const secretSettingMask = "********"
+
+func maskSecretSetting(settingType, value string) string {
+	if settingType == "secret" && value != "" {
+		return secretSettingMask
+	}
+	return value
+}
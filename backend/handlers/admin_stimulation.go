@@ -513,6 +513,9 @@ func DeleteAdminStimulationContent(c echo.Context) error {
 		sanitizedErr := utils.SanitizeError(err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
 	}
+	if err := deleteTranslations("stimulation_content", contentID); err != nil {
+		c.Logger().Warnf("DeleteAdminStimulationContent delete translations error: %v", err)
+	}
 
 	// Log audit
 	contentData := map[string]interface{}{
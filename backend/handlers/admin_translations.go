@@ -0,0 +1,178 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+const translationColumns = `id, entity_type, entity_id, field, language, value, created_at, updated_at`
+
+// translatableTables maps translatable entity types to their tables
+var translatableTables = map[string]string{
+	"milestone":             "milestones",
+	"stimulation_content":   "stimulation_content",
+	"immunization_schedule": "immunization_schedule",
+	"pyramid_rule":          "pyramid_warning_rules",
+}
+
+// GetAdminTranslations lists translations, filtered by entity_type, entity_id and language
+func GetAdminTranslations(c echo.Context) error {
+	query := `SELECT ` + translationColumns + ` FROM translations WHERE 1=1`
+	args := []interface{}{}
+	argIndex := 1
+
+	if entityType := c.QueryParam("entity_type"); entityType != "" {
+		query += ` AND entity_type = $` + strconv.Itoa(argIndex)
+		args = append(args, entityType)
+		argIndex++
+	}
+
+	if entityID := c.QueryParam("entity_id"); entityID != "" {
+		if err := utils.ValidateUUID(entityID); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid entity ID format"})
+		}
+		query += ` AND entity_id = $` + strconv.Itoa(argIndex)
+		args = append(args, entityID)
+		argIndex++
+	}
+
+	if language := c.QueryParam("language"); language != "" {
+		query += ` AND language = $` + strconv.Itoa(argIndex)
+		args = append(args, language)
+		argIndex++
+	}
+
+	query += ` ORDER BY entity_type ASC, entity_id ASC, field ASC, language ASC`
+
+	translations := []models.Translation{}
+	if err := db.DB.Select(&translations, query, args...); err != nil {
+		c.Logger().Errorf("GetAdminTranslations query error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"translations": translations,
+		"total":        len(translations),
+		"languages":    utils.SupportedLanguages,
+		"fields":       utils.TranslatableFields,
+	})
+}
+
+// UpsertAdminTranslation creates or replaces the translation of a field in a language
+func UpsertAdminTranslation(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	var req models.UpsertTranslationRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	table, ok := translatableTables[req.EntityType]
+	if !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid entity_type"})
+	}
+	if !utils.IsTranslatableField(req.EntityType, req.Field) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid field for " + req.EntityType + ". Allowed: " + strings.Join(utils.TranslatableFields[req.EntityType], ", "),
+		})
+	}
+	language := utils.NormalizeLanguage(req.Language)
+	if language == "" || language == utils.DefaultLanguage {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "Invalid language. Indonesian texts are edited on the entity itself",
+		})
+	}
+	if err := utils.ValidateUUID(req.EntityID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid entity ID format"})
+	}
+	req.Value = strings.TrimSpace(req.Value)
+	if err := utils.ValidateStringLength(req.Value, 1, 5000, "value"); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+
+	var exists bool
+	err := db.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM `+table+` WHERE id = $1)`, req.EntityID).Scan(&exists)
+	if err != nil {
+		c.Logger().Errorf("UpsertAdminTranslation check entity error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if !exists {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Entity not found"})
+	}
+
+	// Get existing translation for audit log
+	var existing *models.Translation
+	var current models.Translation
+	err = db.DB.Get(&current, `SELECT `+translationColumns+` FROM translations
+		WHERE entity_type = $1 AND entity_id = $2 AND field = $3 AND language = $4`,
+		req.EntityType, req.EntityID, req.Field, language)
+	if err == nil {
+		existing = &current
+	} else if err != sql.ErrNoRows {
+		c.Logger().Errorf("UpsertAdminTranslation get existing error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	var translation models.Translation
+	err = db.DB.Get(&translation, `
+		INSERT INTO translations (entity_type, entity_id, field, language, value)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (entity_type, entity_id, field, language)
+		DO UPDATE SET value = EXCLUDED.value, updated_at = NOW()
+		RETURNING `+translationColumns,
+		req.EntityType, req.EntityID, req.Field, language, req.Value)
+	if err != nil {
+		c.Logger().Errorf("UpsertAdminTranslation error: %v", err)
+		sanitizedErr := utils.SanitizeError(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
+	}
+
+	// Log audit
+	action := "create"
+	var oldValues interface{}
+	if existing != nil {
+		action = "update"
+		oldValues = existing
+	}
+	utils.LogAudit(adminUserID, action, "translation", &translation.ID, oldValues, translation, ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, translation)
+}
+
+// DeleteAdminTranslation deletes a translation (the text falls back to Indonesian)
+func DeleteAdminTranslation(c echo.Context) error {
+	translationID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+	ipAddress := c.RealIP()
+	userAgent := c.Request().UserAgent()
+
+	// Validate UUID format
+	if err := utils.ValidateUUID(translationID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid translation ID format"})
+	}
+
+	var translation models.Translation
+	err := db.DB.Get(&translation, `DELETE FROM translations WHERE id = $1 RETURNING `+translationColumns, translationID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Translation not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("DeleteAdminTranslation error: %v", err)
+		sanitizedErr := utils.SanitizeError(err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "delete", "translation", &translationID, translation, nil, ipAddress, userAgent)
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Translation deleted successfully"})
+}
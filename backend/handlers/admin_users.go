@@ -198,10 +198,15 @@ func GetAdminUser(c echo.Context) error {
 	var childrenCount int
 	db.DB.QueryRow("SELECT COUNT(*) FROM children WHERE parent_id = $1", userID).Scan(&childrenCount)
 
+	// Get two-factor authentication state
+	var twoFactorEnabled bool
+	db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM user_two_factor WHERE user_id = $1 AND enabled_at IS NOT NULL)", userID).Scan(&twoFactorEnabled)
+
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"user":          user,
 		"statistics":    stats,
 		"children_count": childrenCount,
+		"two_factor_enabled": twoFactorEnabled,
 	})
 }
 
@@ -217,7 +222,7 @@ func CreateAdminUser(c echo.Context) error {
 		Password    string `json:"password"`
 		FullName    string `json:"full_name" validate:"required"`
 		PhoneNumber string `json:"phone_number"`
-		Role        string `json:"role" validate:"required,oneof=parent admin"`
+		Role        string `json:"role" validate:"required,oneof=parent admin kader bidan nutritionist district_viewer"`
 		AuthProvider string `json:"auth_provider" validate:"oneof=email phone google"`
 	}
 
@@ -235,7 +240,7 @@ func CreateAdminUser(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
 	}
 	
-	if req.Role != "parent" && req.Role != "admin" {
+	if !utils.IsValidRole(req.Role) {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid role"})
 	}
 	
@@ -403,7 +408,7 @@ func UpdateAdminUser(c echo.Context) error {
 	}
 
 	if req.Role != nil {
-		if *req.Role != "parent" && *req.Role != "admin" {
+		if !utils.IsValidRole(*req.Role) {
 			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid role"})
 		}
 		// Prevent admin from changing their own role from admin
@@ -534,6 +539,13 @@ func DeleteAdminUser(c echo.Context) error {
 		}
 	}
 
+	// Collect attachment files of the user's children before the rows are cascade-deleted
+	attachmentKeys, err := childAttachmentKeys("child_id IN (SELECT id FROM children WHERE parent_id = $1)", userID)
+	if err != nil {
+		c.Logger().Errorf("DeleteAdminUser error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
 	// Delete user (cascade will delete children)
 	_, err = db.DB.Exec("DELETE FROM users WHERE id = $1", userID)
 	if err != nil {
@@ -541,6 +553,7 @@ func DeleteAdminUser(c echo.Context) error {
 		sanitizedErr := utils.SanitizeError(err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": sanitizedErr})
 	}
+	deleteStoredObjects(c, attachmentKeys)
 
 	// Log audit
 	utils.LogAudit(adminUserID, "delete", "user", &userID, existingUser, nil, ipAddress, userAgent)
@@ -592,6 +605,11 @@ func ResetAdminUserPassword(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reset password"})
 	}
 
+	// Log the user out everywhere, the old password may have been compromised
+	if _, err := revokeUserSessions(userID, sessionRevokedPasswordReset, ""); err != nil {
+		c.Logger().Errorf("ResetAdminUserPassword error: %v", err)
+	}
+
 	// Log audit
 	auditData := map[string]string{"action": "password_reset"}
 	utils.LogAudit(adminUserID, "update", "user", &userID, nil, auditData, ipAddress, userAgent)
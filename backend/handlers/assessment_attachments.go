@@ -0,0 +1,328 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"path/filepath"
+	"strings"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	maxPhotoSizeBytes  = 10 << 20                  // 10 MB
+	maxVideoSizeBytes  = 50 << 20                  // 50 MB
+	maxUploadBodyBytes = maxVideoSizeBytes + 1<<20 // Largest file plus the multipart headers
+	attachmentURLTTL   = 15 * time.Minute
+	attachmentColumns  = `id, assessment_id, child_id, uploaded_by, media_type, content_type, file_name, size_bytes, storage_key, thumbnail_key, created_at`
+	quicktimeMediaType = "video/quicktime"
+)
+
+// allowedAttachmentTypes maps sniffed content types to media type and file extension
+var allowedAttachmentTypes = map[string]struct {
+	MediaType string
+	Extension string
+}{
+	"image/jpeg":       {"photo", ".jpg"},
+	"image/png":        {"photo", ".png"},
+	"image/gif":        {"photo", ".gif"},
+	"image/webp":       {"photo", ".webp"},
+	"video/mp4":        {"video", ".mp4"},
+	"video/webm":       {"video", ".webm"},
+	quicktimeMediaType: {"video", ".mov"},
+}
+
+// attachmentStore is the object store for uploaded attachments (configured by STORAGE_DRIVER)
+var attachmentStore = services.NewObjectStore()
+
+// UploadAssessmentAttachment uploads a photo or video as evidence for an assessment
+func UploadAssessmentAttachment(c echo.Context) error {
+	childID := requestChild(c).ID
+	assessmentID := c.Param("assessmentId")
+	userID := c.Get("user_id").(string)
+
+	if status, msg := verifyChildAssessment(childID, assessmentID); status != 0 {
+		return c.JSON(status, map[string]string{"error": msg})
+	}
+
+	// Stop reading oversized bodies before the multipart form is spooled to disk
+	c.Request().Body = http.MaxBytesReader(c.Response(), c.Request().Body, maxUploadBodyBytes)
+	fileHeader, err := c.FormFile("file")
+	var maxBytesErr *http.MaxBytesError
+	if errors.As(err, &maxBytesErr) {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "File is too large (max 50 MB for videos, 10 MB for photos)"})
+	} else if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "File is required"})
+	}
+	if fileHeader.Size > maxVideoSizeBytes {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "File is too large (max 50 MB for videos, 10 MB for photos)"})
+	}
+
+	file, err := fileHeader.Open()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Failed to read file"})
+	}
+	defer file.Close()
+
+	// Detect the content type from the file contents instead of trusting the client
+	head := make([]byte, 512)
+	n, _ := io.ReadFull(file, head)
+	contentType := detectAttachmentContentType(head[:n])
+	allowed, ok := allowedAttachmentTypes[contentType]
+	if !ok {
+		return c.JSON(http.StatusUnsupportedMediaType, map[string]string{"error": "Unsupported file type. Allowed: JPEG, PNG, GIF, WebP, MP4, WebM, MOV"})
+	}
+	if allowed.MediaType == "photo" && fileHeader.Size > maxPhotoSizeBytes {
+		return c.JSON(http.StatusRequestEntityTooLarge, map[string]string{"error": "Photo is too large (max 10 MB)"})
+	}
+
+	objectID, err := randomObjectID()
+	if err != nil {
+		c.Logger().Errorf("Failed to generate object ID: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to upload file"})
+	}
+	keyPrefix := fmt.Sprintf("children/%s/assessments/%s/%s", childID, assessmentID, objectID)
+	storageKey := keyPrefix + allowed.Extension
+
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to upload file"})
+	}
+	if err := attachmentStore.Put(storageKey, file, fileHeader.Size, contentType); err != nil {
+		c.Logger().Errorf("Failed to store attachment: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to upload file"})
+	}
+
+	// Thumbnail generation is best effort; the attachment is still usable without one
+	var thumbnailKey *string
+	if _, err := file.Seek(0, io.SeekStart); err == nil {
+		var thumb []byte
+		var thumbErr error
+		switch {
+		case allowed.MediaType == "video":
+			thumb, thumbErr = services.GenerateVideoThumbnail(file)
+		case contentType != "image/webp": // No WebP decoder in the standard library
+			thumb, thumbErr = services.GenerateImageThumbnail(file)
+		}
+		if thumbErr != nil {
+			c.Logger().Warnf("Failed to generate thumbnail for %s: %v", storageKey, thumbErr)
+		} else if thumb != nil {
+			key := keyPrefix + "_thumb.jpg"
+			if err := attachmentStore.Put(key, bytes.NewReader(thumb), int64(len(thumb)), "image/jpeg"); err != nil {
+				c.Logger().Warnf("Failed to store thumbnail for %s: %v", storageKey, err)
+			} else {
+				thumbnailKey = &key
+			}
+		}
+	}
+
+	var attachment models.AssessmentAttachment
+	err = db.DB.QueryRowx(`
+		INSERT INTO assessment_attachments (assessment_id, child_id, uploaded_by, media_type, content_type,
+			file_name, size_bytes, storage_key, thumbnail_key)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+attachmentColumns,
+		assessmentID, childID, userID, allowed.MediaType, contentType,
+		filepath.Base(fileHeader.Filename), fileHeader.Size, storageKey, thumbnailKey,
+	).StructScan(&attachment)
+	if err != nil {
+		c.Logger().Errorf("Failed to save attachment: %v", err)
+		keys := []string{storageKey}
+		if thumbnailKey != nil {
+			keys = append(keys, *thumbnailKey)
+		}
+		deleteStoredObjects(c, keys)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to upload file"})
+	}
+
+	signAttachmentURLs(c, &attachment)
+	return c.JSON(http.StatusCreated, attachment)
+}
+
+// GetAssessmentAttachments lists the attachments of an assessment with signed download URLs
+func GetAssessmentAttachments(c echo.Context) error {
+	childID := requestChild(c).ID
+	assessmentID := c.Param("assessmentId")
+
+	if status, msg := verifyChildAssessment(childID, assessmentID); status != 0 {
+		return c.JSON(status, map[string]string{"error": msg})
+	}
+
+	attachments := []models.AssessmentAttachment{}
+	err := db.DB.Select(&attachments, `SELECT `+attachmentColumns+` FROM assessment_attachments
+		WHERE assessment_id = $1 AND child_id = $2 ORDER BY created_at ASC`, assessmentID, childID)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch attachments: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch attachments"})
+	}
+
+	for i := range attachments {
+		signAttachmentURLs(c, &attachments[i])
+	}
+
+	return c.JSON(http.StatusOK, attachments)
+}
+
+// DeleteAssessmentAttachment deletes an attachment and its stored files
+func DeleteAssessmentAttachment(c echo.Context) error {
+	childID := requestChild(c).ID
+	assessmentID := c.Param("assessmentId")
+	attachmentID := c.Param("attachmentId")
+
+	if status, msg := verifyChildAssessment(childID, assessmentID); status != 0 {
+		return c.JSON(status, map[string]string{"error": msg})
+	}
+
+	var storageKey string
+	var thumbnailKey *string
+	err := db.DB.QueryRow(`DELETE FROM assessment_attachments
+		WHERE id = $1 AND assessment_id = $2 AND child_id = $3
+		RETURNING storage_key, thumbnail_key`, attachmentID, assessmentID, childID).
+		Scan(&storageKey, &thumbnailKey)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Attachment not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to delete attachment: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete attachment"})
+	}
+
+	keys := []string{storageKey}
+	if thumbnailKey != nil {
+		keys = append(keys, *thumbnailKey)
+	}
+	deleteStoredObjects(c, keys)
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Attachment deleted successfully"})
+}
+
+// ServeLocalAttachment serves files from the local object store using the signed URLs
+// produced by services.LocalStore. Public route: the signature is the authorization.
+func ServeLocalAttachment(c echo.Context) error {
+	store, ok := attachmentStore.(*services.LocalStore)
+	if !ok {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Not found"})
+	}
+
+	key := c.Param("*")
+	if !store.VerifySignature(key, c.QueryParam("expires"), c.QueryParam("signature")) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Invalid or expired link"})
+	}
+
+	reader, err := store.Get(key)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Not found"})
+	}
+	defer reader.Close()
+
+	contentType := mime.TypeByExtension(filepath.Ext(key))
+	if contentType == "" {
+		contentType = "application/octet-stream"
+	}
+	c.Response().Header().Set(echo.HeaderContentType, contentType)
+	c.Response().Header().Set("Cache-Control", "private, max-age=900")
+
+	// Support range requests so videos can be seeked in the browser
+	if rs, ok := reader.(io.ReadSeeker); ok {
+		http.ServeContent(c.Response(), c.Request(), filepath.Base(key), time.Time{}, rs)
+		return nil
+	}
+	return c.Stream(http.StatusOK, contentType, reader)
+}
+
+// verifyChildAssessment checks that the assessment belongs to the child.
+// Returns a zero status if it does.
+func verifyChildAssessment(childID, assessmentID string) (int, string) {
+	var exists bool
+	err := db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM assessments WHERE id = $1 AND child_id = $2)",
+		assessmentID, childID).Scan(&exists)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to verify assessment"
+	}
+	if !exists {
+		return http.StatusNotFound, "Assessment not found"
+	}
+
+	return 0, ""
+}
+
+// detectAttachmentContentType sniffs the content type from the first bytes of a file.
+// http.DetectContentType does not recognise QuickTime, so check its ftyp brand explicitly.
+func detectAttachmentContentType(head []byte) string {
+	contentType := http.DetectContentType(head)
+	if idx := strings.Index(contentType, ";"); idx != -1 {
+		contentType = contentType[:idx]
+	}
+	if contentType == "application/octet-stream" && len(head) >= 12 &&
+		string(head[4:8]) == "ftyp" && string(head[8:12]) == "qt  " {
+		return quicktimeMediaType
+	}
+	return contentType
+}
+
+// signAttachmentURLs fills in the signed download URLs of an attachment
+func signAttachmentURLs(c echo.Context, attachment *models.AssessmentAttachment) {
+	url, err := attachmentStore.SignedURL(attachment.StorageKey, attachmentURLTTL)
+	if err != nil {
+		c.Logger().Warnf("Failed to sign attachment URL: %v", err)
+	}
+	attachment.URL = url
+
+	if attachment.ThumbnailKey != nil {
+		thumbURL, err := attachmentStore.SignedURL(*attachment.ThumbnailKey, attachmentURLTTL)
+		if err == nil {
+			attachment.ThumbnailURL = &thumbURL
+		}
+	}
+}
+
+// childAttachmentKeys returns the object keys of all attachments of the children matching
+// the given condition (e.g. "child_id = $1"), so they can be removed after the rows are deleted
+func childAttachmentKeys(condition string, args ...interface{}) ([]string, error) {
+	rows, err := db.DB.Query(`SELECT storage_key, thumbnail_key FROM assessment_attachments WHERE `+condition, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var keys []string
+	for rows.Next() {
+		var storageKey string
+		var thumbnailKey *string
+		if err := rows.Scan(&storageKey, &thumbnailKey); err != nil {
+			return nil, err
+		}
+		keys = append(keys, storageKey)
+		if thumbnailKey != nil {
+			keys = append(keys, *thumbnailKey)
+		}
+	}
+	return keys, rows.Err()
+}
+
+// deleteStoredObjects removes objects from the attachment store, logging failures
+func deleteStoredObjects(c echo.Context, keys []string) {
+	for _, key := range keys {
+		if err := attachmentStore.Delete(key); err != nil {
+			c.Logger().Warnf("Failed to delete stored object %s: %v", key, err)
+		}
+	}
+}
+
+func randomObjectID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
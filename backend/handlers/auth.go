@@ -3,13 +3,10 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
-	"os"
-	"time"
 	"tukem-backend/db"
 	"tukem-backend/models"
 	"tukem-backend/utils"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/crypto/bcrypt"
 )
@@ -106,25 +103,19 @@ func Login(c echo.Context) error {
 		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid credentials"})
 	}
 
-	// Generate JWT
-	token := jwt.New(jwt.SigningMethodHS256)
-	claims := token.Claims.(jwt.MapClaims)
-	claims["user_id"] = user.ID
-	claims["role"] = user.Role
-	claims["exp"] = time.Now().Add(time.Hour * 72).Unix() // 3 days
-
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "secret" // Default for development only
-	}
-
-	t, err := token.SignedString([]byte(jwtSecret))
+	// Start session, or ask admins for the second factor
+	tokens, challenge, err := startLogin(c, &user, false, loginMethodPassword)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
 	}
+	if challenge != nil {
+		return c.JSON(http.StatusOK, challenge)
+	}
 
 	return c.JSON(http.StatusOK, models.AuthResponse{
-		Token: t,
-		User:  user,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         user,
 	})
 }
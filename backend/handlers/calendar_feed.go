@@ -0,0 +1,380 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const calendarFeedColumns = `id, user_id, child_id, token, last_accessed_at, revoked_at, created_at`
+
+// CreateCalendarFeed creates a secret ICS subscription URL for a child, or for all children
+// of the user when child_id is omitted
+func CreateCalendarFeed(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	var req models.CreateCalendarFeedRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
+	}
+
+	var childID *string
+	if req.ChildID != nil && *req.ChildID != "" {
+		if err := utils.ValidateUUID(*req.ChildID); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid child ID format"})
+		}
+
+		// Verify the user can access the child
+		if _, err := utils.AuthorizeChild(*req.ChildID, userID, utils.GuardianViewer); err != nil {
+			status, msg := utils.ChildAccessStatus(err)
+			return c.JSON(status, map[string]string{"error": msg})
+		}
+		childID = req.ChildID
+	}
+
+	token, err := randomFeedToken()
+	if err != nil {
+		c.Logger().Errorf("Failed to generate calendar feed token: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create calendar feed"})
+	}
+
+	var feed models.CalendarFeed
+	err = db.DB.Get(&feed, `INSERT INTO calendar_feeds (user_id, child_id, token) VALUES ($1, $2, $3)
+		RETURNING `+calendarFeedColumns, userID, childID, token)
+	if err != nil {
+		c.Logger().Errorf("Failed to create calendar feed: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create calendar feed"})
+	}
+	feed.URL = calendarFeedURL(feed.Token)
+
+	return c.JSON(http.StatusCreated, feed)
+}
+
+// GetCalendarFeeds lists the active calendar feeds of the user
+func GetCalendarFeeds(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	feeds := []models.CalendarFeed{}
+	err := db.DB.Select(&feeds, `SELECT `+calendarFeedColumns+` FROM calendar_feeds
+		WHERE user_id = $1 AND revoked_at IS NULL ORDER BY created_at DESC`, userID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get calendar feeds: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get calendar feeds"})
+	}
+	for i := range feeds {
+		feeds[i].URL = calendarFeedURL(feeds[i].Token)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"feeds": feeds,
+		"total": len(feeds),
+	})
+}
+
+// RevokeCalendarFeed revokes a calendar feed; its URL stops working immediately
+func RevokeCalendarFeed(c echo.Context) error {
+	feedID := c.Param("id")
+
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	if err := utils.ValidateUUID(feedID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid feed ID format"})
+	}
+
+	result, err := db.DB.Exec(`UPDATE calendar_feeds SET revoked_at = NOW()
+		WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, feedID, userID)
+	if err != nil {
+		c.Logger().Errorf("Failed to revoke calendar feed: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to revoke calendar feed"})
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Calendar feed not found"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Tautan kalender berhasil dicabut"})
+}
+
+// ServeCalendarFeed serves the ICS document of a feed. The route is public; the secret token
+// in the URL authorizes access. Events are generated on every request, so changes to records
+// show up on the next calendar refresh.
+func ServeCalendarFeed(c echo.Context) error {
+	token := strings.TrimSuffix(c.Param("token"), ".ics")
+	if token == "" {
+		return c.String(http.StatusNotFound, "Not found")
+	}
+
+	var feed models.CalendarFeed
+	err := db.DB.Get(&feed, `SELECT `+calendarFeedColumns+` FROM calendar_feeds WHERE token = $1`, token)
+	if err == sql.ErrNoRows {
+		return c.String(http.StatusNotFound, "Not found")
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to get calendar feed: %v", err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+	if feed.RevokedAt != nil {
+		return c.String(http.StatusGone, "This calendar feed has been revoked")
+	}
+
+	// Children covered by the feed (only those the user can still access)
+	query := `SELECT c.id, c.parent_id, c.name, c.dob, c.gender, c.is_premature, c.gestational_age FROM children c
+	          JOIN child_guardians g ON g.child_id = c.id WHERE g.user_id = $1`
+	args := []interface{}{feed.UserID}
+	if feed.ChildID != nil {
+		query += ` AND c.id = $2`
+		args = append(args, *feed.ChildID)
+	}
+	query += ` ORDER BY c.dob ASC`
+
+	rows, err := db.DB.Query(query, args...)
+	if err != nil {
+		c.Logger().Errorf("Failed to get children for calendar feed: %v", err)
+		return c.String(http.StatusInternalServerError, "Internal server error")
+	}
+	var children []models.Child
+	for rows.Next() {
+		var child models.Child
+		if err := rows.Scan(&child.ID, &child.ParentID, &child.Name, &child.DOB, &child.Gender,
+			&child.IsPremature, &child.GestationalAge); err != nil {
+			rows.Close()
+			c.Logger().Errorf("Failed to scan child for calendar feed: %v", err)
+			return c.String(http.StatusInternalServerError, "Internal server error")
+		}
+		children = append(children, child)
+	}
+	rows.Close()
+	if feed.ChildID != nil && len(children) == 0 {
+		return c.String(http.StatusNotFound, "Not found")
+	}
+
+	// Feed language follows the user's preferred language
+	lang := utils.DefaultLanguage
+	var preferred *string
+	if err := db.DB.QueryRow("SELECT preferred_language FROM users WHERE id = $1", feed.UserID).Scan(&preferred); err == nil && preferred != nil {
+		if normalized := utils.NormalizeLanguage(*preferred); normalized != "" {
+			lang = normalized
+		}
+	}
+
+	now := time.Now()
+	events := []utils.CalendarEvent{}
+	for _, child := range children {
+		childEvents, err := buildChildCalendarEvents(child, lang, now)
+		if err != nil {
+			c.Logger().Errorf("Failed to build calendar events for child %s: %v", child.ID, err)
+			return c.String(http.StatusInternalServerError, "Internal server error")
+		}
+		events = append(events, childEvents...)
+	}
+
+	if _, err := db.DB.Exec("UPDATE calendar_feeds SET last_accessed_at = NOW() WHERE id = $1", feed.ID); err != nil {
+		c.Logger().Warnf("Failed to update calendar feed access time: %v", err)
+	}
+
+	calendarName := "Jadwal Tumbuh Kembang"
+	if feed.ChildID != nil {
+		calendarName += " - " + children[0].Name
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-cache")
+	c.Response().Header().Set("Content-Disposition", `inline; filename="tukem.ics"`)
+	return c.Blob(http.StatusOK, "text/calendar; charset=utf-8", []byte(utils.BuildICS(calendarName, events, now)))
+}
+
+// buildChildCalendarEvents builds the calendar events of a child: immunizations that are not
+// completed yet, the next Vitamin A / deworming / supplement dose, the next growth measurement
+// and the next KPSP screening
+func buildChildCalendarEvents(child models.Child, lang string, now time.Time) ([]utils.CalendarEvent, error) {
+	events := []utils.CalendarEvent{}
+	today := now.Format("2006-01-02")
+	todayTime, _ := time.Parse("2006-01-02", today)
+
+	dobTime, err := utils.ParseDate(child.DOB)
+	if err != nil {
+		return nil, err
+	}
+	ageInDays, ageInMonths, _, err := utils.CalculateCorrectedAge(child.DOB, today, child.IsPremature, child.GestationalAge)
+	if err != nil {
+		return nil, err
+	}
+
+	// Immunizations, using the same status calculation as the immunization schedule
+	schedules, err := getActiveImmunizationSchedules()
+	if err != nil {
+		return nil, err
+	}
+	if err := translateImmunizationSchedules(schedules, lang); err != nil {
+		return nil, err
+	}
+	completed, err := getCompletedImmunizations(child.ID)
+	if err != nil {
+		return nil, err
+	}
+	reviewFlags, err := getOpenReviewFlags(child.ID)
+	if err != nil {
+		return nil, err
+	}
+	statuses, _ := buildImmunizationStatuses(schedules, child.DOB, ageInDays, ageInMonths, completed, reviewFlags)
+
+	for _, status := range statuses {
+		if status.Status == "completed" || status.DueDate == nil {
+			continue
+		}
+		dueDate, err := utils.ParseDate(*status.DueDate)
+		if err != nil {
+			continue
+		}
+
+		name := status.Schedule.Name
+		if status.Schedule.NameID != nil && *status.Schedule.NameID != "" {
+			name = *status.Schedule.NameID
+		}
+		summary := fmt.Sprintf("Imunisasi %s (dosis %d) - %s", name, status.Schedule.DoseNumber, child.Name)
+		description := status.DueExplanation
+		if status.Status == "overdue" {
+			description = "Terlambat dari jadwal. " + description
+		}
+		if status.ReviewFlag != nil {
+			description += "\n" + status.ReviewFlag.Reason
+		}
+
+		events = append(events, utils.CalendarEvent{
+			UID:         fmt.Sprintf("immunization-%s-%s@tukem", child.ID, status.Schedule.ID),
+			Date:        dueDate,
+			Summary:     summary,
+			Description: description,
+			Alarms:      []string{utils.AlarmWeekBefore, utils.AlarmDayBefore},
+		})
+	}
+
+	// Vitamin A, deworming and supplements: the next dose window of each programme
+	programmes, err := getActiveHealthProgrammes()
+	if err != nil {
+		return nil, err
+	}
+	programmeDoses, err := getChildHealthProgrammeDoses(child.ID)
+	if err != nil {
+		return nil, err
+	}
+	programmeStatuses, _, err := buildHealthProgrammeStatuses(programmes, child.DOB, programmeDoses, todayTime)
+	if err != nil {
+		return nil, err
+	}
+	for _, status := range programmeStatuses {
+		if status.NextSlot == nil {
+			continue
+		}
+		date, err := utils.ParseDate(status.NextSlot.WindowStart)
+		if err != nil {
+			continue
+		}
+		if date.Before(todayTime) {
+			date = todayTime
+		}
+
+		name := status.Programme.Name
+		if status.Programme.NameID != nil && *status.Programme.NameID != "" {
+			name = *status.Programme.NameID
+		}
+		description := fmt.Sprintf("Dapat diberikan sampai %s", status.NextSlot.WindowEnd)
+		if status.Programme.Dosage != nil && *status.Programme.Dosage != "" {
+			description = *status.Programme.Dosage + ". " + description
+		}
+
+		events = append(events, utils.CalendarEvent{
+			UID:         fmt.Sprintf("programme-%s-%s-%d@tukem", child.ID, status.Programme.ID, status.NextSlot.Number),
+			Date:        date,
+			Summary:     fmt.Sprintf("%s - %s", name, child.Name),
+			Description: description,
+			Alarms:      []string{utils.AlarmDayBefore},
+		})
+	}
+
+	// Next growth measurement
+	var lastMeasurement sql.NullString
+	if err := db.DB.QueryRow("SELECT MAX(measurement_date)::text FROM measurements WHERE child_id = $1", child.ID).Scan(&lastMeasurement); err != nil {
+		return nil, err
+	}
+	var last *time.Time
+	if lastMeasurement.Valid {
+		if parsed, err := utils.ParseDate(lastMeasurement.String); err == nil {
+			last = &parsed
+		}
+	}
+	nextMeasurement := utils.NextMeasurementDate(last, ageInMonths, todayTime)
+	events = append(events, utils.CalendarEvent{
+		UID:     fmt.Sprintf("measurement-%s@tukem", child.ID),
+		Date:    nextMeasurement,
+		Summary: fmt.Sprintf("Pengukuran berat & tinggi badan - %s", child.Name),
+		Description: fmt.Sprintf("Pengukuran pertumbuhan dianjurkan setiap %d bulan pada usia anak saat ini",
+			utils.MeasurementIntervalMonths(ageInMonths)),
+		Alarms: []string{utils.AlarmDayBefore},
+	})
+
+	// Next KPSP screening
+	var screeningAges []int
+	if err := db.DB.Select(&screeningAges, `SELECT DISTINCT age_months FROM milestones
+		WHERE source = 'KPSP' AND is_active = true`); err != nil {
+		return nil, err
+	}
+	var screenedAges []int
+	if err := db.DB.Select(&screenedAges, `SELECT DISTINCT m.age_months FROM assessments a
+		JOIN milestones m ON m.id = a.milestone_id
+		WHERE a.child_id = $1 AND m.source = 'KPSP'`, child.ID); err != nil {
+		return nil, err
+	}
+	screened := make(map[int]bool)
+	for _, age := range screenedAges {
+		screened[age] = true
+	}
+
+	chronologicalDays := int(todayTime.Sub(dobTime).Hours() / 24)
+	correctionDays := utils.PrematureCorrectionDays(child.IsPremature, child.GestationalAge, chronologicalDays)
+	if age, date, ok := utils.NextKPSPScreening(dobTime, screeningAges, screened, ageInMonths, correctionDays); ok {
+		if date.Before(todayTime) {
+			date = todayTime
+		}
+		events = append(events, utils.CalendarEvent{
+			UID:         fmt.Sprintf("kpsp-%s-%d@tukem", child.ID, age),
+			Date:        date,
+			Summary:     fmt.Sprintf("Skrining perkembangan KPSP %d bulan - %s", age, child.Name),
+			Description: "Isi kuesioner KPSP di aplikasi atau bersama tenaga kesehatan",
+			Alarms:      []string{utils.AlarmWeekBefore, utils.AlarmDayBefore},
+		})
+	}
+
+	return events, nil
+}
+
+// calendarFeedURL returns the public subscription URL of a feed
+func calendarFeedURL(token string) string {
+	return utils.PublicURL("/calendar/" + token + ".ics")
+}
+
+// randomFeedToken returns a random 256-bit token, hex encoded
+func randomFeedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -0,0 +1,17 @@
+package handlers
+
+import (
+	"tukem-backend/models"
+
+	"github.com/labstack/echo/v4"
+)
+
+// requestChild returns the child stored by RequireChildAccess. Handlers of /children/:id routes
+// rely on it, so a route registered without the middleware fails loudly instead of serving data.
+func requestChild(c echo.Context) *models.Child {
+	child, ok := c.Get("child").(*models.Child)
+	if !ok {
+		panic("child route " + c.Path() + " registered without RequireChildAccess")
+	}
+	return child
+}
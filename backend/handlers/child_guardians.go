@@ -0,0 +1,564 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/services"
+	"tukem-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	childInvitationTTL         = 72 * time.Hour
+	childInvitationMaxAttempts = 5  // Wrong codes before the invitation expires
+	childInvitationMaxPending  = 10 // Pending invitations per child
+)
+
+const childInvitationQuery = `SELECT i.id, i.child_id, c.name AS child_name, i.phone_number, i.role, i.relationship,
+	i.code_hash, i.attempt_count,
+	CASE WHEN i.status = 'pending' AND i.expires_at <= NOW() THEN 'expired' ELSE i.status END AS status,
+	i.invited_by, u.full_name AS inviter_name, i.accepted_by, i.expires_at, i.accepted_at, i.created_at
+	FROM child_invitations i
+	JOIN children c ON c.id = i.child_id
+	LEFT JOIN users u ON u.id = i.invited_by`
+
+// Role names used in the invitation message
+var guardianRoleLabels = map[string]map[string]string{
+	"id": {utils.GuardianEditor: "pencatat (dapat mencatat data)", utils.GuardianViewer: "pemantau (hanya melihat)"},
+	"en": {utils.GuardianEditor: "editor (can record data)", utils.GuardianViewer: "viewer (read only)"},
+}
+
+// GetChildGuardians lists the caregivers with access to a child. Phone numbers are only shown to
+// the owner.
+func GetChildGuardians(c echo.Context) error {
+	child := requestChild(c)
+	role := child.AccessRole
+
+	guardians, err := getChildGuardians(child.ID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get child guardians: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get guardians"})
+	}
+	if role != utils.GuardianOwner {
+		for i := range guardians {
+			guardians[i].PhoneNumber = nil
+		}
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"role":      role,
+		"guardians": guardians,
+	})
+}
+
+// CreateChildInvitation invites a phone number to follow a child as editor or viewer. The code
+// is sent to the number on WhatsApp; a pending invitation for the same number is replaced.
+func CreateChildInvitation(c echo.Context) error {
+	childID := requestChild(c).ID
+	userID := c.Get("user_id").(string)
+
+	req := new(models.CreateChildInvitationRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
+	}
+	phoneNumber, err := utils.ValidatePhoneNumber(req.PhoneNumber)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if req.Role != utils.GuardianEditor && req.Role != utils.GuardianViewer {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "role harus editor atau viewer"})
+	}
+	relationship, msg := normalizeRelationship(req.Relationship)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	var isMember bool
+	err = db.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM child_guardians g JOIN users u ON u.id = g.user_id
+		WHERE g.child_id = $1 AND u.phone_number = $2)`, childID, phoneNumber).Scan(&isMember)
+	if err != nil {
+		c.Logger().Errorf("Failed to check child guardians: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create invitation"})
+	}
+	if isMember {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Nomor ini sudah memiliki akses ke data anak"})
+	}
+
+	var pending int
+	err = db.DB.QueryRow(`SELECT COUNT(*) FROM child_invitations
+		WHERE child_id = $1 AND status = 'pending' AND expires_at > NOW() AND phone_number <> $2`,
+		childID, phoneNumber).Scan(&pending)
+	if err != nil {
+		c.Logger().Errorf("Failed to count child invitations: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create invitation"})
+	}
+	if pending >= childInvitationMaxPending {
+		return c.JSON(http.StatusTooManyRequests, map[string]string{"error": "Terlalu banyak undangan yang belum diterima. Batalkan undangan lama terlebih dahulu."})
+	}
+
+	code, err := utils.GenerateOTP()
+	if err != nil {
+		c.Logger().Errorf("Failed to generate invitation code: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create invitation"})
+	}
+	expiresAt := time.Now().Add(childInvitationTTL)
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create invitation"})
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`UPDATE child_invitations SET status = 'revoked'
+		WHERE child_id = $1 AND phone_number = $2 AND status = 'pending'`, childID, phoneNumber)
+	if err != nil {
+		c.Logger().Errorf("Failed to replace child invitation: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create invitation"})
+	}
+	var invitationID string
+	err = tx.QueryRow(`INSERT INTO child_invitations (child_id, phone_number, role, relationship, code_hash, invited_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		childID, phoneNumber, req.Role, relationship, hashInvitationCode(phoneNumber, code), userID, expiresAt).Scan(&invitationID)
+	if err != nil {
+		c.Logger().Errorf("Failed to create child invitation: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create invitation"})
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create invitation"})
+	}
+
+	invitation, err := getChildInvitation(`i.id = $1`, invitationID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get child invitation: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create invitation"})
+	}
+
+	lang := invitationLanguage(phoneNumber, resolveLanguage(c))
+	inviterName := ""
+	if invitation.InviterName != nil {
+		inviterName = *invitation.InviterName
+	}
+	_, err = notificationService().Enqueue(services.ChannelWhatsApp, phoneNumber, "child_invitation", lang, map[string]interface{}{
+		"InviterName": inviterName,
+		"ChildName":   invitation.ChildName,
+		"Role":        guardianRoleLabel(req.Role, lang),
+		"Code":        code,
+		"ExpiryHours": strconv.Itoa(int(childInvitationTTL.Hours())),
+	}, services.EnqueueOptions{Sensitive: true, ExpiresAt: &expiresAt})
+	if err != nil {
+		c.Logger().Errorf("Failed to send child invitation: %v", err)
+		db.DB.Exec(`UPDATE child_invitations SET status = 'revoked' WHERE id = $1`, invitationID)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengirim undangan. Silakan coba lagi."})
+	}
+
+	// Log audit
+	utils.LogAudit(userID, "create", "child_invitation", &invitationID, nil, invitation, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message":    "Undangan berhasil dikirim melalui WhatsApp",
+		"invitation": invitation,
+	})
+}
+
+// GetChildInvitations lists the invitations of a child
+func GetChildInvitations(c echo.Context) error {
+	childID := requestChild(c).ID
+
+	invitations := []models.ChildInvitation{}
+	err := db.DB.Select(&invitations, childInvitationQuery+` WHERE i.child_id = $1 ORDER BY i.created_at DESC`, childID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get child invitations: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get invitations"})
+	}
+
+	return c.JSON(http.StatusOK, invitations)
+}
+
+// RevokeChildInvitation cancels a pending invitation
+func RevokeChildInvitation(c echo.Context) error {
+	childID := requestChild(c).ID
+	invitationID := c.Param("invitationId")
+	userID := c.Get("user_id").(string)
+
+	if err := utils.ValidateUUID(invitationID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid invitation ID format"})
+	}
+
+	before, err := getChildInvitation(`i.id = $1 AND i.child_id = $2`, invitationID, childID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Undangan tidak ditemukan"})
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to get child invitation: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to revoke invitation"})
+	}
+	if before.Status != "pending" {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Undangan sudah tidak berlaku"})
+	}
+
+	if _, err := db.DB.Exec(`UPDATE child_invitations SET status = 'revoked' WHERE id = $1`, invitationID); err != nil {
+		c.Logger().Errorf("Failed to revoke child invitation: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to revoke invitation"})
+	}
+
+	// Log audit
+	utils.LogAudit(userID, "revoke", "child_invitation", &invitationID, before, nil, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Undangan berhasil dibatalkan"})
+}
+
+// GetMyInvitations lists the pending invitations sent to the user's verified phone number
+func GetMyInvitations(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	invitations := []models.ChildInvitation{}
+	err := db.DB.Select(&invitations, childInvitationQuery+`
+		JOIN users me ON me.phone_number = i.phone_number AND COALESCE(me.phone_verified, false)
+		WHERE me.id = $1 AND i.status = 'pending' AND i.expires_at > NOW()
+		AND NOT EXISTS (SELECT 1 FROM child_guardians g WHERE g.child_id = i.child_id AND g.user_id = me.id)
+		ORDER BY i.created_at DESC`, userID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get invitations: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get invitations"})
+	}
+
+	return c.JSON(http.StatusOK, invitations)
+}
+
+// AcceptChildInvitation accepts an invitation with the code sent on WhatsApp. The user must be
+// logged in with the invited, verified phone number. The invitation expires after too many wrong
+// codes.
+func AcceptChildInvitation(c echo.Context) error {
+	invitationID := c.Param("id")
+
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	if err := utils.ValidateUUID(invitationID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid invitation ID format"})
+	}
+	req := new(models.AcceptChildInvitationRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
+	}
+	code := strings.TrimSpace(req.Code)
+	if code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Kode undangan wajib diisi"})
+	}
+
+	var phoneNumber *string
+	var phoneVerified bool
+	err := db.DB.QueryRow(`SELECT phone_number, COALESCE(phone_verified, false) FROM users WHERE id = $1`, userID).
+		Scan(&phoneNumber, &phoneVerified)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to accept invitation"})
+	}
+
+	invitation, err := getChildInvitation(`i.id = $1`, invitationID)
+	if err != nil && err != sql.ErrNoRows {
+		c.Logger().Errorf("Failed to get child invitation: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to accept invitation"})
+	}
+	// Invitations for other numbers are reported as missing
+	if err == sql.ErrNoRows || phoneNumber == nil || !phoneVerified || *phoneNumber != invitation.PhoneNumber {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Undangan tidak ditemukan"})
+	}
+	if invitation.Status != "pending" {
+		return c.JSON(http.StatusGone, map[string]string{"error": "Undangan sudah tidak berlaku"})
+	}
+
+	if subtle.ConstantTimeCompare([]byte(hashInvitationCode(invitation.PhoneNumber, code)), []byte(invitation.CodeHash)) != 1 {
+		var attempts int
+		err := db.DB.QueryRow(`UPDATE child_invitations SET attempt_count = attempt_count + 1,
+			status = CASE WHEN attempt_count + 1 >= $2 THEN 'expired' ELSE status END
+			WHERE id = $1 AND status = 'pending' RETURNING attempt_count`, invitationID, childInvitationMaxAttempts).Scan(&attempts)
+		if err != nil {
+			c.Logger().Errorf("Failed to record invitation attempt: %v", err)
+		}
+		if attempts >= childInvitationMaxAttempts {
+			return c.JSON(http.StatusGone, map[string]string{"error": "Terlalu banyak percobaan. Minta undangan baru kepada pemilik data anak."})
+		}
+		return c.JSON(http.StatusBadRequest, map[string]interface{}{
+			"error":              "Kode undangan salah",
+			"remaining_attempts": childInvitationMaxAttempts - attempts,
+		})
+	}
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to accept invitation"})
+	}
+	defer tx.Rollback()
+
+	// Claim the invitation so a code can only be used once
+	result, err := tx.Exec(`UPDATE child_invitations SET status = 'accepted', accepted_by = $1, accepted_at = NOW()
+		WHERE id = $2 AND status = 'pending' AND expires_at > NOW()`, userID, invitationID)
+	if err != nil {
+		c.Logger().Errorf("Failed to accept child invitation: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to accept invitation"})
+	}
+	if rows, _ := result.RowsAffected(); rows == 0 {
+		return c.JSON(http.StatusGone, map[string]string{"error": "Undangan sudah tidak berlaku"})
+	}
+	_, err = tx.Exec(`INSERT INTO child_guardians (child_id, user_id, role, relationship, invited_by)
+		VALUES ($1, $2, $3, $4, $5) ON CONFLICT (child_id, user_id) DO NOTHING`,
+		invitation.ChildID, userID, invitation.Role, invitation.Relationship, invitation.InvitedBy)
+	if err != nil {
+		c.Logger().Errorf("Failed to add child guardian: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to accept invitation"})
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to accept invitation"})
+	}
+
+	// Log audit
+	utils.LogAudit(userID, "accept", "child_invitation", &invitationID, nil, map[string]string{
+		"child_id": invitation.ChildID,
+		"role":     invitation.Role,
+	}, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":  "Undangan diterima. Anda sekarang dapat memantau " + invitation.ChildName,
+		"child_id": invitation.ChildID,
+		"role":     invitation.Role,
+	})
+}
+
+// UpdateChildGuardian changes the role of a caregiver. Ownership is changed with
+// TransferChildOwnership.
+func UpdateChildGuardian(c echo.Context) error {
+	childID := requestChild(c).ID
+	guardianID := c.Param("userId")
+	userID := c.Get("user_id").(string)
+
+	if err := utils.ValidateUUID(guardianID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	req := new(models.UpdateChildGuardianRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
+	}
+	if req.Role != utils.GuardianEditor && req.Role != utils.GuardianViewer {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "role harus editor atau viewer"})
+	}
+	relationship, msg := normalizeRelationship(req.Relationship)
+	if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	before, err := getChildGuardian(childID, guardianID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Pengasuh tidak ditemukan"})
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to get child guardian: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update guardian"})
+	}
+	if before.Role == utils.GuardianOwner {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Peran pemilik hanya dapat diubah dengan memindahkan kepemilikan"})
+	}
+
+	_, err = db.DB.Exec(`UPDATE child_guardians SET role = $1, relationship = COALESCE($2, relationship), updated_at = NOW()
+		WHERE child_id = $3 AND user_id = $4`, req.Role, relationship, childID, guardianID)
+	if err != nil {
+		c.Logger().Errorf("Failed to update child guardian: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update guardian"})
+	}
+
+	guardian, err := getChildGuardian(childID, guardianID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get child guardian: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update guardian"})
+	}
+
+	// Log audit
+	utils.LogAudit(userID, "update", "child_guardian", &guardianID, before, guardian, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":  "Akses pengasuh berhasil diperbarui",
+		"guardian": guardian,
+	})
+}
+
+// RemoveChildGuardian removes a caregiver's access. The owner can remove anyone else; other
+// caregivers can only remove themselves.
+func RemoveChildGuardian(c echo.Context) error {
+	child := requestChild(c)
+	childID := child.ID
+	guardianID := c.Param("userId")
+	userID := c.Get("user_id").(string)
+
+	if err := utils.ValidateUUID(guardianID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+	if guardianID != userID && child.AccessRole != utils.GuardianOwner {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+	}
+
+	before, err := getChildGuardian(childID, guardianID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Pengasuh tidak ditemukan"})
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to get child guardian: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to remove guardian"})
+	}
+	if before.Role == utils.GuardianOwner {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Pemilik tidak dapat dihapus. Pindahkan kepemilikan terlebih dahulu."})
+	}
+
+	_, err = db.DB.Exec(`DELETE FROM child_guardians WHERE child_id = $1 AND user_id = $2`, childID, guardianID)
+	if err != nil {
+		c.Logger().Errorf("Failed to remove child guardian: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to remove guardian"})
+	}
+
+	// Calendar feeds of the removed caregiver stop covering the child
+	_, err = db.DB.Exec(`UPDATE calendar_feeds SET revoked_at = NOW()
+		WHERE user_id = $1 AND child_id = $2 AND revoked_at IS NULL`, guardianID, childID)
+	if err != nil {
+		c.Logger().Errorf("Failed to revoke calendar feeds: %v", err)
+	}
+
+	// Log audit
+	utils.LogAudit(userID, "delete", "child_guardian", &guardianID, before, nil, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Akses pengasuh berhasil dihapus"})
+}
+
+// TransferChildOwnership makes another caregiver the owner of the child. The previous owner stays
+// on as editor.
+func TransferChildOwnership(c echo.Context) error {
+	childID := requestChild(c).ID
+	userID := c.Get("user_id").(string)
+
+	req := new(models.TransferChildOwnershipRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
+	}
+	if err := utils.ValidateUUID(req.UserID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+	if req.UserID == userID {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Anda sudah menjadi pemilik"})
+	}
+
+	if _, err := getChildGuardian(childID, req.UserID); err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Kepemilikan hanya dapat dipindahkan ke pengasuh yang sudah memiliki akses"})
+	} else if err != nil {
+		c.Logger().Errorf("Failed to get child guardian: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to transfer ownership"})
+	}
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to transfer ownership"})
+	}
+	defer tx.Rollback()
+
+	// Demote first: only one owner is allowed per child
+	_, err = tx.Exec(`UPDATE child_guardians SET role = $1, updated_at = NOW() WHERE child_id = $2 AND user_id = $3`,
+		utils.GuardianEditor, childID, userID)
+	if err == nil {
+		_, err = tx.Exec(`UPDATE child_guardians SET role = $1, updated_at = NOW() WHERE child_id = $2 AND user_id = $3`,
+			utils.GuardianOwner, childID, req.UserID)
+	}
+	if err == nil {
+		_, err = tx.Exec(`UPDATE children SET parent_id = $1 WHERE id = $2`, req.UserID, childID)
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to transfer child ownership: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to transfer ownership"})
+	}
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to transfer ownership"})
+	}
+
+	// Log audit
+	utils.LogAudit(userID, "transfer_ownership", "child", &childID,
+		map[string]string{"owner_id": userID}, map[string]string{"owner_id": req.UserID}, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Kepemilikan berhasil dipindahkan"})
+}
+
+func getChildGuardians(childID string) ([]models.ChildGuardian, error) {
+	guardians := []models.ChildGuardian{}
+	err := db.DB.Select(&guardians, `SELECT g.child_id, g.user_id, COALESCE(u.full_name, '') AS full_name, u.phone_number,
+		g.role, g.relationship, g.invited_by, g.created_at
+		FROM child_guardians g JOIN users u ON u.id = g.user_id
+		WHERE g.child_id = $1
+		ORDER BY CASE g.role WHEN 'owner' THEN 0 WHEN 'editor' THEN 1 ELSE 2 END, g.created_at`, childID)
+	return guardians, err
+}
+
+func getChildGuardian(childID, userID string) (*models.ChildGuardian, error) {
+	var guardian models.ChildGuardian
+	err := db.DB.Get(&guardian, `SELECT g.child_id, g.user_id, COALESCE(u.full_name, '') AS full_name, u.phone_number,
+		g.role, g.relationship, g.invited_by, g.created_at
+		FROM child_guardians g JOIN users u ON u.id = g.user_id
+		WHERE g.child_id = $1 AND g.user_id = $2`, childID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &guardian, nil
+}
+
+func getChildInvitation(condition string, args ...interface{}) (*models.ChildInvitation, error) {
+	var invitation models.ChildInvitation
+	if err := db.DB.Get(&invitation, childInvitationQuery+` WHERE `+condition, args...); err != nil {
+		return nil, err
+	}
+	return &invitation, nil
+}
+
+// normalizeRelationship trims the relationship to the child (e.g. "nenek"); empty means none
+func normalizeRelationship(relationship *string) (*string, string) {
+	if relationship == nil || strings.TrimSpace(*relationship) == "" {
+		return nil, ""
+	}
+	trimmed := strings.TrimSpace(*relationship)
+	if err := utils.ValidateStringLength(trimmed, 1, 50, "relationship"); err != nil {
+		return nil, err.Error()
+	}
+	return &trimmed, ""
+}
+
+// invitationLanguage is the preferred language of the user with the phone number, if any
+func invitationLanguage(phoneNumber, fallback string) string {
+	var preferred *string
+	err := db.DB.QueryRow(`SELECT preferred_language FROM users WHERE phone_number = $1`, phoneNumber).Scan(&preferred)
+	if err == nil && preferred != nil {
+		if lang := utils.NormalizeLanguage(*preferred); lang != "" {
+			return lang
+		}
+	}
+	return fallback
+}
+
+func guardianRoleLabel(role, lang string) string {
+	if labels, ok := guardianRoleLabels[lang]; ok {
+		return labels[role]
+	}
+	return guardianRoleLabels[utils.DefaultLanguage][role]
+}
+
+// hashInvitationCode hashes an invitation code with the OTP key, so a leaked row cannot be
+// reversed by hashing all 10^6 codes
+func hashInvitationCode(phoneNumber, code string) string {
+	return services.HashOTP(phoneNumber, services.OTPPurposeChildInvitation, code)
+}
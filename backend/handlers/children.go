@@ -1,11 +1,11 @@
 package handlers
 
 import (
-	"database/sql"
 	"net/http"
 	"strings"
 	"tukem-backend/db"
 	"tukem-backend/models"
+	"tukem-backend/utils"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
@@ -31,13 +31,25 @@ func CreateChild(c echo.Context) error {
 		gestationalAge = req.GestationalAge
 	}
 
+	if msg, err := validateChildFacility(req.FacilityID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	} else if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	defer tx.Rollback()
+
 	// Insert into DB
-	query := `INSERT INTO children (parent_id, name, dob, gender, birth_weight, birth_height, is_premature, gestational_age) 
-	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8) 
+	query := `INSERT INTO children (parent_id, name, dob, gender, birth_weight, birth_height, is_premature, gestational_age, facility_id) 
+	          VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) 
 	          RETURNING id, created_at`
 	
 	var child models.Child
-	err := db.DB.QueryRow(query, userID, req.Name, req.DOB, req.Gender, req.BirthWeight, req.BirthHeight, req.IsPremature, gestationalAge).
+	err = tx.QueryRow(query, userID, req.Name, req.DOB, req.Gender, req.BirthWeight, req.BirthHeight, req.IsPremature, gestationalAge, req.FacilityID).
 		Scan(&child.ID, &child.CreatedAt)
 	
 	if err != nil {
@@ -45,6 +57,17 @@ func CreateChild(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create child: " + err.Error()})
 	}
 
+	// The creator owns the child
+	_, err = tx.Exec(`INSERT INTO child_guardians (child_id, user_id, role) VALUES ($1, $2, $3)`, child.ID, userID, utils.GuardianOwner)
+	if err != nil {
+		c.Logger().Error("Database error: ", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create child"})
+	}
+
+	if err := tx.Commit(); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to create child"})
+	}
+
 	// Populate response
 	child.ParentID = userID
 	child.Name = req.Name
@@ -54,6 +77,8 @@ func CreateChild(c echo.Context) error {
 	child.BirthHeight = req.BirthHeight
 	child.IsPremature = req.IsPremature
 	child.GestationalAge = gestationalAge
+	child.FacilityID = req.FacilityID
+	child.AccessRole = utils.GuardianOwner
 
 	c.Logger().Info("Child created successfully: ", child.ID)
 	return c.JSON(http.StatusCreated, child)
@@ -65,8 +90,11 @@ func GetChildren(c echo.Context) error {
 	claims := *user.Claims.(*jwt.MapClaims)
 	userID := claims["user_id"].(string)
 
-	query := `SELECT id, parent_id, name, dob, gender, birth_weight, birth_height, is_premature, gestational_age, created_at 
-	          FROM children WHERE parent_id = $1 ORDER BY created_at DESC`
+	// Children the user owns or has been invited to
+	query := `SELECT c.id, c.parent_id, c.name, c.dob, c.gender, c.birth_weight, c.birth_height, c.is_premature, c.gestational_age, 
+	          c.facility_id, g.role, c.created_at 
+	          FROM children c JOIN child_guardians g ON g.child_id = c.id 
+	          WHERE g.user_id = $1 ORDER BY c.created_at DESC`
 	
 	rows, err := db.DB.Query(query, userID)
 	if err != nil {
@@ -78,7 +106,7 @@ func GetChildren(c echo.Context) error {
 	for rows.Next() {
 		var child models.Child
 		err := rows.Scan(&child.ID, &child.ParentID, &child.Name, &child.DOB, &child.Gender, 
-			&child.BirthWeight, &child.BirthHeight, &child.IsPremature, &child.GestationalAge, &child.CreatedAt)
+			&child.BirthWeight, &child.BirthHeight, &child.IsPremature, &child.GestationalAge, &child.FacilityID, &child.AccessRole, &child.CreatedAt)
 		if err != nil {
 			continue
 		}
@@ -95,37 +123,11 @@ func GetChild(c echo.Context) error {
 		c.Logger().Errorf("GetChild handler called for denver-ii path: %s - THIS SHOULD NOT HAPPEN", path)
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Route not found"})
 	}
-	childID := c.Param("id")
-	
-	// Get user ID from JWT
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	var child models.Child
-	query := `SELECT id, parent_id, name, dob, gender, birth_weight, birth_height, is_premature, gestational_age, created_at 
-	          FROM children WHERE id = $1 AND parent_id = $2`
-	
-	err := db.DB.QueryRow(query, childID, userID).Scan(
-		&child.ID, &child.ParentID, &child.Name, &child.DOB, &child.Gender,
-		&child.BirthWeight, &child.BirthHeight, &child.IsPremature, &child.GestationalAge, &child.CreatedAt)
-	
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	} else if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
-	}
-
-	return c.JSON(http.StatusOK, child)
+	return c.JSON(http.StatusOK, requestChild(c))
 }
 
 func UpdateChild(c echo.Context) error {
-	childID := c.Param("id")
-	
-	// Get user ID from JWT
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
+	childID := requestChild(c).ID
 
 	req := new(models.UpdateChildRequest)
 	if err := c.Bind(req); err != nil {
@@ -138,12 +140,18 @@ func UpdateChild(c echo.Context) error {
 		gestationalAge = req.GestationalAge
 	}
 
+	if msg, err := validateChildFacility(req.FacilityID); err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	} else if msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
 	query := `UPDATE children SET name = $1, dob = $2, gender = $3, birth_weight = $4, birth_height = $5, 
-	          is_premature = $6, gestational_age = $7 
-	          WHERE id = $8 AND parent_id = $9`
+	          is_premature = $6, gestational_age = $7, facility_id = COALESCE($8, facility_id) 
+	          WHERE id = $9`
 	
 	result, err := db.DB.Exec(query, req.Name, req.DOB, req.Gender, req.BirthWeight, req.BirthHeight, 
-		req.IsPremature, gestationalAge, childID, userID)
+		req.IsPremature, gestationalAge, req.FacilityID, childID)
 	
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update child"})
@@ -158,15 +166,17 @@ func UpdateChild(c echo.Context) error {
 }
 
 func DeleteChild(c echo.Context) error {
-	childID := c.Param("id")
-	
-	// Get user ID from JWT
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
+	childID := requestChild(c).ID
 
-	query := `DELETE FROM children WHERE id = $1 AND parent_id = $2`
-	result, err := db.DB.Exec(query, childID, userID)
+	// Collect attachment files before the rows are cascade-deleted
+	attachmentKeys, err := childAttachmentKeys("child_id = $1", childID)
+	if err != nil {
+		c.Logger().Errorf("Failed to fetch child attachments: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete child"})
+	}
+
+	query := `DELETE FROM children WHERE id = $1`
+	result, err := db.DB.Exec(query, childID)
 	
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete child"})
@@ -177,5 +187,7 @@ func DeleteChild(c echo.Context) error {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
 	}
 
+	deleteStoredObjects(c, attachmentKeys)
+
 	return c.JSON(http.StatusOK, map[string]string{"message": "Child deleted successfully"})
 }
@@ -3,8 +3,10 @@ package handlers
 import (
 	"net/http"
 	"strconv"
+	"time"
 	"tukem-backend/db"
 	"tukem-backend/models"
+	"tukem-backend/utils"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
@@ -12,22 +14,8 @@ import (
 
 // GetDenverIIChartData retrieves assessment data grouped by Denver II domain for charting
 func GetDenverIIChartData(c echo.Context) error {
-	childID := c.Param("id")
-
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := db.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err != nil {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
+	child := requestChild(c)
+	childID := child.ID
 
 	// Query assessments with Denver II domain grouping
 	// Only include milestones that have been assessed for this child
@@ -99,6 +87,7 @@ func GetDenverIIChartData(c echo.Context) error {
 // GetDenverIIMilestones retrieves all Denver II milestones (for assessment)
 func GetDenverIIMilestones(c echo.Context) error {
 	ageMonthsStr := c.QueryParam("age_months")
+	childID := c.QueryParam("child_id") // Optional child_id to select by corrected age
 	
 	ageMonths := 0
 	if ageMonthsStr != "" {
@@ -109,10 +98,32 @@ func GetDenverIIMilestones(c echo.Context) error {
 		}
 	}
 
+	ageBasis := utils.AgeBasisChronological
+	if childID != "" {
+		// Get user ID from JWT to verify ownership
+		user := c.Get("user").(*jwt.Token)
+		claims := *user.Claims.(*jwt.MapClaims)
+		userID := claims["user_id"].(string)
+
+		child, err := utils.AuthorizeChild(childID, userID, utils.GuardianViewer)
+		if err != nil {
+			status, msg := utils.ChildAccessStatus(err)
+			return c.JSON(status, map[string]string{"error": msg})
+		}
+		_, referenceMonths, basis, err := utils.CalculateReferenceAge(
+			child.DOB, time.Now().Format("2006-01-02"), child.IsPremature, child.GestationalAge)
+		if err == nil {
+			ageMonths = referenceMonths
+			ageBasis = basis
+		}
+	}
+	setAgeBasisHeaders(c, ageBasis, ageMonths)
+
 	query := `
 		SELECT * FROM milestones 
 		WHERE source = 'DENVER' 
 			AND denver_domain IS NOT NULL
+			AND is_active = true
 			AND age_months >= $1 - 3 AND age_months <= $1 + 6
 		ORDER BY age_months ASC, denver_domain ASC
 	`
@@ -123,6 +134,10 @@ func GetDenverIIMilestones(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch Denver II milestones"})
 	}
 
+	if err := translateMilestones(milestones, resolveLanguage(c)); err != nil {
+		c.Logger().Warnf("Failed to translate Denver II milestones: %v", err)
+	}
+
 	return c.JSON(http.StatusOK, milestones)
 }
 
@@ -135,58 +150,37 @@ func GetDenverIIChartGridData(c echo.Context) error {
 		}
 	}()
 	
-	childID := c.Param("id")
+	child := requestChild(c)
+	childID := child.ID
 	c.Logger().Errorf("GetDenverIIChartGridData CALLED for childID: %s", childID) // Use Errorf to ensure it's logged
 
-	// Get user ID from JWT to verify ownership (same pattern as GetDenverIIChartData)
-	userToken := c.Get("user")
-	if userToken == nil {
-		c.Logger().Errorf("No user token found")
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
-	}
-	
-	user, ok := userToken.(*jwt.Token)
-	if !ok {
-		c.Logger().Errorf("Invalid user token type: %T", userToken)
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
-	}
-	
-	claimsMap, ok := user.Claims.(*jwt.MapClaims)
-	if !ok {
-		c.Logger().Errorf("Invalid claims type: %T", user.Claims)
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
-	}
-	
-	claims := *claimsMap
-	userID, ok := claims["user_id"].(string)
-	if !ok {
-		c.Logger().Errorf("Invalid user_id in claims: %v", claims["user_id"])
-		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Unauthorized"})
-	}
-	
-	c.Logger().Infof("User ID extracted: %s", userID)
+	// Get child's age
+	dob := child.DOB
+	isPremature := child.IsPremature
+	gestationalAge := child.GestationalAge
+	c.Logger().Infof("DOB retrieved: %s", dob)
 
-	// Verify child belongs to user
-	var parentID string
-	err := db.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
+	// The age line is drawn from the reference DOB: for premature children under
+	// 24 months this is the DOB shifted by the weeks born early (corrected age)
+	today := time.Now().Format("2006-01-02")
+	chronoMonths, err := utils.CalculateAgeInMonths(dob, today)
 	if err != nil {
-		c.Logger().Errorf("Child not found: %v", err)
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if parentID != userID {
-		c.Logger().Errorf("Unauthorized: parentID=%s, userID=%s", parentID, userID)
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+		c.Logger().Errorf("Failed to calculate age: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to calculate child age"})
 	}
-	c.Logger().Infof("Child verified: childID=%s", childID)
-
-	// Get child's age
-	var dob string
-	err = db.DB.QueryRow("SELECT dob FROM children WHERE id = $1", childID).Scan(&dob)
+	chronoDays, _ := utils.CalculateAgeInDays(dob, today)
+	referenceDays, referenceMonths, ageBasis, err := utils.CalculateReferenceAge(dob, today, isPremature, gestationalAge)
 	if err != nil {
-		c.Logger().Errorf("Failed to get DOB: %v", err)
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
+		c.Logger().Errorf("Failed to calculate age: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to calculate child age"})
+	}
+	ageLineDOB := dob
+	if len(ageLineDOB) > 10 {
+		ageLineDOB = ageLineDOB[:10]
+	}
+	if dobTime, err := time.Parse("2006-01-02", ageLineDOB); err == nil {
+		ageLineDOB = dobTime.AddDate(0, 0, chronoDays-referenceDays).Format("2006-01-02")
 	}
-	c.Logger().Infof("DOB retrieved: %s", dob)
 
 	// Fetch all Denver II milestones with assessment status
 	// Get the latest assessment for each milestone
@@ -210,6 +204,7 @@ func GetDenverIIChartGridData(c echo.Context) error {
 		) a ON m.id = a.milestone_id
 		WHERE m.source = 'DENVER' 
 			AND m.denver_domain IS NOT NULL
+			AND (m.is_active = true OR a.milestone_id IS NOT NULL) -- Keep answered items of older pack versions
 		ORDER BY 
 			CASE m.denver_domain
 				WHEN 'PS' THEN 1
@@ -303,6 +298,10 @@ func GetDenverIIChartGridData(c echo.Context) error {
 		"dob":      dob,
 		"domains":  domainGroups,
 		"all_milestones": milestones,
+		"age_basis":                ageBasis,
+		"age_months":               referenceMonths,
+		"chronological_age_months": chronoMonths,
+		"age_line_dob":             ageLineDOB,
 	})
 }
 
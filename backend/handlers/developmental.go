@@ -0,0 +1,181 @@
+package handlers
+
+import (
+	"net/http"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetDevelopmentalProfile returns the age-equivalent and developmental quotient (DQ)
+// per Denver II domain, based on the child's Denver and KPSP answers
+func GetDevelopmentalProfile(c echo.Context) error {
+	child := requestChild(c)
+
+	asOf := c.QueryParam("date")
+	if asOf == "" {
+		asOf = time.Now().Format("2006-01-02")
+	} else if _, err := time.Parse("2006-01-02", asOf); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+	}
+
+	profile, err := buildDevelopmentalProfile(*child, asOf)
+	if err != nil {
+		c.Logger().Errorf("Failed to build developmental profile: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to calculate developmental profile"})
+	}
+
+	return c.JSON(http.StatusOK, profile)
+}
+
+// GetDevelopmentalHistory returns the recorded age-equivalent/DQ snapshots for a child, grouped by domain
+func GetDevelopmentalHistory(c echo.Context) error {
+	childID := requestChild(c).ID
+
+	query := `SELECT id, child_id, domain, snapshot_date, chronological_age_months, reference_age_months,
+	          age_basis, age_equivalent_months, developmental_quotient, items_assessed, items_passed,
+	          created_at, updated_at
+	          FROM developmental_snapshots WHERE child_id = $1`
+	args := []interface{}{childID}
+	if domain := c.QueryParam("domain"); domain != "" {
+		query += ` AND domain = $2`
+		args = append(args, domain)
+	}
+	query += ` ORDER BY snapshot_date ASC, domain ASC`
+
+	snapshots := []models.DevelopmentalSnapshot{}
+	if err := db.DB.Select(&snapshots, query, args...); err != nil {
+		c.Logger().Errorf("Failed to fetch developmental history: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch developmental history"})
+	}
+
+	// Group by domain
+	domainGroups := make(map[string][]models.DevelopmentalSnapshot)
+	for _, s := range snapshots {
+		if len(s.SnapshotDate) > 10 {
+			s.SnapshotDate = s.SnapshotDate[:10]
+		}
+		domainGroups[s.Domain] = append(domainGroups[s.Domain], s)
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"child_id": childID,
+		"domains":  domainGroups,
+	})
+}
+
+// buildDevelopmentalProfile calculates age-equivalent and DQ per domain using
+// the answers recorded on or before asOf (YYYY-MM-DD)
+func buildDevelopmentalProfile(child models.Child, asOf string) (*models.DevelopmentalProfile, error) {
+	chronoDays, err := utils.CalculateAgeInDays(child.DOB, asOf)
+	if err != nil {
+		return nil, err
+	}
+	referenceDays, _, ageBasis, err := utils.CalculateReferenceAge(child.DOB, asOf, child.IsPremature, child.GestationalAge)
+	if err != nil {
+		return nil, err
+	}
+
+	query := `
+		SELECT m.age_months, m.denver_domain, m.category, a.status
+		FROM assessments a
+		JOIN milestones m ON a.milestone_id = m.id
+		WHERE a.child_id = $1
+			AND a.assessment_date <= $2
+			AND m.source IN ('DENVER', 'KPSP')
+	`
+
+	rows, err := db.DB.Query(query, child.ID, asOf)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	itemsByDomain := make(map[string][]utils.DevelopmentItem)
+	for rows.Next() {
+		var item utils.DevelopmentItem
+		var denverDomain *string
+		var category string
+		if err := rows.Scan(&item.AgeMonths, &denverDomain, &category, &item.Status); err != nil {
+			continue
+		}
+		domain := utils.DenverDomainForMilestone(denverDomain, category)
+		if domain == "" {
+			continue
+		}
+		itemsByDomain[domain] = append(itemsByDomain[domain], item)
+	}
+
+	profile := &models.DevelopmentalProfile{
+		ChildID:                child.ID,
+		AssessedAt:             asOf,
+		ChronologicalAgeMonths: utils.DaysToMonths(chronoDays),
+		ReferenceAgeMonths:     utils.DaysToMonths(referenceDays),
+		AgeBasis:               ageBasis,
+		Domains:                []models.DomainDevelopment{},
+	}
+
+	for _, domain := range utils.DenverDomains {
+		items := itemsByDomain[domain]
+		if len(items) == 0 {
+			continue
+		}
+		ageEquivalent, passed := utils.CalculateAgeEquivalent(items)
+		profile.Domains = append(profile.Domains, models.DomainDevelopment{
+			Domain:                domain,
+			AgeEquivalentMonths:   ageEquivalent,
+			DevelopmentalQuotient: utils.CalculateDQ(ageEquivalent, profile.ReferenceAgeMonths),
+			ItemsAssessed:         len(items),
+			ItemsPassed:           passed,
+		})
+	}
+
+	return profile, nil
+}
+
+// recordDevelopmentalSnapshot stores the developmental profile of a child as of
+// the given date so age-equivalent and DQ can be tracked over time
+func recordDevelopmentalSnapshot(childID string, snapshotDate string) error {
+	var child models.Child
+	err := db.DB.QueryRow("SELECT id, dob, is_premature, gestational_age FROM children WHERE id = $1", childID).
+		Scan(&child.ID, &child.DOB, &child.IsPremature, &child.GestationalAge)
+	if err != nil {
+		return err
+	}
+
+	profile, err := buildDevelopmentalProfile(child, snapshotDate)
+	if err != nil {
+		return err
+	}
+
+	query := `
+		INSERT INTO developmental_snapshots (child_id, domain, snapshot_date, chronological_age_months,
+			reference_age_months, age_basis, age_equivalent_months, developmental_quotient,
+			items_assessed, items_passed, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NOW())
+		ON CONFLICT (child_id, domain, snapshot_date)
+		DO UPDATE SET
+			chronological_age_months = EXCLUDED.chronological_age_months,
+			reference_age_months = EXCLUDED.reference_age_months,
+			age_basis = EXCLUDED.age_basis,
+			age_equivalent_months = EXCLUDED.age_equivalent_months,
+			developmental_quotient = EXCLUDED.developmental_quotient,
+			items_assessed = EXCLUDED.items_assessed,
+			items_passed = EXCLUDED.items_passed,
+			updated_at = NOW()
+	`
+
+	for _, d := range profile.Domains {
+		_, err := db.DB.Exec(query, childID, d.Domain, snapshotDate, profile.ChronologicalAgeMonths,
+			profile.ReferenceAgeMonths, profile.AgeBasis, d.AgeEquivalentMonths, d.DevelopmentalQuotient,
+			d.ItemsAssessed, d.ItemsPassed)
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,70 @@
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// GetFacilities lists active facilities for parents choosing where their child is followed up.
+// Filters: type, area_id and search (name).
+func GetFacilities(c echo.Context) error {
+	query := `SELECT * FROM facilities WHERE is_active = true`
+	args := []interface{}{}
+	argIndex := 1
+
+	if facilityType := c.QueryParam("type"); facilityType != "" {
+		query += ` AND type = $` + strconv.Itoa(argIndex)
+		args = append(args, facilityType)
+		argIndex++
+	}
+	if areaID := c.QueryParam("area_id"); areaID != "" {
+		if err := utils.ValidateUUID(areaID); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "ID wilayah tidak valid"})
+		}
+		query += ` AND area_id = $` + strconv.Itoa(argIndex)
+		args = append(args, areaID)
+		argIndex++
+	}
+	if search := c.QueryParam("search"); search != "" {
+		query += ` AND name ILIKE $` + strconv.Itoa(argIndex)
+		args = append(args, "%"+search+"%")
+		argIndex++
+	}
+	query += ` ORDER BY name ASC LIMIT 100`
+
+	facilities := []models.Facility{}
+	if err := db.DB.Select(&facilities, query, args...); err != nil {
+		c.Logger().Errorf("GetFacilities error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengambil daftar fasilitas kesehatan"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data": facilities,
+	})
+}
+
+// validateChildFacility checks the facility chosen for a child; it returns a message for the user
+// when it is invalid. No facility is valid.
+func validateChildFacility(facilityID *string) (string, error) {
+	if facilityID == nil {
+		return "", nil
+	}
+	if err := utils.ValidateUUID(*facilityID); err != nil {
+		return "ID fasilitas kesehatan tidak valid", nil
+	}
+
+	var exists bool
+	err := db.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM facilities WHERE id = $1 AND is_active = true)`, *facilityID).Scan(&exists)
+	if err != nil {
+		return "", err
+	}
+	if !exists {
+		return "Fasilitas kesehatan tidak ditemukan", nil
+	}
+	return "", nil
+}
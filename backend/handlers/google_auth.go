@@ -7,18 +7,21 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"net/url"
 	"os"
 	"time"
 	"tukem-backend/db"
 	"tukem-backend/models"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 	"golang.org/x/oauth2"
 )
 
 var googleOAuthConfig *oauth2.Config
 
+// loginExchangeCodeTTL is how long the frontend has to redeem the code of a Google login redirect
+const loginExchangeCodeTTL = time.Minute
+
 func init() {
 	// Initialize Google OAuth config
 	clientID := os.Getenv("GOOGLE_CLIENT_ID")
@@ -109,55 +112,82 @@ func GoogleAuthCallback(c echo.Context) error {
 		})
 	}
 
-	// Generate JWT
-	jwtToken := jwt.New(jwt.SigningMethodHS256)
-	claims := jwtToken.Claims.(jwt.MapClaims)
-	claims["user_id"] = user.ID
-	claims["role"] = user.Role
-	claims["exp"] = time.Now().Add(time.Hour * 72).Unix() // 3 days
-
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "secret" // Default for development
-	}
-
-	t, err := jwtToken.SignedString([]byte(jwtSecret))
+	// Hand the login over to the frontend with a single-use code; tokens never go in the URL
+	exchangeCode, err := newLoginExchangeCode(user.ID, loginMethodGoogle)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, map[string]string{
 			"error": "Failed to generate token",
 		})
 	}
 
-	// Redirect to frontend with token
+	// Redirect to frontend, which redeems the code at /api/auth/google/exchange
 	frontendURL := os.Getenv("FRONTEND_URL")
 	if frontendURL == "" {
 		frontendURL = "http://localhost:3000"
 	}
-	
-	// Encode user data as JSON for frontend
-	userJSON, _ := json.Marshal(user)
-	userData := string(userJSON)
-	
-	// Use HTML redirect with token and user data
-	redirectHTML := fmt.Sprintf(`
-		<!DOCTYPE html>
-		<html>
-		<head>
-			<title>Redirecting...</title>
-		</head>
-		<body>
-			<script>
-				const token = '%s';
-				const userData = %s;
-				const state = '%s';
-				window.location.href = '%s/auth/google/callback?token=' + encodeURIComponent(token) + '&user=' + encodeURIComponent(JSON.stringify(userData)) + '&state=' + encodeURIComponent(state);
-			</script>
-			<p>Redirecting...</p>
-		</body>
-		</html>
-	`, t, userData, state, frontendURL)
+	redirectURL := fmt.Sprintf("%s/auth/google/callback?code=%s&state=%s",
+		frontendURL, url.QueryEscape(exchangeCode), url.QueryEscape(state))
+
+	return c.Redirect(http.StatusFound, redirectURL)
+}
+
+// ExchangeGoogleLogin redeems the code of a Google login redirect for a session, or for a
+// two-factor challenge when the user needs a second factor
+func ExchangeGoogleLogin(c echo.Context) error {
+	req := new(models.LoginExchangeRequest)
+	if err := c.Bind(req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Kode login wajib diisi"})
+	}
+
+	// Only the first redemption of a code wins
+	var userID, method string
+	err := db.DB.QueryRow(`UPDATE login_exchange_codes SET used_at = NOW()
+	                       WHERE code_hash = $1 AND used_at IS NULL AND expires_at > NOW()
+	                       RETURNING user_id, login_method`, hashRefreshToken(req.Code)).Scan(&userID, &method)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Sesi login tidak valid. Silakan masuk kembali."})
+	} else if err != nil {
+		c.Logger().Errorf("Failed to redeem login exchange code: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	user, err := getLoginUser(userID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
 
-	return c.HTML(http.StatusOK, redirectHTML)
+	// Start session, or ask admins for the second factor
+	tokens, challenge, err := startLogin(c, user, false, method)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+	}
+	if challenge != nil {
+		return c.JSON(http.StatusOK, challenge)
+	}
+
+	return c.JSON(http.StatusOK, models.AuthResponse{
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         *user,
+	})
+}
+
+// newLoginExchangeCode stores a code that starts a login of the user when redeemed within
+// loginExchangeCodeTTL, and returns it. Only its hash is stored.
+func newLoginExchangeCode(userID, method string) (string, error) {
+	code, codeHash, err := newRefreshToken()
+	if err != nil {
+		return "", err
+	}
+	_, err = db.DB.Exec(`INSERT INTO login_exchange_codes (user_id, code_hash, login_method, expires_at)
+	                     VALUES ($1, $2, $3, $4)`,
+		userID, codeHash, method, time.Now().Add(loginExchangeCodeTTL))
+	if err != nil {
+		return "", err
+	}
+	return code, nil
 }
 
 // GoogleUserInfo represents Google user information
@@ -0,0 +1,267 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+const healthProgrammeDoseColumns = `id, child_id, programme_id, given_date, given_at_age_months, location,
+	healthcare_facility, notes, created_at, updated_at`
+
+// GetHealthProgrammeSchedule returns the Vitamin A, deworming and supplement schedule of a child:
+// the dose windows of each programme with their status, and the recorded doses
+func GetHealthProgrammeSchedule(c echo.Context) error {
+	child := requestChild(c)
+	childID := child.ID
+
+	// Programmes use chronological age, also for premature children
+	today := time.Now().Format("2006-01-02")
+	_, ageInMonths, _, err := utils.CalculateCorrectedAge(child.DOB, today, false, nil)
+	if err != nil {
+		c.Logger().Errorf("Failed to calculate age: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to calculate child age"})
+	}
+
+	programmes, err := getActiveHealthProgrammes()
+	if err != nil {
+		c.Logger().Errorf("Failed to get health programmes: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get health programmes"})
+	}
+	doses, err := getChildHealthProgrammeDoses(childID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get health programme doses: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get health programme doses"})
+	}
+
+	todayTime, _ := time.Parse("2006-01-02", today)
+	statuses, summary, err := buildHealthProgrammeStatuses(programmes, child.DOB, doses, todayTime)
+	if err != nil {
+		c.Logger().Errorf("Failed to build health programme schedule: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to calculate child age"})
+	}
+
+	byID := make(map[string]*models.HealthProgramme)
+	for i := range programmes {
+		byID[programmes[i].ID] = &programmes[i]
+	}
+	for i := range doses {
+		doses[i].Programme = byID[doses[i].ProgrammeID]
+	}
+
+	return c.JSON(http.StatusOK, models.HealthProgrammeScheduleResponse{
+		ChildID:    childID,
+		AgeMonths:  ageInMonths,
+		Programmes: statuses,
+		Records:    doses,
+		Summary:    summary,
+	})
+}
+
+// RecordHealthProgrammeDose records a Vitamin A, deworming or supplement dose given to a child
+func RecordHealthProgrammeDose(c echo.Context) error {
+	child := requestChild(c)
+	childID := child.ID
+	userID := c.Get("user_id").(string)
+
+	req := new(models.HealthProgrammeDoseRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
+	}
+	if err := utils.ValidateUUID(req.ProgrammeID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid programme ID format"})
+	}
+
+	givenDate, err := time.Parse("2006-01-02", req.GivenDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid given_date format"})
+	}
+	dob, err := utils.ParseDate(child.DOB)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to calculate child age"})
+	}
+	if givenDate.Before(dob) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Tanggal pemberian tidak boleh sebelum tanggal lahir"})
+	}
+	if givenDate.After(time.Now()) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Tanggal pemberian tidak boleh di masa depan"})
+	}
+
+	var programme models.HealthProgramme
+	err = db.DB.Get(&programme, "SELECT * FROM health_programmes WHERE id = $1 AND is_active = true", req.ProgrammeID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Health programme not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to get health programme: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record dose"})
+	}
+
+	_, givenAgeMonths, _, err := utils.CalculateCorrectedAge(child.DOB, req.GivenDate, false, nil)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid given_date format"})
+	}
+
+	var dose models.ChildHealthProgrammeDose
+	err = db.DB.Get(&dose, `INSERT INTO child_health_programme_doses
+		(child_id, programme_id, given_date, given_at_age_months, location, healthcare_facility, notes)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+healthProgrammeDoseColumns,
+		childID, programme.ID, req.GivenDate, givenAgeMonths, req.Location, req.HealthcareFacility, req.Notes)
+	if err != nil {
+		c.Logger().Errorf("Failed to record health programme dose: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record dose"})
+	}
+	dose.Programme = &programme
+
+	// Log audit
+	utils.LogAudit(userID, "create", "child_health_programme_dose", &dose.ID, nil, dose, c.RealIP(), c.Request().UserAgent())
+
+	response := map[string]interface{}{
+		"message": "Pemberian berhasil dicatat",
+		"dose":    dose,
+	}
+	// Doses outside the programme's windows are kept but do not complete a slot
+	if !healthProgrammeDoseInWindow(programme, dob, givenDate) {
+		response["warning"] = "Tanggal pemberian di luar jadwal program ini, sehingga tidak dihitung dalam jadwal"
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+// DeleteHealthProgrammeDose deletes a recorded health programme dose
+func DeleteHealthProgrammeDose(c echo.Context) error {
+	childID := requestChild(c).ID
+	doseID := c.Param("doseId")
+	userID := c.Get("user_id").(string)
+
+	if err := utils.ValidateUUID(doseID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid dose ID format"})
+	}
+
+	var before models.ChildHealthProgrammeDose
+	err := db.DB.Get(&before, `SELECT `+healthProgrammeDoseColumns+` FROM child_health_programme_doses
+		WHERE id = $1 AND child_id = $2`, doseID, childID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Catatan pemberian tidak ditemukan"})
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to get health programme dose: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete dose"})
+	}
+
+	if _, err := db.DB.Exec("DELETE FROM child_health_programme_doses WHERE id = $1", doseID); err != nil {
+		c.Logger().Errorf("Failed to delete health programme dose: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete dose"})
+	}
+
+	// Log audit
+	utils.LogAudit(userID, "delete", "child_health_programme_dose", &doseID, before, nil, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Catatan pemberian berhasil dihapus"})
+}
+
+// getActiveHealthProgrammes returns the active health programmes in display order
+func getActiveHealthProgrammes() ([]models.HealthProgramme, error) {
+	programmes := []models.HealthProgramme{}
+	err := db.DB.Select(&programmes, `SELECT * FROM health_programmes WHERE is_active = true
+		ORDER BY sort_order ASC, age_min_months ASC, name ASC`)
+	return programmes, err
+}
+
+// getChildHealthProgrammeDoses returns the recorded health programme doses of a child, oldest first
+func getChildHealthProgrammeDoses(childID string) ([]models.ChildHealthProgrammeDose, error) {
+	doses := []models.ChildHealthProgrammeDose{}
+	err := db.DB.Select(&doses, `SELECT `+healthProgrammeDoseColumns+` FROM child_health_programme_doses
+		WHERE child_id = $1 ORDER BY given_date ASC, created_at ASC`, childID)
+	if err != nil {
+		return nil, err
+	}
+	for i := range doses {
+		if given, err := utils.ParseDate(doses[i].GivenDate); err == nil {
+			doses[i].GivenDate = given.Format("2006-01-02")
+		}
+	}
+	return doses, nil
+}
+
+// buildHealthProgrammeStatuses calculates the dose windows of every programme for a child and
+// matches the recorded doses to them
+func buildHealthProgrammeStatuses(programmes []models.HealthProgramme, dob string, doses []models.ChildHealthProgrammeDose, today time.Time) ([]models.HealthProgrammeStatus, models.HealthProgrammeSummary, error) {
+	var summary models.HealthProgrammeSummary
+	dobTime, err := utils.ParseDate(dob)
+	if err != nil {
+		return nil, summary, err
+	}
+
+	statuses := []models.HealthProgrammeStatus{}
+	for _, programme := range programmes {
+		// Doses of this programme, already sorted by date
+		var programmeDoses []*models.ChildHealthProgrammeDose
+		var given []time.Time
+		for i := range doses {
+			if doses[i].ProgrammeID != programme.ID {
+				continue
+			}
+			date, err := utils.ParseDate(doses[i].GivenDate)
+			if err != nil {
+				continue
+			}
+			programmeDoses = append(programmeDoses, &doses[i])
+			given = append(given, date)
+		}
+
+		slots := utils.ProgrammeSlots(programme, dobTime)
+		utils.EvaluateProgrammeSlots(slots, given, today)
+
+		status := models.HealthProgrammeStatus{
+			Programme: programme,
+			Slots:     make([]models.HealthProgrammeSlot, 0, len(slots)),
+		}
+		for _, slot := range slots {
+			item := models.HealthProgrammeSlot{
+				Number:      slot.Number,
+				WindowStart: slot.Start.Format("2006-01-02"),
+				WindowEnd:   slot.End.Format("2006-01-02"),
+				Status:      slot.Status,
+			}
+			if slot.DoseIndex >= 0 {
+				item.Dose = programmeDoses[slot.DoseIndex]
+			}
+			status.Slots = append(status.Slots, item)
+
+			switch slot.Status {
+			case utils.ProgrammeSlotCompleted:
+				summary.Completed++
+			case utils.ProgrammeSlotDue:
+				summary.Due++
+			case utils.ProgrammeSlotUpcoming:
+				summary.Upcoming++
+			case utils.ProgrammeSlotMissed:
+				summary.Missed++
+			}
+		}
+		if next := utils.NextProgrammeSlot(slots); next != nil {
+			status.NextSlot = &status.Slots[next.Number-1]
+		}
+		statuses = append(statuses, status)
+	}
+
+	return statuses, summary, nil
+}
+
+// healthProgrammeDoseInWindow reports whether a dose given on date falls in one of the
+// programme's dose windows
+func healthProgrammeDoseInWindow(programme models.HealthProgramme, dob, date time.Time) bool {
+	for _, slot := range utils.ProgrammeSlots(programme, dob) {
+		if !date.Before(slot.Start) && !date.After(slot.End) {
+			return true
+		}
+	}
+	return false
+}
@@ -1,47 +1,20 @@
 package handlers
 
 import (
-	"database/sql"
 	"net/http"
+	"sort"
 	"time"
 	"tukem-backend/db"
 	"tukem-backend/models"
 	"tukem-backend/utils"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 )
 
 // GetImmunizationSchedule fetches immunization schedule and status for a child
 func GetImmunizationSchedule(c echo.Context) error {
-	childID := c.Param("id")
-
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := db.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if err != nil {
-		c.Logger().Errorf("Failed to verify child ownership: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify child ownership"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
-
-	// Get child data
-	var child models.Child
-	err = db.DB.QueryRow("SELECT id, dob, is_premature, gestational_age FROM children WHERE id = $1", childID).
-		Scan(&child.ID, &child.DOB, &child.IsPremature, &child.GestationalAge)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get child data"})
-	}
+	child := requestChild(c)
+	childID := child.ID
 
 	// Calculate current age
 	today := time.Now().Format("2006-01-02")
@@ -60,6 +33,9 @@ func GetImmunizationSchedule(c echo.Context) error {
 		c.Logger().Errorf("Failed to get immunization schedules: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get immunization schedules"})
 	}
+	if err := translateImmunizationSchedules(schedules, resolveLanguage(c)); err != nil {
+		c.Logger().Warnf("Failed to translate immunization schedules: %v", err)
+	}
 
 	// Get completed immunizations for this child
 	completedImmunizations, err := getCompletedImmunizations(childID)
@@ -68,69 +44,120 @@ func GetImmunizationSchedule(c echo.Context) error {
 		completedImmunizations = map[string]*models.ChildImmunization{} // Empty map
 	}
 
-	// Calculate status for each immunization
-	statuses := []models.ImmunizationStatus{}
-	summary := models.ImmunizationSummary{
-		Total: len(schedules),
+	reviewFlags, err := getOpenReviewFlags(childID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get review flags: %v", err)
+		reviewFlags = map[string]*models.ImmunizationReviewFlag{}
 	}
 
-	for _, schedule := range schedules {
-		status := calculateImmunizationStatus(schedule, child.DOB, ageInDays, ageInMonths, completedImmunizations[schedule.ID])
-		
-		statuses = append(statuses, status)
-
-		// Update summary
-		switch status.Status {
-		case "completed":
-			summary.Completed++
-		case "pending":
-			summary.Pending++
-		case "overdue":
-			summary.Overdue++
-		case "upcoming":
-			summary.Upcoming++
+	// Calculate status for each immunization
+	statuses, summary := buildImmunizationStatuses(schedules, child.DOB, ageInDays, ageInMonths, completedImmunizations, reviewFlags)
+
+	// Vitamin A, deworming and supplements are shown alongside; keep the immunization schedule if they fail
+	programmeStatuses := []models.HealthProgrammeStatus{}
+	if programmes, err := getActiveHealthProgrammes(); err != nil {
+		c.Logger().Errorf("Failed to get health programmes: %v", err)
+	} else if doses, err := getChildHealthProgrammeDoses(childID); err != nil {
+		c.Logger().Errorf("Failed to get health programme doses: %v", err)
+	} else {
+		todayTime, _ := time.Parse("2006-01-02", today)
+		if built, _, err := buildHealthProgrammeStatuses(programmes, child.DOB, doses, todayTime); err == nil {
+			programmeStatuses = built
 		}
 	}
 
 	return c.JSON(http.StatusOK, models.ImmunizationScheduleResponse{
-		ChildID:       childID,
-		AgeMonths:     ageInMonths,
-		AgeDays:       ageInDays,
-		Immunizations: statuses,
-		Summary:       summary,
+		ChildID:          childID,
+		AgeMonths:        ageInMonths,
+		AgeDays:          ageInDays,
+		Immunizations:    statuses,
+		Summary:          summary,
+		HealthProgrammes: programmeStatuses,
 	})
 }
 
-// RecordImmunization records an immunization given to a child
-func RecordImmunization(c echo.Context) error {
-	childID := c.Param("id")
+// GetImmunizationCatchUpPlan generates a catch-up plan for missed and upcoming immunizations:
+// the doses to give at the next visit, which doses can be given together and the earliest valid
+// date of each following dose
+func GetImmunizationCatchUpPlan(c echo.Context) error {
+	child := requestChild(c)
+	childID := child.ID
 
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
+	now := time.Now()
+	today := now.Format("2006-01-02")
+	ageInDays, ageInMonths, _, err := utils.CalculateCorrectedAge(
+		child.DOB, today, child.IsPremature, child.GestationalAge)
+	if err != nil {
+		c.Logger().Errorf("Failed to calculate age: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to calculate child age"})
+	}
 
-	// Verify child belongs to user
-	var parentID string
-	err := db.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
+	dobTime, err := utils.ParseDate(child.DOB)
+	if err != nil {
+		c.Logger().Errorf("Failed to parse child DOB: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to calculate child age"})
+	}
+	todayTime, _ := time.Parse("2006-01-02", today)
+
+	schedules, err := getActiveImmunizationSchedules()
+	if err != nil {
+		c.Logger().Errorf("Failed to get immunization schedules: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get immunization schedules"})
+	}
+	if err := translateImmunizationSchedules(schedules, resolveLanguage(c)); err != nil {
+		c.Logger().Warnf("Failed to translate immunization schedules: %v", err)
 	}
+
+	completedImmunizations, err := getCompletedImmunizations(childID)
 	if err != nil {
-		c.Logger().Errorf("Failed to verify child ownership: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify child ownership"})
+		c.Logger().Errorf("Failed to get completed immunizations: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get immunization records"})
 	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
+
+	given := make(map[string]time.Time)
+	for scheduleID, record := range completedImmunizations {
+		if date, err := utils.ParseDate(record.GivenDate); err == nil {
+			given[scheduleID] = date
+		}
 	}
 
-	// Get child data
-	var child models.Child
-	err = db.DB.QueryRow("SELECT id, dob FROM children WHERE id = $1", childID).
-		Scan(&child.ID, &child.DOB)
+	visits, missed := utils.GenerateCatchUpPlan(dobTime, todayTime, schedules, given)
+
+	// Doses flagged after a serious KIPI stay in the plan but need clinician review first
+	reviewFlags, err := getOpenReviewFlags(childID)
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get child data"})
+		c.Logger().Errorf("Failed to get review flags: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get immunization records"})
 	}
+	for i := range visits {
+		for j := range visits[i].Doses {
+			visits[i].Doses[j].ReviewFlag = reviewFlags[visits[i].Doses[j].Schedule.ID]
+		}
+	}
+
+	plan := models.CatchUpPlan{
+		ChildID:   childID,
+		AgeMonths: ageInMonths,
+		AgeDays:   ageInDays,
+		Visits:    visits,
+		Missed:    missed,
+		Notes: []string{
+			"Vaksin yang berbeda dalam satu kunjungan dapat diberikan bersamaan pada hari yang sama",
+			"Jadwal kejar mengikuti rekomendasi IDAI; konfirmasikan dengan dokter atau bidan sebelum pemberian",
+		},
+	}
+	if len(visits) > 0 {
+		plan.NextVisit = &visits[0]
+	}
+
+	return c.JSON(http.StatusOK, plan)
+}
+
+// RecordImmunization records an immunization given to a child
+func RecordImmunization(c echo.Context) error {
+	child := requestChild(c)
+	childID := child.ID
+	userID := c.Get("user_id").(string)
 
 	// Parse request
 	req := new(models.ImmunizationRecordRequest)
@@ -148,7 +175,7 @@ func RecordImmunization(c echo.Context) error {
 
 	// Get immunization schedule
 	var schedule models.ImmunizationSchedule
-	err = db.DB.Get(&schedule, "SELECT * FROM immunization_schedule WHERE id = $1", req.ImmunizationScheduleID)
+	err := db.DB.Get(&schedule, "SELECT * FROM immunization_schedule WHERE id = $1", req.ImmunizationScheduleID)
 	if err != nil {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Immunization schedule not found"})
 	}
@@ -161,63 +188,45 @@ func RecordImmunization(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid given_date format"})
 	}
 
-	// Check if on schedule
-	isOnSchedule := false
-	if schedule.AgeOptimalDays != nil {
-		// Consider on schedule if within ±7 days of optimal age
-		diff := givenAgeDays - *schedule.AgeOptimalDays
-		if diff >= -7 && diff <= 7 {
-			isOnSchedule = true
-		}
-	}
-
-	// Check if catch-up
-	isCatchUp := false
-	if schedule.AgeOptimalDays != nil && givenAgeDays > *schedule.AgeOptimalDays+7 {
+	isOnSchedule, isCatchUp := classifyImmunizationTiming(schedule, givenAgeDays)
+	if req.FromCatchUpPlan {
 		isCatchUp = true
 	}
 
-	// Insert immunization record
+	// Insert immunization record. A schedule item may have several records
+	// (e.g. a dose repeated after an invalid early dose).
 	query := `
 		INSERT INTO child_immunizations (
 			child_id, immunization_schedule_id,
 			given_date, given_at_age_days, given_at_age_months,
 			location, healthcare_facility, doctor_name, vaccine_batch_number, notes,
-			is_on_schedule, is_catch_up
-		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-		ON CONFLICT (child_id, immunization_schedule_id) 
-		DO UPDATE SET
-			given_date = EXCLUDED.given_date,
-			given_at_age_days = EXCLUDED.given_at_age_days,
-			given_at_age_months = EXCLUDED.given_at_age_months,
-			location = EXCLUDED.location,
-			healthcare_facility = EXCLUDED.healthcare_facility,
-			doctor_name = EXCLUDED.doctor_name,
-			vaccine_batch_number = EXCLUDED.vaccine_batch_number,
-			notes = EXCLUDED.notes,
-			is_on_schedule = EXCLUDED.is_on_schedule,
-			is_catch_up = EXCLUDED.is_catch_up,
-			updated_at = CURRENT_TIMESTAMP
-		RETURNING id
+			is_on_schedule, is_catch_up, from_catch_up_plan
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING *
 	`
 
-	var recordID string
-	err = db.DB.QueryRow(query,
+	var record models.ChildImmunization
+	err = db.DB.Get(&record, query,
 		childID, req.ImmunizationScheduleID,
 		req.GivenDate, givenAgeDays, givenAgeMonths,
 		req.Location, req.HealthcareFacility, req.DoctorName, req.VaccineBatchNumber, req.Notes,
-		isOnSchedule, isCatchUp,
-	).Scan(&recordID)
+		isOnSchedule, isCatchUp, req.FromCatchUpPlan,
+	)
 
 	if err != nil {
 		c.Logger().Errorf("Failed to record immunization: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to record immunization"})
 	}
+	recordID := record.ID
+
+	// Log audit
+	utils.LogAudit(userID, "create", "child_immunization", &recordID, nil, record, c.RealIP(), c.Request().UserAgent())
 
 	return c.JSON(http.StatusOK, map[string]interface{}{
 		"id":      recordID,
 		"message": "Imunisasi berhasil dicatat",
 		"status":  "completed",
+		"record":  record,
 	})
 }
 
@@ -230,10 +239,13 @@ func getActiveImmunizationSchedules() ([]models.ImmunizationSchedule, error) {
 	return schedules, err
 }
 
+// getCompletedImmunizations returns the valid record of each completed schedule item.
+// If a dose was recorded more than once, the latest valid record counts.
 func getCompletedImmunizations(childID string) (map[string]*models.ChildImmunization, error) {
 	query := `
 		SELECT * FROM child_immunizations
-		WHERE child_id = $1
+		WHERE child_id = $1 AND is_valid = true
+		ORDER BY given_date ASC, created_at ASC
 	`
 	
 	var records []models.ChildImmunization
@@ -251,9 +263,130 @@ func getCompletedImmunizations(childID string) (map[string]*models.ChildImmuniza
 	return completed, nil
 }
 
+// buildImmunizationStatuses calculates the status of every schedule item and the summary.
+// Due dates depend on earlier doses of the same vaccine series.
+func buildImmunizationStatuses(
+	schedules []models.ImmunizationSchedule,
+	dob string,
+	ageInDays int,
+	ageInMonths int,
+	completedImmunizations map[string]*models.ChildImmunization,
+	reviewFlags map[string]*models.ImmunizationReviewFlag,
+) ([]models.ImmunizationStatus, models.ImmunizationSummary) {
+	statuses := []models.ImmunizationStatus{}
+	summary := models.ImmunizationSummary{
+		Total: len(schedules),
+	}
+
+	dueDates := calculateSeriesDueDates(schedules, dob, completedImmunizations)
+
+	for _, schedule := range schedules {
+		status := calculateImmunizationStatus(schedule, dueDates[schedule.ID], ageInDays, ageInMonths, completedImmunizations[schedule.ID])
+		if status.Status != "completed" {
+			status.ReviewFlag = reviewFlags[schedule.ID]
+		}
+
+		statuses = append(statuses, status)
+
+		// Update summary
+		switch status.Status {
+		case "completed":
+			summary.Completed++
+		case "pending":
+			summary.Pending++
+		case "overdue":
+			summary.Overdue++
+		case "upcoming":
+			summary.Upcoming++
+		}
+	}
+
+	return statuses, summary
+}
+
+// classifyImmunizationTiming reports whether a dose given at the given age was on schedule
+// (within ±7 days of the optimal age) or a catch-up dose (later than that)
+func classifyImmunizationTiming(schedule models.ImmunizationSchedule, givenAgeDays int) (bool, bool) {
+	if schedule.AgeOptimalDays == nil {
+		return false, false
+	}
+	diff := givenAgeDays - *schedule.AgeOptimalDays
+	isOnSchedule := diff >= -utils.CatchUpWindowDays && diff <= utils.CatchUpWindowDays
+	isCatchUp := diff > utils.CatchUpWindowDays
+	return isOnSchedule, isCatchUp
+}
+
+// seriesDueDate is the calculated due date of a schedule item and the previous dose it depends on
+type seriesDueDate struct {
+	due              utils.DoseDueDate
+	previousDoseDate *string
+}
+
+// calculateSeriesDueDates calculates the due date of every schedule item. Doses of the same
+// vaccine (same name) form a series: each dose is due no earlier than the minimum interval after
+// the previous dose was actually given, or after its projected date if it was not given yet.
+func calculateSeriesDueDates(
+	schedules []models.ImmunizationSchedule,
+	dob string,
+	completed map[string]*models.ChildImmunization,
+) map[string]*seriesDueDate {
+	result := make(map[string]*seriesDueDate)
+
+	dobTime, err := utils.ParseDate(dob)
+	if err != nil {
+		return result
+	}
+
+	// Group schedule items by series, ordered by dose number
+	series := make(map[string][]models.ImmunizationSchedule)
+	for _, schedule := range schedules {
+		series[schedule.Name] = append(series[schedule.Name], schedule)
+	}
+
+	for _, doses := range series {
+		sort.SliceStable(doses, func(i, j int) bool {
+			return doses[i].DoseNumber < doses[j].DoseNumber
+		})
+
+		var previous *utils.PreviousDose
+		for _, schedule := range doses {
+			due, ok := utils.CalculateDoseDueDate(dobTime, utils.DoseTiming{
+				DoseNumber:       schedule.DoseNumber,
+				AgeMinDays:       schedule.AgeMinDays,
+				AgeOptimalDays:   schedule.AgeOptimalDays,
+				IntervalFromPrev: schedule.IntervalFromPreviousDays,
+			}, previous)
+
+			item := &seriesDueDate{due: due}
+			if previous != nil && previous.Given != nil {
+				givenStr := previous.Given.Format("2006-01-02")
+				item.previousDoseDate = &givenStr
+			}
+			if ok {
+				result[schedule.ID] = item
+			}
+
+			// This dose becomes the previous dose of the next one
+			next := &utils.PreviousDose{DoseNumber: schedule.DoseNumber}
+			if record := completed[schedule.ID]; record != nil {
+				if given, err := utils.ParseDate(record.GivenDate); err == nil {
+					next.Given = &given
+				}
+			}
+			if next.Given == nil && ok {
+				projected := due.DueDate
+				next.ProjectedDue = &projected
+			}
+			previous = next
+		}
+	}
+
+	return result
+}
+
 func calculateImmunizationStatus(
 	schedule models.ImmunizationSchedule,
-	dob string,
+	seriesDue *seriesDueDate,
 	currentAgeDays int,
 	currentAgeMonths int,
 	record *models.ChildImmunization,
@@ -270,21 +403,20 @@ func calculateImmunizationStatus(
 	}
 
 	// Calculate due date and status
-	if schedule.AgeOptimalDays == nil {
-		status.Status = "pending"
-		return status
-	}
-
-	// Parse DOB and calculate due date
-	dobTime, err := time.Parse("2006-01-02", dob)
-	if err != nil {
+	if seriesDue == nil {
 		status.Status = "pending"
 		return status
 	}
 
-	dueDate := dobTime.AddDate(0, 0, *schedule.AgeOptimalDays)
+	dueDate := seriesDue.due.DueDate
 	dueDateStr := dueDate.Format("2006-01-02")
 	status.DueDate = &dueDateStr
+	earliestStr := seriesDue.due.EarliestDate.Format("2006-01-02")
+	status.EarliestDate = &earliestStr
+	status.DueReason = seriesDue.due.Reason
+	status.DueExplanation = seriesDue.due.Explanation
+	status.PreviousDoseDate = seriesDue.previousDoseDate
+	status.WaitingForPreviousDose = seriesDue.due.WaitingForPreviousDose
 
 	if schedule.AgeOptimalMonths != nil {
 		status.DueAgeMonths = schedule.AgeOptimalMonths
@@ -0,0 +1,391 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/jung-kurt/gofpdf/v2"
+	"github.com/labstack/echo/v4"
+)
+
+const immunizationCertificateColumns = `id, child_id, issued_by, summary, dose_count, issued_at, revoked_at, revoked_reason`
+
+// certificateDose is a valid dose printed on the certificate
+type certificateDose struct {
+	Vaccine     string  `db:"name"`
+	DoseNumber  int     `db:"dose_number"`
+	GivenDate   string  `db:"given_date"`
+	Facility    *string `db:"healthcare_facility"`
+	Location    *string `db:"location"`
+	BatchNumber *string `db:"vaccine_batch_number"`
+}
+
+// ExportImmunizationCertificate issues an immunization certificate for a child and returns it as PDF.
+// Every download issues a new certificate; older ones stay verifiable until revoked.
+func ExportImmunizationCertificate(c echo.Context) error {
+	child := requestChild(c)
+	childID := child.ID
+	userID := c.Get("user_id").(string)
+
+	// Valid doses only; invalidated doses are not certified
+	doses := []certificateDose{}
+	err := db.DB.Select(&doses, `
+		SELECT s.name, s.dose_number, ci.given_date, ci.healthcare_facility, ci.location, ci.vaccine_batch_number
+		FROM child_immunizations ci
+		JOIN immunization_schedule s ON s.id = ci.immunization_schedule_id
+		WHERE ci.child_id = $1 AND ci.is_valid = true
+		ORDER BY ci.given_date ASC, s.name ASC, s.dose_number ASC`, childID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get immunization records: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get immunization records"})
+	}
+	if len(doses) == 0 {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Belum ada imunisasi yang tercatat untuk dibuatkan sertifikat"})
+	}
+
+	summary := models.CertificateSummary{
+		ChildInitials: childInitials(child.Name),
+		Doses:         make([]models.CertificateSummaryDose, 0, len(doses)),
+	}
+	if dob, err := utils.ParseDate(child.DOB); err == nil {
+		child.DOB = dob.Format("2006-01-02")
+		summary.BirthYear = dob.Year()
+	}
+	for i := range doses {
+		if given, err := utils.ParseDate(doses[i].GivenDate); err == nil {
+			doses[i].GivenDate = given.Format("2006-01-02")
+		}
+		summary.Doses = append(summary.Doses, models.CertificateSummaryDose{
+			Vaccine:    doses[i].Vaccine,
+			DoseNumber: doses[i].DoseNumber,
+			GivenDate:  doses[i].GivenDate,
+		})
+	}
+	summaryJSON, err := json.Marshal(summary)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate certificate"})
+	}
+
+	var certificate models.ImmunizationCertificate
+	err = db.DB.Get(&certificate, `INSERT INTO immunization_certificates (child_id, issued_by, summary, dose_count)
+		VALUES ($1, $2, $3, $4) RETURNING `+immunizationCertificateColumns,
+		childID, userID, summaryJSON, len(doses))
+	if err != nil {
+		c.Logger().Errorf("Failed to create immunization certificate: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate certificate"})
+	}
+
+	// Log audit
+	utils.LogAudit(userID, "issue", "immunization_certificate", &certificate.ID, nil, certificate, c.RealIP(), c.Request().UserAgent())
+
+	verifyURL := certificateVerificationURL(certificate.ID)
+	qr, err := utils.EncodeQR(verifyURL)
+	if err != nil {
+		c.Logger().Errorf("Failed to encode certificate QR code: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate certificate"})
+	}
+
+	pdf := generateImmunizationCertificatePDF(*child, doses, certificate, qr, verifyURL)
+
+	// Set response headers
+	c.Response().Header().Set("Content-Type", "application/pdf")
+	c.Response().Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=tukem_sertifikat_imunisasi_%s_%s.pdf", childID[:8], time.Now().Format("20060102")))
+
+	// Write PDF to response
+	err = pdf.Output(c.Response().Writer)
+	if err != nil {
+		c.Logger().Errorf("Failed to write PDF: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate PDF"})
+	}
+
+	return nil
+}
+
+// GetImmunizationCertificates lists the certificates issued for a child, newest first
+func GetImmunizationCertificates(c echo.Context) error {
+	childID := requestChild(c).ID
+
+	certificates := []models.ImmunizationCertificate{}
+	err := db.DB.Select(&certificates, `SELECT `+immunizationCertificateColumns+` FROM immunization_certificates
+		WHERE child_id = $1 ORDER BY issued_at DESC`, childID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get immunization certificates: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get immunization certificates"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"certificates": certificates,
+		"total":        len(certificates),
+	})
+}
+
+// RevokeImmunizationCertificate revokes a certificate; its QR code then verifies as revoked
+func RevokeImmunizationCertificate(c echo.Context) error {
+	childID := requestChild(c).ID
+	certificateID := c.Param("certId")
+	userID := c.Get("user_id").(string)
+
+	if err := utils.ValidateUUID(certificateID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid certificate ID format"})
+	}
+
+	req := new(models.RevokeCertificateRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
+	}
+	var reason *string
+	if req.Reason != nil && strings.TrimSpace(*req.Reason) != "" {
+		trimmed := strings.TrimSpace(*req.Reason)
+		if err := utils.ValidateStringLength(trimmed, 1, 1000, "reason"); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+		}
+		reason = &trimmed
+	}
+
+	var before models.ImmunizationCertificate
+	err := db.DB.Get(&before, `SELECT `+immunizationCertificateColumns+` FROM immunization_certificates
+		WHERE id = $1 AND child_id = $2`, certificateID, childID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Sertifikat tidak ditemukan"})
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to get immunization certificate: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to revoke certificate"})
+	}
+	if before.RevokedAt != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Sertifikat sudah dicabut"})
+	}
+
+	var certificate models.ImmunizationCertificate
+	err = db.DB.Get(&certificate, `UPDATE immunization_certificates SET revoked_at = NOW(), revoked_reason = $1
+		WHERE id = $2 RETURNING `+immunizationCertificateColumns, reason, certificateID)
+	if err != nil {
+		c.Logger().Errorf("Failed to revoke immunization certificate: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to revoke certificate"})
+	}
+
+	// Log audit
+	utils.LogAudit(userID, "revoke", "immunization_certificate", &certificateID, before, certificate, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":     "Sertifikat berhasil dicabut",
+		"certificate": certificate,
+	})
+}
+
+// VerifyImmunizationCertificate is the public endpoint behind the certificate QR code. It checks
+// the signed token and returns the stored summary, or reports that the certificate was revoked.
+// The summary of a revoked certificate is not shown.
+func VerifyImmunizationCertificate(c echo.Context) error {
+	certificateID, err := utils.VerifyCertificateToken(c.Param("token"))
+	if err != nil || utils.ValidateUUID(certificateID) != nil {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{"valid": false, "error": "Sertifikat tidak ditemukan"})
+	}
+
+	var certificate models.ImmunizationCertificate
+	err = db.DB.Get(&certificate, `SELECT `+immunizationCertificateColumns+` FROM immunization_certificates
+		WHERE id = $1`, certificateID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]interface{}{"valid": false, "error": "Sertifikat tidak ditemukan"})
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to get immunization certificate: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+
+	c.Response().Header().Set("Cache-Control", "no-store")
+
+	result := models.CertificateVerification{
+		CertificateID: certificate.ID,
+		IssuedAt:      certificate.IssuedAt,
+	}
+	if certificate.RevokedAt != nil {
+		result.Status = "revoked"
+		result.RevokedAt = certificate.RevokedAt
+		return c.JSON(http.StatusOK, result)
+	}
+
+	var summary models.CertificateSummary
+	if err := json.Unmarshal(certificate.Summary, &summary); err != nil {
+		c.Logger().Errorf("Failed to decode certificate summary: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Internal server error"})
+	}
+	result.Valid = true
+	result.Status = "valid"
+	result.Summary = &summary
+
+	return c.JSON(http.StatusOK, result)
+}
+
+// certificateVerificationURL returns the public verification URL encoded in the certificate QR code
+func certificateVerificationURL(certificateID string) string {
+	return utils.PublicURL("/verify/certificates/" + utils.SignCertificateToken(certificateID))
+}
+
+// childInitials returns the initials of a name, e.g. "Budi Santoso" -> "B.S."
+func childInitials(name string) string {
+	var b strings.Builder
+	for _, word := range strings.Fields(name) {
+		for _, r := range word {
+			b.WriteString(strings.ToUpper(string(r)) + ".")
+			break
+		}
+	}
+	return b.String()
+}
+
+func generateImmunizationCertificatePDF(child models.Child, doses []certificateDose, certificate models.ImmunizationCertificate, qr [][]bool, verifyURL string) *gofpdf.Fpdf {
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTopMargin(25)
+	pdf.SetLeftMargin(18)
+	pdf.SetRightMargin(18)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AddPage()
+
+	// Title
+	pdf.SetFont("Arial", "B", 18)
+	pdf.SetTextColor(0, 102, 204) // Blue color
+	pdf.SetXY(18, 25)
+	pdf.MultiCell(130, 9, "Tukem - Sertifikat Imunisasi Anak", "", "", false)
+
+	pdf.SetFont("Arial", "", 9)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.Cell(130, 5, fmt.Sprintf("Diterbitkan pada: %s", certificate.IssuedAt.Format("02 January 2006, 15:04 WIB")))
+	pdf.Ln(5)
+	pdf.Cell(130, 5, fmt.Sprintf("No. Sertifikat: %s", certificate.ID))
+
+	// QR code in the top right corner, with a 4-module quiet zone
+	const qrSize = 36.0
+	qrX, qrY := 192-qrSize, 20.0
+	addQRCode(pdf, qr, qrX, qrY, qrSize)
+	pdf.SetFont("Arial", "", 6)
+	pdf.SetXY(qrX, qrY+qrSize)
+	pdf.CellFormat(qrSize, 3, "Pindai untuk verifikasi", "", 0, "C", false, 0, "")
+
+	pdf.SetY(62)
+	pdf.SetDrawColor(0, 0, 0)
+	pdf.Line(18, pdf.GetY(), 192, pdf.GetY())
+	pdf.Ln(8)
+
+	// Child info
+	pdf.SetFont("Arial", "B", 14)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Cell(0, 8, "Informasi Anak")
+	pdf.Ln(10)
+
+	dobFormatted := child.DOB
+	if dobTime, err := time.Parse("2006-01-02", child.DOB); err == nil {
+		dobFormatted = dobTime.Format("02 January 2006")
+	}
+	genderText := "Laki-laki"
+	if child.Gender == "female" {
+		genderText = "Perempuan"
+	}
+	for _, row := range [][2]string{{"Nama:", child.Name}, {"Tanggal Lahir:", dobFormatted}, {"Jenis Kelamin:", genderText}} {
+		pdf.SetFont("Arial", "B", 10)
+		pdf.Cell(35, 6, row[0])
+		pdf.SetFont("Arial", "", 10)
+		pdf.Cell(0, 6, row[1])
+		pdf.Ln(7)
+	}
+	pdf.Ln(6)
+
+	// Doses
+	pdf.SetFont("Arial", "B", 14)
+	pdf.Cell(0, 8, fmt.Sprintf("Riwayat Imunisasi (%d dosis)", len(doses)))
+	pdf.Ln(10)
+
+	addCertificateTableHeader(pdf)
+	pdf.SetFont("Arial", "", 8)
+	pdf.SetDrawColor(220, 220, 220)
+	for _, dose := range doses {
+		if pdf.GetY() > 255 {
+			pdf.AddPage()
+			addCertificateTableHeader(pdf)
+			pdf.SetFont("Arial", "", 8)
+			pdf.SetDrawColor(220, 220, 220)
+		}
+
+		givenDate := dose.GivenDate
+		if t, err := time.Parse("2006-01-02", dose.GivenDate); err == nil {
+			givenDate = t.Format("02 Jan 2006")
+		}
+		facility := "-"
+		if dose.Facility != nil && *dose.Facility != "" {
+			facility = *dose.Facility
+		} else if dose.Location != nil && *dose.Location != "" {
+			facility = *dose.Location
+		}
+		batch := "-"
+		if dose.BatchNumber != nil && *dose.BatchNumber != "" {
+			batch = *dose.BatchNumber
+		}
+
+		pdf.CellFormat(56, 6, truncatePDFText(pdf, dose.Vaccine, 55), "B", 0, "", false, 0, "")
+		pdf.CellFormat(14, 6, fmt.Sprintf("%d", dose.DoseNumber), "B", 0, "C", false, 0, "")
+		pdf.CellFormat(26, 6, givenDate, "B", 0, "", false, 0, "")
+		pdf.CellFormat(48, 6, truncatePDFText(pdf, facility, 47), "B", 0, "", false, 0, "")
+		pdf.CellFormat(30, 6, truncatePDFText(pdf, batch, 29), "B", 1, "", false, 0, "")
+	}
+
+	// Verification note
+	pdf.Ln(6)
+	pdf.SetFont("Arial", "", 8)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.MultiCell(0, 4, "Keaslian sertifikat ini dapat diperiksa dengan memindai kode QR atau membuka tautan berikut:", "", "", false)
+	pdf.SetTextColor(0, 102, 204)
+	pdf.MultiCell(0, 4, verifyURL, "", "", false)
+
+	// Footer on last page
+	pdf.SetY(275)
+	pdf.SetFont("Arial", "I", 7)
+	pdf.SetTextColor(150, 150, 150)
+	footerText := "Sertifikat ini dibuat oleh aplikasi Tukem berdasarkan catatan imunisasi yang dimasukkan orang tua. Halaman verifikasi hanya menampilkan inisial, tahun lahir, dan daftar vaksin."
+	pdf.MultiCell(0, 4, footerText, "", "C", false)
+
+	return pdf
+}
+
+func addCertificateTableHeader(pdf *gofpdf.Fpdf) {
+	pdf.SetFont("Arial", "B", 8)
+	pdf.SetFillColor(240, 240, 240)
+	pdf.SetDrawColor(200, 200, 200)
+	pdf.CellFormat(56, 7, "Vaksin", "B", 0, "", true, 0, "")
+	pdf.CellFormat(14, 7, "Dosis", "B", 0, "C", true, 0, "")
+	pdf.CellFormat(26, 7, "Tanggal", "B", 0, "", true, 0, "")
+	pdf.CellFormat(48, 7, "Fasilitas Kesehatan", "B", 0, "", true, 0, "")
+	pdf.CellFormat(30, 7, "No. Batch", "B", 1, "", true, 0, "")
+}
+
+// addQRCode draws QR modules as filled squares inside a size x size box, including the quiet zone
+func addQRCode(pdf *gofpdf.Fpdf, qr [][]bool, x, y, size float64) {
+	const quietZone = 4
+	moduleSize := size / float64(len(qr)+2*quietZone)
+	pdf.SetFillColor(0, 0, 0)
+	for row := range qr {
+		for col := range qr[row] {
+			if qr[row][col] {
+				pdf.Rect(x+float64(col+quietZone)*moduleSize, y+float64(row+quietZone)*moduleSize, moduleSize, moduleSize, "F")
+			}
+		}
+	}
+}
+
+// truncatePDFText shortens text with "..." so it fits in width mm with the current font
+func truncatePDFText(pdf *gofpdf.Fpdf, text string, width float64) string {
+	if pdf.GetStringWidth(text) <= width {
+		return text
+	}
+	runes := []rune(text)
+	for len(runes) > 0 && pdf.GetStringWidth(string(runes)+"...") > width {
+		runes = runes[:len(runes)-1]
+	}
+	return string(runes) + "..."
+}
@@ -0,0 +1,255 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// GetImmunizationRecords lists all immunization records of a child, including repeated
+// and invalid doses, newest first
+func GetImmunizationRecords(c echo.Context) error {
+	childID := requestChild(c).ID
+
+	records := []models.ChildImmunization{}
+	err := db.DB.Select(&records, `SELECT * FROM child_immunizations
+		WHERE child_id = $1 ORDER BY given_date DESC, created_at DESC`, childID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get immunization records: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get immunization records"})
+	}
+
+	// Attach the schedule item of each record
+	ids := make([]string, 0, len(records))
+	for _, r := range records {
+		ids = append(ids, r.ImmunizationScheduleID)
+	}
+	var schedules []models.ImmunizationSchedule
+	if len(ids) > 0 {
+		err = db.DB.Select(&schedules, `SELECT * FROM immunization_schedule WHERE id = ANY($1)`, pq.Array(ids))
+		if err != nil {
+			c.Logger().Errorf("Failed to get immunization schedules: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get immunization records"})
+		}
+	}
+	if err := translateImmunizationSchedules(schedules, resolveLanguage(c)); err != nil {
+		c.Logger().Warnf("Failed to translate immunization schedules: %v", err)
+	}
+	byID := make(map[string]*models.ImmunizationSchedule)
+	for i := range schedules {
+		byID[schedules[i].ID] = &schedules[i]
+	}
+	for i := range records {
+		records[i].Schedule = byID[records[i].ImmunizationScheduleID]
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"records": records,
+		"total":   len(records),
+	})
+}
+
+// UpdateImmunizationRecord edits an immunization record. Changing the given date or schedule
+// item recalculates the age at the given date and the on-schedule / catch-up flags.
+func UpdateImmunizationRecord(c echo.Context) error {
+	child := requestChild(c)
+	recordID := c.Param("recordId")
+	userID := c.Get("user_id").(string)
+
+	before, status, message := getChildImmunizationRecord(child.ID, recordID)
+	if status != 0 {
+		return c.JSON(status, map[string]string{"error": message})
+	}
+
+	req := new(models.ImmunizationRecordUpdateRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
+	}
+
+	scheduleID := before.ImmunizationScheduleID
+	if req.ImmunizationScheduleID != nil && *req.ImmunizationScheduleID != "" {
+		scheduleID = *req.ImmunizationScheduleID
+	}
+	givenDate := before.GivenDate
+	if len(givenDate) > 10 {
+		givenDate = givenDate[:10]
+	}
+	if req.GivenDate != nil && *req.GivenDate != "" {
+		givenDate = *req.GivenDate
+	}
+
+	var schedule models.ImmunizationSchedule
+	err := db.DB.Get(&schedule, "SELECT * FROM immunization_schedule WHERE id = $1", scheduleID)
+	if err != nil {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Immunization schedule not found"})
+	}
+
+	// Recalculate age at given date
+	givenAgeDays, givenAgeMonths, _, err := utils.CalculateCorrectedAge(
+		child.DOB, givenDate, child.IsPremature, child.GestationalAge)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid given_date format"})
+	}
+	isOnSchedule, isCatchUp := classifyImmunizationTiming(schedule, givenAgeDays)
+	// Doses recorded from the catch-up plan stay catch-up doses whatever the new timing
+	if before.FromCatchUpPlan {
+		isCatchUp = true
+	}
+
+	location := before.Location
+	if req.Location != nil {
+		location = req.Location
+	}
+	facility := before.HealthcareFacility
+	if req.HealthcareFacility != nil {
+		facility = req.HealthcareFacility
+	}
+	doctor := before.DoctorName
+	if req.DoctorName != nil {
+		doctor = req.DoctorName
+	}
+	batch := before.VaccineBatchNumber
+	if req.VaccineBatchNumber != nil {
+		batch = req.VaccineBatchNumber
+	}
+	notes := before.Notes
+	if req.Notes != nil {
+		notes = req.Notes
+	}
+
+	var record models.ChildImmunization
+	err = db.DB.Get(&record, `
+		UPDATE child_immunizations SET
+			immunization_schedule_id = $1,
+			given_date = $2, given_at_age_days = $3, given_at_age_months = $4,
+			location = $5, healthcare_facility = $6, doctor_name = $7, vaccine_batch_number = $8, notes = $9,
+			is_on_schedule = $10, is_catch_up = $11,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE id = $12
+		RETURNING *`,
+		scheduleID, givenDate, givenAgeDays, givenAgeMonths,
+		location, facility, doctor, batch, notes,
+		isOnSchedule, isCatchUp, recordID)
+	if err != nil {
+		c.Logger().Errorf("Failed to update immunization record: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update immunization record"})
+	}
+
+	// Log audit
+	utils.LogAudit(userID, "update", "child_immunization", &recordID, before, record, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Catatan imunisasi berhasil diperbarui",
+		"record":  record,
+	})
+}
+
+// SetImmunizationRecordValidity marks an immunization record invalid (with a reason) or valid again.
+// Invalid doses are kept for history but do not count towards the schedule status.
+func SetImmunizationRecordValidity(c echo.Context) error {
+	childID := requestChild(c).ID
+	recordID := c.Param("recordId")
+	userID := c.Get("user_id").(string)
+
+	before, status, message := getChildImmunizationRecord(childID, recordID)
+	if status != 0 {
+		return c.JSON(status, map[string]string{"error": message})
+	}
+
+	req := new(models.ImmunizationValidityRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
+	}
+
+	var record models.ChildImmunization
+	var err error
+	if req.IsValid {
+		err = db.DB.Get(&record, `
+			UPDATE child_immunizations SET
+				is_valid = true, invalid_reason = NULL, invalidated_by = NULL, invalidated_at = NULL,
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1
+			RETURNING *`, recordID)
+	} else {
+		reason := ""
+		if req.Reason != nil {
+			reason = strings.TrimSpace(*req.Reason)
+		}
+		if err := utils.ValidateStringLength(reason, 1, 1000, "reason"); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Alasan dosis tidak valid wajib diisi"})
+		}
+		err = db.DB.Get(&record, `
+			UPDATE child_immunizations SET
+				is_valid = false, invalid_reason = $1, invalidated_by = $2, invalidated_at = NOW(),
+				updated_at = CURRENT_TIMESTAMP
+			WHERE id = $3
+			RETURNING *`, reason, userID, recordID)
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to update immunization record validity: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to update immunization record"})
+	}
+
+	// Log audit
+	action := "invalidate"
+	if req.IsValid {
+		action = "revalidate"
+	}
+	utils.LogAudit(userID, action, "child_immunization", &recordID, before, record, c.RealIP(), c.Request().UserAgent())
+
+	message = "Dosis ditandai tidak valid dan tidak dihitung dalam jadwal"
+	if req.IsValid {
+		message = "Dosis ditandai valid kembali"
+	}
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": message,
+		"record":  record,
+	})
+}
+
+// DeleteImmunizationRecord deletes an immunization record (and its KIPI reports)
+func DeleteImmunizationRecord(c echo.Context) error {
+	childID := requestChild(c).ID
+	recordID := c.Param("recordId")
+	userID := c.Get("user_id").(string)
+
+	before, status, message := getChildImmunizationRecord(childID, recordID)
+	if status != 0 {
+		return c.JSON(status, map[string]string{"error": message})
+	}
+
+	if _, err := db.DB.Exec("DELETE FROM child_immunizations WHERE id = $1", recordID); err != nil {
+		c.Logger().Errorf("Failed to delete immunization record: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to delete immunization record"})
+	}
+
+	// Log audit
+	utils.LogAudit(userID, "delete", "child_immunization", &recordID, before, nil, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Catatan imunisasi berhasil dihapus"})
+}
+
+// getChildImmunizationRecord loads one of the child's immunization records.
+// On failure it returns the HTTP status and error message.
+func getChildImmunizationRecord(childID, recordID string) (*models.ChildImmunization, int, string) {
+	if err := utils.ValidateUUID(recordID); err != nil {
+		return nil, http.StatusBadRequest, "Invalid immunization record ID"
+	}
+
+	var record models.ChildImmunization
+	err := db.DB.Get(&record, "SELECT * FROM child_immunizations WHERE id = $1 AND child_id = $2", recordID, childID)
+	if err == sql.ErrNoRows {
+		return nil, http.StatusNotFound, "Catatan imunisasi tidak ditemukan"
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Failed to get immunization record"
+	}
+
+	return &record, 0, ""
+}
@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"database/sql"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+const kipiReportColumns = `id, child_immunization_id, child_id, immunization_schedule_id, reported_by,
+	onset_at, symptoms, severity, outcome, vaccine_batch_number, is_serious, notes, created_at, updated_at`
+
+const reviewFlagColumns = `id, child_id, immunization_schedule_id, kipi_report_id, reason, status,
+	reviewed_by, reviewed_at, review_notes, created_at`
+
+// CreateKIPIReport records an adverse event following a recorded immunization.
+// A serious event flags the child's later doses of the same vaccine for clinician review.
+func CreateKIPIReport(c echo.Context) error {
+	childID := requestChild(c).ID
+	recordID := c.Param("recordId")
+	userID := c.Get("user_id").(string)
+
+	if err := utils.ValidateUUID(recordID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid immunization record ID"})
+	}
+
+	// Get the immunization record and its vaccine
+	var scheduleID, givenDate, vaccineName string
+	var doseNumber int
+	var recordBatch *string
+	err := db.DB.QueryRow(`
+		SELECT ci.immunization_schedule_id, ci.given_date, ci.vaccine_batch_number, s.name, s.dose_number
+		FROM child_immunizations ci
+		JOIN immunization_schedule s ON s.id = ci.immunization_schedule_id
+		WHERE ci.id = $1 AND ci.child_id = $2`, recordID, childID).
+		Scan(&scheduleID, &givenDate, &recordBatch, &vaccineName, &doseNumber)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Catatan imunisasi tidak ditemukan"})
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to get immunization record: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get immunization record"})
+	}
+
+	// Parse request
+	req := new(models.KIPIReportRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request format"})
+	}
+
+	symptoms, err := utils.NormalizeKIPISymptoms(req.Symptoms)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": err.Error()})
+	}
+	if !utils.IsValidKIPISeverity(req.Severity) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "severity must be one of: " + strings.Join(utils.ValidKIPISeverities, ", "),
+		})
+	}
+	if !utils.IsValidKIPIOutcome(req.Outcome) {
+		return c.JSON(http.StatusBadRequest, map[string]string{
+			"error": "outcome must be one of: " + strings.Join(utils.ValidKIPIOutcomes, ", "),
+		})
+	}
+
+	onsetAt, err := time.Parse(time.RFC3339, req.OnsetAt)
+	if err != nil {
+		onsetAt, err = time.Parse("2006-01-02", req.OnsetAt)
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid onset_at format (use RFC 3339 or YYYY-MM-DD)"})
+	}
+	if given, err := utils.ParseDate(givenDate); err == nil && onsetAt.Before(given) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "onset_at tidak boleh sebelum tanggal imunisasi"})
+	}
+	if onsetAt.After(time.Now()) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "onset_at tidak boleh di masa depan"})
+	}
+
+	// The batch number defaults to the one on the immunization record
+	batchNumber := recordBatch
+	if req.VaccineBatchNumber != nil && strings.TrimSpace(*req.VaccineBatchNumber) != "" {
+		trimmed := strings.TrimSpace(*req.VaccineBatchNumber)
+		batchNumber = &trimmed
+	}
+
+	isSerious := utils.IsSeriousKIPI(req.Severity, req.Outcome, symptoms)
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		c.Logger().Errorf("Failed to begin transaction: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save KIPI report"})
+	}
+	defer tx.Rollback()
+
+	var report models.KIPIReport
+	err = tx.Get(&report, `
+		INSERT INTO kipi_reports (
+			child_immunization_id, child_id, immunization_schedule_id, reported_by,
+			onset_at, symptoms, severity, outcome, vaccine_batch_number, is_serious, notes
+		) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+		RETURNING `+kipiReportColumns,
+		recordID, childID, scheduleID, userID,
+		onsetAt, pq.Array(symptoms), req.Severity, req.Outcome, batchNumber, isSerious, req.Notes)
+	if err != nil {
+		c.Logger().Errorf("Failed to save KIPI report: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save KIPI report"})
+	}
+
+	// Flag later doses of the same vaccine that have not been given yet
+	flags := []models.ImmunizationReviewFlag{}
+	if isSerious {
+		reason := fmt.Sprintf("KIPI serius setelah %s dosis %d (%s). Dosis berikutnya perlu ditinjau dokter sebelum diberikan",
+			vaccineName, doseNumber, onsetAt.Format("2006-01-02"))
+		err = tx.Select(&flags, `
+			INSERT INTO immunization_review_flags (child_id, immunization_schedule_id, kipi_report_id, reason)
+			SELECT $1, s.id, $2, $3
+			FROM immunization_schedule s
+			WHERE s.name = $4 AND s.dose_number > $5 AND s.is_active = true
+				AND NOT EXISTS (
+					SELECT 1 FROM child_immunizations ci
+					WHERE ci.child_id = $1 AND ci.immunization_schedule_id = s.id AND ci.is_valid = true
+				)
+			ON CONFLICT (child_id, immunization_schedule_id, kipi_report_id) DO NOTHING
+			RETURNING `+reviewFlagColumns,
+			childID, report.ID, reason, vaccineName, doseNumber)
+		if err != nil {
+			c.Logger().Errorf("Failed to flag later doses for review: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save KIPI report"})
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		c.Logger().Errorf("Failed to commit KIPI report: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to save KIPI report"})
+	}
+
+	message := "Laporan KIPI berhasil disimpan"
+	if isSerious {
+		message = "Laporan KIPI serius disimpan. Segera hubungi fasilitas kesehatan; dosis berikutnya perlu ditinjau dokter"
+	}
+
+	return c.JSON(http.StatusCreated, map[string]interface{}{
+		"message":      message,
+		"report":       report,
+		"review_flags": flags,
+	})
+}
+
+// GetChildKIPIReports lists the adverse event reports of a child
+func GetChildKIPIReports(c echo.Context) error {
+	childID := requestChild(c).ID
+
+	reports := []models.KIPIReport{}
+	err := db.DB.Select(&reports, `SELECT `+kipiReportColumns+` FROM kipi_reports
+		WHERE child_id = $1 ORDER BY onset_at DESC`, childID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get KIPI reports: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get KIPI reports"})
+	}
+
+	flags := []models.ImmunizationReviewFlag{}
+	err = db.DB.Select(&flags, `SELECT `+reviewFlagColumns+` FROM immunization_review_flags
+		WHERE child_id = $1 ORDER BY created_at DESC`, childID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get review flags: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get KIPI reports"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"reports":      reports,
+		"review_flags": flags,
+		"total":        len(reports),
+	})
+}
+
+// getOpenReviewFlags returns the review flags of a child that still block a dose
+// (pending review or contraindicated), keyed by schedule ID
+func getOpenReviewFlags(childID string) (map[string]*models.ImmunizationReviewFlag, error) {
+	var flags []models.ImmunizationReviewFlag
+	err := db.DB.Select(&flags, `SELECT `+reviewFlagColumns+` FROM immunization_review_flags
+		WHERE child_id = $1 AND status IN ('pending', 'contraindicated')
+		ORDER BY created_at ASC`, childID)
+	if err != nil {
+		return nil, err
+	}
+
+	result := make(map[string]*models.ImmunizationReviewFlag)
+	for i := range flags {
+		result[flags[i].ImmunizationScheduleID] = &flags[i]
+	}
+	return result, nil
+}
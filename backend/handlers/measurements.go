@@ -8,36 +8,12 @@ import (
 	"tukem-backend/models"
 	"tukem-backend/utils"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 )
 
 // CreateMeasurement creates a new measurement for a child
 func CreateMeasurement(c echo.Context) error {
-	childID := c.Param("id")
-
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := db.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
-
-	// Get child data for calculations (including premature info)
-	var child models.Child
-	err = db.DB.QueryRow("SELECT id, dob, gender, is_premature, gestational_age FROM children WHERE id = $1", childID).
-		Scan(&child.ID, &child.DOB, &child.Gender, &child.IsPremature, &child.GestationalAge)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get child data"})
-	}
+	child := requestChild(c)
 
 	// Parse request
 	req := new(models.CreateMeasurementRequest)
@@ -48,35 +24,62 @@ func CreateMeasurement(c echo.Context) error {
 
 	c.Logger().Info("Received measurement request: ", req)
 
-	// Validate required fields
+	if msg := validateMeasurementRequest(*child, req); msg != "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": msg})
+	}
+
+	response, err := createMeasurement(c, *child, req)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{
+			"error": "Gagal menyimpan pengukuran. Pastikan data yang diinput valid.",
+			"details": err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusCreated, response)
+}
+
+// validateMeasurementRequest checks a new measurement of the child; returns the error message,
+// or "" if the measurement is valid
+func validateMeasurementRequest(child models.Child, req *models.CreateMeasurementRequest) string {
 	if req.MeasurementDate == "" {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "measurement_date is required"})
+		return "measurement_date is required"
 	}
 	if req.Weight <= 0 {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "weight must be greater than 0"})
+		return "weight must be greater than 0"
 	}
 	if req.Height <= 0 {
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "height must be greater than 0"})
+		return "height must be greater than 0"
+	}
+	if _, _, _, err := utils.CalculateCorrectedAge(child.DOB, req.MeasurementDate, child.IsPremature, child.GestationalAge); err != nil {
+		return "Invalid measurement date format. Expected YYYY-MM-DD"
 	}
+	return ""
+}
+
+// createMeasurement calculates the age, Z-scores and nutritional status of a validated
+// measurement and stores it. Shared by CreateMeasurement and WhatsApp chat commands.
+func createMeasurement(c echo.Context, child models.Child, req *models.CreateMeasurementRequest) (*models.MeasurementResponse, error) {
+	childID := child.ID
 
 	// Calculate age (using corrected age if premature and < 24 months)
 	ageInDays, ageInMonths, useCorrected, err := utils.CalculateCorrectedAge(
 		child.DOB, req.MeasurementDate, child.IsPremature, child.GestationalAge)
 	if err != nil {
 		c.Logger().Error("Age calculation error: ", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid measurement date format. Expected YYYY-MM-DD"})
+		return nil, err
 	}
 
 	// Also calculate chronological age for storage/display
 	chronoDays, err := utils.CalculateAgeInDays(child.DOB, req.MeasurementDate)
 	if err != nil {
 		c.Logger().Error("Chronological age calculation error: ", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid measurement date format. Expected YYYY-MM-DD"})
+		return nil, err
 	}
 	chronoMonths, err := utils.CalculateAgeInMonths(child.DOB, req.MeasurementDate)
 	if err != nil {
 		c.Logger().Error("Chronological age calculation error: ", err)
-		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid measurement date format. Expected YYYY-MM-DD"})
+		return nil, err
 	}
 
 	if useCorrected {
@@ -168,10 +171,7 @@ func CreateMeasurement(c echo.Context) error {
 		c.Logger().Errorf("Query: %s", query)
 		c.Logger().Errorf("Params: childID=%s, date=%s, weight=%.2f, height=%.2f", 
 			childID, req.MeasurementDate, req.Weight, req.Height)
-		return c.JSON(http.StatusInternalServerError, map[string]string{
-			"error": "Gagal menyimpan pengukuran. Pastikan data yang diinput valid.",
-			"details": err.Error(),
-		})
+		return nil, err
 	}
 
 	c.Logger().Info("Measurement created successfully with ID: ", measurement.ID)
@@ -202,27 +202,12 @@ func CreateMeasurement(c echo.Context) error {
 		c.Logger().Info("Z-scores calculated using corrected age for premature child")
 	}
 
-	return c.JSON(http.StatusCreated, response)
+	return &response, nil
 }
 
 // GetMeasurements retrieves all measurements for a child
 func GetMeasurements(c echo.Context) error {
-	childID := c.Param("id")
-
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := db.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
+	childID := requestChild(c).ID
 
 	// Get measurements
 	query := `SELECT id, child_id, measurement_date, weight, height, head_circumference, 
@@ -286,22 +271,7 @@ func GetMeasurements(c echo.Context) error {
 
 // GetLatestMeasurement retrieves the most recent measurement for a child
 func GetLatestMeasurement(c echo.Context) error {
-	childID := c.Param("id")
-
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := db.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
+	childID := requestChild(c).ID
 
 	// Get latest measurement
 	var m models.Measurement
@@ -314,7 +284,7 @@ func GetLatestMeasurement(c echo.Context) error {
 		nutritional_status, height_status, weight_for_height_status, created_at 
 		FROM measurements WHERE child_id = $1 ORDER BY measurement_date DESC LIMIT 1`
 
-	err = db.DB.QueryRow(query, childID).Scan(
+	err := db.DB.QueryRow(query, childID).Scan(
 		&m.ID, &m.ChildID, &m.MeasurementDate, &m.Weight, &m.Height, &m.HeadCircumference,
 		&m.AgeInDays, &m.AgeInMonths, &m.WeightForAgeZScore, &m.HeightForAgeZScore,
 		&wfhZScore, &hcZScore,
@@ -360,24 +330,10 @@ func GetLatestMeasurement(c echo.Context) error {
 
 // DeleteMeasurement deletes a measurement
 func DeleteMeasurement(c echo.Context) error {
-	childID := c.Param("id")
+	child := requestChild(c)
+	childID := child.ID
 	measurementID := c.Param("measurementId")
 
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := db.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
-
 	// Delete measurement
 	query := `DELETE FROM measurements WHERE id = $1 AND child_id = $2`
 	result, err := db.DB.Exec(query, measurementID, childID)
@@ -401,27 +357,13 @@ func DeleteMeasurement(c echo.Context) error {
 
 // UpdateMeasurement updates an existing measurement
 func UpdateMeasurement(c echo.Context) error {
-	childID := c.Param("id")
+	child := requestChild(c)
+	childID := child.ID
 	measurementID := c.Param("measurementId")
 
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := db.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
-
 	// Get existing measurement to verify it belongs to child
 	var existingChildID string
-	err = db.DB.QueryRow("SELECT child_id FROM measurements WHERE id = $1", measurementID).Scan(&existingChildID)
+	err := db.DB.QueryRow("SELECT child_id FROM measurements WHERE id = $1", measurementID).Scan(&existingChildID)
 	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, map[string]string{"error": "Measurement not found"})
 	}
@@ -429,14 +371,6 @@ func UpdateMeasurement(c echo.Context) error {
 		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Measurement does not belong to this child"})
 	}
 
-	// Get child data for calculations (including premature info)
-	var child models.Child
-	err = db.DB.QueryRow("SELECT id, dob, gender, is_premature, gestational_age FROM children WHERE id = $1", childID).
-		Scan(&child.ID, &child.DOB, &child.Gender, &child.IsPremature, &child.GestationalAge)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get child data"})
-	}
-
 	// Parse request
 	req := new(models.CreateMeasurementRequest)
 	if err := c.Bind(req); err != nil {
@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"database/sql"
 	"net/http"
 	"strconv"
 	"time"
@@ -36,28 +35,31 @@ func (h *MilestoneHandler) GetMilestones(c echo.Context) error {
 		}
 	}
 	
-	// If child_id is provided, calculate corrected age if applicable
+	// If child_id is provided, select milestones by the child's reference age
+	// (corrected age for premature children under 24 months)
+	ageBasis := utils.AgeBasisChronological
 	if childID != "" {
 		// Get user ID from JWT to verify ownership
 		user := c.Get("user").(*jwt.Token)
 		claims := *user.Claims.(*jwt.MapClaims)
 		userID := claims["user_id"].(string)
 		
-		// Verify child belongs to user and get child data
-		var child models.Child
-		err := h.DB.QueryRow("SELECT id, dob, is_premature, gestational_age FROM children WHERE id = $1 AND parent_id = $2", 
-			childID, userID).Scan(&child.ID, &child.DOB, &child.IsPremature, &child.GestationalAge)
+		// Verify the user can access the child and get child data
+		child, err := utils.AuthorizeChild(childID, userID, utils.GuardianViewer)
+		if err != nil {
+			status, msg := utils.ChildAccessStatus(err)
+			return c.JSON(status, map[string]string{"error": msg})
+		}
+		today := time.Now().Format("2006-01-02")
+		_, referenceMonths, basis, err := utils.CalculateReferenceAge(
+			child.DOB, today, child.IsPremature, child.GestationalAge)
 		if err == nil {
-			// Calculate corrected age using current date as measurement date
-			today := time.Now().Format("2006-01-02")
-			_, correctedMonths, useCorrected, err := utils.CalculateCorrectedAge(
-				child.DOB, today, child.IsPremature, child.GestationalAge)
-			if err == nil && useCorrected {
-				ageMonths = correctedMonths
-				c.Logger().Infof("Using corrected age %d months for premature child %s", ageMonths, childID)
-			}
+			ageMonths = referenceMonths
+			ageBasis = basis
+			c.Logger().Infof("Using %s age %d months for child %s", ageBasis, ageMonths, childID)
 		}
 	}
+	setAgeBasisHeaders(c, ageBasis, ageMonths)
 
 	// Logic: Fetch milestones for:
 	// 1. Current age target (e.g., if child is 7 months, target is 9 months or 6 months depending on proximity)
@@ -74,6 +76,7 @@ func (h *MilestoneHandler) GetMilestones(c echo.Context) error {
 	query := `
 		SELECT * FROM milestones 
 		WHERE age_months >= $1 - 3 AND age_months <= $1 + 6
+			AND is_active = true
 		ORDER BY age_months ASC, pyramid_level ASC
 	`
 	
@@ -83,32 +86,18 @@ func (h *MilestoneHandler) GetMilestones(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to fetch milestones"})
 	}
 
+	if err := translateMilestones(milestones, resolveLanguage(c)); err != nil {
+		c.Logger().Warnf("Failed to translate milestones: %v", err)
+	}
+
 	return c.JSON(http.StatusOK, milestones)
 }
 
 // GetChildAssessments fetches all assessments for a child
 func (h *MilestoneHandler) GetChildAssessments(c echo.Context) error {
-	childID := c.Param("id")
-
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := h.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if err != nil {
-		c.Logger().Errorf("Failed to verify child ownership: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify child ownership"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
-	
+	child := requestChild(c)
+	childID := child.ID
+
 	query := `
 		SELECT 
 			a.id,
@@ -123,6 +112,7 @@ func (h *MilestoneHandler) GetChildAssessments(c echo.Context) error {
 			m.age_months as "milestone.age_months",
 			m.category as "milestone.category",
 			m.question as "milestone.question",
+			COALESCE(m.question_en, '') as "milestone.question_en",
 			m.pyramid_level as "milestone.pyramid_level",
 			m.source as "milestone.source",
 			m.denver_domain as "milestone.denver_domain"
@@ -157,6 +147,7 @@ func (h *MilestoneHandler) GetChildAssessments(c echo.Context) error {
 			&m.AgeMonths,
 			&m.Category,
 			&m.Question,
+			&m.QuestionEn,
 			&m.PyramidLevel,
 			&m.Source,
 			&m.DenverDomain,
@@ -170,32 +161,26 @@ func (h *MilestoneHandler) GetChildAssessments(c echo.Context) error {
 		assessments = append(assessments, a)
 	}
 
+	// Translate the joined milestone questions
+	milestones := make([]models.Milestone, len(assessments))
+	for i, a := range assessments {
+		milestones[i] = *a.Milestone
+	}
+	if err := translateMilestones(milestones, resolveLanguage(c)); err != nil {
+		c.Logger().Warnf("Failed to translate milestones: %v", err)
+	}
+	for i := range assessments {
+		assessments[i].Milestone = &milestones[i]
+	}
+
 	return c.JSON(http.StatusOK, assessments)
 }
 
 // BatchUpsertAssessments handles bulk update of assessments
 func (h *MilestoneHandler) BatchUpsertAssessments(c echo.Context) error {
-	childID := c.Param("id")
-
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := h.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if err != nil {
-		c.Logger().Errorf("Failed to verify child ownership: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify child ownership"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
-	
+	child := requestChild(c)
+	childID := child.ID
+
 	var req models.BatchAssessmentRequest
 	if err := c.Bind(&req); err != nil {
 		c.Logger().Errorf("Failed to bind request: %v", err)
@@ -276,6 +261,12 @@ func (h *MilestoneHandler) BatchUpsertAssessments(c echo.Context) error {
 	}
 
 	c.Logger().Infof("Successfully saved %d assessments for child %s", len(req.Items), childID)
+
+	// Track developmental age-equivalent/DQ over time
+	if err := recordDevelopmentalSnapshot(childID, req.AssessmentDate); err != nil {
+		c.Logger().Warnf("Failed to record developmental snapshot for child %s: %v", childID, err)
+	}
+
 	return c.JSON(http.StatusOK, map[string]string{
 		"message": "Penilaian berhasil disimpan",
 		"count":   strconv.Itoa(len(req.Items)),
@@ -284,37 +275,27 @@ func (h *MilestoneHandler) BatchUpsertAssessments(c echo.Context) error {
 
 // GetAssessmentSummary calculates pyramid health and returns summary
 func (h *MilestoneHandler) GetAssessmentSummary(c echo.Context) error {
-	childID := c.Param("id")
-
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := h.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if err != nil {
-		c.Logger().Errorf("Failed to verify child ownership: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify child ownership"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
+	child := requestChild(c)
+	childID := child.ID
 
 	// 1. Fetch all assessments for this child joined with milestones
 	// Only include KPSP milestones for pyramid calculation (Denver II uses different domain system)
 	query := `
-		SELECT a.status, m.category, m.pyramid_level, m.is_red_flag, m.question
+		SELECT a.status, m.id, m.category, m.pyramid_level, m.is_red_flag, m.question, COALESCE(m.question_en, ''),
+			m.age_months, m.max_age_range
 		FROM assessments a
 		JOIN milestones m ON a.milestone_id = m.id
 		WHERE a.child_id = $1
 			AND m.source = 'KPSP'
 	`
 
+	_, ageMonths, ageBasis, err := utils.CalculateReferenceAge(
+		child.DOB, time.Now().Format("2006-01-02"), child.IsPremature, child.GestationalAge)
+	if err != nil {
+		c.Logger().Errorf("Failed to calculate age: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to calculate child age"})
+	}
+
 	rows, err := h.DB.Queryx(query, childID)
 	if err != nil {
 		c.Logger().Errorf("Failed to fetch assessment data: %v", err)
@@ -325,16 +306,20 @@ func (h *MilestoneHandler) GetAssessmentSummary(c echo.Context) error {
 	// Data structures for calculation
 	type AssessmentData struct {
 		Status       string
+		MilestoneID  string
 		Category     string
 		PyramidLevel int
 		IsRedFlag    bool
 		Question     string
+		QuestionEn   string
+		AgeMonths    int
+		MaxAgeRange  *int
 	}
 
 	var data []AssessmentData
 	for rows.Next() {
 		var d AssessmentData
-		if err := rows.Scan(&d.Status, &d.Category, &d.PyramidLevel, &d.IsRedFlag, &d.Question); err != nil {
+		if err := rows.Scan(&d.Status, &d.MilestoneID, &d.Category, &d.PyramidLevel, &d.IsRedFlag, &d.Question, &d.QuestionEn, &d.AgeMonths, &d.MaxAgeRange); err != nil {
 			continue
 		}
 		data = append(data, d)
@@ -343,22 +328,35 @@ func (h *MilestoneHandler) GetAssessmentSummary(c echo.Context) error {
 	// 2. Calculate scores
 	totalByLevel := make(map[int]int)
 	completedByLevel := make(map[int]int)
+	totalByCategory := make(map[string]int)
+	completedByCategory := make(map[string]int)
 	redFlags := []models.Milestone{}
 	
 	for _, d := range data {
 		totalByLevel[d.PyramidLevel]++
+		totalByCategory[d.Category]++
 		if d.Status == "yes" {
 			completedByLevel[d.PyramidLevel]++
+			completedByCategory[d.Category]++
 		}
 		
-		if d.IsRedFlag && d.Status == "no" {
+		// With corrected age, a premature child is not flagged for milestones not yet due
+		if d.IsRedFlag && d.Status == "no" &&
+			(ageBasis != utils.AgeBasisCorrected || utils.IsMilestoneDue(d.AgeMonths, d.MaxAgeRange, ageMonths)) {
 			redFlags = append(redFlags, models.Milestone{
-				Question: d.Question,
-				Category: d.Category,
+				ID:         d.MilestoneID,
+				Question:   d.Question,
+				QuestionEn: d.QuestionEn,
+				Category:   d.Category,
 			})
 		}
 	}
 
+	lang := resolveLanguage(c)
+	if err := translateMilestones(redFlags, lang); err != nil {
+		c.Logger().Warnf("Failed to translate red flags: %v", err)
+	}
+
 	// 3. Calculate percentages
 	progressByCategory := make(map[string]float64)
 	// Map levels to categories for response
@@ -376,32 +374,29 @@ func (h *MilestoneHandler) GetAssessmentSummary(c echo.Context) error {
 		}
 	}
 
-	// 4. Logic Warnings (Pyramid Imbalance)
-	warnings := []string{}
-	
-	// Check: High Cognitive (Level 4) but Low Sensory (Level 1)
-	// Thresholds: Cognitive > 70% and Sensory < 50%
-	sensoryScore := 0.0
-	if totalByLevel[1] > 0 {
-		sensoryScore = float64(completedByLevel[1]) / float64(totalByLevel[1]) * 100
-	}
-	
-	cognitiveScore := 0.0
-	if totalByLevel[4] > 0 {
-		cognitiveScore = float64(completedByLevel[4]) / float64(totalByLevel[4]) * 100
-	}
-
-	if cognitiveScore > 70 && sensoryScore < 50 {
-		warnings = append(warnings, "Terdeteksi 'Lompatan Perkembangan'. Anak mahir kognitif tapi pondasi sensorik (Level 1) belum kuat. Risiko: Masalah fokus/emosi di kemudian hari.")
+	// 4. Logic Warnings (Pyramid Imbalance) - evaluated from configurable rules
+	warnings, warningDetails, err := evaluatePyramidWarnings(totalByLevel, completedByLevel, totalByCategory, completedByCategory, lang)
+	if err != nil {
+		c.Logger().Errorf("Failed to evaluate pyramid warning rules: %v", err)
 	}
 
 	summary := models.AssessmentSummary{
-		TotalMilestones:     len(data),
-		CompletedMilestones: len(data), // This logic might need adjustment, currently just count of assessed items
-		ProgressByCategory:  progressByCategory,
-		RedFlagsDetected:    redFlags,
-		PyramidWarnings:     warnings,
+		TotalMilestones:       len(data),
+		CompletedMilestones:   len(data), // This logic might need adjustment, currently just count of assessed items
+		ProgressByCategory:    progressByCategory,
+		RedFlagsDetected:      redFlags,
+		PyramidWarnings:       warnings,
+		PyramidWarningDetails: warningDetails,
+		AgeMonths:             ageMonths,
+		AgeBasis:              ageBasis,
 	}
 
 	return c.JSON(http.StatusOK, summary)
 }
+
+// setAgeBasisHeaders reports which age was used for milestone selection on
+// endpoints that return a plain list
+func setAgeBasisHeaders(c echo.Context, ageBasis string, ageMonths int) {
+	c.Response().Header().Set("X-Age-Basis", ageBasis)
+	c.Response().Header().Set("X-Age-Months", strconv.Itoa(ageMonths))
+}
@@ -0,0 +1,1018 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"io"
+	"log"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/services"
+	"tukem-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jung-kurt/gofpdf/v2"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	monthlyDigestTemplate       = "monthly_digest"
+	monthlyDigestLockKey        = 7341042 // pg_advisory_lock key: one digest run at a time across replicas
+	monthlyDigestMaxActivities  = 3
+	monthlyDigestCheckInterval  = time.Hour
+	monthlyDigestPDFContentType = "application/pdf"
+)
+
+// digestRecipient is a parent who receives the monthly digest
+type digestRecipient struct {
+	UserID            string  `db:"user_id"`
+	ParentName        *string `db:"parent_name"`
+	Email             *string `db:"email"`
+	PhoneNumber       *string `db:"phone_number"`
+	PhoneVerified     bool    `db:"phone_verified"`
+	PreferredLanguage *string `db:"preferred_language"`
+	DigestChannel     string  `db:"digest_channel"`
+	QuietHoursStart   *string `db:"quiet_hours_start"`
+	QuietHoursEnd     *string `db:"quiet_hours_end"`
+	Timezone          *string `db:"timezone"`
+}
+
+// digestChildRow is a child included in a digest
+type digestChildRow struct {
+	ID             string `db:"id"`
+	Name           string `db:"name"`
+	DOB            string `db:"dob"`
+	IsPremature    bool   `db:"is_premature"`
+	GestationalAge *int   `db:"gestational_age"`
+}
+
+// StartMonthlyDigestScheduler checks every hour whether the digests of the previous month are due
+// (from monthly_digest_day on) and generates those not generated yet. monthly_digests keeps each
+// family from receiving a digest twice; replicas coordinate through a Postgres advisory lock.
+func StartMonthlyDigestScheduler() {
+	go func() {
+		// Let the server finish starting before the first run
+		time.Sleep(2 * time.Minute)
+		for {
+			if sent, err := RunMonthlyDigests(time.Now()); err != nil {
+				log.Printf("Digests: %v", err)
+			} else if sent > 0 {
+				log.Printf("Digests: generated %d monthly digest(s)", sent)
+			}
+			time.Sleep(monthlyDigestCheckInterval)
+		}
+	}()
+}
+
+// RunMonthlyDigests generates the digests of the previous month once monthly_digest_day is
+// reached (in the reminder time zone). Returns the number of digests generated.
+func RunMonthlyDigests(now time.Time) (int, error) {
+	service := notificationService()
+	settings, err := service.Settings()
+	if err != nil {
+		return 0, err
+	}
+	if settings.Get("enable_monthly_digest", "", "true") != "true" {
+		return 0, nil
+	}
+
+	local := now.In(reminderLocation(settings))
+	day, _ := strconv.Atoi(settings.Get("monthly_digest_day", "", "1"))
+	if day < 1 || day > 28 {
+		day = 1
+	}
+	if local.Day() < day {
+		return 0, nil
+	}
+	period := time.Date(local.Year(), local.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0)
+	return generateMonthlyDigests(service, settings, period, now)
+}
+
+// RunAdminMonthlyDigests generates the digests of a period now (admin). Defaults to the previous
+// month; families that already have a digest for the period are skipped.
+func RunAdminMonthlyDigests(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+
+	period, err := parseDigestPeriod(c.QueryParam("period"), time.Now())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid period, expected YYYY-MM"})
+	}
+
+	service := notificationService()
+	settings, err := service.Settings()
+	if err != nil {
+		c.Logger().Errorf("RunAdminMonthlyDigests error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	generated, err := generateMonthlyDigests(service, settings, period, time.Now())
+	if err != nil {
+		c.Logger().Errorf("RunAdminMonthlyDigests error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "run", "monthly_digests", nil, nil,
+		map[string]interface{}{"period": period.Format("2006-01"), "generated": generated},
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":   "Monthly digest run completed",
+		"period":    period.Format("2006-01"),
+		"generated": generated,
+	})
+}
+
+// PreviewAdminMonthlyDigest builds the digest of a family without storing or sending it (admin).
+// format: json (default), text (the rendered whatsapp or email message), html or pdf.
+func PreviewAdminMonthlyDigest(c echo.Context) error {
+	userID := c.QueryParam("user_id")
+	if err := utils.ValidateUUID(userID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user_id"})
+	}
+	period, err := parseDigestPeriod(c.QueryParam("period"), time.Now())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid period, expected YYYY-MM"})
+	}
+
+	var parent struct {
+		FullName          *string `db:"full_name"`
+		PreferredLanguage *string `db:"preferred_language"`
+	}
+	err = db.DB.Get(&parent, `SELECT full_name, preferred_language FROM users WHERE id = $1`, userID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("PreviewAdminMonthlyDigest error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	lang := utils.DefaultLanguage
+	if parent.PreferredLanguage != nil && *parent.PreferredLanguage != "" {
+		lang = *parent.PreferredLanguage
+	}
+	if c.QueryParam("lang") != "" {
+		lang = utils.NormalizeLanguage(c.QueryParam("lang"))
+	}
+
+	content, err := buildMonthlyDigest(userID, digestParentName(parent.FullName, lang), period, lang, time.Now())
+	if err != nil {
+		c.Logger().Errorf("PreviewAdminMonthlyDigest error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	switch c.QueryParam("format") {
+	case "", "json":
+		return c.JSON(http.StatusOK, content)
+	case "text":
+		channel := c.QueryParam("channel")
+		if channel == "" {
+			channel = services.ChannelWhatsApp
+		}
+		msg, err := notificationService().Render(monthlyDigestTemplate, channel, lang, digestTemplateData(content, lang))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": utils.SanitizeError(err)})
+		}
+		return c.JSON(http.StatusOK, map[string]string{"channel": channel, "subject": msg.Subject, "body": msg.Body})
+	case "html":
+		html, err := renderDigestHTML(content, lang)
+		if err != nil {
+			c.Logger().Errorf("PreviewAdminMonthlyDigest error: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to render digest"})
+		}
+		return c.HTML(http.StatusOK, html)
+	case "pdf":
+		return writeDigestPDF(c, content, lang)
+	default:
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "format must be json, text, html or pdf"})
+	}
+}
+
+// GetMonthlyDigests lists the stored digests of the user, newest first
+func GetMonthlyDigests(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	digests := []models.MonthlyDigest{}
+	err := db.DB.Select(&digests, `SELECT id, user_id, period, language, pdf_key, channel, outbound_message_id,
+			read_at, created_at
+		FROM monthly_digests WHERE user_id = $1 ORDER BY period DESC`, userID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get monthly digests: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengambil ringkasan bulanan"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"digests": digests,
+		"total":   len(digests),
+	})
+}
+
+// GetMonthlyDigest returns a stored digest of the user and marks it as read
+func GetMonthlyDigest(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	digest, err := getUserMonthlyDigest(c.Param("id"), userID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Ringkasan tidak ditemukan"})
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to get monthly digest: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengambil ringkasan bulanan"})
+	}
+
+	if digest.ReadAt == nil {
+		now := time.Now()
+		if _, err := db.DB.Exec(`UPDATE monthly_digests SET read_at = $1 WHERE id = $2`, now, digest.ID); err != nil {
+			c.Logger().Warnf("Failed to mark monthly digest as read: %v", err)
+		} else {
+			digest.ReadAt = &now
+		}
+	}
+
+	return c.JSON(http.StatusOK, digest)
+}
+
+// ExportMonthlyDigestPDF downloads the PDF of a stored digest, regenerating it from the stored
+// content if the object store no longer has it
+func ExportMonthlyDigestPDF(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	digest, err := getUserMonthlyDigest(c.Param("id"), userID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Ringkasan tidak ditemukan"})
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to get monthly digest: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengambil ringkasan bulanan"})
+	}
+
+	if digest.PDFKey != nil {
+		reader, err := attachmentStore.Get(*digest.PDFKey)
+		if err == nil {
+			defer reader.Close()
+			c.Response().Header().Set("Content-Type", monthlyDigestPDFContentType)
+			c.Response().Header().Set("Content-Disposition", "attachment; filename="+digestPDFFilename(digest.Period))
+			c.Response().WriteHeader(http.StatusOK)
+			_, err = io.Copy(c.Response().Writer, reader)
+			return err
+		}
+		c.Logger().Warnf("Stored digest PDF %s unavailable, regenerating: %v", *digest.PDFKey, err)
+	}
+
+	var content models.DigestContent
+	if err := json.Unmarshal(digest.Content, &content); err != nil {
+		c.Logger().Errorf("Failed to decode monthly digest: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal membuat PDF ringkasan"})
+	}
+	return writeDigestPDF(c, &content, digest.Language)
+}
+
+func getUserMonthlyDigest(digestID, userID string) (*models.MonthlyDigest, error) {
+	if utils.ValidateUUID(digestID) != nil {
+		return nil, sql.ErrNoRows
+	}
+	var digest models.MonthlyDigest
+	err := db.DB.Get(&digest, `SELECT * FROM monthly_digests WHERE id = $1 AND user_id = $2`, digestID, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &digest, nil
+}
+
+// generateMonthlyDigests builds, stores and sends the digests of period (first day of the month)
+// for every family with children that opted in and has no digest for the period yet. Returns 0
+// without error if another replica holds the digest lock.
+func generateMonthlyDigests(service *services.NotificationService, settings services.NotificationSettings, period, now time.Time) (int, error) {
+	ctx := context.Background()
+	conn, err := db.DB.Connx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	var locked bool
+	if err := conn.GetContext(ctx, &locked, `SELECT pg_try_advisory_lock($1)`, monthlyDigestLockKey); err != nil {
+		return 0, fmt.Errorf("failed to acquire digest lock: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, monthlyDigestLockKey)
+
+	recipients := []digestRecipient{}
+	err = db.DB.Select(&recipients, `SELECT u.id AS user_id, u.full_name AS parent_name, u.email, u.phone_number,
+			COALESCE(u.phone_verified, false) AS phone_verified, u.preferred_language,
+			COALESCE(p.digest_channel, 'whatsapp') AS digest_channel,
+			p.quiet_hours_start, p.quiet_hours_end, p.timezone
+		FROM users u
+		LEFT JOIN user_notification_preferences p ON p.user_id = u.id
+		WHERE EXISTS (SELECT 1 FROM child_guardians g WHERE g.user_id = u.id)
+			AND COALESCE(p.monthly_digest, true)
+			AND NOT EXISTS (SELECT 1 FROM monthly_digests d WHERE d.user_id = u.id AND d.period = $1)
+		ORDER BY u.created_at`, period.Format("2006-01"))
+	if err != nil {
+		return 0, fmt.Errorf("failed to load digest recipients: %w", err)
+	}
+
+	generated := 0
+	for _, recipient := range recipients {
+		ok, err := sendMonthlyDigest(service, settings, recipient, period, now)
+		if err != nil {
+			log.Printf("Digests: user %s: %v", recipient.UserID, err)
+		}
+		if ok {
+			generated++
+		}
+	}
+	return generated, nil
+}
+
+// sendMonthlyDigest builds and stores the digest of one family, then queues it on the parent's
+// digest channel (falling back to the other channel). The digest stays readable in the app if it
+// cannot be sent. Returns whether a digest was stored.
+func sendMonthlyDigest(service *services.NotificationService, settings services.NotificationSettings,
+	recipient digestRecipient, period, now time.Time) (bool, error) {
+	lang := utils.DefaultLanguage
+	if recipient.PreferredLanguage != nil && *recipient.PreferredLanguage != "" {
+		lang = *recipient.PreferredLanguage
+	}
+
+	content, err := buildMonthlyDigest(recipient.UserID, digestParentName(recipient.ParentName, lang), period, lang, now)
+	if err != nil {
+		return false, err
+	}
+	if len(content.Children) == 0 {
+		return false, nil
+	}
+	raw, err := json.Marshal(content)
+	if err != nil {
+		return false, err
+	}
+
+	// Claim the period: another run may have stored the digest in the meantime
+	var digestID string
+	err = db.DB.Get(&digestID, `INSERT INTO monthly_digests (user_id, period, language, content)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, period) DO NOTHING
+		RETURNING id`, recipient.UserID, content.Period, lang, raw)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to store digest: %w", err)
+	}
+
+	pdfKey := fmt.Sprintf("digests/%s/%s.pdf", recipient.UserID, content.Period)
+	var buf bytes.Buffer
+	if err := generateMonthlyDigestPDF(content, lang).Output(&buf); err != nil {
+		log.Printf("Digests: failed to generate PDF of digest %s: %v", digestID, err)
+		pdfKey = ""
+	} else if err := attachmentStore.Put(pdfKey, bytes.NewReader(buf.Bytes()), int64(buf.Len()), monthlyDigestPDFContentType); err != nil {
+		log.Printf("Digests: failed to store PDF of digest %s: %v", digestID, err)
+		pdfKey = ""
+	} else if _, err := db.DB.Exec(`UPDATE monthly_digests SET pdf_key = $1 WHERE id = $2`, pdfKey, digestID); err != nil {
+		log.Printf("Digests: failed to link PDF of digest %s: %v", digestID, err)
+	}
+
+	channel, to := digestDestination(recipient, settings)
+	if channel == "" {
+		return true, nil
+	}
+
+	data := digestTemplateData(content, lang)
+	opts := services.EnqueueOptions{
+		NotBefore: quietHoursNotBefore(recipient.QuietHoursStart, recipient.QuietHoursEnd, recipient.Timezone, settings, now),
+	}
+	var messageID string
+	if channel == services.ChannelEmail {
+		msg, err := service.Render(monthlyDigestTemplate, channel, lang, data)
+		if err != nil {
+			return true, fmt.Errorf("digest %s stored but not sent: %w", digestID, err)
+		}
+		msg.To = to
+		if msg.HTML, err = renderDigestHTML(content, lang); err != nil {
+			log.Printf("Digests: failed to render HTML of digest %s: %v", digestID, err)
+		}
+		if pdfKey != "" {
+			opts.Attachment = &services.StoredAttachment{
+				Key:         pdfKey,
+				Filename:    digestPDFFilename(content.Period),
+				ContentType: monthlyDigestPDFContentType,
+			}
+		}
+		messageID, err = service.EnqueueMessage(msg, monthlyDigestTemplate, opts)
+		if err != nil {
+			return true, fmt.Errorf("digest %s stored but not sent: %w", digestID, err)
+		}
+	} else {
+		messageID, err = service.Enqueue(channel, to, monthlyDigestTemplate, lang, data, opts)
+		if err != nil {
+			return true, fmt.Errorf("digest %s stored but not sent: %w", digestID, err)
+		}
+	}
+
+	if _, err := db.DB.Exec(`UPDATE monthly_digests SET channel = $1, outbound_message_id = $2 WHERE id = $3`,
+		channel, messageID, digestID); err != nil {
+		log.Printf("Digests: failed to link digest %s to message %s: %v", digestID, messageID, err)
+	}
+	return true, nil
+}
+
+// digestDestination picks the channel and address of the digest: the parent's digest channel if
+// usable (an email address, or a verified phone number) and enabled, otherwise the other one.
+// Returns an empty channel when neither is usable; the digest is then only shown in the app.
+func digestDestination(recipient digestRecipient, settings services.NotificationSettings) (string, string) {
+	destinations := map[string]string{}
+	if recipient.Email != nil && strings.Contains(*recipient.Email, "@") && settings.Enabled(services.ChannelEmail) {
+		destinations[services.ChannelEmail] = *recipient.Email
+	}
+	if recipient.PhoneNumber != nil && *recipient.PhoneNumber != "" && recipient.PhoneVerified && settings.Enabled(services.ChannelWhatsApp) {
+		destinations[services.ChannelWhatsApp] = *recipient.PhoneNumber
+	}
+
+	order := []string{services.ChannelWhatsApp, services.ChannelEmail}
+	if recipient.DigestChannel == services.ChannelEmail {
+		order = []string{services.ChannelEmail, services.ChannelWhatsApp}
+	}
+	for _, channel := range order {
+		if to, ok := destinations[channel]; ok {
+			return channel, to
+		}
+	}
+	return "", ""
+}
+
+// buildMonthlyDigest collects, for each child of the parent, the latest measurement up to the end
+// of the period compared with the previous one, the milestones achieved during the period, the
+// currently overdue immunizations and a few recommended activities
+func buildMonthlyDigest(userID, parentName string, period time.Time, lang string, now time.Time) (*models.DigestContent, error) {
+	periodEnd := period.AddDate(0, 1, -1)
+	content := &models.DigestContent{
+		Period:      period.Format("2006-01"),
+		PeriodStart: period.Format("2006-01-02"),
+		PeriodEnd:   periodEnd.Format("2006-01-02"),
+		ParentName:  parentName,
+		GeneratedAt: now,
+		Children:    []models.DigestChild{},
+	}
+
+	children := []digestChildRow{}
+	err := db.DB.Select(&children, `SELECT c.id, c.name, c.dob, c.is_premature, c.gestational_age
+		FROM children c JOIN child_guardians g ON g.child_id = c.id
+		WHERE g.user_id = $1 AND c.dob <= $2 ORDER BY c.dob`, userID, content.PeriodEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load children: %w", err)
+	}
+	if len(children) == 0 {
+		return content, nil
+	}
+
+	schedules, err := getActiveImmunizationSchedules()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load immunization schedules: %w", err)
+	}
+	if err := translateImmunizationSchedules(schedules, lang); err != nil {
+		log.Printf("Digests: failed to translate immunization schedules: %v", err)
+	}
+
+	today := now.Format("2006-01-02")
+	for _, child := range children {
+		digestChild, err := buildDigestChild(child, schedules, content, lang, today)
+		if err != nil {
+			return nil, fmt.Errorf("child %s: %w", child.ID, err)
+		}
+		content.Children = append(content.Children, *digestChild)
+	}
+	return content, nil
+}
+
+func buildDigestChild(child digestChildRow, schedules []models.ImmunizationSchedule, content *models.DigestContent,
+	lang, today string) (*models.DigestChild, error) {
+	ageInDays, ageInMonths, _, err := utils.CalculateCorrectedAge(child.DOB, today, child.IsPremature, child.GestationalAge)
+	if err != nil {
+		return nil, err
+	}
+	_, referenceMonths, _, err := utils.CalculateReferenceAge(child.DOB, today, child.IsPremature, child.GestationalAge)
+	if err != nil {
+		return nil, err
+	}
+	digestChild := &models.DigestChild{
+		ChildID:         child.ID,
+		Name:            child.Name,
+		AgeMonths:       referenceMonths,
+		ZScoreChanges:   []models.DigestZScoreChange{},
+		NewMilestones:   []models.DigestMilestone{},
+		OverdueVaccines: []string{},
+		Activities:      []models.DigestActivity{},
+	}
+
+	// Growth: latest measurement up to the end of the period and the one before it
+	measurements := []models.DigestMeasurement{}
+	err = db.DB.Select(&measurements, `SELECT measurement_date::text AS date, weight, height,
+			weight_for_age_zscore, height_for_age_zscore, weight_for_height_zscore, nutritional_status
+		FROM measurements WHERE child_id = $1 AND measurement_date <= $2
+		ORDER BY measurement_date DESC, created_at DESC LIMIT 2`, child.ID, content.PeriodEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load measurements: %w", err)
+	}
+	if len(measurements) > 0 {
+		digestChild.LatestMeasurement = &measurements[0]
+	}
+	if len(measurements) > 1 {
+		digestChild.PreviousMeasurement = &measurements[1]
+		change := roundDigestValue(measurements[0].Weight - measurements[1].Weight)
+		digestChild.WeightChange = &change
+	}
+	digestChild.ZScoreChanges = digestZScoreChanges(digestChild.LatestMeasurement, digestChild.PreviousMeasurement)
+
+	// Milestones achieved during the period
+	rows, err := db.DB.Query(`SELECT m.id, m.question, COALESCE(m.question_en, ''), m.category, a.assessment_date::text
+		FROM assessments a
+		JOIN milestones m ON m.id = a.milestone_id
+		WHERE a.child_id = $1 AND a.status = 'yes' AND a.assessment_date BETWEEN $2 AND $3
+		ORDER BY a.assessment_date, m.age_months`, child.ID, content.PeriodStart, content.PeriodEnd)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load milestones: %w", err)
+	}
+	milestones := []models.Milestone{}
+	dates := []string{}
+	for rows.Next() {
+		var m models.Milestone
+		var date string
+		if err := rows.Scan(&m.ID, &m.Question, &m.QuestionEn, &m.Category, &date); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		milestones = append(milestones, m)
+		dates = append(dates, date)
+	}
+	rows.Close()
+	if err := translateMilestones(milestones, lang); err != nil {
+		log.Printf("Digests: failed to translate milestones: %v", err)
+	}
+	for i, m := range milestones {
+		digestChild.NewMilestones = append(digestChild.NewMilestones, models.DigestMilestone{
+			Question: m.Question,
+			Category: m.Category,
+			Date:     dates[i],
+		})
+	}
+
+	// Overdue immunizations, as on the immunization schedule
+	completed, err := getCompletedImmunizations(child.ID)
+	if err != nil {
+		return nil, err
+	}
+	reviewFlags, err := getOpenReviewFlags(child.ID)
+	if err != nil {
+		return nil, err
+	}
+	statuses, _ := buildImmunizationStatuses(schedules, child.DOB, ageInDays, ageInMonths, completed, reviewFlags)
+	for _, status := range statuses {
+		if status.Status != "overdue" || status.ReviewFlag != nil {
+			continue
+		}
+		// Doses past their maximum age can no longer be given
+		if status.Schedule.AgeMaxDays != nil && ageInDays > *status.Schedule.AgeMaxDays {
+			continue
+		}
+		digestChild.OverdueVaccines = append(digestChild.OverdueVaccines, status.Schedule.Name)
+	}
+
+	// Recommended activities
+	incompleteMilestones, err := getIncompleteMilestones(child.ID, referenceMonths)
+	if err != nil {
+		log.Printf("Digests: failed to get incomplete milestones of child %s: %v", child.ID, err)
+		incompleteMilestones = []models.Milestone{}
+	}
+	recommendations, err := getRecommendationsForChild(child.ID, referenceMonths, incompleteMilestones)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get recommendations: %w", err)
+	}
+	if len(recommendations) > monthlyDigestMaxActivities {
+		recommendations = recommendations[:monthlyDigestMaxActivities]
+	}
+	if err := translateRecommendations(recommendations, lang); err != nil {
+		log.Printf("Digests: failed to translate recommendations: %v", err)
+	}
+	for _, recommendation := range recommendations {
+		digestChild.Activities = append(digestChild.Activities, models.DigestActivity{
+			Title:    recommendation.Content.Title,
+			Category: recommendation.Content.Category,
+			URL:      recommendation.Content.URL,
+			Reason:   recommendation.Reason,
+		})
+	}
+
+	return digestChild, nil
+}
+
+// digestZScoreChanges compares the z-scores of the latest measurement with the previous one
+func digestZScoreChanges(latest, previous *models.DigestMeasurement) []models.DigestZScoreChange {
+	changes := []models.DigestZScoreChange{}
+	if latest == nil {
+		return changes
+	}
+	indicators := []struct {
+		name  string
+		value func(*models.DigestMeasurement) *float64
+	}{
+		{"weight_for_age", func(m *models.DigestMeasurement) *float64 { return m.WeightForAgeZScore }},
+		{"height_for_age", func(m *models.DigestMeasurement) *float64 { return m.HeightForAgeZScore }},
+		{"weight_for_height", func(m *models.DigestMeasurement) *float64 { return m.WeightForHeightZScore }},
+	}
+	for _, indicator := range indicators {
+		current := indicator.value(latest)
+		if current == nil {
+			continue
+		}
+		change := models.DigestZScoreChange{Indicator: indicator.name, Current: *current}
+		if previous != nil {
+			if prev := indicator.value(previous); prev != nil {
+				diff := roundDigestValue(*current - *prev)
+				change.Previous = prev
+				change.Change = &diff
+			}
+		}
+		changes = append(changes, change)
+	}
+	return changes
+}
+
+// digestLabels are the texts of the digest HTML and PDF in one language
+type digestLabels struct {
+	Title         string
+	Greeting      string
+	Intro         string
+	Generated     string
+	Months        string
+	Weight        string
+	Height        string
+	Measured      string
+	NoMeasurement string
+	ZScores       string
+	NewMilestones string
+	NoMilestones  string
+	Overdue       string
+	NoOverdue     string
+	Activities    string
+	Footer        string
+	Indicators    map[string]string
+}
+
+var digestLabelsByLanguage = map[string]digestLabels{
+	"id": {
+		Title:         "Ringkasan Bulanan",
+		Greeting:      "Halo",
+		Intro:         "Berikut ringkasan tumbuh kembang anak Anda bulan %s.",
+		Generated:     "Dibuat pada",
+		Months:        "bulan",
+		Weight:        "Berat badan",
+		Height:        "Tinggi badan",
+		Measured:      "Pengukuran terakhir",
+		NoMeasurement: "Belum ada pengukuran",
+		ZScores:       "Z-score",
+		NewMilestones: "Milestone baru",
+		NoMilestones:  "Belum ada milestone baru bulan ini",
+		Overdue:       "Imunisasi terlambat",
+		NoOverdue:     "Tidak ada imunisasi terlambat",
+		Activities:    "Aktivitas yang disarankan",
+		Footer:        "Ringkasan ini dibuat oleh aplikasi Tukem. Untuk konsultasi lebih lanjut, hubungi dokter spesialis anak Anda.",
+		Indicators: map[string]string{
+			"weight_for_age":    "BB/U",
+			"height_for_age":    "TB/U",
+			"weight_for_height": "BB/TB",
+		},
+	},
+	"en": {
+		Title:         "Monthly Summary",
+		Greeting:      "Hello",
+		Intro:         "Here is your children's growth and development summary for %s.",
+		Generated:     "Generated on",
+		Months:        "months",
+		Weight:        "Weight",
+		Height:        "Height",
+		Measured:      "Latest measurement",
+		NoMeasurement: "No measurement yet",
+		ZScores:       "Z-score",
+		NewMilestones: "New milestones",
+		NoMilestones:  "No new milestones this month",
+		Overdue:       "Overdue immunizations",
+		NoOverdue:     "No overdue immunizations",
+		Activities:    "Recommended activities",
+		Footer:        "This summary was generated by the Tukem app. For further consultation, contact your pediatrician.",
+		Indicators: map[string]string{
+			"weight_for_age":    "Weight-for-age",
+			"height_for_age":    "Height-for-age",
+			"weight_for_height": "Weight-for-height",
+		},
+	},
+}
+
+var digestMonthsID = []string{"Januari", "Februari", "Maret", "April", "Mei", "Juni",
+	"Juli", "Agustus", "September", "Oktober", "November", "Desember"}
+
+func digestLabelsFor(lang string) digestLabels {
+	if labels, ok := digestLabelsByLanguage[lang]; ok {
+		return labels
+	}
+	return digestLabelsByLanguage[utils.DefaultLanguage]
+}
+
+// digestPeriodLabel formats a YYYY-MM period as "September 2026" in the digest language
+func digestPeriodLabel(period, lang string) string {
+	t, err := time.Parse("2006-01", period)
+	if err != nil {
+		return period
+	}
+	if lang == "en" {
+		return t.Format("January 2006")
+	}
+	return fmt.Sprintf("%s %d", digestMonthsID[t.Month()-1], t.Year())
+}
+
+// digestTemplateChild is a child as shown in the digest message templates and HTML
+type digestTemplateChild struct {
+	Name            string
+	AgeMonths       int
+	Weight          string
+	Height          string
+	MeasuredOn      string
+	WeightChange    string
+	ZScores         []string
+	Milestones      string
+	OverdueVaccines string
+	Activities      string
+
+	MilestoneList []models.DigestMilestone
+	OverdueList   []string
+	ActivityList  []models.DigestActivity
+}
+
+func digestTemplateChildren(content *models.DigestContent, lang, arrow string) []digestTemplateChild {
+	labels := digestLabelsFor(lang)
+	children := make([]digestTemplateChild, len(content.Children))
+	for i, child := range content.Children {
+		view := digestTemplateChild{
+			Name:          child.Name,
+			AgeMonths:     child.AgeMonths,
+			ZScores:       []string{},
+			MilestoneList: child.NewMilestones,
+			OverdueList:   child.OverdueVaccines,
+			ActivityList:  child.Activities,
+		}
+		if child.LatestMeasurement != nil {
+			view.Weight = formatDigestValue(child.LatestMeasurement.Weight)
+			view.Height = formatDigestValue(child.LatestMeasurement.Height)
+			view.MeasuredOn = reminderDueDate(child.LatestMeasurement.Date)
+		}
+		if child.WeightChange != nil {
+			view.WeightChange = formatDigestChange(*child.WeightChange)
+		}
+		for _, change := range child.ZScoreChanges {
+			line := fmt.Sprintf("%s: %s", labels.Indicators[change.Indicator], formatDigestValue(change.Current))
+			if change.Previous != nil && change.Change != nil {
+				line = fmt.Sprintf("%s: %s %s %s (%s)", labels.Indicators[change.Indicator], formatDigestValue(*change.Previous),
+					arrow, formatDigestValue(change.Current), formatDigestChange(*change.Change))
+			}
+			view.ZScores = append(view.ZScores, line)
+		}
+
+		milestones := make([]string, len(child.NewMilestones))
+		for j, milestone := range child.NewMilestones {
+			milestones[j] = milestone.Question
+		}
+		view.Milestones = strings.Join(milestones, "; ")
+		view.OverdueVaccines = strings.Join(child.OverdueVaccines, ", ")
+		activities := make([]string, len(child.Activities))
+		for j, activity := range child.Activities {
+			activities[j] = activity.Title
+		}
+		view.Activities = strings.Join(activities, "; ")
+		children[i] = view
+	}
+	return children
+}
+
+// digestTemplateData is the data of the monthly_digest notification templates
+func digestTemplateData(content *models.DigestContent, lang string) map[string]interface{} {
+	return map[string]interface{}{
+		"Period":     digestPeriodLabel(content.Period, lang),
+		"ParentName": content.ParentName,
+		"Children":   digestTemplateChildren(content, lang, "→"),
+	}
+}
+
+var digestHTMLTemplate = template.Must(template.New("digest").Parse(`<!DOCTYPE html>
+<html lang="{{.Lang}}">
+<head><meta charset="utf-8"><title>Tukem - {{.Labels.Title}} {{.Period}}</title></head>
+<body style="margin:0;padding:24px;background:#f5f7fa;font-family:Arial,Helvetica,sans-serif;color:#222;">
+<div style="max-width:600px;margin:0 auto;background:#fff;border-radius:8px;padding:24px;">
+<h1 style="margin:0 0 4px;color:#0066cc;font-size:22px;">Tukem - {{.Labels.Title}} {{.Period}}</h1>
+<p>{{.Labels.Greeting}} {{.ParentName}},</p>
+<p>{{.Intro}}</p>
+{{range .Children}}
+<div style="border-top:1px solid #e0e0e0;padding-top:12px;margin-top:16px;">
+<h2 style="margin:0 0 8px;font-size:18px;">{{.Name}} <span style="color:#666;font-weight:normal;font-size:14px;">({{.AgeMonths}} {{$.Labels.Months}})</span></h2>
+{{if .Weight}}<p style="margin:4px 0;">{{$.Labels.Weight}}: <strong>{{.Weight}} kg</strong>{{if .WeightChange}} ({{.WeightChange}} kg){{end}} &middot; {{$.Labels.Height}}: <strong>{{.Height}} cm</strong></p>
+<p style="margin:4px 0;color:#666;font-size:13px;">{{$.Labels.Measured}}: {{.MeasuredOn}}</p>
+{{else}}<p style="margin:4px 0;color:#666;">{{$.Labels.NoMeasurement}}</p>{{end}}
+{{if .ZScores}}<p style="margin:8px 0 4px;"><strong>{{$.Labels.ZScores}}</strong></p>
+<ul style="margin:0;padding-left:20px;">{{range .ZScores}}<li>{{.}}</li>{{end}}</ul>{{end}}
+<p style="margin:8px 0 4px;"><strong>{{$.Labels.NewMilestones}}</strong></p>
+{{if .MilestoneList}}<ul style="margin:0;padding-left:20px;">{{range .MilestoneList}}<li>{{.Question}}</li>{{end}}</ul>
+{{else}}<p style="margin:0;color:#666;">{{$.Labels.NoMilestones}}</p>{{end}}
+<p style="margin:8px 0 4px;"><strong>{{$.Labels.Overdue}}</strong></p>
+{{if .OverdueList}}<ul style="margin:0;padding-left:20px;color:#c0392b;">{{range .OverdueList}}<li>{{.}}</li>{{end}}</ul>
+{{else}}<p style="margin:0;color:#666;">{{$.Labels.NoOverdue}}</p>{{end}}
+{{if .ActivityList}}<p style="margin:8px 0 4px;"><strong>{{$.Labels.Activities}}</strong></p>
+<ul style="margin:0;padding-left:20px;">{{range .ActivityList}}<li>{{if .URL}}<a href="{{.URL}}" style="color:#0066cc;">{{.Title}}</a>{{else}}{{.Title}}{{end}}{{if .Reason}}<br><span style="color:#666;font-size:13px;">{{.Reason}}</span>{{end}}</li>{{end}}</ul>{{end}}
+</div>
+{{end}}
+<p style="margin-top:24px;color:#999;font-size:12px;">{{.Labels.Footer}}</p>
+</div>
+</body>
+</html>`))
+
+// renderDigestHTML renders the HTML body of the digest email
+func renderDigestHTML(content *models.DigestContent, lang string) (string, error) {
+	labels := digestLabelsFor(lang)
+	period := digestPeriodLabel(content.Period, lang)
+	var buf bytes.Buffer
+	err := digestHTMLTemplate.Execute(&buf, map[string]interface{}{
+		"Lang":       lang,
+		"Labels":     labels,
+		"Period":     period,
+		"ParentName": content.ParentName,
+		"Intro":      fmt.Sprintf(labels.Intro, period),
+		"Children":   digestTemplateChildren(content, lang, "→"),
+	})
+	return buf.String(), err
+}
+
+func generateMonthlyDigestPDF(content *models.DigestContent, lang string) *gofpdf.Fpdf {
+	labels := digestLabelsFor(lang)
+	period := digestPeriodLabel(content.Period, lang)
+
+	pdf := gofpdf.New("P", "mm", "A4", "")
+	pdf.SetTopMargin(25)
+	pdf.SetLeftMargin(18)
+	pdf.SetRightMargin(18)
+	pdf.SetAutoPageBreak(true, 20)
+	pdf.AddPage()
+
+	// Title
+	pdf.SetFont("Arial", "B", 18)
+	pdf.SetTextColor(0, 102, 204) // Blue color
+	pdf.Cell(0, 10, fmt.Sprintf("Tukem - %s %s", labels.Title, period))
+	pdf.Ln(10)
+
+	pdf.SetFont("Arial", "", 9)
+	pdf.SetTextColor(100, 100, 100)
+	pdf.Cell(0, 5, fmt.Sprintf("%s: %s", labels.Generated, content.GeneratedAt.Format("02 January 2006, 15:04 WIB")))
+	pdf.Ln(8)
+
+	pdf.SetFont("Arial", "", 10)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.MultiCell(0, 5, fmt.Sprintf("%s %s, %s", labels.Greeting, content.ParentName, fmt.Sprintf(labels.Intro, period)), "", "", false)
+	pdf.Ln(2)
+
+	for _, child := range digestTemplateChildren(content, lang, "->") {
+		if pdf.GetY() > 230 {
+			pdf.AddPage()
+		}
+		pdf.SetDrawColor(200, 200, 200)
+		pdf.Line(18, pdf.GetY()+2, 192, pdf.GetY()+2)
+		pdf.Ln(6)
+
+		pdf.SetFont("Arial", "B", 14)
+		pdf.SetTextColor(0, 0, 0)
+		pdf.Cell(0, 8, fmt.Sprintf("%s (%d %s)", child.Name, child.AgeMonths, labels.Months))
+		pdf.Ln(9)
+
+		pdf.SetFont("Arial", "", 10)
+		if child.Weight != "" {
+			weight := fmt.Sprintf("%s: %s kg", labels.Weight, child.Weight)
+			if child.WeightChange != "" {
+				weight += fmt.Sprintf(" (%s kg)", child.WeightChange)
+			}
+			pdf.Cell(0, 6, fmt.Sprintf("%s   %s: %s cm", weight, labels.Height, child.Height))
+			pdf.Ln(6)
+			pdf.SetTextColor(100, 100, 100)
+			pdf.Cell(0, 5, fmt.Sprintf("%s: %s", labels.Measured, child.MeasuredOn))
+			pdf.Ln(6)
+			pdf.SetTextColor(0, 0, 0)
+		} else {
+			pdf.Cell(0, 6, labels.NoMeasurement)
+			pdf.Ln(6)
+		}
+		if len(child.ZScores) > 0 {
+			addDigestPDFList(pdf, labels.ZScores, child.ZScores, "")
+		}
+
+		milestones := make([]string, len(child.MilestoneList))
+		for i, milestone := range child.MilestoneList {
+			milestones[i] = milestone.Question
+		}
+		addDigestPDFList(pdf, labels.NewMilestones, milestones, labels.NoMilestones)
+		addDigestPDFList(pdf, labels.Overdue, child.OverdueList, labels.NoOverdue)
+
+		if len(child.ActivityList) > 0 {
+			activities := make([]string, len(child.ActivityList))
+			for i, activity := range child.ActivityList {
+				activities[i] = activity.Title
+				if activity.Reason != "" {
+					activities[i] += " - " + activity.Reason
+				}
+			}
+			addDigestPDFList(pdf, labels.Activities, activities, "")
+		}
+	}
+
+	// Footer on last page
+	pdf.SetY(275)
+	pdf.SetFont("Arial", "I", 7)
+	pdf.SetTextColor(150, 150, 150)
+	pdf.MultiCell(0, 4, labels.Footer, "", "C", false)
+
+	return pdf
+}
+
+// addDigestPDFList writes a bold heading followed by one "- " line per item, or the empty text
+func addDigestPDFList(pdf *gofpdf.Fpdf, heading string, items []string, empty string) {
+	pdf.Ln(1)
+	pdf.SetFont("Arial", "B", 10)
+	pdf.SetTextColor(0, 0, 0)
+	pdf.Cell(0, 6, heading)
+	pdf.Ln(6)
+	pdf.SetFont("Arial", "", 9)
+	if len(items) == 0 {
+		pdf.SetTextColor(100, 100, 100)
+		pdf.MultiCell(0, 5, empty, "", "", false)
+		pdf.SetTextColor(0, 0, 0)
+		return
+	}
+	for _, item := range items {
+		pdf.MultiCell(0, 5, "- "+item, "", "", false)
+	}
+}
+
+func writeDigestPDF(c echo.Context, content *models.DigestContent, lang string) error {
+	c.Response().Header().Set("Content-Type", monthlyDigestPDFContentType)
+	c.Response().Header().Set("Content-Disposition", "attachment; filename="+digestPDFFilename(content.Period))
+
+	if err := generateMonthlyDigestPDF(content, lang).Output(c.Response().Writer); err != nil {
+		c.Logger().Errorf("Failed to write PDF: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate PDF"})
+	}
+	return nil
+}
+
+func digestPDFFilename(period string) string {
+	return fmt.Sprintf("tukem_digest_%s.pdf", period)
+}
+
+// parseDigestPeriod parses a YYYY-MM period; empty means the month before now
+func parseDigestPeriod(value string, now time.Time) (time.Time, error) {
+	if value == "" {
+		return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC).AddDate(0, -1, 0), nil
+	}
+	return time.Parse("2006-01", value)
+}
+
+func digestParentName(fullName *string, lang string) string {
+	return reminderParentName(reminderCandidate{ParentName: fullName}, lang)
+}
+
+func roundDigestValue(value float64) float64 {
+	return math.Round(value*100) / 100
+}
+
+func formatDigestValue(value float64) string {
+	return strconv.FormatFloat(roundDigestValue(value), 'f', -1, 64)
+}
+
+// formatDigestChange formats a change with its sign, e.g. "+0.3" or "-0.15"
+func formatDigestChange(value float64) string {
+	if value >= 0 {
+		return "+" + formatDigestValue(value)
+	}
+	return formatDigestValue(value)
+}
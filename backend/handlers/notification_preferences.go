@@ -0,0 +1,138 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+	"strings"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/services"
+	"tukem-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// GetNotificationPreferences returns the reminder and digest preferences of the user (defaults if never set)
+func GetNotificationPreferences(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	prefs, err := getNotificationPreferences(userID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get notification preferences: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengambil preferensi notifikasi"})
+	}
+
+	return c.JSON(http.StatusOK, prefs)
+}
+
+// UpdateNotificationPreferences updates reminder and digest opt-outs, digest channel, quiet hours
+// and time zone
+func UpdateNotificationPreferences(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	var req models.NotificationPreferencesRequest
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	prefs, err := getNotificationPreferences(userID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get notification preferences: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengambil preferensi notifikasi"})
+	}
+
+	if req.ImmunizationReminders != nil {
+		prefs.ImmunizationReminders = *req.ImmunizationReminders
+	}
+	if req.MeasurementReminders != nil {
+		prefs.MeasurementReminders = *req.MeasurementReminders
+	}
+	if req.MonthlyDigest != nil {
+		prefs.MonthlyDigest = *req.MonthlyDigest
+	}
+	if req.DigestChannel != nil {
+		if *req.DigestChannel != services.ChannelWhatsApp && *req.DigestChannel != services.ChannelEmail {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Saluran ringkasan harus whatsapp atau email"})
+		}
+		prefs.DigestChannel = *req.DigestChannel
+	}
+	for _, field := range []struct {
+		value  *string
+		target **string
+	}{
+		{req.QuietHoursStart, &prefs.QuietHoursStart},
+		{req.QuietHoursEnd, &prefs.QuietHoursEnd},
+	} {
+		if field.value == nil {
+			continue
+		}
+		value := strings.TrimSpace(*field.value)
+		if value == "" {
+			*field.target = nil
+			continue
+		}
+		if _, err := utils.ParseClock(value); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Format jam tenang tidak valid (HH:MM)"})
+		}
+		*field.target = &value
+	}
+	if (prefs.QuietHoursStart == nil) != (prefs.QuietHoursEnd == nil) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Jam mulai dan jam selesai tenang harus diisi bersamaan"})
+	}
+	if req.Timezone != nil {
+		timezone := strings.TrimSpace(*req.Timezone)
+		if timezone == "" {
+			prefs.Timezone = nil
+		} else if _, err := time.LoadLocation(timezone); err != nil {
+			return c.JSON(http.StatusBadRequest, map[string]string{"error": "Zona waktu tidak valid"})
+		} else {
+			prefs.Timezone = &timezone
+		}
+	}
+
+	err = db.DB.Get(prefs, `INSERT INTO user_notification_preferences
+			(user_id, immunization_reminders, measurement_reminders, monthly_digest, digest_channel,
+			quiet_hours_start, quiet_hours_end, timezone)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (user_id) DO UPDATE SET
+			immunization_reminders = EXCLUDED.immunization_reminders,
+			measurement_reminders = EXCLUDED.measurement_reminders,
+			monthly_digest = EXCLUDED.monthly_digest,
+			digest_channel = EXCLUDED.digest_channel,
+			quiet_hours_start = EXCLUDED.quiet_hours_start,
+			quiet_hours_end = EXCLUDED.quiet_hours_end,
+			timezone = EXCLUDED.timezone,
+			updated_at = CURRENT_TIMESTAMP
+		RETURNING *`,
+		userID, prefs.ImmunizationReminders, prefs.MeasurementReminders, prefs.MonthlyDigest, prefs.DigestChannel, prefs.QuietHoursStart, prefs.QuietHoursEnd, prefs.Timezone)
+	if err != nil {
+		c.Logger().Errorf("Failed to update notification preferences: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal menyimpan preferensi notifikasi"})
+	}
+
+	return c.JSON(http.StatusOK, prefs)
+}
+
+// getNotificationPreferences loads the preferences of a user, with defaults if no row exists
+func getNotificationPreferences(userID string) (*models.NotificationPreferences, error) {
+	prefs := models.NotificationPreferences{
+		UserID:                userID,
+		ImmunizationReminders: true,
+		MeasurementReminders:  true,
+		MonthlyDigest:         true,
+		DigestChannel:         services.ChannelWhatsApp,
+	}
+	err := db.DB.Get(&prefs, `SELECT * FROM user_notification_preferences WHERE user_id = $1`, userID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, err
+	}
+	return &prefs, nil
+}
@@ -0,0 +1,87 @@
+package handlers
+
+import (
+	"crypto/subtle"
+	"errors"
+	"net/http"
+	"strings"
+	"tukem-backend/models"
+	"tukem-backend/services"
+
+	"github.com/labstack/echo/v4"
+)
+
+// deliveryStatusAliases maps status names used by gateways to outbox statuses
+var deliveryStatusAliases = map[string]string{
+	"sent":        services.OutboxSent,
+	"accepted":    services.OutboxSent,
+	"delivered":   services.OutboxDelivered,
+	"read":        services.OutboxRead,
+	"seen":        services.OutboxRead,
+	"failed":      services.OutboxFailed,
+	"error":       services.OutboxFailed,
+	"undelivered": services.OutboxFailed,
+	"rejected":    services.OutboxFailed,
+}
+
+// ReceiveNotificationStatus handles delivery status callbacks from notification gateways.
+// The gateway must send notification_webhook_secret in the X-Webhook-Token header or the token
+// query parameter; callbacks are refused while no secret is configured.
+func ReceiveNotificationStatus(c echo.Context) error {
+	channel := c.Param("channel")
+	if !isNotificationChannel(channel) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Unknown channel"})
+	}
+
+	service := notificationService()
+	settings, err := service.Settings()
+	if err != nil {
+		c.Logger().Errorf("ReceiveNotificationStatus settings error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if status, msg := checkWebhookToken(c, settings); status != 0 {
+		return c.JSON(status, map[string]string{"error": msg})
+	}
+
+	var req models.DeliveryStatusCallback
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	req.MessageID = strings.TrimSpace(req.MessageID)
+	status, ok := deliveryStatusAliases[strings.ToLower(strings.TrimSpace(req.Status))]
+	if req.MessageID == "" || !ok {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "message_id and a known status are required"})
+	}
+
+	message, err := service.ApplyDeliveryStatus(channel, req.MessageID, status, req.Error)
+	if errors.Is(err, services.ErrOutboundMessageNotFound) {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Outbound message not found"})
+	}
+	if err != nil {
+		c.Logger().Errorf("ReceiveNotificationStatus error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"id":     message.ID,
+		"status": message.Status,
+	})
+}
+
+// checkWebhookToken verifies the notification_webhook_secret sent by a gateway in the
+// X-Webhook-Token header or the token query parameter. Returns the status and message to refuse
+// the request with, or 0 if the token is valid; requests are refused while no secret is set.
+func checkWebhookToken(c echo.Context, settings services.NotificationSettings) (int, string) {
+	secret := settings.Get("notification_webhook_secret", "NOTIFICATION_WEBHOOK_SECRET", "")
+	if secret == "" {
+		return http.StatusServiceUnavailable, "Gateway webhooks are not configured"
+	}
+	token := c.Request().Header.Get("X-Webhook-Token")
+	if token == "" {
+		token = c.QueryParam("token")
+	}
+	if subtle.ConstantTimeCompare([]byte(token), []byte(secret)) != 1 {
+		return http.StatusUnauthorized, "Invalid webhook token"
+	}
+	return 0, ""
+}
@@ -2,10 +2,10 @@ package handlers
 
 import (
 	"database/sql"
-	"fmt"
+	"errors"
+	"math"
 	"net/http"
-	"os"
-	"strings"
+	"strconv"
 	"time"
 	"tukem-backend/db"
 	"tukem-backend/models"
@@ -16,7 +16,60 @@ import (
 	"github.com/labstack/echo/v4"
 )
 
-var whatsappService = services.NewWhatsAppService()
+// notificationService returns the notification service (settings and templates are read per send)
+func notificationService() *services.NotificationService {
+	return services.NewNotificationService(db.DB)
+}
+
+// otpService returns the OTP service (settings are read per request)
+func otpService() *services.OTPService {
+	return services.NewOTPService(db.DB)
+}
+
+// otpErrorResponse responds to an error of the OTP service
+func otpErrorResponse(c echo.Context, err error) error {
+	var limitErr *services.OTPLimitError
+	switch {
+	case errors.As(err, &limitErr):
+		retryAfter := int(math.Ceil(limitErr.RetryAfter.Seconds()))
+		c.Response().Header().Set("Retry-After", strconv.Itoa(retryAfter))
+		message := "Terlalu banyak permintaan. Silakan coba lagi nanti"
+		if limitErr.LockedOut {
+			message = "Terlalu banyak percobaan gagal. Silakan coba lagi nanti"
+		}
+		return c.JSON(http.StatusTooManyRequests, models.OTPResponse{
+			Success:    false,
+			Error:      message,
+			RetryAfter: retryAfter,
+		})
+	case errors.Is(err, services.ErrOTPInvalid):
+		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
+			Success: false,
+			Error:   "OTP tidak valid",
+		})
+	case errors.Is(err, services.ErrOTPExpired):
+		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
+			Success: false,
+			Error:   "OTP sudah kadaluarsa",
+		})
+	case errors.Is(err, services.ErrOTPAttemptsExceeded):
+		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
+			Success: false,
+			Error:   "Terlalu banyak percobaan. Silakan request OTP baru",
+		})
+	default:
+		c.Logger().Errorf("OTP error: %v", err)
+		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
+			Success: false,
+			Error:   "Database error",
+		})
+	}
+}
+
+// otpExpiresIn returns the seconds until an OTP expires
+func otpExpiresIn(expiresAt time.Time) int {
+	return int(math.Round(time.Until(expiresAt).Seconds()))
+}
 
 // RequestOTP handles OTP request
 func RequestOTP(c echo.Context) error {
@@ -55,85 +108,26 @@ func RequestOTP(c echo.Context) error {
 		})
 	}
 
-	// Check rate limiting (max 3 requests per 15 minutes)
-	canRequest, retryAfter, err := checkRateLimit(phoneNumber)
-	if err != nil {
-		c.Logger().Errorf("Rate limit check error: %v", err)
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Database error: " + err.Error(),
-		})
-	}
-
-	if !canRequest {
-		return c.JSON(http.StatusTooManyRequests, models.OTPResponse{
-			Success:    false,
-			Error:      "Terlalu banyak permintaan. Silakan coba lagi nanti",
-			RetryAfter: retryAfter,
-		})
-	}
-
-	// Generate OTP
-	otpCode, err := utils.GenerateOTP()
+	// Issue OTP (request limits and lockout are enforced by the OTP service)
+	otpCode, expiresAt, err := otpService().Issue(phoneNumber, services.OTPPurposeLogin, c.RealIP(), c.Request().UserAgent())
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Failed to generate OTP",
-		})
+		return otpErrorResponse(c, err)
 	}
 
-	// Store OTP in database
-	expiresAt := utils.GetOTPExpiration()
-	ipAddress := c.RealIP()
-	userAgent := c.Request().UserAgent()
-
-	query := `INSERT INTO otp_codes (phone_number, otp_code, purpose, expires_at, ip_address, user_agent, max_attempts)
-	          VALUES ($1, $2, 'login', $3, $4, $5, 3)
-	          RETURNING id`
-	var otpID string
-	err = db.DB.QueryRow(query, phoneNumber, otpCode, expiresAt, ipAddress, userAgent).Scan(&otpID)
+	// Queue OTP for delivery via WhatsApp (sent by the outbox workers)
+	err = notificationService().SendOTP(phoneNumber, otpCode, resolveLanguage(c), expiresAt)
 	if err != nil {
+		c.Logger().Errorf("Failed to queue WhatsApp OTP: %v", err)
 		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
 			Success: false,
-			Error:   "Failed to store OTP",
-		})
-	}
-
-	// Update rate limit
-	err = updateRateLimit(phoneNumber)
-	if err != nil {
-		// Log error but don't fail the request
-		// OTP is already sent
-	}
-
-	// Send OTP via WhatsApp
-	err = whatsappService.SendOTP(phoneNumber, otpCode)
-	if err != nil {
-		// Log error - this is important for debugging
-		c.Logger().Errorf("Failed to send WhatsApp OTP: %v", err)
-		// Check if it's a gateway error (chat not initiated)
-		errorMsg := err.Error()
-		if strings.Contains(errorMsg, "Lid is missing in chat table") || 
-		   strings.Contains(errorMsg, "chat table") ||
-		   strings.Contains(errorMsg, "belum pernah") {
-			// This means the number hasn't chatted with the gateway bot yet
-			// Return error with helpful message
-			return c.JSON(http.StatusBadRequest, models.OTPResponse{
-				Success: false,
-				Error:   "Nomor WhatsApp Anda belum pernah mengirim pesan ke bot kami. Silakan kirim pesan ke nomor bot terlebih dahulu, kemudian coba request OTP lagi.",
-			})
-		}
-		// For other gateway errors, return error but keep OTP in DB for manual verification
-		return c.JSON(http.StatusBadGateway, models.OTPResponse{
-			Success: false,
-			Error:   "Gagal mengirim OTP melalui WhatsApp. Silakan coba lagi nanti atau hubungi administrator.",
+			Error:   "Gagal mengirim OTP melalui WhatsApp. Silakan coba lagi nanti.",
 		})
 	}
 
 	return c.JSON(http.StatusOK, models.OTPResponse{
 		Success:   true,
 		Message:   "OTP telah dikirim ke WhatsApp Anda",
-		ExpiresIn: 300, // 5 minutes
+		ExpiresIn: otpExpiresIn(expiresAt),
 	})
 }
 
@@ -156,59 +150,9 @@ func VerifyOTP(c echo.Context) error {
 		})
 	}
 
-	// Find OTP record
-	var otp models.OTPCode
-	query := `SELECT id, phone_number, otp_code, purpose, expires_at, used_at, is_used, attempt_count, max_attempts
-	          FROM otp_codes
-	          WHERE phone_number = $1 AND otp_code = $2 AND is_used = false
-	          ORDER BY created_at DESC
-	          LIMIT 1`
-	
-	var usedAt sql.NullTime
-	err = db.DB.QueryRow(query, phoneNumber, req.OTP).Scan(
-		&otp.ID, &otp.PhoneNumber, &otp.OTPCode, &otp.Purpose,
-		&otp.ExpiresAt, &usedAt, &otp.IsUsed, &otp.AttemptCount, &otp.MaxAttempts)
-	
-	if err == sql.ErrNoRows {
-		// OTP not found - increment attempt count on latest OTP for this phone
-		incrementOTPAttempt(phoneNumber)
-		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
-			Success: false,
-			Error:   "OTP tidak valid",
-		})
-	} else if err != nil {
-		c.Logger().Errorf("OTP verification query error: %v", err)
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Database error: " + err.Error(),
-		})
-	}
-
-	// Check if OTP is expired
-	if utils.IsOTPExpired(otp.ExpiresAt) {
-		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
-			Success: false,
-			Error:   "OTP sudah kadaluarsa",
-		})
-	}
-
-	// Check if max attempts exceeded
-	if otp.AttemptCount >= otp.MaxAttempts {
-		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
-			Success: false,
-			Error:   "Terlalu banyak percobaan. Silakan request OTP baru",
-		})
-	}
-
-	// Mark OTP as used
-	now := time.Now()
-	updateQuery := `UPDATE otp_codes SET is_used = true, used_at = $1 WHERE id = $2`
-	_, err = db.DB.Exec(updateQuery, now, otp.ID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Failed to update OTP",
-		})
+	// Verify OTP (attempt limits and lockout are enforced by the OTP service)
+	if err := otpService().Verify(phoneNumber, services.OTPPurposeLogin, req.OTP, c.RealIP()); err != nil {
+		return otpErrorResponse(c, err)
 	}
 
 	// Find user (must be registered)
@@ -225,18 +169,23 @@ func VerifyOTP(c echo.Context) error {
 		})
 	}
 
-	// Generate JWT
-	token, err := generateJWT(user)
+	// Start session, or ask admins for the second factor
+	tokens, challenge, err := startLogin(c, user, false, loginMethodOTP)
 	if err != nil {
 		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
 			Success: false,
 			Error:   "Failed to generate token",
 		})
 	}
+	if challenge != nil {
+		return c.JSON(http.StatusOK, challenge)
+	}
 
 	return c.JSON(http.StatusOK, models.AuthResponse{
-		Token: token,
-		User:  *user,
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         *user,
 	})
 }
 
@@ -277,90 +226,26 @@ func ResendOTP(c echo.Context) error {
 		})
 	}
 
-	// Check rate limiting
-	canRequest, retryAfter, err := checkRateLimit(phoneNumber)
+	// Issue a new OTP, which invalidates the previous ones
+	otpCode, expiresAt, err := otpService().Issue(phoneNumber, services.OTPPurposeLogin, c.RealIP(), c.Request().UserAgent())
 	if err != nil {
-		c.Logger().Errorf("Rate limit check error (resend): %v", err)
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Database error: " + err.Error(),
-		})
-	}
-
-	if !canRequest {
-		return c.JSON(http.StatusTooManyRequests, models.OTPResponse{
-			Success:    false,
-			Error:      "Terlalu banyak permintaan. Silakan coba lagi nanti",
-			RetryAfter: retryAfter,
-		})
+		return otpErrorResponse(c, err)
 	}
 
-	// Invalidate old OTPs for this phone number
-	_, err = db.DB.Exec(`UPDATE otp_codes SET is_used = true WHERE phone_number = $1 AND is_used = false`, phoneNumber)
-	if err != nil {
-		// Log but continue
-	}
-
-	// Generate new OTP
-	otpCode, err := utils.GenerateOTP()
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Failed to generate OTP",
-		})
-	}
-
-	// Store new OTP
-	expiresAt := utils.GetOTPExpiration()
-	ipAddress := c.RealIP()
-	userAgent := c.Request().UserAgent()
-
-	query := `INSERT INTO otp_codes (phone_number, otp_code, purpose, expires_at, ip_address, user_agent, max_attempts)
-	          VALUES ($1, $2, 'login', $3, $4, $5, 3)
-	          RETURNING id`
-	var otpID string
-	err = db.DB.QueryRow(query, phoneNumber, otpCode, expiresAt, ipAddress, userAgent).Scan(&otpID)
+	// Queue OTP for delivery via WhatsApp (sent by the outbox workers)
+	err = notificationService().SendOTP(phoneNumber, otpCode, resolveLanguage(c), expiresAt)
 	if err != nil {
+		c.Logger().Errorf("Failed to queue WhatsApp OTP (resend): %v", err)
 		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
 			Success: false,
-			Error:   "Failed to store OTP",
-		})
-	}
-
-	// Update rate limit
-	err = updateRateLimit(phoneNumber)
-	if err != nil {
-		// Log error but don't fail the request
-	}
-
-	// Send OTP via WhatsApp
-	err = whatsappService.SendOTP(phoneNumber, otpCode)
-	if err != nil {
-		// Log error - this is important for debugging
-		c.Logger().Errorf("Failed to send WhatsApp OTP (resend): %v", err)
-		// Check if it's a gateway error (chat not initiated)
-		errorMsg := err.Error()
-		if strings.Contains(errorMsg, "Lid is missing in chat table") || 
-		   strings.Contains(errorMsg, "chat table") ||
-		   strings.Contains(errorMsg, "belum pernah") {
-			// This means the number hasn't chatted with the gateway bot yet
-			// Return error with helpful message
-			return c.JSON(http.StatusBadRequest, models.OTPResponse{
-				Success: false,
-				Error:   "Nomor WhatsApp Anda belum pernah mengirim pesan ke bot kami. Silakan kirim pesan ke nomor bot terlebih dahulu, kemudian coba request OTP lagi.",
-			})
-		}
-		// For other gateway errors, return error but keep OTP in DB for manual verification
-		return c.JSON(http.StatusBadGateway, models.OTPResponse{
-			Success: false,
-			Error:   "Gagal mengirim OTP melalui WhatsApp. Silakan coba lagi nanti atau hubungi administrator.",
+			Error:   "Gagal mengirim OTP melalui WhatsApp. Silakan coba lagi nanti.",
 		})
 	}
 
 	return c.JSON(http.StatusOK, models.OTPResponse{
 		Success:   true,
 		Message:   "OTP baru telah dikirim ke WhatsApp Anda",
-		ExpiresIn: 300,
+		ExpiresIn: otpExpiresIn(expiresAt),
 	})
 }
 
@@ -411,53 +296,6 @@ func findPhoneUser(phoneNumber string) (*models.User, error) {
 	return &user, nil
 }
 
-func checkRateLimit(phoneNumber string) (bool, int, error) {
-	// Check requests in last 15 minutes
-	windowStart := time.Now().Add(-15 * time.Minute)
-	windowEnd := time.Now()
-
-	var count int
-	query := `SELECT COALESCE(SUM(request_count), 0) 
-	          FROM otp_rate_limits 
-	          WHERE phone_number = $1 AND window_start >= $2 AND window_end <= $3`
-	err := db.DB.QueryRow(query, phoneNumber, windowStart, windowEnd).Scan(&count)
-	if err != nil && err != sql.ErrNoRows {
-		// Log the actual error for debugging
-		return false, 0, fmt.Errorf("rate limit query failed: %w", err)
-	}
-
-	// Max 3 requests per 15 minutes
-	if count >= 3 {
-		// Calculate retry after (seconds until next window)
-		retryAfter := int(time.Until(windowEnd.Add(15 * time.Minute)).Seconds())
-		return false, retryAfter, nil
-	}
-
-	return true, 0, nil
-}
-
-func updateRateLimit(phoneNumber string) error {
-	// Create or update rate limit record for current 15-minute window
-	windowStart := time.Now().Truncate(15 * time.Minute)
-	windowEnd := windowStart.Add(15 * time.Minute)
-
-	query := `INSERT INTO otp_rate_limits (phone_number, request_count, window_start, window_end, updated_at)
-	          VALUES ($1, 1, $2, $3, $4)
-	          ON CONFLICT (phone_number, window_start) 
-	          DO UPDATE SET request_count = otp_rate_limits.request_count + 1, updated_at = $4`
-	_, err := db.DB.Exec(query, phoneNumber, windowStart, windowEnd, time.Now())
-	return err
-}
-
-func incrementOTPAttempt(phoneNumber string) {
-	query := `UPDATE otp_codes 
-	          SET attempt_count = attempt_count + 1 
-	          WHERE phone_number = $1 AND is_used = false 
-	          ORDER BY created_at DESC 
-	          LIMIT 1`
-	db.DB.Exec(query, phoneNumber)
-}
-
 func findOrCreatePhoneUser(phoneNumber string) (*models.User, bool, error) {
 	var user models.User
 	var email sql.NullString
@@ -550,18 +388,14 @@ func findOrCreatePhoneUser(phoneNumber string) (*models.User, bool, error) {
 	return &user, true, nil
 }
 
-func generateJWT(user *models.User) (string, error) {
+func generateJWT(user *models.User, sessionID string) (string, error) {
 	token := jwt.New(jwt.SigningMethodHS256)
 	claims := token.Claims.(jwt.MapClaims)
 	claims["user_id"] = user.ID
 	claims["role"] = user.Role
-	claims["exp"] = time.Now().Add(time.Hour * 72).Unix() // 3 days
-
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "secret" // Default for development only
-	}
+	claims["sid"] = sessionID
+	claims["exp"] = time.Now().Add(accessTokenTTL).Unix()
 
-	return token.SignedString([]byte(jwtSecret))
+	return token.SignedString(utils.SecretKey(utils.SecretJWT))
 }
 
@@ -9,43 +9,16 @@ import (
 	"tukem-backend/models"
 	"tukem-backend/utils"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/jung-kurt/gofpdf/v2"
 	"github.com/labstack/echo/v4"
 )
 
 // ExportChildReport generates a PDF report for a child
 func ExportChildReport(c echo.Context) error {
-	childID := c.Param("id")
+	child := requestChild(c)
+	childID := child.ID
 	c.Logger().Infof("PDF Export requested for child ID: %s", childID)
 
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := db.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if err != nil {
-		c.Logger().Errorf("Failed to verify child ownership: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify child ownership"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
-
-	// Get child data
-	var child models.Child
-	err = db.DB.QueryRow("SELECT id, name, dob, gender, birth_weight, birth_height, is_premature, gestational_age FROM children WHERE id = $1", childID).
-		Scan(&child.ID, &child.Name, &child.DOB, &child.Gender, &child.BirthWeight, &child.BirthHeight, &child.IsPremature, &child.GestationalAge)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get child data"})
-	}
-
 	// Get measurements (get all, not limited)
 	measurements, err := getMeasurementsForReport(childID)
 	if err != nil {
@@ -59,7 +32,7 @@ func ExportChildReport(c echo.Context) error {
 	}
 
 	// Get assessment summary
-	summary, err := getAssessmentSummaryForReport(childID)
+	summary, err := getAssessmentSummaryForReport(*child, resolveLanguage(c))
 	if err != nil {
 		c.Logger().Errorf("Failed to get assessment summary: %v", err)
 		// Continue even if summary fails
@@ -67,7 +40,7 @@ func ExportChildReport(c echo.Context) error {
 	}
 
 	// Generate PDF
-	pdf := generatePDFReport(child, measurements, summary)
+	pdf := generatePDFReport(*child, measurements, summary)
 
 	// Set response headers
 	c.Response().Header().Set("Content-Type", "application/pdf")
@@ -143,17 +116,24 @@ func getMeasurementsForReport(childID string) ([]models.MeasurementResponse, err
 	return measurements, nil
 }
 
-func getAssessmentSummaryForReport(childID string) (*models.AssessmentSummary, error) {
+func getAssessmentSummaryForReport(child models.Child, lang string) (*models.AssessmentSummary, error) {
+	// Red flags are evaluated against the child's reference age
+	_, ageMonths, ageBasis, err := utils.CalculateReferenceAge(
+		child.DOB, time.Now().Format("2006-01-02"), child.IsPremature, child.GestationalAge)
+	if err != nil {
+		return nil, err
+	}
+
 	// Fetch all assessments for this child joined with milestones
 	query := `
-		SELECT a.status, m.category, m.pyramid_level, m.is_red_flag, m.question
+		SELECT a.status, m.category, m.pyramid_level, m.is_red_flag, m.question, m.age_months, m.max_age_range
 		FROM assessments a
 		JOIN milestones m ON a.milestone_id = m.id
 		WHERE a.child_id = $1
 			AND m.source = 'KPSP'
 	`
 
-	rows, err := db.DB.Queryx(query, childID)
+	rows, err := db.DB.Queryx(query, child.ID)
 	if err != nil {
 		return nil, err
 	}
@@ -165,12 +145,14 @@ func getAssessmentSummaryForReport(childID string) (*models.AssessmentSummary, e
 		PyramidLevel int
 		IsRedFlag    bool
 		Question     string
+		AgeMonths    int
+		MaxAgeRange  *int
 	}
 
 	var data []AssessmentData
 	for rows.Next() {
 		var d AssessmentData
-		if err := rows.Scan(&d.Status, &d.Category, &d.PyramidLevel, &d.IsRedFlag, &d.Question); err != nil {
+		if err := rows.Scan(&d.Status, &d.Category, &d.PyramidLevel, &d.IsRedFlag, &d.Question, &d.AgeMonths, &d.MaxAgeRange); err != nil {
 			continue
 		}
 		data = append(data, d)
@@ -179,15 +161,21 @@ func getAssessmentSummaryForReport(childID string) (*models.AssessmentSummary, e
 	// Calculate scores
 	totalByLevel := make(map[int]int)
 	completedByLevel := make(map[int]int)
+	totalByCategory := make(map[string]int)
+	completedByCategory := make(map[string]int)
 	redFlags := []models.Milestone{}
 
 	for _, d := range data {
 		totalByLevel[d.PyramidLevel]++
+		totalByCategory[d.Category]++
 		if d.Status == "yes" {
 			completedByLevel[d.PyramidLevel]++
+			completedByCategory[d.Category]++
 		}
 
-		if d.IsRedFlag && d.Status == "no" {
+		// With corrected age, a premature child is not flagged for milestones not yet due
+		if d.IsRedFlag && d.Status == "no" &&
+			(ageBasis != utils.AgeBasisCorrected || utils.IsMilestoneDue(d.AgeMonths, d.MaxAgeRange, ageMonths)) {
 			redFlags = append(redFlags, models.Milestone{
 				Question: d.Question,
 				Category: d.Category,
@@ -211,29 +199,19 @@ func getAssessmentSummaryForReport(childID string) (*models.AssessmentSummary, e
 		}
 	}
 
-	// Logic Warnings (Pyramid Imbalance)
-	warnings := []string{}
-
-	sensoryScore := 0.0
-	if totalByLevel[1] > 0 {
-		sensoryScore = float64(completedByLevel[1]) / float64(totalByLevel[1]) * 100
-	}
-
-	cognitiveScore := 0.0
-	if totalByLevel[4] > 0 {
-		cognitiveScore = float64(completedByLevel[4]) / float64(totalByLevel[4]) * 100
-	}
-
-	if cognitiveScore > 70 && sensoryScore < 50 {
-		warnings = append(warnings, "Terdeteksi 'Lompatan Perkembangan'. Anak mahir kognitif tapi pondasi sensorik (Level 1) belum kuat. Risiko: Masalah fokus/emosi di kemudian hari.")
-	}
+	// Logic Warnings (Pyramid Imbalance) - evaluated from configurable rules
+	// Keep the rest of the summary if the rules cannot be loaded
+	warnings, warningDetails, _ := evaluatePyramidWarnings(totalByLevel, completedByLevel, totalByCategory, completedByCategory, lang)
 
 	summary := models.AssessmentSummary{
-		TotalMilestones:     len(data),
-		CompletedMilestones: len(data),
-		ProgressByCategory:  progressByCategory,
-		RedFlagsDetected:    redFlags,
-		PyramidWarnings:     warnings,
+		TotalMilestones:       len(data),
+		CompletedMilestones:   len(data),
+		ProgressByCategory:    progressByCategory,
+		RedFlagsDetected:      redFlags,
+		PyramidWarnings:       warnings,
+		PyramidWarningDetails: warningDetails,
+		AgeMonths:             ageMonths,
+		AgeBasis:              ageBasis,
 	}
 
 	return &summary, nil
@@ -608,6 +586,12 @@ func addDevelopmentalAssessment(pdf *gofpdf.Fpdf, summary *models.AssessmentSumm
 	pdf.Cell(0, 6, fmt.Sprintf("Total Milestone yang Dinilai: %d", summary.TotalMilestones))
 	pdf.Ln(8)
 
+	if summary.AgeBasis == utils.AgeBasisCorrected {
+		pdf.SetFont("Arial", "", 9)
+		pdf.Cell(0, 6, fmt.Sprintf("Dinilai berdasarkan usia koreksi: %d bulan", summary.AgeMonths))
+		pdf.Ln(8)
+	}
+
 	// Progress by category
 	if len(summary.ProgressByCategory) > 0 {
 		pdf.SetFont("Arial", "B", 10)
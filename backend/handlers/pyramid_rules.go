@@ -0,0 +1,88 @@
+package handlers
+
+import (
+	"encoding/json"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+)
+
+const pyramidRuleColumns = `id, name, description, conditions, severity, message, message_en,
+	is_active, sort_order, created_at, updated_at`
+
+// rowScanner is implemented by *sql.Row and *sql.Rows
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanPyramidRule scans a pyramid_warning_rules row selected with pyramidRuleColumns
+func scanPyramidRule(row rowScanner) (models.PyramidWarningRule, error) {
+	var rule models.PyramidWarningRule
+	var conditions []byte
+
+	err := row.Scan(&rule.ID, &rule.Name, &rule.Description, &conditions, &rule.Severity,
+		&rule.Message, &rule.MessageEn, &rule.IsActive, &rule.SortOrder, &rule.CreatedAt, &rule.UpdatedAt)
+	if err != nil {
+		return rule, err
+	}
+
+	if err := json.Unmarshal(conditions, &rule.Conditions); err != nil {
+		return rule, err
+	}
+	return rule, nil
+}
+
+// getActivePyramidRules fetches all active pyramid warning rules in evaluation order
+func getActivePyramidRules() ([]models.PyramidWarningRule, error) {
+	rows, err := db.DB.Query(`SELECT ` + pyramidRuleColumns + ` FROM pyramid_warning_rules
+		WHERE is_active = true ORDER BY sort_order ASC, created_at ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	rules := []models.PyramidWarningRule{}
+	for rows.Next() {
+		rule, err := scanPyramidRule(rows)
+		if err != nil {
+			continue
+		}
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// evaluatePyramidWarnings calculates level/category scores and evaluates the active rules.
+// Returns the warning messages and the detailed warnings.
+func evaluatePyramidWarnings(totalByLevel, completedByLevel map[int]int, totalByCategory, completedByCategory map[string]int, lang string) ([]string, []models.PyramidWarning, error) {
+	levelScores := make(map[int]float64)
+	for level, total := range totalByLevel {
+		if total > 0 {
+			levelScores[level] = float64(completedByLevel[level]) / float64(total) * 100
+		}
+	}
+	categoryScores := make(map[string]float64)
+	for category, total := range totalByCategory {
+		if total > 0 {
+			categoryScores[category] = float64(completedByCategory[category]) / float64(total) * 100
+		}
+	}
+
+	rules, err := getActivePyramidRules()
+	if err != nil {
+		return []string{}, []models.PyramidWarning{}, err
+	}
+
+	// Messages are translated up front from the translation table; the rules fall back to
+	// message_en for English when they are evaluated
+	if err := translatePyramidRules(rules, lang); err != nil {
+		return []string{}, []models.PyramidWarning{}, err
+	}
+
+	details := utils.EvaluatePyramidRules(rules, levelScores, categoryScores, lang)
+	messages := make([]string, 0, len(details))
+	for _, w := range details {
+		messages = append(messages, w.Message)
+	}
+	return messages, details, nil
+}
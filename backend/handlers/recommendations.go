@@ -1,7 +1,6 @@
 package handlers
 
 import (
-	"database/sql"
 	"net/http"
 	"strconv"
 	"time"
@@ -9,53 +8,26 @@ import (
 	"tukem-backend/models"
 	"tukem-backend/utils"
 
-	"github.com/golang-jwt/jwt/v5"
 	"github.com/labstack/echo/v4"
 )
 
 // GetRecommendations fetches personalized recommendations for a child
 // based on their milestone assessments and age
 func GetRecommendations(c echo.Context) error {
-	childID := c.Param("id")
-
-	// Get user ID from JWT to verify ownership
-	user := c.Get("user").(*jwt.Token)
-	claims := *user.Claims.(*jwt.MapClaims)
-	userID := claims["user_id"].(string)
-
-	// Verify child belongs to user
-	var parentID string
-	err := db.DB.QueryRow("SELECT parent_id FROM children WHERE id = $1", childID).Scan(&parentID)
-	if err == sql.ErrNoRows {
-		return c.JSON(http.StatusNotFound, map[string]string{"error": "Child not found"})
-	}
-	if err != nil {
-		c.Logger().Errorf("Failed to verify child ownership: %v", err)
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to verify child ownership"})
-	}
-	if parentID != userID {
-		return c.JSON(http.StatusForbidden, map[string]string{"error": "Unauthorized"})
-	}
-
-	// Get child data
-	var child models.Child
-	err = db.DB.QueryRow("SELECT id, dob, is_premature, gestational_age FROM children WHERE id = $1", childID).
-		Scan(&child.ID, &child.DOB, &child.IsPremature, &child.GestationalAge)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get child data"})
-	}
+	child := requestChild(c)
+	childID := child.ID
 
 	// Calculate current age (using corrected age if applicable)
 	// Use current date for age calculation
 	today := time.Now().Format("2006-01-02")
-	_, ageInMonths, useCorrected, err := utils.CalculateCorrectedAge(
+	_, ageInMonths, ageBasis, err := utils.CalculateReferenceAge(
 		child.DOB, today, child.IsPremature, child.GestationalAge)
 	if err != nil {
 		c.Logger().Errorf("Failed to calculate age: %v", err)
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to calculate child age"})
 	}
 
-	c.Logger().Infof("Getting recommendations for child %s, age: %d months (corrected: %v)", childID, ageInMonths, useCorrected)
+	c.Logger().Infof("Getting recommendations for child %s, age: %d months (%s)", childID, ageInMonths, ageBasis)
 
 	// Get incomplete milestones for this child
 	incompleteMilestones, err := getIncompleteMilestones(childID, ageInMonths)
@@ -72,19 +44,26 @@ func GetRecommendations(c echo.Context) error {
 		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to get recommendations"})
 	}
 
+	if err := translateRecommendations(recommendations, resolveLanguage(c)); err != nil {
+		c.Logger().Warnf("Failed to translate recommendations: %v", err)
+	}
+
 	return c.JSON(http.StatusOK, models.RecommendationsResponse{
 		ChildID:        childID,
 		AgeMonths:      ageInMonths,
+		AgeBasis:       ageBasis,
 		Recommendations: recommendations,
 	})
 }
 
 // getIncompleteMilestones fetches milestones that are not yet completed (status = "no" or not assessed)
+// ageMonths is the child's reference age (corrected age for premature children, see utils.CalculateReferenceAge)
 func getIncompleteMilestones(childID string, ageMonths int) ([]models.Milestone, error) {
 	query := `
 		SELECT m.* FROM milestones m
 		LEFT JOIN assessments a ON a.milestone_id = m.id AND a.child_id = $1
 		WHERE (m.age_months <= $2 + 3) -- Include milestones up to 3 months ahead
+		  AND m.is_active = true
 		  AND (a.status IS NULL OR a.status = 'no')
 		ORDER BY m.age_months ASC, m.pyramid_level ASC
 		LIMIT 20
@@ -245,3 +224,30 @@ func getRecommendationsForChild(childID string, ageMonths int, incompleteMilesto
 	return recommendations, nil
 }
 
+// translateRecommendations translates the content and related milestone of each recommendation
+func translateRecommendations(recommendations []models.Recommendation, lang string) error {
+	contents := make([]*models.StimulationContent, len(recommendations))
+	var related []models.Milestone
+	for i := range recommendations {
+		contents[i] = &recommendations[i].Content
+		if recommendations[i].RelatedMilestone != nil {
+			related = append(related, *recommendations[i].RelatedMilestone)
+		}
+	}
+
+	if err := translateStimulationContents(contents, lang); err != nil {
+		return err
+	}
+	if err := translateMilestones(related, lang); err != nil {
+		return err
+	}
+
+	j := 0
+	for i := range recommendations {
+		if recommendations[i].RelatedMilestone != nil {
+			recommendations[i].RelatedMilestone = &related[j]
+			j++
+		}
+	}
+	return nil
+}
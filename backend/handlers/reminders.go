@@ -0,0 +1,456 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/services"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// Scheduled reminder types (reminder_log.reminder_type)
+const (
+	ReminderImmunizationDue7d    = "immunization_due_7d"
+	ReminderImmunizationDue1d    = "immunization_due_1d"
+	ReminderImmunizationOverdue  = "immunization_overdue"
+	ReminderMeasurementDue       = "measurement_due"
+	reminderSchedulerLockKey     = 7341041 // pg_advisory_lock key: one scheduler run at a time across replicas
+	reminderMaxMeasurementMonths = 60      // Growth monitoring reminders stop at 5 years
+)
+
+// reminderTemplates maps reminder types to notification template keys
+var reminderTemplates = map[string]string{
+	ReminderImmunizationDue7d:   "immunization_reminder_due_7d",
+	ReminderImmunizationDue1d:   "immunization_reminder_due_1d",
+	ReminderImmunizationOverdue: "immunization_reminder_overdue",
+	ReminderMeasurementDue:      "measurement_reminder",
+}
+
+// reminderCandidate is a child with one of its guardians who can receive WhatsApp reminders.
+// ParentID and ParentName are the guardian's.
+type reminderCandidate struct {
+	ChildID               string    `db:"child_id"`
+	ChildName             string    `db:"child_name"`
+	DOB                   string    `db:"dob"`
+	IsPremature           bool      `db:"is_premature"`
+	GestationalAge        *int      `db:"gestational_age"`
+	ChildCreatedAt        time.Time `db:"child_created_at"`
+	ParentID              string    `db:"parent_id"`
+	ParentName            *string   `db:"parent_name"`
+	PhoneNumber           string    `db:"phone_number"`
+	PreferredLanguage     *string   `db:"preferred_language"`
+	ImmunizationReminders bool      `db:"immunization_reminders"`
+	MeasurementReminders  bool      `db:"measurement_reminders"`
+	QuietHoursStart       *string   `db:"quiet_hours_start"`
+	QuietHoursEnd         *string   `db:"quiet_hours_end"`
+	Timezone              *string   `db:"timezone"`
+	LastMeasurementDate   *string   `db:"last_measurement_date"`
+}
+
+// reminderItem is one dose (or measurement gap) a reminder is about
+type reminderItem struct {
+	ReferenceKey string
+	Label        string
+}
+
+// StartReminderScheduler runs the reminder scheduler in the background every
+// reminder_interval_minutes. Replicas coordinate through a Postgres advisory lock, and
+// reminder_log keeps each reminder from being sent twice across runs and restarts.
+func StartReminderScheduler() {
+	go func() {
+		// Let the server finish starting before the first run
+		time.Sleep(time.Minute)
+		for {
+			if sent, err := RunScheduledReminders(time.Now()); err != nil {
+				log.Printf("Reminders: %v", err)
+			} else if sent > 0 {
+				log.Printf("Reminders: queued %d reminder(s)", sent)
+			}
+			time.Sleep(reminderInterval())
+		}
+	}()
+}
+
+// RunAdminScheduledReminders runs the reminder scheduler now (admin)
+func RunAdminScheduledReminders(c echo.Context) error {
+	adminUserID := c.Get("user_id").(string)
+
+	sent, err := RunScheduledReminders(time.Now())
+	if err != nil {
+		c.Logger().Errorf("RunAdminScheduledReminders error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	// Log audit
+	utils.LogAudit(adminUserID, "run", "scheduled_reminders", nil, nil, map[string]int{"queued": sent},
+		c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Reminder run completed",
+		"queued":  sent,
+	})
+}
+
+// RunScheduledReminders finds immunizations due in 7 days, due in 1 day or overdue, and children
+// not measured recently, and queues WhatsApp reminders for each of their guardians. Returns the number of
+// reminders queued; returns 0 without error if another replica holds the scheduler lock.
+func RunScheduledReminders(now time.Time) (int, error) {
+	service := notificationService()
+	settings, err := service.Settings()
+	if err != nil {
+		return 0, err
+	}
+	if settings.Get("enable_scheduled_reminders", "", "true") != "true" || !settings.Enabled(services.ChannelWhatsApp) {
+		return 0, nil
+	}
+
+	ctx := context.Background()
+	conn, err := db.DB.Connx(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+	var locked bool
+	if err := conn.GetContext(ctx, &locked, `SELECT pg_try_advisory_lock($1)`, reminderSchedulerLockKey); err != nil {
+		return 0, fmt.Errorf("failed to acquire reminder lock: %w", err)
+	}
+	if !locked {
+		return 0, nil
+	}
+	defer conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, reminderSchedulerLockKey)
+
+	candidates := []reminderCandidate{}
+	err = db.DB.Select(&candidates, `SELECT c.id AS child_id, c.name AS child_name, c.dob, c.is_premature,
+			c.gestational_age, c.created_at AS child_created_at, u.id AS parent_id, u.full_name AS parent_name,
+			u.phone_number, u.preferred_language,
+			COALESCE(p.immunization_reminders, true) AS immunization_reminders,
+			COALESCE(p.measurement_reminders, true) AS measurement_reminders,
+			p.quiet_hours_start, p.quiet_hours_end, p.timezone,
+			(SELECT MAX(m.measurement_date)::text FROM measurements m WHERE m.child_id = c.id) AS last_measurement_date
+		FROM children c
+		JOIN child_guardians g ON g.child_id = c.id
+		JOIN users u ON u.id = g.user_id
+		LEFT JOIN user_notification_preferences p ON p.user_id = u.id
+		WHERE u.phone_number IS NOT NULL AND u.phone_verified = true
+			AND (p.user_id IS NULL OR p.immunization_reminders OR p.measurement_reminders)
+		ORDER BY u.id, c.dob`)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load reminder candidates: %w", err)
+	}
+	if len(candidates) == 0 {
+		return 0, nil
+	}
+
+	schedules, err := getActiveImmunizationSchedules()
+	if err != nil {
+		return 0, fmt.Errorf("failed to load immunization schedules: %w", err)
+	}
+	translated := map[string][]models.ImmunizationSchedule{}
+	measurementDays, _ := strconv.Atoi(settings.Get("measurement_reminder_days", "", "30"))
+	if measurementDays <= 0 {
+		measurementDays = 30
+	}
+
+	sent := 0
+	for _, candidate := range candidates {
+		lang := utils.DefaultLanguage
+		if candidate.PreferredLanguage != nil && *candidate.PreferredLanguage != "" {
+			lang = *candidate.PreferredLanguage
+		}
+		notBefore := reminderNotBefore(candidate, settings, now)
+
+		if candidate.ImmunizationReminders {
+			if _, ok := translated[lang]; !ok {
+				copied := append([]models.ImmunizationSchedule(nil), schedules...)
+				if err := translateImmunizationSchedules(copied, lang); err != nil {
+					log.Printf("Reminders: failed to translate immunization schedules: %v", err)
+				}
+				translated[lang] = copied
+			}
+			n, err := queueImmunizationReminders(service, candidate, translated[lang], lang, notBefore, now)
+			if err != nil {
+				log.Printf("Reminders: child %s: %v", candidate.ChildID, err)
+			}
+			sent += n
+		}
+
+		if candidate.MeasurementReminders {
+			n, err := queueMeasurementReminder(service, candidate, measurementDays, lang, notBefore, now)
+			if err != nil {
+				log.Printf("Reminders: child %s: %v", candidate.ChildID, err)
+			}
+			sent += n
+		}
+	}
+	return sent, nil
+}
+
+// queueImmunizationReminders groups the child's doses due in 7 days, in 1 day and overdue (as
+// calculated for the immunization schedule) into one reminder per type and due date
+func queueImmunizationReminders(service *services.NotificationService, candidate reminderCandidate,
+	schedules []models.ImmunizationSchedule, lang string, notBefore *time.Time, now time.Time) (int, error) {
+	ageInDays, ageInMonths, _, err := utils.CalculateCorrectedAge(
+		candidate.DOB, now.Format("2006-01-02"), candidate.IsPremature, candidate.GestationalAge)
+	if err != nil {
+		return 0, err
+	}
+	completed, err := getCompletedImmunizations(candidate.ChildID)
+	if err != nil {
+		return 0, err
+	}
+	reviewFlags, err := getOpenReviewFlags(candidate.ChildID)
+	if err != nil {
+		return 0, err
+	}
+	statuses, _ := buildImmunizationStatuses(schedules, candidate.DOB, ageInDays, ageInMonths, completed, reviewFlags)
+	dob, err := utils.ParseDate(candidate.DOB)
+	if err != nil {
+		return 0, err
+	}
+
+	today, _ := time.Parse("2006-01-02", now.Format("2006-01-02"))
+	type group struct {
+		reminderType string
+		dueDate      string
+		daysUntilDue int
+		items        []reminderItem
+	}
+	groups := map[string]*group{}
+	for _, status := range statuses {
+		// Doses waiting for a previous dose or for clinician review after a KIPI are not reminded
+		if status.DueDate == nil || status.WaitingForPreviousDose || status.ReviewFlag != nil {
+			continue
+		}
+		dueDate, err := time.Parse("2006-01-02", *status.DueDate)
+		if err != nil {
+			continue
+		}
+		daysUntilDue := int(dueDate.Sub(today).Hours() / 24)
+
+		var reminderType string
+		switch {
+		case status.Status == "overdue":
+			// Doses past their maximum age can no longer be given. Like the catch-up plan, the
+			// maximum age is chronological, even for premature children.
+			if status.Schedule.AgeMaxDays != nil && dob.AddDate(0, 0, *status.Schedule.AgeMaxDays).Before(today) {
+				continue
+			}
+			reminderType = ReminderImmunizationOverdue
+		case daysUntilDue >= 0 && daysUntilDue <= 1:
+			reminderType = ReminderImmunizationDue1d
+		case daysUntilDue > 1 && daysUntilDue <= 7:
+			reminderType = ReminderImmunizationDue7d
+		default:
+			continue
+		}
+
+		groupKey := reminderType
+		if reminderType != ReminderImmunizationOverdue {
+			groupKey += ":" + *status.DueDate
+		}
+		g, ok := groups[groupKey]
+		if !ok {
+			g = &group{reminderType: reminderType, dueDate: *status.DueDate, daysUntilDue: daysUntilDue}
+			groups[groupKey] = g
+		}
+		g.items = append(g.items, reminderItem{
+			ReferenceKey: status.Schedule.ID + ":" + *status.DueDate,
+			Label:        status.Schedule.Name,
+		})
+	}
+
+	keys := make([]string, 0, len(groups))
+	for key := range groups {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	sent := 0
+	for _, key := range keys {
+		g := groups[key]
+		ok, err := queueReminder(service, candidate, g.reminderType, g.items, lang, notBefore, func(items []reminderItem) map[string]interface{} {
+			return map[string]interface{}{
+				"DueDate":      reminderDueDate(g.dueDate),
+				"DaysUntilDue": g.daysUntilDue,
+				"Vaccines":     reminderItemList(items),
+			}
+		})
+		if err != nil {
+			return sent, err
+		}
+		if ok {
+			sent++
+		}
+	}
+	return sent, nil
+}
+
+// queueMeasurementReminder reminds the parent when the child (under 5 years) has not been
+// measured for measurementDays, and again after every further measurementDays
+func queueMeasurementReminder(service *services.NotificationService, candidate reminderCandidate,
+	measurementDays int, lang string, notBefore *time.Time, now time.Time) (int, error) {
+	dob, err := utils.ParseDate(candidate.DOB)
+	if err != nil {
+		return 0, err
+	}
+	if !now.Before(dob.AddDate(0, reminderMaxMeasurementMonths, 0)) {
+		return 0, nil
+	}
+
+	since := candidate.ChildCreatedAt
+	reference := "none"
+	if candidate.LastMeasurementDate != nil {
+		if since, err = utils.ParseDate(*candidate.LastMeasurementDate); err != nil {
+			return 0, err
+		}
+		reference = since.Format("2006-01-02")
+	}
+	daysSince := int(now.Sub(since).Hours() / 24)
+	if daysSince < measurementDays {
+		return 0, nil
+	}
+
+	item := reminderItem{ReferenceKey: reference + ":" + strconv.Itoa(daysSince/measurementDays)}
+	ok, err := queueReminder(service, candidate, ReminderMeasurementDue, []reminderItem{item}, lang, notBefore, func([]reminderItem) map[string]interface{} {
+		return map[string]interface{}{"DaysSinceMeasurement": daysSince}
+	})
+	if err != nil || !ok {
+		return 0, err
+	}
+	return 1, nil
+}
+
+// queueReminder claims the items in reminder_log and queues one message for those this run
+// claimed. Items already logged (by an earlier run or another replica) are skipped; claims are
+// released if the message cannot be queued, so the next run tries again.
+func queueReminder(service *services.NotificationService, candidate reminderCandidate, reminderType string,
+	items []reminderItem, lang string, notBefore *time.Time, data func([]reminderItem) map[string]interface{}) (bool, error) {
+	claimed := []reminderItem{}
+	logIDs := []string{}
+	for _, item := range items {
+		var id string
+		err := db.DB.Get(&id, `INSERT INTO reminder_log (child_id, user_id, reminder_type, reference_key)
+			VALUES ($1, $2, $3, $4)
+			ON CONFLICT (child_id, user_id, reminder_type, reference_key) DO NOTHING
+			RETURNING id`, candidate.ChildID, candidate.ParentID, reminderType, item.ReferenceKey)
+		if err == sql.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			releaseReminderClaims(logIDs)
+			return false, fmt.Errorf("failed to log reminder: %w", err)
+		}
+		claimed = append(claimed, item)
+		logIDs = append(logIDs, id)
+	}
+	if len(claimed) == 0 {
+		return false, nil
+	}
+
+	values := data(claimed)
+	values["ParentName"] = reminderParentName(candidate, lang)
+	values["ChildName"] = candidate.ChildName
+
+	messageID, err := service.Enqueue(services.ChannelWhatsApp, candidate.PhoneNumber, reminderTemplates[reminderType], lang, values,
+		services.EnqueueOptions{NotBefore: notBefore})
+	if err != nil {
+		releaseReminderClaims(logIDs)
+		return false, err
+	}
+	for _, id := range logIDs {
+		if _, err := db.DB.Exec(`UPDATE reminder_log SET outbound_message_id = $1 WHERE id = $2`, messageID, id); err != nil {
+			log.Printf("Reminders: failed to link reminder %s to message %s: %v", id, messageID, err)
+		}
+	}
+	return true, nil
+}
+
+func releaseReminderClaims(ids []string) {
+	for _, id := range ids {
+		if _, err := db.DB.Exec(`DELETE FROM reminder_log WHERE id = $1`, id); err != nil {
+			log.Printf("Reminders: failed to release reminder %s: %v", id, err)
+		}
+	}
+}
+
+// reminderNotBefore returns the end of the parent's quiet hours if now falls within them
+func reminderNotBefore(candidate reminderCandidate, settings services.NotificationSettings, now time.Time) *time.Time {
+	return quietHoursNotBefore(candidate.QuietHoursStart, candidate.QuietHoursEnd, candidate.Timezone, settings, now)
+}
+
+// quietHoursNotBefore returns the end of the quiet hours if now falls within them. Parents
+// without their own quiet hours or time zone use the system defaults.
+func quietHoursNotBefore(quietStart, quietEnd, userTimezone *string, settings services.NotificationSettings, now time.Time) *time.Time {
+	start := settings.Get("reminder_quiet_hours_start", "", "21:00")
+	end := settings.Get("reminder_quiet_hours_end", "", "07:00")
+	if quietStart != nil && quietEnd != nil {
+		start, end = *quietStart, *quietEnd
+	}
+	loc := reminderLocation(settings)
+	if userTimezone != nil && *userTimezone != "" {
+		if userLoc, err := time.LoadLocation(*userTimezone); err == nil {
+			loc = userLoc
+		}
+	}
+
+	if until, quiet := utils.QuietHoursEnd(now, start, end, loc); quiet {
+		return &until
+	}
+	return nil
+}
+
+// reminderInterval returns how long the scheduler waits between runs (reminder_interval_minutes)
+func reminderInterval() time.Duration {
+	minutes := 60
+	if settings, err := notificationService().Settings(); err == nil {
+		if n, err := strconv.Atoi(settings.Get("reminder_interval_minutes", "", "60")); err == nil && n > 0 {
+			minutes = n
+		}
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+// reminderLocation is the default time zone of parents (reminder_timezone)
+func reminderLocation(settings services.NotificationSettings) *time.Location {
+	loc, err := time.LoadLocation(settings.Get("reminder_timezone", "", "Asia/Jakarta"))
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+func reminderParentName(candidate reminderCandidate, lang string) string {
+	if candidate.ParentName != nil && strings.TrimSpace(*candidate.ParentName) != "" {
+		return strings.TrimSpace(*candidate.ParentName)
+	}
+	if lang == "en" {
+		return "Parent"
+	}
+	return "Ayah/Bunda"
+}
+
+func reminderItemList(items []reminderItem) string {
+	lines := make([]string, len(items))
+	for i, item := range items {
+		lines[i] = "• " + item.Label
+	}
+	return strings.Join(lines, "\n")
+}
+
+// reminderDueDate formats a YYYY-MM-DD due date as DD/MM/YYYY, as on the PDF report
+func reminderDueDate(date string) string {
+	if t, err := utils.ParseDate(date); err == nil {
+		return t.Format("02/01/2006")
+	}
+	return date
+}
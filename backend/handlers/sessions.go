@@ -0,0 +1,353 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"net/http"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	accessTokenTTL       = 15 * time.Minute
+	refreshTokenTTL      = 30 * 24 * time.Hour // Extended on every refresh
+	adminRefreshTokenTTL = 24 * time.Hour      // Not extended: admins log in again every day
+)
+
+// Session revocation reasons
+const (
+	sessionRevokedLogout         = "logout"
+	sessionRevokedLogoutAll      = "logout_all"
+	sessionRevokedByUser         = "revoked_by_user"
+	sessionRevokedByAdmin        = "revoked_by_admin"
+	sessionRevokedPasswordReset  = "password_reset"
+	sessionRevokedTokenReuse     = "refresh_token_reuse"
+	sessionRevokedTwoFactorReset = "two_factor_reset"
+)
+
+// issueSession starts a session for the user and returns its access and refresh tokens. Logins
+// go through startLogin, which asks admins for the second factor first.
+func issueSession(c echo.Context, user *models.User, isAdmin, twoFactorVerified bool) (*models.TokenResponse, error) {
+	refreshToken, refreshHash, err := newRefreshToken()
+	if err != nil {
+		return nil, err
+	}
+
+	ttl := refreshTokenTTL
+	if isAdmin {
+		ttl = adminRefreshTokenTTL
+	}
+
+	var sessionID string
+	err = db.DB.QueryRow(`INSERT INTO user_sessions (user_id, refresh_token_hash, is_admin, two_factor_verified, user_agent, ip_address, expires_at)
+	                      VALUES ($1, $2, $3, $4, $5, $6, $7) RETURNING id`,
+		user.ID, refreshHash, isAdmin, twoFactorVerified, c.Request().UserAgent(), c.RealIP(), time.Now().Add(ttl)).Scan(&sessionID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessToken, err := sessionAccessToken(user, sessionID, isAdmin)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+	}, nil
+}
+
+// sessionAccessToken signs an access token for a session
+func sessionAccessToken(user *models.User, sessionID string, isAdmin bool) (string, error) {
+	if isAdmin {
+		return generateAdminJWT(user, sessionID)
+	}
+	return generateJWT(user, sessionID)
+}
+
+// newRefreshToken returns a random refresh token and the hash stored for it
+func newRefreshToken() (string, string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", "", err
+	}
+	token := base64.RawURLEncoding.EncodeToString(b)
+	return token, hashRefreshToken(token), nil
+}
+
+func hashRefreshToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// RefreshSession exchanges a refresh token for a new access token and refresh token. The old
+// refresh token stops working; presenting it again means it was copied, so the session is revoked.
+func RefreshSession(c echo.Context) error {
+	req := new(models.RefreshTokenRequest)
+	if err := c.Bind(req); err != nil || req.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Refresh token wajib diisi"})
+	}
+
+	hash := hashRefreshToken(req.RefreshToken)
+	var session models.UserSession
+	err := db.DB.Get(&session, `SELECT * FROM user_sessions WHERE refresh_token_hash = $1`, hash)
+	if err == sql.ErrNoRows {
+		res, err := db.DB.Exec(`UPDATE user_sessions SET revoked_at = $1, revoked_reason = $2
+		                        WHERE previous_token_hash = $3 AND revoked_at IS NULL`,
+			time.Now(), sessionRevokedTokenReuse, hash)
+		if err != nil {
+			c.Logger().Errorf("Failed to revoke reused session: %v", err)
+		} else if n, _ := res.RowsAffected(); n > 0 {
+			c.Logger().Warnf("Refresh token reuse detected from %s, session revoked", c.RealIP())
+		}
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Sesi tidak valid. Silakan masuk kembali."})
+	} else if err != nil {
+		c.Logger().Errorf("Failed to get session: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	now := time.Now()
+	if session.RevokedAt != nil || !session.ExpiresAt.After(now) {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Sesi telah berakhir. Silakan masuk kembali."})
+	}
+
+	// Reload the role so a changed role takes effect on the next refresh
+	var user models.User
+	err = db.DB.QueryRow(`SELECT id, role FROM users WHERE id = $1`, session.UserID).Scan(&user.ID, &user.Role)
+	if err != nil {
+		c.Logger().Errorf("Failed to get session user: %v", err)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Sesi tidak valid. Silakan masuk kembali."})
+	}
+	if session.IsAdmin && user.Role != "admin" {
+		revokeSession(session.ID, sessionRevokedByAdmin)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Sesi tidak valid. Silakan masuk kembali."})
+	}
+
+	refreshToken, refreshHash, err := newRefreshToken()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+	}
+	expiresAt := session.ExpiresAt
+	if !session.IsAdmin {
+		expiresAt = now.Add(refreshTokenTTL)
+	}
+
+	// Only the first of two concurrent refreshes with the same token wins
+	res, err := db.DB.Exec(`UPDATE user_sessions
+	                        SET refresh_token_hash = $1, previous_token_hash = $2, last_used_at = $3,
+	                            ip_address = $4, user_agent = $5, expires_at = $6
+	                        WHERE id = $7 AND refresh_token_hash = $2 AND revoked_at IS NULL`,
+		refreshHash, hash, now, c.RealIP(), c.Request().UserAgent(), expiresAt, session.ID)
+	if err != nil {
+		c.Logger().Errorf("Failed to rotate refresh token: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Sesi tidak valid. Silakan masuk kembali."})
+	}
+
+	accessToken, err := sessionAccessToken(&user, session.ID, session.IsAdmin)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate token"})
+	}
+
+	return c.JSON(http.StatusOK, models.TokenResponse{
+		Token:        accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    int(accessTokenTTL.Seconds()),
+	})
+}
+
+// Logout revokes the session of the current token
+func Logout(c echo.Context) error {
+	sessionID, _ := c.Get("session_id").(string)
+	if err := revokeSession(sessionID, sessionRevokedLogout); err != nil {
+		c.Logger().Errorf("Failed to revoke session: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal keluar"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Berhasil keluar"})
+}
+
+// GetUserSessions lists the active sessions of the user
+func GetUserSessions(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	sessions, err := getUserSessions(userID, false)
+	if err != nil {
+		c.Logger().Errorf("Failed to get sessions: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengambil daftar sesi"})
+	}
+
+	currentID, _ := c.Get("session_id").(string)
+	for i := range sessions {
+		sessions[i].Current = sessions[i].ID == currentID
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data": sessions,
+	})
+}
+
+// RevokeUserSession logs out one session of the user
+func RevokeUserSession(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	sessionID := c.Param("id")
+	if err := utils.ValidateUUID(sessionID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "ID sesi tidak valid"})
+	}
+
+	res, err := db.DB.Exec(`UPDATE user_sessions SET revoked_at = $1, revoked_reason = $2
+	                        WHERE id = $3 AND user_id = $4 AND revoked_at IS NULL`,
+		time.Now(), sessionRevokedByUser, sessionID, userID)
+	if err != nil {
+		c.Logger().Errorf("Failed to revoke session: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengakhiri sesi"})
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Sesi tidak ditemukan"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{"message": "Sesi berhasil diakhiri"})
+}
+
+// RevokeAllUserSessions logs out every session of the user. With keep_current=true the session
+// of the current token stays active.
+func RevokeAllUserSessions(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	exceptID := ""
+	if c.QueryParam("keep_current") == "true" {
+		exceptID, _ = c.Get("session_id").(string)
+	}
+
+	count, err := revokeUserSessions(userID, sessionRevokedLogoutAll, exceptID)
+	if err != nil {
+		c.Logger().Errorf("Failed to revoke sessions: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengakhiri sesi"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Semua sesi berhasil diakhiri",
+		"revoked": count,
+	})
+}
+
+// GetAdminUserSessions lists the sessions of a user (admin only). Revoked and expired sessions
+// are included with include_inactive=true.
+func GetAdminUserSessions(c echo.Context) error {
+	userID := c.Param("id")
+	if err := utils.ValidateUUID(userID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	var exists bool
+	err := db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if !exists {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+	}
+
+	sessions, err := getUserSessions(userID, c.QueryParam("include_inactive") == "true")
+	if err != nil {
+		c.Logger().Errorf("GetAdminUserSessions error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"data": sessions,
+	})
+}
+
+// RevokeAdminUserSessions logs a user out of every session (admin only)
+func RevokeAdminUserSessions(c echo.Context) error {
+	userID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+	if err := utils.ValidateUUID(userID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+
+	var exists bool
+	err := db.DB.QueryRow("SELECT EXISTS(SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if !exists {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "User not found"})
+	}
+
+	count, err := revokeUserSessions(userID, sessionRevokedByAdmin, "")
+	if err != nil {
+		c.Logger().Errorf("RevokeAdminUserSessions error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": utils.SanitizeError(err)})
+	}
+
+	auditData := map[string]interface{}{"action": "revoke_sessions", "sessions_revoked": count}
+	utils.LogAudit(adminUserID, "update", "user", &userID, nil, auditData, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Sessions revoked successfully",
+		"revoked": count,
+	})
+}
+
+// getUserSessions returns the sessions of a user, most recently used first
+func getUserSessions(userID string, includeInactive bool) ([]models.UserSession, error) {
+	query := `SELECT * FROM user_sessions WHERE user_id = $1`
+	args := []interface{}{userID}
+	if !includeInactive {
+		query += ` AND revoked_at IS NULL AND expires_at > $2`
+		args = append(args, time.Now())
+	}
+	query += ` ORDER BY last_used_at DESC LIMIT 100`
+
+	sessions := []models.UserSession{}
+	err := db.DB.Select(&sessions, query, args...)
+	return sessions, err
+}
+
+// revokeSession revokes one session
+func revokeSession(sessionID, reason string) error {
+	_, err := db.DB.Exec(`UPDATE user_sessions SET revoked_at = $1, revoked_reason = $2
+	                      WHERE id = $3 AND revoked_at IS NULL`, time.Now(), reason, sessionID)
+	return err
+}
+
+// revokeUserSessions revokes every active session of a user except exceptSessionID (if set) and
+// returns how many were revoked
+func revokeUserSessions(userID, reason, exceptSessionID string) (int64, error) {
+	query := `UPDATE user_sessions SET revoked_at = $1, revoked_reason = $2
+	          WHERE user_id = $3 AND revoked_at IS NULL`
+	args := []interface{}{time.Now(), reason, userID}
+	if exceptSessionID != "" {
+		query += ` AND id <> $4`
+		args = append(args, exceptSessionID)
+	}
+
+	res, err := db.DB.Exec(query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
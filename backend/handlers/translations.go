@@ -0,0 +1,182 @@
+package handlers
+
+import (
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+	"github.com/lib/pq"
+)
+
+// resolveLanguage picks the response language: ?lang= first, then the user's preferred
+// language, then Accept-Language, falling back to Bahasa Indonesia.
+// The chosen language is reported in the Content-Language header.
+func resolveLanguage(c echo.Context) string {
+	lang := utils.NormalizeLanguage(c.QueryParam("lang"))
+
+	if lang == "" {
+		if token, ok := c.Get("user").(*jwt.Token); ok {
+			if claims, ok := token.Claims.(*jwt.MapClaims); ok {
+				if userID, ok := (*claims)["user_id"].(string); ok {
+					var preferred *string
+					err := db.DB.QueryRow("SELECT preferred_language FROM users WHERE id = $1", userID).Scan(&preferred)
+					if err == nil && preferred != nil {
+						lang = utils.NormalizeLanguage(*preferred)
+					}
+				}
+			}
+		}
+	}
+
+	if lang == "" {
+		lang = utils.ParseAcceptLanguage(c.Request().Header.Get("Accept-Language"))
+	}
+	if lang == "" {
+		lang = utils.DefaultLanguage
+	}
+
+	c.Response().Header().Set("Content-Language", lang)
+	return lang
+}
+
+// loadTranslations returns entity ID -> field -> translated value for the given entities
+func loadTranslations(entityType string, ids []string, lang string) (map[string]map[string]string, error) {
+	result := make(map[string]map[string]string)
+	if lang == utils.DefaultLanguage || len(ids) == 0 {
+		return result, nil
+	}
+
+	rows, err := db.DB.Query(`SELECT entity_id, field, value FROM translations
+		WHERE entity_type = $1 AND language = $2 AND entity_id = ANY($3)`, entityType, lang, pq.Array(ids))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var entityID, field, value string
+		if err := rows.Scan(&entityID, &field, &value); err != nil {
+			return nil, err
+		}
+		if result[entityID] == nil {
+			result[entityID] = make(map[string]string)
+		}
+		result[entityID][field] = value
+	}
+	return result, rows.Err()
+}
+
+// translateMilestones replaces milestone questions with their translation.
+// English falls back to question_en; other missing translations keep the Indonesian text.
+func translateMilestones(milestones []models.Milestone, lang string) error {
+	if lang == utils.DefaultLanguage {
+		return nil
+	}
+
+	ids := make([]string, len(milestones))
+	for i, m := range milestones {
+		ids[i] = m.ID
+	}
+	translations, err := loadTranslations("milestone", ids, lang)
+	if err != nil {
+		return err
+	}
+
+	for i := range milestones {
+		if v, ok := translations[milestones[i].ID]["question"]; ok {
+			milestones[i].Question = v
+		} else if lang == "en" && milestones[i].QuestionEn != "" {
+			milestones[i].Question = milestones[i].QuestionEn
+		}
+	}
+	return nil
+}
+
+// translateStimulationContents replaces content titles and descriptions with their translation
+func translateStimulationContents(contents []*models.StimulationContent, lang string) error {
+	if lang == utils.DefaultLanguage {
+		return nil
+	}
+
+	ids := make([]string, len(contents))
+	for i, content := range contents {
+		ids[i] = content.ID
+	}
+	translations, err := loadTranslations("stimulation_content", ids, lang)
+	if err != nil {
+		return err
+	}
+
+	for _, content := range contents {
+		if v, ok := translations[content.ID]["title"]; ok {
+			content.Title = v
+		}
+		if v, ok := translations[content.ID]["description"]; ok {
+			content.Description = v
+		}
+	}
+	return nil
+}
+
+// translateImmunizationSchedules replaces the display name (name_id) and description of schedules
+// with their translation. The canonical vaccine name is kept as is.
+func translateImmunizationSchedules(schedules []models.ImmunizationSchedule, lang string) error {
+	if lang == utils.DefaultLanguage {
+		return nil
+	}
+
+	ids := make([]string, len(schedules))
+	for i, s := range schedules {
+		ids[i] = s.ID
+	}
+	translations, err := loadTranslations("immunization_schedule", ids, lang)
+	if err != nil {
+		return err
+	}
+
+	for i := range schedules {
+		if v, ok := translations[schedules[i].ID]["name"]; ok {
+			value := v
+			schedules[i].NameID = &value
+		}
+		if v, ok := translations[schedules[i].ID]["description"]; ok {
+			value := v
+			schedules[i].Description = &value
+		}
+	}
+	return nil
+}
+
+// translatePyramidRules sets the message of each rule from the translation table. A translation
+// replaces message_en, which utils.EvaluatePyramidRules otherwise uses for English; other missing
+// translations keep the Indonesian message.
+func translatePyramidRules(rules []models.PyramidWarningRule, lang string) error {
+	if lang == utils.DefaultLanguage {
+		return nil
+	}
+
+	ids := make([]string, len(rules))
+	for i, r := range rules {
+		ids[i] = r.ID
+	}
+	translations, err := loadTranslations("pyramid_rule", ids, lang)
+	if err != nil {
+		return err
+	}
+
+	for i := range rules {
+		if v, ok := translations[rules[i].ID]["message"]; ok {
+			rules[i].Message = v
+			rules[i].MessageEn = nil
+		}
+	}
+	return nil
+}
+
+// deleteTranslations removes the translations of a deleted entity
+func deleteTranslations(entityType, entityID string) error {
+	_, err := db.DB.Exec("DELETE FROM translations WHERE entity_type = $1 AND entity_id = $2", entityType, entityID)
+	return err
+}
@@ -0,0 +1,577 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/base64"
+	"net/http"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	twoFactorChallengeTTL         = 10 * time.Minute // Leaves time to install an authenticator app when enrolling
+	twoFactorChallengeMaxAttempts = 5
+	twoFactorMaxFailures          = 10 // Wrong codes per user within twoFactorFailureWindow, across challenges
+	twoFactorFailureWindow        = 15 * time.Minute
+	recoveryCodeCount             = 10
+)
+
+// Login methods, recorded on two-factor challenges and admin login audit logs
+const (
+	loginMethodPassword = "password"
+	loginMethodOTP      = "otp"
+	loginMethodAdminOTP = "admin_otp"
+	loginMethodGoogle   = "google"
+)
+
+// requiresTwoFactor reports whether logins of the user need a second factor
+func requiresTwoFactor(user *models.User) bool {
+	return user.Role == "admin"
+}
+
+// startLogin is the last step of every login method: it starts a session, or for users that need
+// a second factor returns a challenge to answer at /api/auth/2fa/verify instead.
+func startLogin(c echo.Context, user *models.User, isAdmin bool, method string) (*models.TokenResponse, *models.TwoFactorChallengeResponse, error) {
+	if !requiresTwoFactor(user) {
+		tokens, err := issueSession(c, user, isAdmin, false)
+		return tokens, nil, err
+	}
+
+	tf, err := getUserTwoFactor(user.ID)
+	if err != nil && err != sql.ErrNoRows {
+		return nil, nil, err
+	}
+
+	token, tokenHash, err := newRefreshToken()
+	if err != nil {
+		return nil, nil, err
+	}
+	_, err = db.DB.Exec(`INSERT INTO two_factor_challenges (user_id, token_hash, login_method, is_admin, expires_at)
+	                     VALUES ($1, $2, $3, $4, $5)`,
+		user.ID, tokenHash, method, isAdmin, time.Now().Add(twoFactorChallengeTTL))
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return nil, &models.TwoFactorChallengeResponse{
+		TwoFactorRequired:  true,
+		EnrollmentRequired: tf == nil || tf.EnabledAt == nil,
+		ChallengeToken:     token,
+		ExpiresIn:          int(twoFactorChallengeTTL.Seconds()),
+	}, nil
+}
+
+// VerifyTwoFactor completes a login with a TOTP code or a recovery code
+func VerifyTwoFactor(c echo.Context) error {
+	req := new(models.TwoFactorVerifyRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.Code == "" && req.RecoveryCode == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Kode autentikasi atau kode pemulihan wajib diisi"})
+	}
+
+	challenge, status, msg := getTwoFactorChallenge(req.ChallengeToken)
+	if status != 0 {
+		return c.JSON(status, map[string]string{"error": msg})
+	}
+
+	tf, err := getUserTwoFactor(challenge.UserID)
+	if err == sql.ErrNoRows || (err == nil && tf.EnabledAt == nil) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Autentikasi dua langkah belum diaktifkan"})
+	} else if err != nil {
+		c.Logger().Errorf("Failed to get two-factor enrollment: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	var ok bool
+	secondFactor := "totp"
+	if req.RecoveryCode != "" {
+		secondFactor = "recovery_code"
+		ok, err = useRecoveryCode(challenge.UserID, req.RecoveryCode)
+	} else {
+		ok, err = checkTOTP(tf, req.Code)
+	}
+	if err != nil {
+		c.Logger().Errorf("Failed to verify two-factor code: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if !ok {
+		recordTwoFactorFailure(challenge.ID)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Kode autentikasi tidak valid"})
+	}
+
+	res, status, msg := completeTwoFactorLogin(c, challenge, secondFactor)
+	if status != 0 {
+		return c.JSON(status, map[string]string{"error": msg})
+	}
+	return c.JSON(http.StatusOK, res)
+}
+
+// SetupTwoFactor starts the enrollment of a login that requires it. It returns a new secret and
+// its QR code for the authenticator app; the enrollment is confirmed with EnableTwoFactor.
+func SetupTwoFactor(c echo.Context) error {
+	req := new(models.TwoFactorChallengeRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+
+	challenge, status, msg := getTwoFactorChallenge(req.ChallengeToken)
+	if status != 0 {
+		return c.JSON(status, map[string]string{"error": msg})
+	}
+
+	tf, err := getUserTwoFactor(challenge.UserID)
+	if err != nil && err != sql.ErrNoRows {
+		c.Logger().Errorf("Failed to get two-factor enrollment: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if tf != nil && tf.EnabledAt != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Autentikasi dua langkah sudah aktif"})
+	}
+
+	user, err := getLoginUser(challenge.UserID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	secret, err := utils.GenerateTOTPSecret()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate secret"})
+	}
+	encrypted, err := utils.EncryptTOTPSecret(secret)
+	if err != nil {
+		c.Logger().Errorf("Failed to encrypt TOTP secret: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate secret"})
+	}
+
+	// A new setup replaces the secret of an unconfirmed one
+	_, err = db.DB.Exec(`INSERT INTO user_two_factor (user_id, secret_encrypted) VALUES ($1, $2)
+	                     ON CONFLICT (user_id) DO UPDATE SET secret_encrypted = $2, last_used_step = 0, updated_at = NOW()
+	                     WHERE user_two_factor.enabled_at IS NULL`, user.ID, encrypted)
+	if err != nil {
+		c.Logger().Errorf("Failed to store TOTP secret: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	account := user.Email
+	if account == "" && user.PhoneNumber != nil {
+		account = *user.PhoneNumber
+	}
+	otpauthURL := utils.TOTPProvisioningURL(account, secret)
+	qr, err := utils.EncodeQRPNG(otpauthURL, 6)
+	if err != nil {
+		c.Logger().Errorf("Failed to encode TOTP QR code: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate QR code"})
+	}
+
+	return c.JSON(http.StatusOK, models.TwoFactorSetupResponse{
+		Secret:     secret,
+		OTPAuthURL: otpauthURL,
+		QRCode:     "data:image/png;base64," + base64.StdEncoding.EncodeToString(qr),
+	})
+}
+
+// EnableTwoFactor confirms an enrollment with the first code of the authenticator app and
+// completes the login. The recovery codes are returned once.
+func EnableTwoFactor(c echo.Context) error {
+	req := new(models.TwoFactorVerifyRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Kode autentikasi wajib diisi"})
+	}
+
+	challenge, status, msg := getTwoFactorChallenge(req.ChallengeToken)
+	if status != 0 {
+		return c.JSON(status, map[string]string{"error": msg})
+	}
+
+	tf, err := getUserTwoFactor(challenge.UserID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Mulai pengaturan autentikasi dua langkah terlebih dahulu"})
+	} else if err != nil {
+		c.Logger().Errorf("Failed to get two-factor enrollment: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if tf.EnabledAt != nil {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Autentikasi dua langkah sudah aktif"})
+	}
+
+	ok, err := checkTOTP(tf, req.Code)
+	if err != nil {
+		c.Logger().Errorf("Failed to verify two-factor code: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if !ok {
+		recordTwoFactorFailure(challenge.ID)
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Kode autentikasi tidak valid"})
+	}
+
+	recoveryCodes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate recovery codes"})
+	}
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	defer tx.Rollback()
+
+	res, err := tx.Exec(`UPDATE user_two_factor SET enabled_at = NOW(), updated_at = NOW()
+	                     WHERE user_id = $1 AND enabled_at IS NULL`, challenge.UserID)
+	if err != nil {
+		c.Logger().Errorf("Failed to enable two-factor authentication: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return c.JSON(http.StatusConflict, map[string]string{"error": "Autentikasi dua langkah sudah aktif"})
+	}
+	if err := replaceRecoveryCodes(tx, challenge.UserID, recoveryCodes); err != nil {
+		c.Logger().Errorf("Failed to store recovery codes: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if err := tx.Commit(); err != nil {
+		c.Logger().Errorf("Failed to enable two-factor authentication: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	// Log audit
+	auditData := map[string]string{"action": "two_factor_enabled"}
+	utils.LogAudit(challenge.UserID, "update", "user", &challenge.UserID, nil, auditData, c.RealIP(), c.Request().UserAgent())
+
+	auth, status, msg := completeTwoFactorLogin(c, challenge, "totp")
+	if status != 0 {
+		return c.JSON(status, map[string]string{"error": msg})
+	}
+	return c.JSON(http.StatusOK, models.TwoFactorEnrollResponse{
+		AuthResponse:  *auth,
+		RecoveryCodes: recoveryCodes,
+	})
+}
+
+// GetTwoFactorStatus returns the two-factor authentication state of the user
+func GetTwoFactorStatus(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	status := models.TwoFactorStatus{}
+	tf, err := getUserTwoFactor(userID)
+	if err != nil && err != sql.ErrNoRows {
+		c.Logger().Errorf("Failed to get two-factor enrollment: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if tf != nil && tf.EnabledAt != nil {
+		status.Enabled = true
+		status.EnabledAt = tf.EnabledAt
+		err = db.DB.Get(&status.RecoveryCodesRemaining, `SELECT COUNT(*) FROM user_recovery_codes
+		                                                 WHERE user_id = $1 AND used_at IS NULL`, userID)
+		if err != nil {
+			c.Logger().Errorf("Failed to count recovery codes: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+		}
+	}
+
+	return c.JSON(http.StatusOK, status)
+}
+
+// RegenerateRecoveryCodes replaces the recovery codes of the user after checking a current TOTP
+// code. The previous codes stop working.
+func RegenerateRecoveryCodes(c echo.Context) error {
+	// Get user ID from JWT
+	user := c.Get("user").(*jwt.Token)
+	claims := *user.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	req := new(models.RegenerateRecoveryCodesRequest)
+	if err := c.Bind(req); err != nil || req.Code == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Kode autentikasi wajib diisi"})
+	}
+
+	tf, err := getUserTwoFactor(userID)
+	if err == sql.ErrNoRows || (err == nil && tf.EnabledAt == nil) {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Autentikasi dua langkah belum diaktifkan"})
+	} else if err != nil {
+		c.Logger().Errorf("Failed to get two-factor enrollment: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	ok, err := checkTOTP(tf, req.Code)
+	if err != nil {
+		c.Logger().Errorf("Failed to verify two-factor code: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Kode autentikasi tidak valid"})
+	}
+
+	recoveryCodes, err := utils.GenerateRecoveryCodes(recoveryCodeCount)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to generate recovery codes"})
+	}
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	defer tx.Rollback()
+
+	if err := replaceRecoveryCodes(tx, userID, recoveryCodes); err != nil {
+		c.Logger().Errorf("Failed to store recovery codes: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if err := tx.Commit(); err != nil {
+		c.Logger().Errorf("Failed to store recovery codes: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	// Log audit
+	auditData := map[string]string{"action": "recovery_codes_regenerated"}
+	utils.LogAudit(userID, "update", "user", &userID, nil, auditData, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message":        "Kode pemulihan berhasil dibuat ulang",
+		"recovery_codes": recoveryCodes,
+	})
+}
+
+// ResetAdminUserTwoFactor removes the two-factor authentication of another user, e.g. an admin
+// who lost their phone and recovery codes (admin only). The acting admin confirms with their own
+// TOTP code. The user is logged out everywhere and enrolls again on the next login.
+func ResetAdminUserTwoFactor(c echo.Context) error {
+	userID := c.Param("id")
+	adminUserID := c.Get("user_id").(string)
+	if err := utils.ValidateUUID(userID); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid user ID format"})
+	}
+	if userID == adminUserID {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "You cannot reset your own two-factor authentication. Use a recovery code instead."})
+	}
+
+	req := new(models.ResetTwoFactorRequest)
+	if err := c.Bind(req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	if req.Code == "" || req.Reason == "" {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "code and reason are required"})
+	}
+
+	// Re-authenticate the acting admin
+	adminTF, err := getUserTwoFactor(adminUserID)
+	if err == sql.ErrNoRows || (err == nil && adminTF.EnabledAt == nil) {
+		return c.JSON(http.StatusForbidden, map[string]string{"error": "Two-factor authentication must be enabled on your account"})
+	} else if err != nil {
+		c.Logger().Errorf("ResetAdminUserTwoFactor error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	ok, err := checkTOTP(adminTF, req.Code)
+	if err != nil {
+		c.Logger().Errorf("ResetAdminUserTwoFactor error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if !ok {
+		return c.JSON(http.StatusUnauthorized, map[string]string{"error": "Invalid authentication code"})
+	}
+
+	tf, err := getUserTwoFactor(userID)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusNotFound, map[string]string{"error": "Two-factor authentication is not set up for this user"})
+	} else if err != nil {
+		c.Logger().Errorf("ResetAdminUserTwoFactor error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	tx, err := db.DB.Beginx()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	defer tx.Rollback()
+
+	for _, query := range []string{
+		`DELETE FROM user_two_factor WHERE user_id = $1`,
+		`DELETE FROM user_recovery_codes WHERE user_id = $1`,
+		`DELETE FROM two_factor_challenges WHERE user_id = $1 AND completed_at IS NULL`,
+	} {
+		if _, err := tx.Exec(query, userID); err != nil {
+			c.Logger().Errorf("ResetAdminUserTwoFactor error: %v", err)
+			return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reset two-factor authentication"})
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		c.Logger().Errorf("ResetAdminUserTwoFactor error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Failed to reset two-factor authentication"})
+	}
+
+	// Sessions of the user may have been started by whoever holds the lost device
+	count, err := revokeUserSessions(userID, sessionRevokedTwoFactorReset, "")
+	if err != nil {
+		c.Logger().Errorf("ResetAdminUserTwoFactor error: %v", err)
+	}
+
+	// Log audit
+	beforeData := map[string]interface{}{"two_factor_enabled": tf.EnabledAt != nil, "enabled_at": tf.EnabledAt}
+	auditData := map[string]interface{}{"action": "two_factor_reset", "reason": req.Reason, "sessions_revoked": count}
+	utils.LogAudit(adminUserID, "update", "user", &userID, beforeData, auditData, c.RealIP(), c.Request().UserAgent())
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"message": "Two-factor authentication reset successfully",
+		"revoked": count,
+	})
+}
+
+// getTwoFactorChallenge returns the open challenge of a token, or the status and message to
+// respond with
+func getTwoFactorChallenge(token string) (*models.TwoFactorChallenge, int, string) {
+	if token == "" {
+		return nil, http.StatusBadRequest, "challenge_token wajib diisi"
+	}
+
+	var challenge models.TwoFactorChallenge
+	err := db.DB.Get(&challenge, `SELECT * FROM two_factor_challenges WHERE token_hash = $1`, hashRefreshToken(token))
+	if err == sql.ErrNoRows {
+		return nil, http.StatusUnauthorized, "Sesi login tidak valid. Silakan masuk kembali."
+	} else if err != nil {
+		return nil, http.StatusInternalServerError, "Database error"
+	}
+	if challenge.CompletedAt != nil || !challenge.ExpiresAt.After(time.Now()) {
+		return nil, http.StatusUnauthorized, "Sesi login telah berakhir. Silakan masuk kembali."
+	}
+	if challenge.AttemptCount >= twoFactorChallengeMaxAttempts {
+		return nil, http.StatusUnauthorized, "Terlalu banyak percobaan. Silakan masuk kembali."
+	}
+
+	// Starting new logins does not give more attempts
+	var failures int
+	err = db.DB.Get(&failures, `SELECT COALESCE(SUM(attempt_count), 0) FROM two_factor_challenges
+	                            WHERE user_id = $1 AND created_at > $2`,
+		challenge.UserID, time.Now().Add(-twoFactorFailureWindow))
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Database error"
+	}
+	if failures >= twoFactorMaxFailures {
+		return nil, http.StatusTooManyRequests, "Terlalu banyak percobaan. Silakan coba lagi nanti."
+	}
+
+	return &challenge, 0, ""
+}
+
+// completeTwoFactorLogin closes the challenge and starts the session of the login
+func completeTwoFactorLogin(c echo.Context, challenge *models.TwoFactorChallenge, secondFactor string) (*models.AuthResponse, int, string) {
+	// Only the first of two concurrent answers to the same challenge wins
+	res, err := db.DB.Exec(`UPDATE two_factor_challenges SET completed_at = NOW()
+	                        WHERE id = $1 AND completed_at IS NULL`, challenge.ID)
+	if err != nil {
+		c.Logger().Errorf("Failed to complete two-factor challenge: %v", err)
+		return nil, http.StatusInternalServerError, "Database error"
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return nil, http.StatusUnauthorized, "Sesi login telah berakhir. Silakan masuk kembali."
+	}
+
+	user, err := getLoginUser(challenge.UserID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get user: %v", err)
+		return nil, http.StatusInternalServerError, "Database error"
+	}
+
+	tokens, err := issueSession(c, user, challenge.IsAdmin, true)
+	if err != nil {
+		return nil, http.StatusInternalServerError, "Failed to generate token"
+	}
+
+	if user.Role == "admin" {
+		logAdminLogin(user.ID, challenge.LoginMethod, secondFactor, c.RealIP(), c.Request().UserAgent())
+	}
+
+	return &models.AuthResponse{
+		Token:        tokens.Token,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    tokens.ExpiresIn,
+		User:         *user,
+	}, 0, ""
+}
+
+// getUserTwoFactor returns the TOTP enrollment of a user (sql.ErrNoRows if there is none)
+func getUserTwoFactor(userID string) (*models.UserTwoFactor, error) {
+	var tf models.UserTwoFactor
+	err := db.DB.Get(&tf, `SELECT * FROM user_two_factor WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &tf, nil
+}
+
+// checkTOTP checks a TOTP code and records its time step so it cannot be used again
+func checkTOTP(tf *models.UserTwoFactor, code string) (bool, error) {
+	secret, err := utils.DecryptTOTPSecret(tf.SecretEncrypted)
+	if err != nil {
+		return false, err
+	}
+	step, ok := utils.ValidateTOTP(secret, code, time.Now(), tf.LastUsedStep)
+	if !ok {
+		return false, nil
+	}
+
+	res, err := db.DB.Exec(`UPDATE user_two_factor SET last_used_step = $1
+	                        WHERE user_id = $2 AND last_used_step < $1`, step, tf.UserID)
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// useRecoveryCode marks a recovery code of the user as used; false if it is wrong or was used
+func useRecoveryCode(userID, code string) (bool, error) {
+	res, err := db.DB.Exec(`UPDATE user_recovery_codes SET used_at = NOW()
+	                        WHERE user_id = $1 AND code_hash = $2 AND used_at IS NULL`,
+		userID, utils.HashRecoveryCode(code))
+	if err != nil {
+		return false, err
+	}
+	n, _ := res.RowsAffected()
+	return n > 0, nil
+}
+
+// replaceRecoveryCodes stores new recovery codes of a user in place of the old ones
+func replaceRecoveryCodes(tx *sqlx.Tx, userID string, codes []string) error {
+	if _, err := tx.Exec(`DELETE FROM user_recovery_codes WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	for _, code := range codes {
+		_, err := tx.Exec(`INSERT INTO user_recovery_codes (user_id, code_hash) VALUES ($1, $2)`,
+			userID, utils.HashRecoveryCode(code))
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func recordTwoFactorFailure(challengeID string) {
+	db.DB.Exec(`UPDATE two_factor_challenges SET attempt_count = attempt_count + 1 WHERE id = $1`, challengeID)
+}
+
+// getLoginUser loads the user returned with the tokens of a login
+func getLoginUser(userID string) (*models.User, error) {
+	var user models.User
+	err := db.DB.Get(&user, `SELECT id, COALESCE(email, '') AS email, phone_number, COALESCE(full_name, '') AS full_name,
+	                         role, COALESCE(auth_provider, 'phone') AS auth_provider, COALESCE(phone_verified, false) AS phone_verified,
+	                         phone_verified_at, preferred_language, created_at
+	                         FROM users WHERE id = $1`, userID)
+	if err != nil {
+		return nil, err
+	}
+	return &user, nil
+}
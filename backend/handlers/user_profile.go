@@ -3,7 +3,6 @@ package handlers
 import (
 	"database/sql"
 	"net/http"
-	"strings"
 	"tukem-backend/db"
 	"tukem-backend/models"
 	"tukem-backend/services"
@@ -28,11 +27,11 @@ func GetUserProfile(c echo.Context) error {
 	var phoneVerifiedAt sql.NullTime
 
 	query := `SELECT id, email, phone_number, full_name, role, google_id, auth_provider, 
-	          phone_verified, phone_verified_at, created_at
+	          phone_verified, phone_verified_at, preferred_language, created_at
 	          FROM users WHERE id = $1`
 	err := db.DB.QueryRow(query, userID).Scan(
 		&user.ID, &email, &phoneNumber, &user.FullName, &user.Role,
-		&googleID, &authProvider, &user.PhoneVerified, &phoneVerifiedAt, &user.CreatedAt)
+		&googleID, &authProvider, &user.PhoneVerified, &phoneVerifiedAt, &user.PreferredLanguage, &user.CreatedAt)
 
 	if err == sql.ErrNoRows {
 		return c.JSON(http.StatusNotFound, map[string]string{
@@ -124,6 +123,17 @@ func UpdateUserProfile(c echo.Context) error {
 		}
 	}
 
+	// Validate preferred language if provided
+	preferredLanguage := ""
+	if req.PreferredLanguage != "" {
+		preferredLanguage = utils.NormalizeLanguage(req.PreferredLanguage)
+		if preferredLanguage == "" {
+			return c.JSON(http.StatusBadRequest, map[string]string{
+				"error": "Bahasa tidak didukung. Pilihan: " + utils.JoinStrings(utils.SupportedLanguages, ", "),
+			})
+		}
+	}
+
 	// Build update query dynamically
 	updates := []string{}
 	args := []interface{}{}
@@ -141,6 +151,12 @@ func UpdateUserProfile(c echo.Context) error {
 		argIndex++
 	}
 
+	if preferredLanguage != "" {
+		updates = append(updates, "preferred_language = $"+utils.IntToString(argIndex))
+		args = append(args, preferredLanguage)
+		argIndex++
+	}
+
 	if len(updates) == 0 {
 		return c.JSON(http.StatusBadRequest, map[string]string{
 			"error": "Tidak ada data yang diupdate",
@@ -168,11 +184,11 @@ func UpdateUserProfile(c echo.Context) error {
 	var phoneVerifiedAt sql.NullTime
 
 	selectQuery := `SELECT id, email, phone_number, full_name, role, google_id, auth_provider, 
-	                phone_verified, phone_verified_at, created_at
+	                phone_verified, phone_verified_at, preferred_language, created_at
 	                FROM users WHERE id = $1`
 	err = db.DB.QueryRow(selectQuery, userID).Scan(
 		&user.ID, &email, &phoneNumber, &user.FullName, &user.Role,
-		&googleID, &authProvider, &user.PhoneVerified, &phoneVerifiedAt, &user.CreatedAt)
+		&googleID, &authProvider, &user.PhoneVerified, &phoneVerifiedAt, &user.PreferredLanguage, &user.CreatedAt)
 
 	if err != nil {
 		c.Logger().Errorf("Get updated user error: %v", err)
@@ -247,85 +263,26 @@ func RequestPhoneVerification(c echo.Context) error {
 		})
 	}
 
-	// Check rate limiting
-	canRequest, retryAfter, err := checkRateLimit(phoneNumber)
+	// Issue OTP with purpose 'verify_phone' (request limits and lockout are enforced by the OTP service)
+	otpCode, expiresAt, err := otpService().Issue(phoneNumber, services.OTPPurposeVerifyPhone, c.RealIP(), c.Request().UserAgent())
 	if err != nil {
-		c.Logger().Errorf("Rate limit check error: %v", err)
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Database error",
-		})
-	}
-
-	if !canRequest {
-		return c.JSON(http.StatusTooManyRequests, models.OTPResponse{
-			Success:    false,
-			Error:      "Terlalu banyak permintaan. Silakan coba lagi nanti",
-			RetryAfter: retryAfter,
-		})
+		return otpErrorResponse(c, err)
 	}
 
-	// Generate OTP
-	otpCode, err := utils.GenerateOTP()
+	// Queue OTP for delivery via WhatsApp (notificationService is declared in otp_auth.go)
+	err = notificationService().SendOTP(phoneNumber, otpCode, resolveLanguage(c), expiresAt)
 	if err != nil {
+		c.Logger().Errorf("Failed to queue WhatsApp OTP: %v", err)
 		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
 			Success: false,
-			Error:   "Failed to generate OTP",
-		})
-	}
-
-	// Store OTP in database
-	expiresAt := utils.GetOTPExpiration()
-	ipAddress := c.RealIP()
-	userAgent := c.Request().UserAgent()
-
-	query := `INSERT INTO otp_codes (phone_number, otp_code, purpose, expires_at, ip_address, user_agent, max_attempts)
-	          VALUES ($1, $2, 'verify_phone', $3, $4, $5, 3)
-	          RETURNING id`
-	var otpID string
-	err = db.DB.QueryRow(query, phoneNumber, otpCode, expiresAt, ipAddress, userAgent).Scan(&otpID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Failed to store OTP",
-		})
-	}
-
-	// Update rate limit
-	err = updateRateLimit(phoneNumber)
-	if err != nil {
-		// Log error but don't fail the request
-		c.Logger().Warnf("Failed to update rate limit: %v", err)
-	}
-
-	// Send OTP via WhatsApp (using service from otp_auth.go)
-	// Note: whatsappService is declared in otp_auth.go
-	err = services.NewWhatsAppService().SendOTP(phoneNumber, otpCode)
-	if err != nil {
-		c.Logger().Errorf("Failed to send WhatsApp OTP: %v", err)
-		// Check if it's a gateway error (chat not initiated)
-		errorMsg := err.Error()
-		if strings.Contains(errorMsg, "Lid is missing in chat table") || 
-		   strings.Contains(errorMsg, "chat table") ||
-		   strings.Contains(errorMsg, "belum pernah") {
-			// This means the number hasn't chatted with the gateway bot yet
-			// Return error with helpful message
-			return c.JSON(http.StatusBadRequest, models.OTPResponse{
-				Success: false,
-				Error:   "Nomor WhatsApp Anda belum pernah mengirim pesan ke bot kami. Silakan kirim pesan ke nomor bot terlebih dahulu, kemudian coba request OTP lagi.",
-			})
-		}
-		// For other gateway errors, return error but keep OTP in DB for manual verification
-		return c.JSON(http.StatusBadGateway, models.OTPResponse{
-			Success: false,
-			Error:   "Gagal mengirim OTP melalui WhatsApp. Silakan coba lagi nanti atau hubungi administrator.",
+			Error:   "Gagal mengirim OTP melalui WhatsApp. Silakan coba lagi nanti.",
 		})
 	}
 
 	return c.JSON(http.StatusOK, models.OTPResponse{
 		Success:   true,
 		Message:   "OTP telah dikirim ke WhatsApp Anda",
-		ExpiresIn: 300, // 5 minutes
+		ExpiresIn: otpExpiresIn(expiresAt),
 	})
 }
 
@@ -353,59 +310,9 @@ func ConfirmPhoneVerification(c echo.Context) error {
 		})
 	}
 
-	// Find OTP record
-	var otp models.OTPCode
-	query := `SELECT id, phone_number, otp_code, purpose, expires_at, used_at, is_used, attempt_count, max_attempts
-	          FROM otp_codes
-	          WHERE phone_number = $1 AND otp_code = $2 AND purpose = 'verify_phone' AND is_used = false
-	          ORDER BY created_at DESC
-	          LIMIT 1`
-
-	var usedAt sql.NullTime
-	err = db.DB.QueryRow(query, phoneNumber, req.OTP).Scan(
-		&otp.ID, &otp.PhoneNumber, &otp.OTPCode, &otp.Purpose,
-		&otp.ExpiresAt, &usedAt, &otp.IsUsed, &otp.AttemptCount, &otp.MaxAttempts)
-
-	if err == sql.ErrNoRows {
-		// OTP not found - increment attempt count
-		incrementOTPAttempt(phoneNumber)
-		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
-			Success: false,
-			Error:   "OTP tidak valid",
-		})
-	} else if err != nil {
-		c.Logger().Errorf("OTP verification query error: %v", err)
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Database error",
-		})
-	}
-
-	// Check if OTP is expired
-	if utils.IsOTPExpired(otp.ExpiresAt) {
-		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
-			Success: false,
-			Error:   "OTP sudah kadaluarsa",
-		})
-	}
-
-	// Check if max attempts exceeded
-	if otp.AttemptCount >= otp.MaxAttempts {
-		return c.JSON(http.StatusUnauthorized, models.OTPResponse{
-			Success: false,
-			Error:   "Terlalu banyak percobaan. Silakan request OTP baru",
-		})
-	}
-
-	// Mark OTP as used
-	now := utils.GetCurrentTime()
-	updateOTPQuery := `UPDATE otp_codes SET is_used = true, used_at = $1 WHERE id = $2`
-	_, err = db.DB.Exec(updateOTPQuery, now, otp.ID)
-	if err != nil {
-		return c.JSON(http.StatusInternalServerError, models.OTPResponse{
-			Success: false,
-			Error:   "Failed to update OTP",
-		})
+	// Verify OTP with purpose 'verify_phone' (attempt limits and lockout are enforced by the OTP service)
+	if err := otpService().Verify(phoneNumber, services.OTPPurposeVerifyPhone, req.OTP, c.RealIP()); err != nil {
+		return otpErrorResponse(c, err)
 	}
 
 	// Check if phone number is already used by another user
@@ -424,7 +331,8 @@ func ConfirmPhoneVerification(c echo.Context) error {
 			Error:   "Database error",
 		})
 	}
-
+	// Update user's phone number and verification status
+	now := utils.GetCurrentTime()
 	// Update user's phone number and verification status
 	updateUserQuery := `UPDATE users 
 	                    SET phone_number = $1, phone_verified = true, phone_verified_at = $2
@@ -465,11 +373,11 @@ func ConfirmPhoneVerification(c echo.Context) error {
 	var phoneVerifiedAt sql.NullTime
 
 	selectQuery := `SELECT id, email, phone_number, full_name, role, google_id, auth_provider, 
-	                phone_verified, phone_verified_at, created_at
+	                phone_verified, phone_verified_at, preferred_language, created_at
 	                FROM users WHERE id = $1`
 	err = db.DB.QueryRow(selectQuery, userID).Scan(
 		&user.ID, &email, &phoneNum, &user.FullName, &user.Role,
-		&googleID, &authProvider, &user.PhoneVerified, &phoneVerifiedAt, &user.CreatedAt)
+		&googleID, &authProvider, &user.PhoneVerified, &phoneVerifiedAt, &user.PreferredLanguage, &user.CreatedAt)
 
 	if err != nil {
 		c.Logger().Errorf("Get updated user error: %v", err)
@@ -506,13 +414,39 @@ func ConfirmPhoneVerification(c echo.Context) error {
 	})
 }
 
+// GetUserPermissions returns the permissions of the user with where they hold them, and their
+// health-worker role assignments, so the app can show the staff pages they can use
+func GetUserPermissions(c echo.Context) error {
+	// Get user ID from JWT
+	userToken := c.Get("user").(*jwt.Token)
+	claims := *userToken.Claims.(*jwt.MapClaims)
+	userID := claims["user_id"].(string)
+
+	permissions, err := utils.UserPermissions(userID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get permissions: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengambil hak akses"})
+	}
+
+	assignments, err := getRoleAssignments(`ra.user_id = $1`, userID)
+	if err != nil {
+		c.Logger().Errorf("Failed to get role assignments: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Gagal mengambil hak akses"})
+	}
+
+	return c.JSON(http.StatusOK, map[string]interface{}{
+		"permissions": permissions,
+		"assignments": assignments,
+	})
+}
+
 // Helper function to get user statistics
 func getUserStatistics(userID string) (map[string]interface{}, error) {
 	stats := make(map[string]interface{})
 
-	// Count children (using parent_id column)
+	// Count children the user owns or has been invited to
 	var childrenCount int
-	err := db.DB.QueryRow(`SELECT COUNT(*) FROM children WHERE parent_id = $1`, userID).Scan(&childrenCount)
+	err := db.DB.QueryRow(`SELECT COUNT(*) FROM child_guardians WHERE user_id = $1`, userID).Scan(&childrenCount)
 	if err != nil {
 		return nil, err
 	}
@@ -523,8 +457,8 @@ func getUserStatistics(userID string) (map[string]interface{}, error) {
 	err = db.DB.QueryRow(`
 		SELECT COUNT(*) 
 		FROM measurements m
-		JOIN children c ON m.child_id = c.id
-		WHERE c.parent_id = $1
+		JOIN child_guardians g ON m.child_id = g.child_id
+		WHERE g.user_id = $1
 	`, userID).Scan(&measurementsCount)
 	if err != nil {
 		// If table doesn't exist or error, set to 0
@@ -537,8 +471,8 @@ func getUserStatistics(userID string) (map[string]interface{}, error) {
 	err = db.DB.QueryRow(`
 		SELECT COUNT(*) 
 		FROM child_milestones cm
-		JOIN children c ON cm.child_id = c.id
-		WHERE c.parent_id = $1
+		JOIN child_guardians g ON cm.child_id = g.child_id
+		WHERE g.user_id = $1
 	`, userID).Scan(&milestonesCount)
 	if err != nil {
 		// If table doesn't exist, set to 0
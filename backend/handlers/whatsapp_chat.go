@@ -0,0 +1,548 @@
+package handlers
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/models"
+	"tukem-backend/services"
+	"tukem-backend/utils"
+
+	"github.com/labstack/echo/v4"
+)
+
+// WhatsApp conversation states (whatsapp_conversations.state)
+const (
+	chatStateIdle          = "idle"
+	chatStateAwaitingChild = "awaiting_child"
+	chatConversationTTL    = 15 * time.Minute // A pending question is forgotten after this
+	chatReplyKey           = "whatsapp_chat_reply"
+	chatMaxScheduleItems   = 5
+)
+
+// chatPending is a command waiting for the parent to choose one of the children offered
+type chatPending struct {
+	Command  utils.ChatCommand `json:"command"`
+	ChildIDs []string          `json:"child_ids"`
+}
+
+// chatChild is a child of the parent chatting
+type chatChild struct {
+	ID                  string  `db:"id"`
+	Name                string  `db:"name"`
+	DOB                 string  `db:"dob"`
+	Gender              string  `db:"gender"`
+	IsPremature         bool    `db:"is_premature"`
+	GestationalAge      *int    `db:"gestational_age"`
+	LastMeasurementDate *string `db:"last_measurement_date"`
+	Role                string  `db:"role"` // Guardian role of the user
+}
+
+// Notification templates (whatsapp channel) of the chat replies, editable by admins
+const (
+	chatTemplateUnregistered      = "chat_unregistered"
+	chatTemplateHelp              = "chat_help"
+	chatTemplateUnknown           = "chat_unknown"
+	chatTemplateCancelled         = "chat_cancelled"
+	chatTemplateNoChildren        = "chat_no_children"
+	chatTemplateMeasurementFormat = "chat_measurement_format"
+	chatTemplateWhichChild        = "chat_which_child"        // Children
+	chatTemplateChildNotFound     = "chat_child_not_found"    // Name, Children
+	chatTemplateChooseNumber      = "chat_choose_number"      // Count, Children
+	chatTemplateMeasurementSaved  = "chat_measurement_saved"  // ChildName, Date, Weight, Height, HeadCircumference, Statuses
+	chatTemplateMeasurementFailed = "chat_measurement_failed" // Reason
+	chatTemplateViewerOnly        = "chat_viewer_only"        // ChildName
+	chatTemplateSchedule          = "chat_schedule"           // ChildName, Items, NextMeasurement
+	chatTemplateImmunization      = "chat_immunization"       // ChildName, Completed, Total, Overdue, NextDose, NextDoseDate, AllComplete
+	chatTemplateError             = "chat_error"
+)
+
+// chatScheduleItem is a dose listed in the chat_schedule reply
+type chatScheduleItem struct {
+	Name    string
+	DueDate string // DD/MM/YYYY
+	Overdue bool
+}
+
+// chatReply renders a chat reply template. A template that cannot be rendered is replaced by the
+// chat_error reply; "" means no reply can be sent at all.
+func chatReply(c echo.Context, lang, key string, data map[string]interface{}) string {
+	msg, err := notificationService().Render(key, services.ChannelWhatsApp, lang, data)
+	if err == nil {
+		return msg.Body
+	}
+	c.Logger().Errorf("Failed to render WhatsApp chat reply %s: %v", key, err)
+	if key == chatTemplateError {
+		return ""
+	}
+	return chatReply(c, lang, chatTemplateError, nil)
+}
+
+// ReceiveWhatsAppMessage handles incoming WhatsApp messages posted by the gateway. The sender is
+// identified by their verified phone number; the reply is queued in the outbox and also returned
+// for gateways that answer synchronously. The gateway authenticates like delivery callbacks.
+func ReceiveWhatsAppMessage(c echo.Context) error {
+	service := notificationService()
+	settings, err := service.Settings()
+	if err != nil {
+		c.Logger().Errorf("ReceiveWhatsAppMessage settings error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+	if status, msg := checkWebhookToken(c, settings); status != 0 {
+		return c.JSON(status, map[string]string{"error": msg})
+	}
+	if settings.Get("enable_whatsapp_commands", "", "true") != "true" {
+		return c.JSON(http.StatusOK, map[string]string{"status": "ignored"})
+	}
+
+	var req models.WhatsAppInboundMessage
+	if err := c.Bind(&req); err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid request"})
+	}
+	sender := req.From
+	if at := strings.Index(sender, "@"); at >= 0 {
+		sender = sender[:at]
+	}
+	phoneNumber, err := utils.ValidatePhoneNumber(sender)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid sender number"})
+	}
+	text := strings.TrimSpace(req.Message)
+	if text == "" {
+		return c.JSON(http.StatusOK, map[string]string{"status": "ignored"})
+	}
+
+	// Gateways redeliver webhooks they consider failed: handle each message once
+	var inboundID string
+	err = db.DB.Get(&inboundID, `INSERT INTO whatsapp_inbound_messages (provider_message_id, phone_number, body)
+		VALUES (NULLIF($1, ''), $2, $3)
+		ON CONFLICT (provider_message_id) DO NOTHING
+		RETURNING id`, strings.TrimSpace(req.MessageID), phoneNumber, text)
+	if err == sql.ErrNoRows {
+		return c.JSON(http.StatusOK, map[string]string{"status": "duplicate"})
+	}
+	if err != nil {
+		c.Logger().Errorf("ReceiveWhatsAppMessage error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	}
+
+	var user struct {
+		ID                string  `db:"id"`
+		PreferredLanguage *string `db:"preferred_language"`
+	}
+	var userID *string
+	var reply, command string
+	err = db.DB.Get(&user, `SELECT id, preferred_language FROM users WHERE phone_number = $1 AND phone_verified = true`, phoneNumber)
+	switch {
+	case err == sql.ErrNoRows:
+		reply = chatReply(c, utils.DefaultLanguage, chatTemplateUnregistered, nil)
+	case err != nil:
+		c.Logger().Errorf("ReceiveWhatsAppMessage error: %v", err)
+		return c.JSON(http.StatusInternalServerError, map[string]string{"error": "Database error"})
+	default:
+		lang := utils.DefaultLanguage
+		if user.PreferredLanguage != nil && *user.PreferredLanguage != "" {
+			lang = *user.PreferredLanguage
+		}
+		userID = &user.ID
+		reply, command = handleChatMessage(c, user.ID, phoneNumber, text, lang, settings)
+	}
+
+	var replyMessageID *string
+	if reply != "" {
+		replyID, err := service.EnqueueMessage(services.Message{Channel: services.ChannelWhatsApp, To: phoneNumber, Body: reply},
+			chatReplyKey, services.EnqueueOptions{})
+		if err != nil {
+			c.Logger().Errorf("Failed to queue WhatsApp reply: %v", err)
+		} else {
+			replyMessageID = &replyID
+		}
+	}
+	if _, err := db.DB.Exec(`UPDATE whatsapp_inbound_messages SET user_id = $1, command = NULLIF($2, ''), reply = $3,
+			reply_message_id = $4
+		WHERE id = $5`, userID, command, reply, replyMessageID, inboundID); err != nil {
+		c.Logger().Warnf("Failed to update inbound WhatsApp message: %v", err)
+	}
+
+	return c.JSON(http.StatusOK, map[string]string{
+		"status":  "processed",
+		"command": command,
+		"reply":   reply,
+	})
+}
+
+// handleChatMessage runs the conversation state machine: a command that needs a child is run at
+// once if the child is clear (named, or the only child), otherwise the parent is asked to choose
+// and the command waits in whatsapp_conversations until the answer. Returns the reply and the
+// command type.
+func handleChatMessage(c echo.Context, userID, phoneNumber, text, lang string, settings services.NotificationSettings) (string, string) {
+	cmd := utils.ParseChatCommand(text)
+
+	pending, err := loadChatPending(phoneNumber, userID)
+	if err != nil {
+		c.Logger().Errorf("Failed to load WhatsApp conversation: %v", err)
+		return chatReply(c, lang, chatTemplateError, nil), cmd.Type
+	}
+
+	switch cmd.Type {
+	case utils.ChatCommandCancel:
+		clearChatConversation(c, phoneNumber)
+		return chatReply(c, lang, chatTemplateCancelled, nil), cmd.Type
+	case utils.ChatCommandHelp:
+		return chatReply(c, lang, chatTemplateHelp, nil), cmd.Type
+	case utils.ChatCommandChoice, utils.ChatCommandUnknown:
+		if pending == nil {
+			return chatReply(c, lang, chatTemplateUnknown, nil), cmd.Type
+		}
+		children, err := loadChatChildren(userID, pending.ChildIDs)
+		if err != nil {
+			c.Logger().Errorf("Failed to load children: %v", err)
+			return chatReply(c, lang, chatTemplateError, nil), cmd.Type
+		}
+		var matched []chatChild
+		if cmd.Type == utils.ChatCommandChoice {
+			if cmd.Choice <= len(children) {
+				matched = children[cmd.Choice-1 : cmd.Choice]
+			}
+		} else {
+			matched = matchChatChildren(children, text)
+		}
+		if len(matched) != 1 {
+			return chatReply(c, lang, chatTemplateChooseNumber, map[string]interface{}{
+				"Count":    len(children),
+				"Children": chatChildList(children),
+			}), pending.Command.Type
+		}
+		clearChatConversation(c, phoneNumber)
+		return runChatCommand(c, pending.Command, matched[0], lang, settings), pending.Command.Type
+	}
+
+	if cmd.Type == utils.ChatCommandMeasurement && (cmd.Weight == 0 || cmd.Height == 0) {
+		return chatReply(c, lang, chatTemplateMeasurementFormat, nil), cmd.Type
+	}
+
+	children, err := loadChatChildren(userID, nil)
+	if err != nil {
+		c.Logger().Errorf("Failed to load children: %v", err)
+		return chatReply(c, lang, chatTemplateError, nil), cmd.Type
+	}
+	if len(children) == 0 {
+		return chatReply(c, lang, chatTemplateNoChildren, nil), cmd.Type
+	}
+
+	matched := matchChatChildren(children, cmd.ChildName)
+	if len(matched) == 1 {
+		clearChatConversation(c, phoneNumber)
+		return runChatCommand(c, cmd, matched[0], lang, settings), cmd.Type
+	}
+
+	// Ask which child, offering the children matching the name (or all of them)
+	question := chatTemplateWhichChild
+	if len(matched) == 0 {
+		question = chatTemplateChildNotFound
+		matched = children
+	}
+	ids := make([]string, len(matched))
+	for i, child := range matched {
+		ids[i] = child.ID
+	}
+	if err := saveChatPending(phoneNumber, userID, chatPending{Command: cmd, ChildIDs: ids}); err != nil {
+		c.Logger().Errorf("Failed to save WhatsApp conversation: %v", err)
+		return chatReply(c, lang, chatTemplateError, nil), cmd.Type
+	}
+	return chatReply(c, lang, question, map[string]interface{}{
+		"Name":     cmd.ChildName,
+		"Children": chatChildList(matched),
+	}), cmd.Type
+}
+
+// runChatCommand runs a command for a child and returns the reply
+func runChatCommand(c echo.Context, cmd utils.ChatCommand, child chatChild, lang string, settings services.NotificationSettings) string {
+	today := time.Now().In(reminderLocation(settings)).Format("2006-01-02")
+	model := models.Child{
+		ID:             child.ID,
+		DOB:            child.DOB,
+		Gender:         child.Gender,
+		IsPremature:    child.IsPremature,
+		GestationalAge: child.GestationalAge,
+	}
+
+	switch cmd.Type {
+	case utils.ChatCommandMeasurement:
+		if !utils.HasGuardianRole(child.Role, utils.GuardianEditor) {
+			return chatReply(c, lang, chatTemplateViewerOnly, map[string]interface{}{"ChildName": child.Name})
+		}
+		req := &models.CreateMeasurementRequest{
+			MeasurementDate:   today,
+			Weight:            cmd.Weight,
+			Height:            cmd.Height,
+			HeadCircumference: cmd.HeadCircumference,
+		}
+		if msg := validateMeasurementRequest(model, req); msg != "" {
+			return chatReply(c, lang, chatTemplateMeasurementFailed, map[string]interface{}{"Reason": msg})
+		}
+		measurement, err := createMeasurement(c, model, req)
+		if err != nil {
+			return chatReply(c, lang, chatTemplateError, nil)
+		}
+		return chatMeasurementReply(c, child, measurement, lang)
+
+	case utils.ChatCommandSchedule, utils.ChatCommandImmunization:
+		statuses, summary, err := chatImmunizationStatuses(model, lang, today)
+		if err != nil {
+			c.Logger().Errorf("Failed to get immunization status: %v", err)
+			return chatReply(c, lang, chatTemplateError, nil)
+		}
+		if cmd.Type == utils.ChatCommandImmunization {
+			return chatImmunizationReply(c, child, statuses, summary, lang)
+		}
+		return chatScheduleReply(c, child, statuses, lang, settings)
+	}
+	return chatReply(c, lang, chatTemplateUnknown, nil)
+}
+
+func chatMeasurementReply(c echo.Context, child chatChild, measurement *models.MeasurementResponse, lang string) string {
+	var headCircumference string
+	if measurement.HeadCircumference != nil {
+		headCircumference = formatDigestValue(*measurement.HeadCircumference)
+	}
+	statuses := []string{}
+	for _, status := range []string{measurement.NutritionalStatus, measurement.HeightStatus, measurement.WeightForHeightStatus} {
+		if status != "" {
+			statuses = append(statuses, chatStatusLabel(status, lang))
+		}
+	}
+	return chatReply(c, lang, chatTemplateMeasurementSaved, map[string]interface{}{
+		"ChildName":         child.Name,
+		"Date":              reminderDueDate(measurement.MeasurementDate),
+		"Weight":            formatDigestValue(measurement.Weight),
+		"Height":            formatDigestValue(measurement.Height),
+		"HeadCircumference": headCircumference,
+		"Statuses":          statuses,
+	})
+}
+
+func chatScheduleReply(c echo.Context, child chatChild, statuses []models.ImmunizationStatus, lang string, settings services.NotificationSettings) string {
+	due := []models.ImmunizationStatus{}
+	for _, status := range statuses {
+		if status.Status == "completed" || status.DueDate == nil {
+			continue
+		}
+		due = append(due, status)
+	}
+	sort.SliceStable(due, func(i, j int) bool { return *due[i].DueDate < *due[j].DueDate })
+	if len(due) > chatMaxScheduleItems {
+		due = due[:chatMaxScheduleItems]
+	}
+	items := make([]chatScheduleItem, len(due))
+	for i, status := range due {
+		items[i] = chatScheduleItem{
+			Name:    status.Schedule.Name,
+			DueDate: reminderDueDate(*status.DueDate),
+			Overdue: status.Status == "overdue",
+		}
+	}
+
+	var nextMeasurement string
+	if child.LastMeasurementDate != nil {
+		if last, err := utils.ParseDate(*child.LastMeasurementDate); err == nil {
+			days, _ := strconv.Atoi(settings.Get("measurement_reminder_days", "", "30"))
+			if days <= 0 {
+				days = 30
+			}
+			nextMeasurement = reminderDueDate(last.AddDate(0, 0, days).Format("2006-01-02"))
+		}
+	}
+	return chatReply(c, lang, chatTemplateSchedule, map[string]interface{}{
+		"ChildName":       child.Name,
+		"Items":           items,
+		"NextMeasurement": nextMeasurement,
+	})
+}
+
+func chatImmunizationReply(c echo.Context, child chatChild, statuses []models.ImmunizationStatus, summary models.ImmunizationSummary, lang string) string {
+	overdue := []string{}
+	var next *models.ImmunizationStatus
+	for i, status := range statuses {
+		switch {
+		case status.Status == "overdue":
+			overdue = append(overdue, status.Schedule.Name)
+		case status.Status != "completed" && status.DueDate != nil:
+			if next == nil || *status.DueDate < *next.DueDate {
+				next = &statuses[i]
+			}
+		}
+	}
+	var nextDose, nextDoseDate string
+	if next != nil {
+		nextDose = next.Schedule.Name
+		nextDoseDate = reminderDueDate(*next.DueDate)
+	}
+	return chatReply(c, lang, chatTemplateImmunization, map[string]interface{}{
+		"ChildName":    child.Name,
+		"Completed":    summary.Completed,
+		"Total":        summary.Total,
+		"Overdue":      overdue,
+		"NextDose":     nextDose,
+		"NextDoseDate": nextDoseDate,
+		"AllComplete":  summary.Completed == summary.Total,
+	})
+}
+
+// chatImmunizationStatuses calculates the immunization schedule of a child as of today, without
+// the doses that can no longer be given
+func chatImmunizationStatuses(child models.Child, lang, today string) ([]models.ImmunizationStatus, models.ImmunizationSummary, error) {
+	ageInDays, ageInMonths, _, err := utils.CalculateCorrectedAge(child.DOB, today, child.IsPremature, child.GestationalAge)
+	if err != nil {
+		return nil, models.ImmunizationSummary{}, err
+	}
+	schedules, err := getActiveImmunizationSchedules()
+	if err != nil {
+		return nil, models.ImmunizationSummary{}, err
+	}
+	if err := translateImmunizationSchedules(schedules, lang); err != nil {
+		return nil, models.ImmunizationSummary{}, err
+	}
+	completed, err := getCompletedImmunizations(child.ID)
+	if err != nil {
+		return nil, models.ImmunizationSummary{}, err
+	}
+	reviewFlags, err := getOpenReviewFlags(child.ID)
+	if err != nil {
+		return nil, models.ImmunizationSummary{}, err
+	}
+	statuses, summary := buildImmunizationStatuses(schedules, child.DOB, ageInDays, ageInMonths, completed, reviewFlags)
+
+	// Doses past their maximum age can no longer be given
+	current := []models.ImmunizationStatus{}
+	for _, status := range statuses {
+		if status.Status != "completed" && status.Schedule.AgeMaxDays != nil && ageInDays > *status.Schedule.AgeMaxDays {
+			continue
+		}
+		current = append(current, status)
+	}
+	return current, summary, nil
+}
+
+// matchChatChildren returns the children matching a name: exact full names first, then first
+// names, then names starting with it. An empty name matches every child.
+func matchChatChildren(children []chatChild, name string) []chatChild {
+	name = strings.ToLower(strings.Join(strings.Fields(name), " "))
+	if name == "" {
+		return children
+	}
+	tiers := []func(string) bool{
+		func(childName string) bool { return childName == name },
+		func(childName string) bool { return strings.Fields(childName)[0] == name },
+		func(childName string) bool { return strings.HasPrefix(childName, name) },
+	}
+	for _, matches := range tiers {
+		matched := []chatChild{}
+		for _, child := range children {
+			childName := strings.ToLower(strings.Join(strings.Fields(child.Name), " "))
+			if childName != "" && matches(childName) {
+				matched = append(matched, child)
+			}
+		}
+		if len(matched) > 0 {
+			return matched
+		}
+	}
+	return nil
+}
+
+func chatChildList(children []chatChild) string {
+	lines := make([]string, len(children))
+	for i, child := range children {
+		lines[i] = fmt.Sprintf("%d. %s", i+1, child.Name)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// chatStatusLabel picks the language of a bilingual status such as "Normal Weight / Gizi Baik"
+func chatStatusLabel(status, lang string) string {
+	parts := strings.SplitN(status, " / ", 2)
+	if len(parts) != 2 {
+		return status
+	}
+	if lang == "en" {
+		return parts[0]
+	}
+	return parts[1]
+}
+
+// loadChatChildren loads the children the user can access, limited to ids (in that order) if given
+func loadChatChildren(userID string, ids []string) ([]chatChild, error) {
+	children := []chatChild{}
+	err := db.DB.Select(&children, `SELECT c.id, c.name, c.dob, COALESCE(c.gender, '') AS gender, c.is_premature, c.gestational_age,
+			(SELECT MAX(m.measurement_date)::text FROM measurements m WHERE m.child_id = c.id) AS last_measurement_date, g.role
+		FROM children c JOIN child_guardians g ON g.child_id = c.id WHERE g.user_id = $1 ORDER BY c.dob`, userID)
+	if err != nil || ids == nil {
+		return children, err
+	}
+
+	byID := make(map[string]chatChild, len(children))
+	for _, child := range children {
+		byID[child.ID] = child
+	}
+	ordered := []chatChild{}
+	for _, id := range ids {
+		if child, ok := byID[id]; ok {
+			ordered = append(ordered, child)
+		}
+	}
+	return ordered, nil
+}
+
+// loadChatPending returns the command waiting for a child to be chosen, or nil
+func loadChatPending(phoneNumber, userID string) (*chatPending, error) {
+	var conversation models.WhatsAppConversation
+	err := db.DB.Get(&conversation, `SELECT phone_number, user_id, state, COALESCE(pending, 'null'::jsonb) AS pending,
+			expires_at, updated_at
+		FROM whatsapp_conversations
+		WHERE phone_number = $1 AND user_id = $2 AND state = $3 AND expires_at > CURRENT_TIMESTAMP`,
+		phoneNumber, userID, chatStateAwaitingChild)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var pending chatPending
+	if err := json.Unmarshal(conversation.Pending, &pending); err != nil || len(pending.ChildIDs) == 0 {
+		return nil, nil
+	}
+	return &pending, nil
+}
+
+func saveChatPending(phoneNumber, userID string, pending chatPending) error {
+	raw, err := json.Marshal(pending)
+	if err != nil {
+		return err
+	}
+	_, err = db.DB.Exec(`INSERT INTO whatsapp_conversations (phone_number, user_id, state, pending, expires_at)
+		VALUES ($1, $2, $3, $4, $5)
+		ON CONFLICT (phone_number) DO UPDATE SET
+			user_id = EXCLUDED.user_id,
+			state = EXCLUDED.state,
+			pending = EXCLUDED.pending,
+			expires_at = EXCLUDED.expires_at,
+			updated_at = CURRENT_TIMESTAMP`,
+		phoneNumber, userID, chatStateAwaitingChild, raw, time.Now().Add(chatConversationTTL))
+	return err
+}
+
+func clearChatConversation(c echo.Context, phoneNumber string) {
+	_, err := db.DB.Exec(`UPDATE whatsapp_conversations SET state = $1, pending = NULL, expires_at = NULL,
+			updated_at = CURRENT_TIMESTAMP
+		WHERE phone_number = $2`, chatStateIdle, phoneNumber)
+	if err != nil {
+		c.Logger().Warnf("Failed to clear WhatsApp conversation: %v", err)
+	}
+}
@@ -213,11 +213,693 @@ CREATE TABLE IF NOT EXISTS child_immunizations (
     updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
 );
 
-CREATE UNIQUE INDEX IF NOT EXISTS idx_child_immunizations_unique ON child_immunizations(child_id, immunization_schedule_id);
 CREATE INDEX IF NOT EXISTS idx_child_immunizations_child ON child_immunizations(child_id);
 CREATE INDEX IF NOT EXISTS idx_child_immunizations_date ON child_immunizations(given_date);
 CREATE INDEX IF NOT EXISTS idx_child_immunizations_schedule ON child_immunizations(immunization_schedule_id);
 
+-- ============================================
+-- 10. DEVELOPMENTAL SNAPSHOTS TABLE
+-- ============================================
+CREATE TABLE IF NOT EXISTS developmental_snapshots (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    domain VARCHAR(10) NOT NULL,
+    snapshot_date DATE NOT NULL,
+    chronological_age_months NUMERIC(5,2) NOT NULL,
+    reference_age_months NUMERIC(5,2) NOT NULL,
+    age_basis VARCHAR(20) NOT NULL DEFAULT 'chronological',
+    age_equivalent_months NUMERIC(5,2) NOT NULL,
+    developmental_quotient NUMERIC(6,2),
+    items_assessed INT NOT NULL DEFAULT 0,
+    items_passed INT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(child_id, domain, snapshot_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_developmental_snapshots_child ON developmental_snapshots(child_id);
+CREATE INDEX IF NOT EXISTS idx_developmental_snapshots_date ON developmental_snapshots(child_id, snapshot_date);
+
+-- ============================================
+-- 11. PYRAMID WARNING RULES TABLE
+-- ============================================
+CREATE TABLE IF NOT EXISTS pyramid_warning_rules (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+    conditions JSONB NOT NULL,
+    severity VARCHAR(20) NOT NULL DEFAULT 'warning',
+    message TEXT NOT NULL,
+    message_en TEXT,
+    is_active BOOLEAN DEFAULT TRUE,
+    sort_order INT DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pyramid_warning_rules_active ON pyramid_warning_rules(is_active);
+
+INSERT INTO pyramid_warning_rules (name, description, conditions, severity, message, message_en, sort_order)
+SELECT
+    'Lompatan Perkembangan',
+    'High cognitive (level 4) score while the sensory foundation (level 1) is weak',
+    '[{"scope": "level", "target": "4", "operator": ">", "value": 70}, {"scope": "level", "target": "1", "operator": "<", "value": 50}]',
+    'warning',
+    'Terdeteksi ''Lompatan Perkembangan''. Anak mahir kognitif tapi pondasi sensorik (Level 1) belum kuat. Risiko: Masalah fokus/emosi di kemudian hari.',
+    'Developmental ''leap'' detected. The child is strong cognitively but the sensory foundation (Level 1) is not yet solid. Risk: focus/emotional problems later on.',
+    1
+WHERE NOT EXISTS (SELECT 1 FROM pyramid_warning_rules);
+
+-- ============================================
+-- 12. ASSESSMENT ATTACHMENTS TABLE
+-- ============================================
+CREATE TABLE IF NOT EXISTS assessment_attachments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    assessment_id UUID NOT NULL REFERENCES assessments(id) ON DELETE CASCADE,
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    media_type VARCHAR(10) NOT NULL,
+    content_type VARCHAR(100) NOT NULL,
+    file_name VARCHAR(255) NOT NULL,
+    size_bytes BIGINT NOT NULL,
+    storage_key TEXT NOT NULL,
+    thumbnail_key TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_assessment_attachments_assessment ON assessment_attachments(assessment_id);
+CREATE INDEX IF NOT EXISTS idx_assessment_attachments_child ON assessment_attachments(child_id);
+
+-- ============================================
+-- 13. CONTENT PACKS (versioned milestone sources)
+-- ============================================
+CREATE TABLE IF NOT EXISTS content_packs (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    source VARCHAR(50) NOT NULL,
+    version VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    notes TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',
+    milestone_count INT NOT NULL DEFAULT 0,
+    content_count INT NOT NULL DEFAULT 0,
+    imported_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    activated_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(source, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_content_packs_source_status ON content_packs(source, status);
+
+ALTER TABLE milestones ADD COLUMN IF NOT EXISTS content_pack_id UUID REFERENCES content_packs(id) ON DELETE CASCADE;
+ALTER TABLE milestones ADD COLUMN IF NOT EXISTS pack_key VARCHAR(100);
+ALTER TABLE milestones ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE stimulation_content ADD COLUMN IF NOT EXISTS content_pack_id UUID REFERENCES content_packs(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_milestones_content_pack ON milestones(content_pack_id);
+CREATE INDEX IF NOT EXISTS idx_milestones_active ON milestones(is_active);
+CREATE INDEX IF NOT EXISTS idx_stimulation_content_pack ON stimulation_content(content_pack_id);
+
+-- ============================================
+-- 14. TRANSLATIONS
+-- ============================================
+CREATE TABLE IF NOT EXISTS translations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    entity_type VARCHAR(50) NOT NULL,
+    entity_id UUID NOT NULL,
+    field VARCHAR(50) NOT NULL,
+    language VARCHAR(10) NOT NULL,
+    value TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(entity_type, entity_id, field, language)
+);
+
+CREATE INDEX IF NOT EXISTS idx_translations_entity ON translations(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_translations_language ON translations(entity_type, language);
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS preferred_language VARCHAR(10);
+
+-- ============================================
+-- 15. KIPI REPORTS (adverse events following immunization)
+-- ============================================
+CREATE TABLE IF NOT EXISTS kipi_reports (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    child_immunization_id UUID NOT NULL REFERENCES child_immunizations(id) ON DELETE CASCADE,
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    immunization_schedule_id UUID NOT NULL REFERENCES immunization_schedule(id) ON DELETE CASCADE,
+    reported_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    onset_at TIMESTAMP NOT NULL,
+    symptoms TEXT[] NOT NULL,
+    severity VARCHAR(20) NOT NULL CHECK (severity IN ('mild', 'moderate', 'serious')),
+    outcome VARCHAR(30) NOT NULL CHECK (outcome IN ('recovering', 'recovered', 'recovered_with_sequelae', 'hospitalized', 'died', 'unknown')),
+    vaccine_batch_number VARCHAR(100),
+    is_serious BOOLEAN NOT NULL DEFAULT FALSE,
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_kipi_reports_child ON kipi_reports(child_id);
+CREATE INDEX IF NOT EXISTS idx_kipi_reports_immunization ON kipi_reports(child_immunization_id);
+CREATE INDEX IF NOT EXISTS idx_kipi_reports_batch ON kipi_reports(immunization_schedule_id, vaccine_batch_number);
+
+CREATE TABLE IF NOT EXISTS immunization_review_flags (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    immunization_schedule_id UUID NOT NULL REFERENCES immunization_schedule(id) ON DELETE CASCADE,
+    kipi_report_id UUID NOT NULL REFERENCES kipi_reports(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'cleared', 'contraindicated')),
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    review_notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(child_id, immunization_schedule_id, kipi_report_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_immunization_review_flags_child ON immunization_review_flags(child_id, status);
+
+-- ============================================
+-- 16. IMMUNIZATION RECORD VALIDITY (multiple records per schedule item)
+-- ============================================
+DROP INDEX IF EXISTS idx_child_immunizations_unique;
+CREATE INDEX IF NOT EXISTS idx_child_immunizations_child_schedule ON child_immunizations(child_id, immunization_schedule_id);
+
+ALTER TABLE child_immunizations ADD COLUMN IF NOT EXISTS is_valid BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE child_immunizations ADD COLUMN IF NOT EXISTS invalid_reason TEXT;
+ALTER TABLE child_immunizations ADD COLUMN IF NOT EXISTS invalidated_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE child_immunizations ADD COLUMN IF NOT EXISTS invalidated_at TIMESTAMP;
+
+-- ============================================
+-- 17. CALENDAR FEEDS (ICS subscriptions)
+-- ============================================
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    child_id UUID REFERENCES children(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    last_accessed_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendar_feeds_user ON calendar_feeds(user_id);
+
+-- ============================================
+-- 18. IMMUNIZATION CERTIFICATES
+-- ============================================
+CREATE TABLE IF NOT EXISTS immunization_certificates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    issued_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    summary JSONB NOT NULL,
+    dose_count INT NOT NULL DEFAULT 0,
+    issued_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP,
+    revoked_reason TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_immunization_certificates_child ON immunization_certificates(child_id);
+
+-- ============================================
+-- 19. HEALTH PROGRAMMES (Vitamin A, deworming, supplements)
+-- ============================================
+CREATE TABLE IF NOT EXISTS health_programmes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    code VARCHAR(50) NOT NULL UNIQUE,
+    name VARCHAR(255) NOT NULL,
+    name_id VARCHAR(255),
+    description TEXT,
+    programme_type VARCHAR(30) NOT NULL DEFAULT 'supplement',
+    schedule_type VARCHAR(20) NOT NULL DEFAULT 'age',
+
+    age_min_months INT NOT NULL DEFAULT 0,
+    age_max_months INT NOT NULL,
+
+    campaign_months INT[],
+    interval_months INT,
+
+    dosage VARCHAR(255),
+    source VARCHAR(100) DEFAULT 'Kemenkes',
+    sort_order INT DEFAULT 0,
+    is_active BOOLEAN DEFAULT TRUE,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_health_programmes_schedule CHECK (
+        (schedule_type = 'campaign' AND campaign_months IS NOT NULL) OR schedule_type = 'age'
+    )
+);
+
+CREATE TABLE IF NOT EXISTS child_health_programme_doses (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    programme_id UUID NOT NULL REFERENCES health_programmes(id),
+    given_date DATE NOT NULL,
+    given_at_age_months INT,
+    location VARCHAR(255),
+    healthcare_facility VARCHAR(255),
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_child_health_programme_doses_child ON child_health_programme_doses(child_id);
+CREATE INDEX IF NOT EXISTS idx_child_health_programme_doses_programme ON child_health_programme_doses(programme_id, given_date);
+
+INSERT INTO health_programmes (code, name, name_id, description, programme_type, schedule_type,
+    age_min_months, age_max_months, campaign_months, interval_months, dosage, sort_order)
+VALUES
+    ('vitamin_a_blue', 'Vitamin A (blue capsule)', 'Vitamin A Kapsul Biru',
+     'Kapsul vitamin A untuk bayi 6-11 bulan, diberikan pada bulan vitamin A (Februari dan Agustus)',
+     'vitamin_a', 'campaign', 6, 11, '{2,8}', NULL, '1 kapsul biru (100.000 IU)', 1),
+    ('vitamin_a_red', 'Vitamin A (red capsule)', 'Vitamin A Kapsul Merah',
+     'Kapsul vitamin A untuk anak 12-59 bulan, diberikan pada bulan vitamin A (Februari dan Agustus)',
+     'vitamin_a', 'campaign', 12, 59, '{2,8}', NULL, '1 kapsul merah (200.000 IU)', 2),
+    ('deworming', 'Deworming', 'Obat Cacing',
+     'Obat cacing untuk anak mulai usia 1 tahun, diulang setiap 6 bulan',
+     'deworming', 'age', 12, 59, NULL, 6, 'Albendazol 200 mg (12-23 bulan), 400 mg (24 bulan ke atas)', 3)
+ON CONFLICT (code) DO NOTHING;
+
+-- ============================================
+-- 20. NOTIFICATION TEMPLATES
+-- ============================================
+CREATE TABLE IF NOT EXISTS notification_templates (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    key VARCHAR(100) NOT NULL,
+    channel VARCHAR(20) NOT NULL,
+    language VARCHAR(10) NOT NULL DEFAULT 'id',
+    subject TEXT,
+    body TEXT NOT NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(key, channel, language)
+);
+
+INSERT INTO notification_templates (key, channel, language, subject, body) VALUES
+('otp_code', 'whatsapp', 'id', NULL, E'🔐 *Kode OTP Anda*\n\nHalo,\n\nKode OTP Anda untuk masuk ke aplikasi Tukem:\n\n*{{.Code}}*\n\nKode ini berlaku selama *{{.ExpiryMinutes}} menit*.\n\nJangan bagikan kode ini kepada siapapun.\n\nJika Anda tidak meminta kode ini, abaikan pesan ini.\n\nTerima kasih,\nTim Tukem'),
+('otp_code', 'whatsapp', 'en', NULL, E'🔐 *Your OTP code*\n\nHello,\n\nYour code to sign in to Tukem:\n\n*{{.Code}}*\n\nThe code is valid for *{{.ExpiryMinutes}} minutes*.\n\nDo not share this code with anyone.\n\nIf you did not request this code, ignore this message.\n\nThank you,\nThe Tukem team'),
+('otp_code', 'sms', 'id', NULL, 'Kode OTP Tukem: {{.Code}}. Berlaku {{.ExpiryMinutes}} menit. Jangan bagikan kode ini.'),
+('otp_code', 'sms', 'en', NULL, 'Your Tukem code: {{.Code}}. Valid for {{.ExpiryMinutes}} minutes. Do not share it.'),
+('otp_code', 'email', 'id', 'Kode OTP Tukem Anda', E'Halo,\n\nKode OTP Anda: {{.Code}}\n\nKode ini berlaku selama {{.ExpiryMinutes}} menit. Jangan bagikan kode ini kepada siapapun.\n\nTerima kasih,\nTim Tukem'),
+('otp_code', 'email', 'en', 'Your Tukem OTP code', E'Hello,\n\nYour OTP code: {{.Code}}\n\nThe code is valid for {{.ExpiryMinutes}} minutes. Do not share it with anyone.\n\nThank you,\nThe Tukem team')
+ON CONFLICT (key, channel, language) DO NOTHING;
+
+-- ============================================
+-- 21. OUTBOUND MESSAGE QUEUE
+-- ============================================
+CREATE TABLE IF NOT EXISTS outbound_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    channel VARCHAR(20) NOT NULL,
+    recipient VARCHAR(255) NOT NULL,
+    template_key VARCHAR(100),
+    subject TEXT,
+    body TEXT,
+    is_sensitive BOOLEAN DEFAULT FALSE,
+
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP,
+    expires_at TIMESTAMP,
+    last_error TEXT,
+    provider_message_id VARCHAR(255),
+
+    sent_at TIMESTAMP,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_due ON outbound_messages(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_provider_id ON outbound_messages(channel, provider_message_id) WHERE provider_message_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_created ON outbound_messages(created_at);
+
+CREATE TABLE IF NOT EXISTS outbound_message_events (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    message_id UUID NOT NULL REFERENCES outbound_messages(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    detail TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbound_message_events_message ON outbound_message_events(message_id, created_at);
+
+-- ============================================
+-- 22. SCHEDULED REMINDERS
+-- ============================================
+CREATE TABLE IF NOT EXISTS user_notification_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    immunization_reminders BOOLEAN DEFAULT TRUE,
+    measurement_reminders BOOLEAN DEFAULT TRUE,
+    quiet_hours_start VARCHAR(5),
+    quiet_hours_end VARCHAR(5),
+    timezone VARCHAR(50),
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reminder_log (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reminder_type VARCHAR(50) NOT NULL,
+    reference_key VARCHAR(255) NOT NULL,
+    outbound_message_id UUID REFERENCES outbound_messages(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(child_id, reminder_type, reference_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminder_log_user ON reminder_log(user_id, created_at);
+
+INSERT INTO notification_templates (key, channel, language, subject, body) VALUES
+('immunization_reminder_due_7d', 'whatsapp', 'id', NULL, E'💉 *Pengingat Imunisasi*\n\nHalo {{.ParentName}},\n\nImunisasi {{.ChildName}} akan jatuh tempo dalam {{.DaysUntilDue}} hari ({{.DueDate}}):\n{{.Vaccines}}\n\nSilakan jadwalkan kunjungan ke posyandu, puskesmas atau dokter.\n\nTim Tukem'),
+('immunization_reminder_due_7d', 'whatsapp', 'en', NULL, E'💉 *Immunization reminder*\n\nHello {{.ParentName}},\n\n{{.ChildName}}''s immunization is due in {{.DaysUntilDue}} days ({{.DueDate}}):\n{{.Vaccines}}\n\nPlease plan a visit to the posyandu, health centre or doctor.\n\nThe Tukem team'),
+('immunization_reminder_due_1d', 'whatsapp', 'id', NULL, E'💉 *Pengingat Imunisasi*\n\nHalo {{.ParentName}},\n\nImunisasi {{.ChildName}} jatuh tempo {{if eq .DaysUntilDue 0}}hari ini{{else}}besok{{end}} ({{.DueDate}}):\n{{.Vaccines}}\n\nJangan lupa membawa buku KIA.\n\nTim Tukem'),
+('immunization_reminder_due_1d', 'whatsapp', 'en', NULL, E'💉 *Immunization reminder*\n\nHello {{.ParentName}},\n\n{{.ChildName}}''s immunization is due {{if eq .DaysUntilDue 0}}today{{else}}tomorrow{{end}} ({{.DueDate}}):\n{{.Vaccines}}\n\nRemember to bring the KIA book.\n\nThe Tukem team'),
+('immunization_reminder_overdue', 'whatsapp', 'id', NULL, E'⚠️ *Imunisasi Terlambat*\n\nHalo {{.ParentName}},\n\nImunisasi berikut untuk {{.ChildName}} sudah melewati jadwal:\n{{.Vaccines}}\n\nImunisasi kejar masih dapat diberikan. Silakan konsultasikan dengan bidan atau dokter.\n\nTim Tukem'),
+('immunization_reminder_overdue', 'whatsapp', 'en', NULL, E'⚠️ *Overdue immunization*\n\nHello {{.ParentName}},\n\nThe following immunizations for {{.ChildName}} are past their schedule:\n{{.Vaccines}}\n\nCatch-up doses can still be given. Please consult a midwife or doctor.\n\nThe Tukem team'),
+('measurement_reminder', 'whatsapp', 'id', NULL, E'📏 *Pengingat Penimbangan*\n\nHalo {{.ParentName}},\n\n{{.ChildName}} belum ditimbang dan diukur selama {{.DaysSinceMeasurement}} hari. Penimbangan rutin setiap bulan membantu memantau pertumbuhan.\n\nCatat hasil penimbangan berikutnya di aplikasi Tukem.\n\nTim Tukem'),
+('measurement_reminder', 'whatsapp', 'en', NULL, E'📏 *Measurement reminder*\n\nHello {{.ParentName}},\n\n{{.ChildName}} has not been weighed and measured for {{.DaysSinceMeasurement}} days. Monthly measurements help track growth.\n\nRecord the next measurement in the Tukem app.\n\nThe Tukem team')
+ON CONFLICT (key, channel, language) DO NOTHING;
+
+-- ============================================
+-- 23. MONTHLY DIGESTS
+-- ============================================
+ALTER TABLE user_notification_preferences ADD COLUMN IF NOT EXISTS monthly_digest BOOLEAN DEFAULT TRUE;
+ALTER TABLE user_notification_preferences ADD COLUMN IF NOT EXISTS digest_channel VARCHAR(20) DEFAULT 'whatsapp';
+
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS html_body TEXT;
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS attachment_key VARCHAR(500);
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS attachment_filename VARCHAR(255);
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS attachment_content_type VARCHAR(100);
+
+CREATE TABLE IF NOT EXISTS monthly_digests (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    period VARCHAR(7) NOT NULL,
+    language VARCHAR(10) NOT NULL DEFAULT 'id',
+    content JSONB NOT NULL,
+    pdf_key VARCHAR(500),
+    channel VARCHAR(20),
+    outbound_message_id UUID REFERENCES outbound_messages(id) ON DELETE SET NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_monthly_digests_user ON monthly_digests(user_id, period DESC);
+
+INSERT INTO notification_templates (key, channel, language, subject, body) VALUES
+('monthly_digest', 'whatsapp', 'id', NULL, E'📊 *Ringkasan Bulanan {{.Period}}*\n\nHalo {{.ParentName}},\n{{range .Children}}\n*{{.Name}}* ({{.AgeMonths}} bulan)\n{{if .WeightChange}}• Berat badan: {{.Weight}} kg ({{.WeightChange}} kg)\n{{else if .Weight}}• Berat badan: {{.Weight}} kg\n{{else}}• Belum ada penimbangan bulan ini\n{{end}}{{range .ZScores}}• {{.}}\n{{end}}{{if .Milestones}}• Milestone baru: {{.Milestones}}\n{{end}}{{if .OverdueVaccines}}• Imunisasi terlambat: {{.OverdueVaccines}}\n{{end}}{{if .Activities}}• Aktivitas: {{.Activities}}\n{{end}}{{end}}\nLihat ringkasan lengkap di aplikasi Tukem.\n\nTim Tukem'),
+('monthly_digest', 'whatsapp', 'en', NULL, E'📊 *Monthly summary {{.Period}}*\n\nHello {{.ParentName}},\n{{range .Children}}\n*{{.Name}}* ({{.AgeMonths}} months)\n{{if .WeightChange}}• Weight: {{.Weight}} kg ({{.WeightChange}} kg)\n{{else if .Weight}}• Weight: {{.Weight}} kg\n{{else}}• No measurement this month\n{{end}}{{range .ZScores}}• {{.}}\n{{end}}{{if .Milestones}}• New milestones: {{.Milestones}}\n{{end}}{{if .OverdueVaccines}}• Overdue immunizations: {{.OverdueVaccines}}\n{{end}}{{if .Activities}}• Activities: {{.Activities}}\n{{end}}{{end}}\nSee the full summary in the Tukem app.\n\nThe Tukem team'),
+('monthly_digest', 'email', 'id', 'Ringkasan Bulanan Tukem {{.Period}}', E'Halo {{.ParentName}},\n\nBerikut ringkasan tumbuh kembang anak Anda bulan {{.Period}}. Laporan lengkap terlampir dalam PDF.\n{{range .Children}}\n{{.Name}} ({{.AgeMonths}} bulan)\n{{if .WeightChange}}- Berat badan: {{.Weight}} kg ({{.WeightChange}} kg)\n{{else if .Weight}}- Berat badan: {{.Weight}} kg\n{{else}}- Belum ada penimbangan bulan ini\n{{end}}{{range .ZScores}}- {{.}}\n{{end}}{{if .Milestones}}- Milestone baru: {{.Milestones}}\n{{end}}{{if .OverdueVaccines}}- Imunisasi terlambat: {{.OverdueVaccines}}\n{{end}}{{if .Activities}}- Aktivitas: {{.Activities}}\n{{end}}{{end}}\nTerima kasih,\nTim Tukem'),
+('monthly_digest', 'email', 'en', 'Your Tukem monthly summary {{.Period}}', E'Hello {{.ParentName}},\n\nHere is your children''s growth and development summary for {{.Period}}. The full report is attached as a PDF.\n{{range .Children}}\n{{.Name}} ({{.AgeMonths}} months)\n{{if .WeightChange}}- Weight: {{.Weight}} kg ({{.WeightChange}} kg)\n{{else if .Weight}}- Weight: {{.Weight}} kg\n{{else}}- No measurement this month\n{{end}}{{range .ZScores}}- {{.}}\n{{end}}{{if .Milestones}}- New milestones: {{.Milestones}}\n{{end}}{{if .OverdueVaccines}}- Overdue immunizations: {{.OverdueVaccines}}\n{{end}}{{if .Activities}}- Activities: {{.Activities}}\n{{end}}{{end}}\nThank you,\nThe Tukem team')
+ON CONFLICT (key, channel, language) DO NOTHING;
+
+-- ============================================
+-- 24. WHATSAPP CHAT COMMANDS
+-- ============================================
+CREATE TABLE IF NOT EXISTS whatsapp_conversations (
+    phone_number VARCHAR(20) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    state VARCHAR(30) NOT NULL DEFAULT 'idle',
+    pending JSONB,
+    expires_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS whatsapp_inbound_messages (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    provider_message_id VARCHAR(255) UNIQUE,
+    phone_number VARCHAR(20) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    command VARCHAR(30),
+    reply TEXT,
+    reply_message_id UUID REFERENCES outbound_messages(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_whatsapp_inbound_phone ON whatsapp_inbound_messages(phone_number, created_at DESC);
+
+-- ============================================
+-- 25. USER SESSIONS
+-- ============================================
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    previous_token_hash VARCHAR(64),
+    is_admin BOOLEAN NOT NULL DEFAULT false,
+    user_agent TEXT,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    revoked_reason VARCHAR(50)
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_previous_token ON user_sessions(previous_token_hash);
+
+-- ============================================
+-- 26. HEALTH-WORKER ROLES
+-- ============================================
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'check_role') THEN
+        ALTER TABLE users DROP CONSTRAINT check_role;
+    END IF;
+END $$;
+
+ALTER TABLE users
+ADD CONSTRAINT check_role CHECK (role IN ('parent', 'admin', 'kader', 'bidan', 'nutritionist', 'district_viewer'));
+
+CREATE TABLE IF NOT EXISTS areas (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    level VARCHAR(20) NOT NULL CHECK (level IN ('province', 'district', 'subdistrict', 'village')),
+    parent_id UUID REFERENCES areas(id) ON DELETE RESTRICT,
+    code VARCHAR(20) UNIQUE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_areas_parent ON areas(parent_id);
+
+CREATE TABLE IF NOT EXISTS facilities (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('posyandu', 'puskesmas', 'clinic', 'hospital')),
+    area_id UUID REFERENCES areas(id) ON DELETE SET NULL,
+    parent_id UUID REFERENCES facilities(id) ON DELETE SET NULL,
+    address TEXT,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_facilities_area ON facilities(area_id);
+CREATE INDEX IF NOT EXISTS idx_facilities_parent ON facilities(parent_id);
+
+ALTER TABLE children ADD COLUMN IF NOT EXISTS facility_id UUID REFERENCES facilities(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_children_facility ON children(facility_id);
+
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(30) NOT NULL,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+CREATE TABLE IF NOT EXISTS role_assignments (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(30) NOT NULL CHECK (role IN ('kader', 'bidan', 'nutritionist', 'district_viewer')),
+    facility_id UUID REFERENCES facilities(id) ON DELETE CASCADE,
+    area_id UUID REFERENCES areas(id) ON DELETE CASCADE,
+    assigned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (facility_id IS NULL OR area_id IS NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_assignments_user ON role_assignments(user_id);
+
+INSERT INTO role_permissions (role, permission) VALUES
+('kader', 'child:view'),
+('kader', 'measurement:view'),
+('kader', 'measurement:create'),
+('kader', 'assessment:view'),
+('kader', 'immunization:view'),
+('bidan', 'child:view'),
+('bidan', 'measurement:view'),
+('bidan', 'measurement:create'),
+('bidan', 'assessment:view'),
+('bidan', 'immunization:view'),
+('bidan', 'report:view'),
+('nutritionist', 'child:view'),
+('nutritionist', 'measurement:view'),
+('nutritionist', 'measurement:create'),
+('nutritionist', 'assessment:view'),
+('nutritionist', 'report:view'),
+('district_viewer', 'child:view'),
+('district_viewer', 'measurement:view'),
+('district_viewer', 'assessment:view'),
+('district_viewer', 'immunization:view'),
+('district_viewer', 'report:view')
+ON CONFLICT (role, permission) DO NOTHING;
+
+-- ============================================
+-- 27. SHARED CHILD ACCESS
+-- ============================================
+CREATE TABLE IF NOT EXISTS child_guardians (
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    relationship VARCHAR(50),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (child_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_child_guardians_owner ON child_guardians(child_id) WHERE role = 'owner';
+CREATE INDEX IF NOT EXISTS idx_child_guardians_user ON child_guardians(user_id);
+
+INSERT INTO child_guardians (child_id, user_id, role)
+SELECT id, parent_id, 'owner' FROM children WHERE parent_id IS NOT NULL
+ON CONFLICT (child_id, user_id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS child_invitations (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    phone_number VARCHAR(20) NOT NULL,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('editor', 'viewer')),
+    relationship VARCHAR(50),
+    code_hash VARCHAR(64) NOT NULL,
+    attempt_count INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'revoked', 'expired')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    accepted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_child_invitations_child ON child_invitations(child_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_child_invitations_phone ON child_invitations(phone_number, status);
+
+-- Scheduled reminders go to every guardian, each of them once
+ALTER TABLE reminder_log DROP CONSTRAINT IF EXISTS reminder_log_child_id_reminder_type_reference_key_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reminder_log_recipient
+    ON reminder_log(child_id, user_id, reminder_type, reference_key);
+
+INSERT INTO notification_templates (key, channel, language, subject, body) VALUES
+('child_invitation', 'whatsapp', 'id', NULL, E'👨‍👩‍👧 *Undangan Tukem*\n\n{{.InviterName}} mengundang Anda untuk ikut memantau tumbuh kembang {{.ChildName}} sebagai {{.Role}}.\n\nKode undangan: *{{.Code}}*\nBerlaku {{.ExpiryHours}} jam.\n\nMasuk ke aplikasi Tukem dengan nomor WhatsApp ini, lalu masukkan kode di menu Undangan. Abaikan pesan ini jika Anda tidak mengenal pengirimnya.\n\nTim Tukem'),
+('child_invitation', 'whatsapp', 'en', NULL, E'👨‍👩‍👧 *Tukem invitation*\n\n{{.InviterName}} invited you to follow {{.ChildName}}''s growth and development as {{.Role}}.\n\nInvitation code: *{{.Code}}*\nValid for {{.ExpiryHours}} hours.\n\nLog in to the Tukem app with this WhatsApp number and enter the code under Invitations. Ignore this message if you don''t know the sender.\n\nThe Tukem team')
+ON CONFLICT (key, channel, language) DO NOTHING;
+
+-- ============================================
+-- 28. ADMIN TWO-FACTOR AUTHENTICATION
+-- ============================================
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_encrypted TEXT NOT NULL,
+    enabled_at TIMESTAMP,
+    last_used_step BIGINT NOT NULL DEFAULT 0,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user ON user_recovery_codes(user_id);
+
+CREATE TABLE IF NOT EXISTS two_factor_challenges (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    login_method VARCHAR(20) NOT NULL,
+    is_admin BOOLEAN NOT NULL DEFAULT false,
+    attempt_count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_two_factor_challenges_user ON two_factor_challenges(user_id, created_at DESC);
+
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS two_factor_verified BOOLEAN NOT NULL DEFAULT false;
+
+-- ============================================
+-- 29. GOOGLE LOGIN EXCHANGE CODES
+-- ============================================
+CREATE TABLE IF NOT EXISTS login_exchange_codes (
+    id UUID PRIMARY KEY DEFAULT uuid_generate_v4(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL UNIQUE,
+    login_method VARCHAR(20) NOT NULL,
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_exchange_codes_expires ON login_exchange_codes(expires_at);
+
+-- ============================================
+-- 30. CATCH-UP PLAN ORIGIN OF IMMUNIZATION RECORDS
+-- ============================================
+ALTER TABLE child_immunizations ADD COLUMN IF NOT EXISTS from_catch_up_plan BOOLEAN NOT NULL DEFAULT FALSE;
+
+UPDATE child_immunizations SET from_catch_up_plan = TRUE
+WHERE is_catch_up = TRUE AND is_on_schedule = TRUE AND from_catch_up_plan = FALSE;
+
+-- ============================================
+-- 31. WHATSAPP CHAT REPLY TEMPLATES
+-- ============================================
+INSERT INTO notification_templates (key, channel, language, subject, body) VALUES
+('chat_unregistered', 'whatsapp', 'id', NULL, 'Nomor ini belum terdaftar atau belum diverifikasi di Tukem. Masuk ke aplikasi Tukem dengan nomor WhatsApp ini untuk menggunakan fitur chat.'),
+('chat_unregistered', 'whatsapp', 'en', NULL, 'This number is not registered or verified on Tukem. Sign in to the Tukem app with this WhatsApp number to use chat commands.'),
+('chat_help', 'whatsapp', 'id', NULL, E'🤖 *Perintah Tukem*\n\n• *BB 8.2 TB 70 Budi* - catat berat badan (kg) dan tinggi badan (cm), tambahkan *LK 44* untuk lingkar kepala\n• *jadwal Budi* - jadwal imunisasi dan penimbangan berikutnya\n• *imunisasi Budi* - status imunisasi\n• *batal* - batalkan pertanyaan\n\nNama anak boleh dikosongkan jika Anda hanya memiliki satu anak.'),
+('chat_help', 'whatsapp', 'en', NULL, E'🤖 *Tukem commands*\n\n• *BB 8.2 TB 70 Budi* - record weight (kg) and height (cm), add *LK 44* for head circumference\n• *schedule Budi* - next immunizations and measurement\n• *immunization Budi* - immunization status\n• *cancel* - cancel a question\n\nThe child''s name can be left out if you have only one child.'),
+('chat_unknown', 'whatsapp', 'id', NULL, 'Maaf, pesan tidak dikenali. Ketik *bantuan* untuk melihat daftar perintah.'),
+('chat_unknown', 'whatsapp', 'en', NULL, 'Sorry, this message was not understood. Send *help* for the list of commands.'),
+('chat_cancelled', 'whatsapp', 'id', NULL, 'Baik, dibatalkan.'),
+('chat_cancelled', 'whatsapp', 'en', NULL, 'OK, cancelled.'),
+('chat_no_children', 'whatsapp', 'id', NULL, 'Belum ada data anak. Tambahkan anak di aplikasi Tukem terlebih dahulu.'),
+('chat_no_children', 'whatsapp', 'en', NULL, 'No children yet. Add your child in the Tukem app first.'),
+('chat_measurement_format', 'whatsapp', 'id', NULL, 'Kirim berat dan tinggi badan sekaligus, contoh: *BB 8.2 TB 70 Budi*'),
+('chat_measurement_format', 'whatsapp', 'en', NULL, 'Send weight and height together, e.g. *BB 8.2 TB 70 Budi*'),
+('chat_which_child', 'whatsapp', 'id', NULL, E'Untuk anak yang mana? Balas dengan nomornya:\n{{.Children}}'),
+('chat_which_child', 'whatsapp', 'en', NULL, E'For which child? Reply with the number:\n{{.Children}}'),
+('chat_child_not_found', 'whatsapp', 'id', NULL, E'Anak bernama "{{.Name}}" tidak ditemukan. Balas dengan nomor anak:\n{{.Children}}'),
+('chat_child_not_found', 'whatsapp', 'en', NULL, E'No child named "{{.Name}}" was found. Reply with the number of the child:\n{{.Children}}'),
+('chat_choose_number', 'whatsapp', 'id', NULL, E'Balas dengan nomor 1 sampai {{.Count}}, atau *batal*.\n{{.Children}}'),
+('chat_choose_number', 'whatsapp', 'en', NULL, E'Reply with a number from 1 to {{.Count}}, or *cancel*.\n{{.Children}}'),
+('chat_measurement_saved', 'whatsapp', 'id', NULL, E'✅ Pengukuran {{.ChildName}} tanggal {{.Date}} tersimpan.\n• Berat badan: {{.Weight}} kg\n• Tinggi badan: {{.Height}} cm{{if .HeadCircumference}}\n• Lingkar kepala: {{.HeadCircumference}} cm{{end}}{{range .Statuses}}\n• {{.}}{{end}}'),
+('chat_measurement_saved', 'whatsapp', 'en', NULL, E'✅ Measurement of {{.ChildName}} on {{.Date}} saved.\n• Weight: {{.Weight}} kg\n• Height: {{.Height}} cm{{if .HeadCircumference}}\n• Head circumference: {{.HeadCircumference}} cm{{end}}{{range .Statuses}}\n• {{.}}{{end}}'),
+('chat_measurement_failed', 'whatsapp', 'id', NULL, 'Pengukuran tidak dapat disimpan: {{.Reason}}'),
+('chat_measurement_failed', 'whatsapp', 'en', NULL, 'The measurement could not be saved: {{.Reason}}'),
+('chat_viewer_only', 'whatsapp', 'id', NULL, 'Anda hanya dapat melihat data {{.ChildName}}. Minta pemilik data anak untuk mengubah akses Anda agar dapat mencatat pengukuran.'),
+('chat_viewer_only', 'whatsapp', 'en', NULL, 'You can only view the data of {{.ChildName}}. Ask the child''s owner to change your access to record measurements.'),
+('chat_schedule', 'whatsapp', 'id', NULL, E'📅 *Jadwal {{.ChildName}}*{{range .Items}}\n• {{.Name}} - {{.DueDate}}{{if .Overdue}} (terlambat){{end}}{{else}}\nTidak ada imunisasi yang dijadwalkan.{{end}}{{if .NextMeasurement}}\n\nPenimbangan berikutnya: {{.NextMeasurement}}{{end}}'),
+('chat_schedule', 'whatsapp', 'en', NULL, E'📅 *Schedule of {{.ChildName}}*{{range .Items}}\n• {{.Name}} - {{.DueDate}}{{if .Overdue}} (overdue){{end}}{{else}}\nNo immunizations scheduled.{{end}}{{if .NextMeasurement}}\n\nNext measurement: {{.NextMeasurement}}{{end}}'),
+('chat_immunization', 'whatsapp', 'id', NULL, E'💉 *Imunisasi {{.ChildName}}*\n{{.Completed}} dari {{.Total}} imunisasi sudah diberikan.{{if .Overdue}}\n\nTerlambat:{{range .Overdue}}\n• {{.}}{{end}}{{end}}{{if .NextDose}}\n\nBerikutnya: {{.NextDose}} ({{.NextDoseDate}}){{end}}{{if .AllComplete}}\n\nSemua imunisasi sudah lengkap. 🎉{{end}}'),
+('chat_immunization', 'whatsapp', 'en', NULL, E'💉 *Immunizations of {{.ChildName}}*\n{{.Completed}} of {{.Total}} immunizations given.{{if .Overdue}}\n\nOverdue:{{range .Overdue}}\n• {{.}}{{end}}{{end}}{{if .NextDose}}\n\nNext: {{.NextDose}} ({{.NextDoseDate}}){{end}}{{if .AllComplete}}\n\nAll immunizations are complete. 🎉{{end}}'),
+('chat_error', 'whatsapp', 'id', NULL, 'Maaf, terjadi kesalahan. Silakan coba lagi nanti.'),
+('chat_error', 'whatsapp', 'en', NULL, 'Sorry, something went wrong. Please try again later.')
+ON CONFLICT (key, channel, language) DO NOTHING;
+
 -- ============================================
 -- DONE! Tables created automatically on first start
 -- ============================================
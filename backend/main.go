@@ -2,12 +2,14 @@ package main
 
 import (
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"strings"
 	"tukem-backend/db"
 	"tukem-backend/handlers"
 	customMiddleware "tukem-backend/middleware"
+	"tukem-backend/services"
 	"tukem-backend/utils"
 
 	"github.com/labstack/echo/v4"
@@ -15,6 +17,11 @@ import (
 )
 
 func main() {
+	// Refuse to sign tokens and URLs with the development key outside development
+	if err := utils.CheckSecretKeys(); err != nil {
+		log.Fatalf("Configuration error: %v", err)
+	}
+
 	// Initialize Database
 	db.Init()
 
@@ -41,6 +48,23 @@ func main() {
 		log.Printf("Warning: Immunization schedule seeding failed: %v", err)
 	}
 
+	if err := utils.EnsureBaselineContentPacks(db.DB); err != nil {
+		log.Printf("Warning: Baseline content pack registration failed: %v", err)
+	}
+
+	// Outbound notification workers (WhatsApp, email, SMS outbox)
+	services.StartOutboxWorkers(db.DB, services.NewNotificationService(db.DB).OutboxWorkerCount())
+
+	// Hourly cleanup of expired OTP codes and old failed verifications
+	services.StartOTPCleanup(db.DB)
+
+	// Hourly cleanup of idle rate limit buckets
+	services.StartRateLimitCleanup(db.DB)
+
+	// Scheduled immunization and measurement reminders
+	handlers.StartReminderScheduler()
+	handlers.StartMonthlyDigestScheduler()
+
 	e := EchoServer()
 	
 	port := os.Getenv("PORT")
@@ -53,6 +77,9 @@ func main() {
 
 func EchoServer() *echo.Echo {
 	e := echo.New()
+	// Client IP addresses (c.RealIP) key rate limits, bans and the OTP lockout, so forwarded
+	// headers are only trusted from known proxies
+	e.IPExtractor = ipExtractor()
 
 	e.Use(middleware.Logger())
 	e.Use(middleware.Recover())
@@ -68,9 +95,15 @@ func EchoServer() *echo.Echo {
 		AllowOrigins: corsOrigins,
 		AllowMethods: []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodDelete, http.MethodOptions},
 		AllowHeaders: []string{echo.HeaderOrigin, echo.HeaderContentType, echo.HeaderAccept, echo.HeaderAuthorization},
-		ExposeHeaders: []string{"Content-Disposition"}, // Expose Content-Disposition for file downloads
+		ExposeHeaders: []string{"Content-Disposition", "X-Age-Basis", "X-Age-Months", // Expose Content-Disposition for file downloads, age basis for milestone lists
+			"Retry-After", "RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy"}, // and the rate limit of the client
 	}))
 
+	// Rate limiting per route class: per client IP address for every request, per user after
+	// the JWT check. Limits, store and bans are configured in system_settings (rate_limit).
+	rateLimiter := services.NewRateLimiter(db.DB)
+	e.Use(customMiddleware.RateLimit(rateLimiter, services.RateLimitByIP))
+
 	e.GET("/", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{
 			"message": "Welcome to Tukem API",
@@ -84,6 +117,19 @@ func EchoServer() *echo.Echo {
 		})
 	})
 
+	// Signed file downloads for the local attachment store (public, authorized by the URL signature)
+	e.GET("/files/*", handlers.ServeLocalAttachment)
+
+	// ICS calendar feeds (public, authorized by the secret token in the URL)
+	e.GET("/calendar/:token", handlers.ServeCalendarFeed)
+
+	// Immunization certificate verification (public, authorized by the signed token in the QR code)
+	e.GET("/verify/certificates/:token", handlers.VerifyImmunizationCertificate)
+
+	// Delivery status callbacks from notification gateways (public, authorized by the webhook secret)
+	e.POST("/webhooks/notifications/:channel", handlers.ReceiveNotificationStatus)
+	e.POST("/webhooks/whatsapp/inbound", handlers.ReceiveWhatsAppMessage)
+
 	// Auth Routes
 	auth := e.Group("/api/auth")
 	auth.POST("/register", handlers.Register)
@@ -98,10 +144,18 @@ func EchoServer() *echo.Echo {
 	// Google OAuth Routes
 	auth.GET("/google", handlers.GetGoogleAuthURL)
 	auth.GET("/google/callback", handlers.GoogleAuthCallback)
+	auth.POST("/google/exchange", handlers.ExchangeGoogleLogin)
+	// Session Routes
+	auth.POST("/refresh", handlers.RefreshSession)
+	// Two-factor authentication of admin logins (authorized by the challenge token of the login)
+	auth.POST("/2fa/verify", handlers.VerifyTwoFactor)
+	auth.POST("/2fa/setup", handlers.SetupTwoFactor)
+	auth.POST("/2fa/enable", handlers.EnableTwoFactor)
 
 	// Protected Routes
 	api := e.Group("/api")
 	api.Use(customMiddleware.JWTMiddleware())
+	api.Use(customMiddleware.RateLimit(rateLimiter, services.RateLimitByUser))
 	api.GET("/me", func(c echo.Context) error {
 		return c.JSON(http.StatusOK, map[string]string{
 			"message": "You are authorized!",
@@ -113,18 +167,53 @@ func EchoServer() *echo.Echo {
 	api.PUT("/user/profile", handlers.UpdateUserProfile)
 	api.POST("/user/verify-phone", handlers.RequestPhoneVerification)
 	api.POST("/user/verify-phone/confirm", handlers.ConfirmPhoneVerification)
+	api.GET("/user/calendar-feeds", handlers.GetCalendarFeeds)
+	api.POST("/user/calendar-feeds", handlers.CreateCalendarFeed)
+	api.DELETE("/user/calendar-feeds/:id", handlers.RevokeCalendarFeed)
+	api.GET("/user/notification-preferences", handlers.GetNotificationPreferences)
+	api.PUT("/user/notification-preferences", handlers.UpdateNotificationPreferences)
+	api.GET("/user/digests", handlers.GetMonthlyDigests)
+	api.GET("/user/digests/:id/pdf", handlers.ExportMonthlyDigestPDF)
+	api.GET("/user/digests/:id", handlers.GetMonthlyDigest)
+	api.GET("/user/permissions", handlers.GetUserPermissions)
+	api.GET("/facilities", handlers.GetFacilities)
+	api.POST("/auth/logout", handlers.Logout)
+	api.GET("/user/sessions", handlers.GetUserSessions)
+	api.DELETE("/user/sessions", handlers.RevokeAllUserSessions)
+	api.DELETE("/user/sessions/:id", handlers.RevokeUserSession)
+	api.GET("/user/2fa", handlers.GetTwoFactorStatus)
+	api.POST("/user/2fa/recovery-codes", handlers.RegenerateRecoveryCodes)
+	api.GET("/user/invitations", handlers.GetMyInvitations)
+	api.POST("/user/invitations/:id/accept", handlers.AcceptChildInvitation)
 
 	// Milestone Routes
 	milestoneHandler := handlers.NewMilestoneHandler(db.DB) // Assuming db.DB is the sqlx.DB instance
 	api.GET("/milestones", milestoneHandler.GetMilestones)
 	api.GET("/milestones/denver-ii", handlers.GetDenverIIMilestones)
 	
+	// Child Routes - each checks the user's guardian role for the child (see RequireChildAccess)
+	childViewer := customMiddleware.RequireChildAccess(utils.GuardianViewer)
+	childEditor := customMiddleware.RequireChildAccess(utils.GuardianEditor)
+	childOwner := customMiddleware.RequireChildAccess(utils.GuardianOwner)
+
 	// Recommendations Route - MUST be BEFORE /children/:id to avoid route conflict
-	api.GET("/children/:id/recommendations", handlers.GetRecommendations)
+	api.GET("/children/:id/recommendations", handlers.GetRecommendations, childViewer)
 	
 	// Immunization Routes - MUST be BEFORE /children/:id to avoid route conflict
-	api.GET("/children/:id/immunizations", handlers.GetImmunizationSchedule)
-	api.POST("/children/:id/immunizations", handlers.RecordImmunization)
+	api.GET("/children/:id/immunizations", handlers.GetImmunizationSchedule, childViewer)
+	api.POST("/children/:id/immunizations", handlers.RecordImmunization, childEditor)
+	api.GET("/children/:id/immunizations/catch-up", handlers.GetImmunizationCatchUpPlan, childViewer)
+	api.GET("/children/:id/immunizations/records", handlers.GetImmunizationRecords, childViewer)
+	api.PUT("/children/:id/immunizations/:recordId", handlers.UpdateImmunizationRecord, childEditor)
+	api.DELETE("/children/:id/immunizations/:recordId", handlers.DeleteImmunizationRecord, childEditor)
+	api.PUT("/children/:id/immunizations/:recordId/validity", handlers.SetImmunizationRecordValidity, childEditor)
+	api.POST("/children/:id/immunizations/:recordId/kipi", handlers.CreateKIPIReport, childEditor)
+	api.GET("/children/:id/kipi-reports", handlers.GetChildKIPIReports, childViewer)
+
+	// Health programme Routes (Vitamin A, deworming, supplements)
+	api.GET("/children/:id/health-programmes", handlers.GetHealthProgrammeSchedule, childViewer)
+	api.POST("/children/:id/health-programmes", handlers.RecordHealthProgrammeDose, childEditor)
+	api.DELETE("/children/:id/health-programmes/:doseId", handlers.DeleteHealthProgrammeDose, childEditor)
 	
 	// Children Routes (general routes first)
 	api.POST("/children", handlers.CreateChild)
@@ -132,100 +221,212 @@ func EchoServer() *echo.Echo {
 	
 	// Denver II Routes - MUST be registered BEFORE /children/:id to avoid route conflict
 	// Echo matches routes in order, so more specific routes must come first
-	api.GET("/children/:id/denver-ii/chart-data", handlers.GetDenverIIChartData)
-	api.GET("/children/:id/denver-ii/grid-data", handlers.GetDenverIIChartGridData)
+	api.GET("/children/:id/denver-ii/chart-data", handlers.GetDenverIIChartData, childViewer)
+	api.GET("/children/:id/denver-ii/grid-data", handlers.GetDenverIIChartGridData, childViewer)
 	
 	// PDF Export Route - MUST be BEFORE /children/:id and other /children/:id/* routes to avoid route conflict
-	api.GET("/children/:id/export-pdf", handlers.ExportChildReport)
+	api.GET("/children/:id/export-pdf", handlers.ExportChildReport, childViewer)
+	api.GET("/children/:id/immunization-certificate", handlers.ExportImmunizationCertificate, childViewer)
+	api.GET("/children/:id/immunization-certificates", handlers.GetImmunizationCertificates, childViewer)
+	api.POST("/children/:id/immunization-certificates/:certId/revoke", handlers.RevokeImmunizationCertificate, childEditor)
 	
 	// Measurement Routes (must come before /children/:id to avoid conflict)
-	api.POST("/children/:id/measurements", handlers.CreateMeasurement)
-	api.GET("/children/:id/measurements", handlers.GetMeasurements)
-	api.GET("/children/:id/measurements/latest", handlers.GetLatestMeasurement)
-	api.PUT("/children/:id/measurements/:measurementId", handlers.UpdateMeasurement)
-	api.DELETE("/children/:id/measurements/:measurementId", handlers.DeleteMeasurement)
+	api.POST("/children/:id/measurements", handlers.CreateMeasurement, childEditor)
+	api.GET("/children/:id/measurements", handlers.GetMeasurements, childViewer)
+	api.GET("/children/:id/measurements/latest", handlers.GetLatestMeasurement, childViewer)
+	api.PUT("/children/:id/measurements/:measurementId", handlers.UpdateMeasurement, childEditor)
+	api.DELETE("/children/:id/measurements/:measurementId", handlers.DeleteMeasurement, childEditor)
 	
 	// Assessment Routes (must come before /children/:id to avoid conflict)
-	api.GET("/children/:id/assessments", milestoneHandler.GetChildAssessments)
-	api.PUT("/children/:id/assessments/batch", milestoneHandler.BatchUpsertAssessments)
-	api.GET("/children/:id/assessments/summary", milestoneHandler.GetAssessmentSummary)
+	api.GET("/children/:id/assessments", milestoneHandler.GetChildAssessments, childViewer)
+	api.PUT("/children/:id/assessments/batch", milestoneHandler.BatchUpsertAssessments, childEditor)
+	api.GET("/children/:id/assessments/summary", milestoneHandler.GetAssessmentSummary, childViewer)
+	api.POST("/children/:id/assessments/:assessmentId/attachments", handlers.UploadAssessmentAttachment, childEditor)
+	api.GET("/children/:id/assessments/:assessmentId/attachments", handlers.GetAssessmentAttachments, childViewer)
+	api.DELETE("/children/:id/assessments/:assessmentId/attachments/:attachmentId", handlers.DeleteAssessmentAttachment, childEditor)
+	
+	// Developmental Profile Routes (must come before /children/:id to avoid conflict)
+	api.GET("/children/:id/development", handlers.GetDevelopmentalProfile, childViewer)
+	api.GET("/children/:id/development/history", handlers.GetDevelopmentalHistory, childViewer)
+	
+	// Shared access Routes (must come before /children/:id to avoid conflict)
+	api.GET("/children/:id/guardians", handlers.GetChildGuardians, childViewer)
+	api.PUT("/children/:id/guardians/:userId", handlers.UpdateChildGuardian, childOwner)
+	api.DELETE("/children/:id/guardians/:userId", handlers.RemoveChildGuardian, childViewer)
+	api.GET("/children/:id/invitations", handlers.GetChildInvitations, childOwner)
+	api.POST("/children/:id/invitations", handlers.CreateChildInvitation, childOwner)
+	api.DELETE("/children/:id/invitations/:invitationId", handlers.RevokeChildInvitation, childOwner)
+	api.POST("/children/:id/transfer-ownership", handlers.TransferChildOwnership, childOwner)
 	
 	// Children detail routes (must come after ALL specific /children/:id/* routes)
-	api.GET("/children/:id", handlers.GetChild)
-	api.PUT("/children/:id", handlers.UpdateChild)
-	api.DELETE("/children/:id", handlers.DeleteChild)
+	api.GET("/children/:id", handlers.GetChild, childViewer)
+	api.PUT("/children/:id", handlers.UpdateChild, childEditor)
+	api.DELETE("/children/:id", handlers.DeleteChild, childOwner)
 
 	// Growth Charts Routes
 	api.GET("/who-standards", handlers.GetWHOStandardsForChart)
 
-	// Admin Routes - Protected with admin middleware
+	// Admin and health-worker routes - each protected by a permission (admins hold all of them)
 	// Note: api group already has JWTMiddleware, so admin routes are already JWT protected
 	admin := api.Group("/admin")
-	admin.Use(customMiddleware.RequireAdmin())
+	perm := customMiddleware.RequirePermission // Every admin route requires a permission
 
 	// Admin User Management
-	admin.GET("/users", handlers.GetAdminUsers)
-	admin.GET("/users/:id", handlers.GetAdminUser)
-	admin.POST("/users", handlers.CreateAdminUser)
-	admin.PUT("/users/:id", handlers.UpdateAdminUser)
-	admin.DELETE("/users/:id", handlers.DeleteAdminUser)
-	admin.POST("/users/:id/reset-password", handlers.ResetAdminUserPassword)
+	admin.GET("/users", handlers.GetAdminUsers, perm("user:view"))
+	admin.GET("/users/:id", handlers.GetAdminUser, perm("user:view"))
+	admin.POST("/users", handlers.CreateAdminUser, perm("user:manage"))
+	admin.PUT("/users/:id", handlers.UpdateAdminUser, perm("user:manage"))
+	admin.DELETE("/users/:id", handlers.DeleteAdminUser, perm("user:manage"))
+	admin.POST("/users/:id/reset-password", handlers.ResetAdminUserPassword, perm("user:manage"))
+	admin.GET("/users/:id/sessions", handlers.GetAdminUserSessions, perm("user:view"))
+	admin.DELETE("/users/:id/sessions", handlers.RevokeAdminUserSessions, perm("user:manage"))
+	admin.POST("/users/:id/reset-2fa", handlers.ResetAdminUserTwoFactor, perm("user:manage"))
+	admin.GET("/users/:id/roles", handlers.GetAdminUserRoles, perm("user:view"))
+	admin.POST("/users/:id/roles", handlers.CreateAdminUserRole, perm("role:manage"))
+	admin.DELETE("/users/:id/roles/:assignment_id", handlers.DeleteAdminUserRole, perm("role:manage"))
+
+	// Health-worker roles, facilities and areas
+	admin.GET("/roles", handlers.GetAdminRoles, perm("role:manage"))
+	admin.PUT("/roles/:role/permissions", handlers.UpdateAdminRolePermissions, perm("role:manage"))
+	admin.GET("/facilities", handlers.GetAdminFacilities, perm("facility:manage"))
+	admin.POST("/facilities", handlers.CreateAdminFacility, perm("facility:manage"))
+	admin.PUT("/facilities/:id", handlers.UpdateAdminFacility, perm("facility:manage"))
+	admin.GET("/areas", handlers.GetAdminAreas, perm("facility:manage"))
+	admin.POST("/areas", handlers.CreateAdminArea, perm("facility:manage"))
 
 	// Admin Analytics
-	admin.GET("/analytics/overview", handlers.GetAdminOverview)
-	admin.GET("/analytics/users", handlers.GetAdminUsersAnalytics)
-	admin.GET("/analytics/children", handlers.GetAdminChildrenAnalytics)
-	admin.GET("/analytics/measurements", handlers.GetAdminMeasurementsAnalytics)
-	admin.GET("/analytics/assessments", handlers.GetAdminAssessmentsAnalytics)
-	admin.GET("/analytics/immunizations", handlers.GetAdminImmunizationsAnalytics)
+	admin.GET("/analytics/overview", handlers.GetAdminOverview, perm("analytics:view"))
+	admin.GET("/analytics/users", handlers.GetAdminUsersAnalytics, perm("analytics:view"))
+	admin.GET("/analytics/children", handlers.GetAdminChildrenAnalytics, perm("analytics:view"))
+	admin.GET("/analytics/measurements", handlers.GetAdminMeasurementsAnalytics, perm("analytics:view"))
+	admin.GET("/analytics/assessments", handlers.GetAdminAssessmentsAnalytics, perm("analytics:view"))
+	admin.GET("/analytics/immunizations", handlers.GetAdminImmunizationsAnalytics, perm("analytics:view"))
+	admin.GET("/analytics/health-programmes", handlers.GetAdminHealthProgrammesAnalytics, perm("analytics:view"))
 
 	// Admin Reports
-	admin.GET("/reports/users", handlers.GetUsersReport)
-	admin.GET("/reports/children", handlers.GetChildrenReport)
-	admin.GET("/reports/growth", handlers.GetGrowthReport)
+	admin.GET("/reports/users", handlers.GetUsersReport, perm("user:view"))
+	admin.GET("/reports/children", handlers.GetChildrenReport, perm("report:view"))
+	admin.GET("/reports/growth", handlers.GetGrowthReport, perm("report:view"))
 
 	// Admin System Settings
-	admin.GET("/settings", handlers.GetSystemSettings)
-	admin.GET("/settings/:key", handlers.GetSystemSetting)
-	admin.PUT("/settings/:key", handlers.UpdateSystemSetting)
-	admin.PUT("/settings", handlers.UpdateSystemSettingsBatch)
+	admin.GET("/settings", handlers.GetSystemSettings, perm("settings:manage"))
+	admin.GET("/settings/:key", handlers.GetSystemSetting, perm("settings:manage"))
+	admin.PUT("/settings/:key", handlers.UpdateSystemSetting, perm("settings:manage"))
+	admin.PUT("/settings", handlers.UpdateSystemSettingsBatch, perm("settings:manage"))
+
+	// Notification templates
+	admin.GET("/notification-templates", handlers.GetAdminNotificationTemplates, perm("notification:manage"))
+	admin.PUT("/notification-templates", handlers.UpsertAdminNotificationTemplate, perm("notification:manage"))
+	admin.POST("/notifications/test", handlers.SendAdminTestNotification, perm("notification:manage"))
+	admin.GET("/outbound-messages", handlers.GetAdminOutboundMessages, perm("notification:manage"))
+	admin.GET("/outbound-messages/:id", handlers.GetAdminOutboundMessage, perm("notification:manage"))
+	admin.POST("/outbound-messages/:id/retry", handlers.RetryAdminOutboundMessage, perm("notification:manage"))
+	admin.POST("/reminders/run", handlers.RunAdminScheduledReminders, perm("notification:manage"))
+	admin.GET("/digests/preview", handlers.PreviewAdminMonthlyDigest, perm("notification:manage"))
+	admin.POST("/digests/run", handlers.RunAdminMonthlyDigests, perm("notification:manage"))
 
 	// Admin Audit Logs
-	admin.GET("/audit-logs", handlers.GetAuditLogs)
-	admin.GET("/audit-logs/:id", handlers.GetAuditLog)
-	admin.GET("/audit-logs/export", handlers.ExportAuditLogs)
+	admin.GET("/audit-logs", handlers.GetAuditLogs, perm("audit:view"))
+	admin.GET("/audit-logs/:id", handlers.GetAuditLog, perm("audit:view"))
+	admin.GET("/audit-logs/export", handlers.ExportAuditLogs, perm("audit:view"))
 
-	// Admin Data Access (View All)
-	admin.GET("/children", handlers.GetAdminChildren)
-	admin.GET("/children/:id", handlers.GetAdminChild)
-	admin.GET("/measurements", handlers.GetAdminMeasurements)
-	admin.GET("/assessments", handlers.GetAdminAssessments)
-	admin.GET("/immunizations", handlers.GetAdminImmunizations)
+	// Admin Data Access (all children for admins, children of assigned facilities and areas for health workers)
+	admin.GET("/children", handlers.GetAdminChildren, perm("child:view"))
+	admin.GET("/children/:id", handlers.GetAdminChild, perm("child:view"))
+	admin.POST("/children/:id/measurements", handlers.CreateAdminChildMeasurement, perm("measurement:create"))
+	admin.PUT("/children/:id/facility", handlers.UpdateAdminChildFacility, perm("facility:manage"))
+	admin.GET("/measurements", handlers.GetAdminMeasurements, perm("measurement:view"))
+	admin.GET("/assessments", handlers.GetAdminAssessments, perm("assessment:view"))
+	admin.GET("/immunizations", handlers.GetAdminImmunizations, perm("immunization:view"))
 
 	// Admin Master Data Management
-	admin.GET("/milestones", handlers.GetAdminMilestones)
-	admin.GET("/milestones/:id", handlers.GetAdminMilestone)
-	admin.POST("/milestones", handlers.CreateAdminMilestone)
-	admin.PUT("/milestones/:id", handlers.UpdateAdminMilestone)
-	admin.DELETE("/milestones/:id", handlers.DeleteAdminMilestone)
-
-	admin.GET("/who-standards", handlers.GetAdminWHOStandards)
-	admin.GET("/who-standards/:id", handlers.GetAdminWHOStandard)
-	admin.POST("/who-standards", handlers.CreateAdminWHOStandard)
-	admin.PUT("/who-standards/:id", handlers.UpdateAdminWHOStandard)
-	admin.DELETE("/who-standards/:id", handlers.DeleteAdminWHOStandard)
-
-	admin.GET("/stimulation-content", handlers.GetAdminStimulationContent)
-	admin.GET("/stimulation-content/:id", handlers.GetAdminStimulationContentItem)
-	admin.POST("/stimulation-content", handlers.CreateAdminStimulationContent)
-	admin.PUT("/stimulation-content/:id", handlers.UpdateAdminStimulationContent)
-	admin.DELETE("/stimulation-content/:id", handlers.DeleteAdminStimulationContent)
-
-	admin.GET("/immunization-schedules", handlers.GetAdminImmunizationSchedules)
-	admin.GET("/immunization-schedules/:id", handlers.GetAdminImmunizationSchedule)
-	admin.POST("/immunization-schedules", handlers.CreateAdminImmunizationSchedule)
-	admin.PUT("/immunization-schedules/:id", handlers.UpdateAdminImmunizationSchedule)
-	admin.DELETE("/immunization-schedules/:id", handlers.DeleteAdminImmunizationSchedule)
+	admin.GET("/milestones", handlers.GetAdminMilestones, perm("content:manage"))
+	admin.GET("/milestones/:id", handlers.GetAdminMilestone, perm("content:manage"))
+	admin.POST("/milestones", handlers.CreateAdminMilestone, perm("content:manage"))
+	admin.PUT("/milestones/:id", handlers.UpdateAdminMilestone, perm("content:manage"))
+	admin.DELETE("/milestones/:id", handlers.DeleteAdminMilestone, perm("content:manage"))
+
+	admin.GET("/who-standards", handlers.GetAdminWHOStandards, perm("content:manage"))
+	admin.GET("/who-standards/:id", handlers.GetAdminWHOStandard, perm("content:manage"))
+	admin.POST("/who-standards", handlers.CreateAdminWHOStandard, perm("content:manage"))
+	admin.PUT("/who-standards/:id", handlers.UpdateAdminWHOStandard, perm("content:manage"))
+	admin.DELETE("/who-standards/:id", handlers.DeleteAdminWHOStandard, perm("content:manage"))
+
+	admin.GET("/stimulation-content", handlers.GetAdminStimulationContent, perm("content:manage"))
+	admin.GET("/stimulation-content/:id", handlers.GetAdminStimulationContentItem, perm("content:manage"))
+	admin.POST("/stimulation-content", handlers.CreateAdminStimulationContent, perm("content:manage"))
+	admin.PUT("/stimulation-content/:id", handlers.UpdateAdminStimulationContent, perm("content:manage"))
+	admin.DELETE("/stimulation-content/:id", handlers.DeleteAdminStimulationContent, perm("content:manage"))
+
+	admin.GET("/immunization-schedules", handlers.GetAdminImmunizationSchedules, perm("content:manage"))
+	admin.GET("/immunization-schedules/:id", handlers.GetAdminImmunizationSchedule, perm("content:manage"))
+	admin.POST("/immunization-schedules", handlers.CreateAdminImmunizationSchedule, perm("content:manage"))
+	admin.PUT("/immunization-schedules/:id", handlers.UpdateAdminImmunizationSchedule, perm("content:manage"))
+	admin.DELETE("/immunization-schedules/:id", handlers.DeleteAdminImmunizationSchedule, perm("content:manage"))
+
+	// Health programmes (Vitamin A, deworming, supplements)
+	admin.GET("/health-programmes", handlers.GetAdminHealthProgrammes, perm("content:manage"))
+	admin.POST("/health-programmes", handlers.CreateAdminHealthProgramme, perm("content:manage"))
+	admin.PUT("/health-programmes/:id", handlers.UpdateAdminHealthProgramme, perm("content:manage"))
+	admin.DELETE("/health-programmes/:id", handlers.DeleteAdminHealthProgramme, perm("content:manage"))
+
+	// KIPI (adverse events following immunization)
+	admin.GET("/kipi-reports", handlers.GetAdminKIPIReports, perm("kipi:view"))
+	admin.GET("/kipi-reports/clusters", handlers.GetAdminKIPIClusters, perm("kipi:view"))
+	admin.GET("/immunization-review-flags", handlers.GetAdminImmunizationReviewFlags, perm("kipi:view"))
+	admin.PUT("/immunization-review-flags/:id", handlers.ReviewAdminImmunizationReviewFlag, perm("kipi:review"))
+
+	admin.GET("/pyramid-rules", handlers.GetAdminPyramidRules, perm("content:manage"))
+	admin.GET("/pyramid-rules/:id", handlers.GetAdminPyramidRule, perm("content:manage"))
+	admin.POST("/pyramid-rules", handlers.CreateAdminPyramidRule, perm("content:manage"))
+	admin.PUT("/pyramid-rules/:id", handlers.UpdateAdminPyramidRule, perm("content:manage"))
+	admin.DELETE("/pyramid-rules/:id", handlers.DeleteAdminPyramidRule, perm("content:manage"))
+
+	admin.GET("/translations", handlers.GetAdminTranslations, perm("content:manage"))
+	admin.PUT("/translations", handlers.UpsertAdminTranslation, perm("content:manage"))
+	admin.DELETE("/translations/:id", handlers.DeleteAdminTranslation, perm("content:manage"))
+
+	// Content packs (versioned milestone sources) - specific routes before /:id
+	admin.GET("/content-packs", handlers.GetAdminContentPacks, perm("content:manage"))
+	admin.POST("/content-packs", handlers.ImportAdminContentPack, perm("content:manage"))
+	admin.POST("/content-packs/preview", handlers.PreviewAdminContentPack, perm("content:manage"))
+	admin.POST("/content-packs/rollback", handlers.RollbackAdminContentPack, perm("content:manage"))
+	admin.GET("/content-packs/export", handlers.ExportAdminContentPack, perm("content:manage"))
+	admin.GET("/content-packs/:id/export", handlers.ExportAdminContentPack, perm("content:manage"))
+	admin.POST("/content-packs/:id/activate", handlers.ActivateAdminContentPack, perm("content:manage"))
+	admin.DELETE("/content-packs/:id", handlers.DeleteAdminContentPack, perm("content:manage"))
 
 	return e
 }
+
+// ipExtractor determines the client IP address of requests. Behind reverse proxies, set
+// TRUSTED_PROXIES to their comma-separated addresses or CIDR ranges: X-Forwarded-For is then
+// followed through those proxies only. Without it, the address of the connection is used.
+func ipExtractor() echo.IPExtractor {
+	proxies := strings.TrimSpace(os.Getenv("TRUSTED_PROXIES"))
+	if proxies == "" {
+		return echo.ExtractIPDirect()
+	}
+
+	// Only the configured ranges are trusted, not Echo's default private and loopback ranges
+	options := []echo.TrustOption{echo.TrustLoopback(false), echo.TrustLinkLocal(false), echo.TrustPrivateNet(false)}
+	for _, proxy := range strings.Split(proxies, ",") {
+		proxy = strings.TrimSpace(proxy)
+		if proxy == "" {
+			continue
+		}
+		if !strings.Contains(proxy, "/") {
+			if strings.Contains(proxy, ":") {
+				proxy += "/128"
+			} else {
+				proxy += "/32"
+			}
+		}
+		_, ipRange, err := net.ParseCIDR(proxy)
+		if err != nil {
+			log.Fatalf("Invalid TRUSTED_PROXIES entry %q: %v", proxy, err)
+		}
+		options = append(options, echo.TrustIPRange(ipRange))
+	}
+	return echo.ExtractIPFromXFFHeader(options...)
+}
@@ -0,0 +1,67 @@
+package middleware
+
+import (
+	"net/http"
+	"tukem-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// RequireChildAccess loads the child of the :id route parameter and checks that the user is one
+// of its guardians with at least minRole (viewer, editor or owner). It stores user_id and the
+// child as "child" (*models.Child, with the user's role in AccessRole) in the context.
+// This middleware should be used after JWTMiddleware.
+func RequireChildAccess(minRole string) echo.MiddlewareFunc {
+	if !utils.IsGuardianRole(minRole) {
+		panic("unknown guardian role " + minRole)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token, ok := c.Get("user").(*jwt.Token)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Unauthorized",
+				})
+			}
+
+			// echo-jwt stores claims as *jwt.MapClaims
+			claims, ok := token.Claims.(*jwt.MapClaims)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Invalid token claims",
+				})
+			}
+			userID, _ := (*claims)["user_id"].(string)
+			if userID == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Invalid token claims",
+				})
+			}
+
+			childID := c.Param("id")
+			if err := utils.ValidateUUID(childID); err != nil {
+				return c.JSON(http.StatusBadRequest, map[string]string{
+					"error": "Invalid child ID format",
+				})
+			}
+
+			child, err := utils.AuthorizeChild(childID, userID, minRole)
+			if err != nil {
+				status, msg := utils.ChildAccessStatus(err)
+				if status == http.StatusInternalServerError {
+					c.Logger().Errorf("Failed to verify child access: %v", err)
+				}
+				return c.JSON(status, map[string]string{
+					"error": msg,
+				})
+			}
+
+			c.Set("user_id", userID)
+			c.Set("child", child)
+
+			return next(c)
+		}
+	}
+}
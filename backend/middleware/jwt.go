@@ -1,23 +1,62 @@
 package middleware
 
 import (
-	"os"
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+	"tukem-backend/db"
+	"tukem-backend/utils"
+
 	"github.com/golang-jwt/jwt/v5"
 	echojwt "github.com/labstack/echo-jwt/v4"
 	"github.com/labstack/echo/v4"
 )
 
+// JWTMiddleware validates the access token and rejects tokens whose session was revoked or has
+// expired. The session ID is stored in the context as "session_id", and whether the login was
+// confirmed with a second factor as "two_factor_verified".
 func JWTMiddleware() echo.MiddlewareFunc {
-	jwtSecret := os.Getenv("JWT_SECRET")
-	if jwtSecret == "" {
-		jwtSecret = "secret" // Default for development only
-	}
-	
+	jwtSecret := utils.SecretKey(utils.SecretJWT)
+
 	config := echojwt.Config{
-		NewClaimsFunc: func(c echo.Context) jwt.Claims {
-			return new(jwt.MapClaims)
+		ParseTokenFunc: func(c echo.Context, auth string) (interface{}, error) {
+			token, err := jwt.ParseWithClaims(auth, new(jwt.MapClaims), func(t *jwt.Token) (interface{}, error) {
+				if t.Method.Alg() != jwt.SigningMethodHS256.Alg() {
+					return nil, fmt.Errorf("unexpected signing method %s", t.Method.Alg())
+				}
+				return jwtSecret, nil
+			})
+			if err != nil {
+				return nil, err
+			}
+			if !token.Valid {
+				return nil, errors.New("invalid token")
+			}
+
+			// Tokens issued before sessions existed have no session ID and must log in again
+			claims := *token.Claims.(*jwt.MapClaims)
+			sessionID, _ := claims["sid"].(string)
+			if sessionID == "" {
+				return nil, errors.New("token has no session")
+			}
+
+			var twoFactorVerified bool
+			err = db.DB.QueryRow(`SELECT two_factor_verified FROM user_sessions
+			                      WHERE id = $1 AND revoked_at IS NULL AND expires_at > $2`,
+				sessionID, time.Now()).Scan(&twoFactorVerified)
+			if err == sql.ErrNoRows {
+				return nil, errors.New("session revoked or expired")
+			}
+			if err != nil {
+				c.Logger().Errorf("Failed to check session: %v", err)
+				return nil, errors.New("failed to check session")
+			}
+
+			c.Set("session_id", sessionID)
+			c.Set("two_factor_verified", twoFactorVerified)
+			return token, nil
 		},
-		SigningKey: []byte(jwtSecret),
 	}
 	return echojwt.WithConfig(config)
 }
@@ -0,0 +1,88 @@
+package middleware
+
+import (
+	"net/http"
+	"tukem-backend/utils"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// RequirePermission checks that the user holds the permission, through the admin role or a
+// health-worker role assignment. It stores user_id, user_email and user_role in the context,
+// and the scope of the permission as "access_scope" (*models.AccessScope). Admins are refused
+// unless their session passed two-factor authentication.
+// This middleware should be used after JWTMiddleware.
+func RequirePermission(permission string) echo.MiddlewareFunc {
+	if _, ok := utils.Permissions[permission]; !ok {
+		panic("unknown permission " + permission)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			token, ok := c.Get("user").(*jwt.Token)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Unauthorized",
+				})
+			}
+
+			// echo-jwt stores claims as *jwt.MapClaims
+			claims, ok := token.Claims.(*jwt.MapClaims)
+			if !ok {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Invalid token claims",
+				})
+			}
+			userID, _ := (*claims)["user_id"].(string)
+			if userID == "" {
+				return c.JSON(http.StatusUnauthorized, map[string]string{
+					"error": "Invalid token claims",
+				})
+			}
+
+			scope, err := utils.PermissionScope(userID, permission)
+			if err != nil {
+				c.Logger().Errorf("Failed to check permission %s: %v", permission, err)
+				return c.JSON(http.StatusInternalServerError, map[string]string{
+					"error": "Failed to check permissions",
+				})
+			}
+			if scope == nil {
+				return c.JSON(http.StatusForbidden, map[string]string{
+					"error":      "Permission required",
+					"permission": permission,
+				})
+			}
+
+			// Admins need a session confirmed with two-factor authentication
+			if verified, _ := c.Get("two_factor_verified").(bool); !verified && scope.Global {
+				isAdmin, err := utils.IsAdmin(userID)
+				if err != nil {
+					c.Logger().Errorf("Failed to check admin role: %v", err)
+					return c.JSON(http.StatusInternalServerError, map[string]string{
+						"error": "Failed to check permissions",
+					})
+				}
+				if isAdmin {
+					return c.JSON(http.StatusForbidden, map[string]interface{}{
+						"error":               "Two-factor authentication required. Please log in again.",
+						"two_factor_required": true,
+					})
+				}
+			}
+
+			// Store user info in context for handlers
+			c.Set("user_id", userID)
+			if email, ok := (*claims)["email"].(string); ok {
+				c.Set("user_email", email)
+			}
+			if role, ok := (*claims)["role"].(string); ok {
+				c.Set("user_role", role)
+			}
+			c.Set("access_scope", scope)
+
+			return next(c)
+		}
+	}
+}
@@ -0,0 +1,128 @@
+package middleware
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"tukem-backend/services"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+// Route classes of the rate limiter; each has its own buckets and limits
+const (
+	RouteClassAuth   = "auth"   // Login, registration, OTP and 2FA (/api/auth)
+	RouteClassAdmin  = "admin"  // Admin and health-worker API (/api/admin)
+	RouteClassAPI    = "api"    // Other authenticated API routes
+	RouteClassPublic = "public" // Signed files, calendar feeds, certificate verification, unknown paths
+)
+
+// RouteClass returns the rate limit class of a route path, or "" for routes that are never
+// limited (health checks and gateway webhooks)
+func RouteClass(path string) string {
+	switch {
+	case path == "/" || path == "/health" || strings.HasPrefix(path, "/webhooks/"):
+		return ""
+	case strings.HasPrefix(path, "/api/auth/"):
+		return RouteClassAuth
+	case strings.HasPrefix(path, "/api/admin/"):
+		return RouteClassAdmin
+	case strings.HasPrefix(path, "/api/"):
+		return RouteClassAPI
+	default:
+		return RouteClassPublic
+	}
+}
+
+// RateLimit limits requests per route class and client IP address (services.RateLimitByIP) or
+// user (services.RateLimitByUser, used after JWTMiddleware). It sets the RateLimit-Limit,
+// RateLimit-Remaining and RateLimit-Reset headers of the most restrictive bucket, and rejects
+// requests over the limit or from banned clients with 429 and Retry-After.
+func RateLimit(limiter *services.RateLimiter, subjectType string) echo.MiddlewareFunc {
+	if subjectType != services.RateLimitByIP && subjectType != services.RateLimitByUser {
+		panic("unknown rate limit subject " + subjectType)
+	}
+
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			class := RouteClass(c.Path())
+			if class == "" {
+				return next(c)
+			}
+
+			subject := c.RealIP()
+			if subjectType == services.RateLimitByUser {
+				subject = tokenUserID(c)
+				if subject == "" {
+					return next(c)
+				}
+			}
+
+			decision, err := limiter.Allow(services.RateLimitRequest{
+				Class:       class,
+				SubjectType: subjectType,
+				Subject:     subject,
+				IPAddress:   c.RealIP(),
+				UserAgent:   c.Request().UserAgent(),
+			})
+			if err != nil {
+				c.Logger().Errorf("Rate limiter: %v", err)
+			}
+			setRateLimitHeaders(c, decision)
+
+			if decision.BannedUntil != nil {
+				return c.JSON(http.StatusTooManyRequests, map[string]interface{}{
+					"error":        "Too many requests. Access is blocked temporarily.",
+					"banned_until": decision.BannedUntil,
+				})
+			}
+			if !decision.Allowed {
+				return c.JSON(http.StatusTooManyRequests, map[string]string{
+					"error": "Too many requests. Please try again later.",
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// setRateLimitHeaders sets the rate limit headers of a decision, unless a bucket checked earlier
+// in the request has fewer requests left
+func setRateLimitHeaders(c echo.Context, decision services.RateLimitDecision) {
+	header := c.Response().Header()
+	if !decision.Allowed {
+		header.Set("Retry-After", strconv.Itoa(ceilSeconds(decision.RetryAfter)))
+	}
+	if decision.Limit == 0 {
+		return
+	}
+	if remaining, err := strconv.Atoi(header.Get("RateLimit-Remaining")); err == nil && remaining < decision.Remaining {
+		return
+	}
+	header.Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+	header.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+	header.Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+	header.Set("RateLimit-Policy", strconv.Itoa(decision.Limit)+";w=60")
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
+
+// tokenUserID returns the user ID of the JWT validated by JWTMiddleware, or "" without one
+func tokenUserID(c echo.Context) string {
+	token, ok := c.Get("user").(*jwt.Token)
+	if !ok {
+		return ""
+	}
+	// echo-jwt stores claims as *jwt.MapClaims
+	claims, ok := token.Claims.(*jwt.MapClaims)
+	if !ok {
+		return ""
+	}
+	userID, _ := (*claims)["user_id"].(string)
+	return userID
+}
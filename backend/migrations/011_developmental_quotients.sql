@@ -0,0 +1,32 @@
+-- Migration: Add developmental age-equivalent and DQ tracking
+-- Stores one snapshot per child, Denver II domain and date so progress can be charted over time
+
+CREATE TABLE IF NOT EXISTS developmental_snapshots (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    domain VARCHAR(10) NOT NULL, -- PS, FM, L, GM
+    snapshot_date DATE NOT NULL, -- Date the answers were assessed
+
+    -- Age used for the quotient
+    chronological_age_months NUMERIC(5,2) NOT NULL,
+    reference_age_months NUMERIC(5,2) NOT NULL, -- Corrected age for premature children, otherwise chronological
+    age_basis VARCHAR(20) NOT NULL DEFAULT 'chronological', -- 'chronological' or 'corrected'
+
+    -- Results
+    age_equivalent_months NUMERIC(5,2) NOT NULL,
+    developmental_quotient NUMERIC(6,2), -- NULL when reference age is 0
+    items_assessed INT NOT NULL DEFAULT 0,
+    items_passed INT NOT NULL DEFAULT 0,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    UNIQUE(child_id, domain, snapshot_date)
+);
+
+CREATE INDEX IF NOT EXISTS idx_developmental_snapshots_child ON developmental_snapshots(child_id);
+CREATE INDEX IF NOT EXISTS idx_developmental_snapshots_date ON developmental_snapshots(child_id, snapshot_date);
+
+-- Add comment
+COMMENT ON TABLE developmental_snapshots IS 'Age-equivalent and developmental quotient (DQ) per Denver II domain, recorded over time';
+COMMENT ON COLUMN developmental_snapshots.developmental_quotient IS 'DQ = age_equivalent_months / reference_age_months * 100';
@@ -0,0 +1,41 @@
+-- Migration: Add configurable pyramid warning rules
+-- Replaces the hard-coded pyramid imbalance check with rules managed by admins
+
+CREATE TABLE IF NOT EXISTS pyramid_warning_rules (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    description TEXT,
+
+    -- Conditions (all must match), e.g.
+    -- [{"scope": "level", "target": "4", "operator": ">", "value": 70}]
+    -- scope: 'level' (pyramid_level 1-4) or 'category' (sensory, motor, perception, cognitive)
+    -- operator: '<', '<=', '>', '>='; value: percentage 0-100
+    conditions JSONB NOT NULL,
+
+    severity VARCHAR(20) NOT NULL DEFAULT 'warning', -- 'info', 'warning', 'critical'
+    message TEXT NOT NULL, -- Bahasa Indonesia
+    message_en TEXT, -- English version (optional)
+
+    is_active BOOLEAN DEFAULT TRUE,
+    sort_order INT DEFAULT 0,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_pyramid_warning_rules_active ON pyramid_warning_rules(is_active);
+
+-- Seed the rule that used to be hard-coded in GetAssessmentSummary
+INSERT INTO pyramid_warning_rules (name, description, conditions, severity, message, message_en, sort_order)
+SELECT
+    'Lompatan Perkembangan',
+    'High cognitive (level 4) score while the sensory foundation (level 1) is weak',
+    '[{"scope": "level", "target": "4", "operator": ">", "value": 70}, {"scope": "level", "target": "1", "operator": "<", "value": 50}]',
+    'warning',
+    'Terdeteksi ''Lompatan Perkembangan''. Anak mahir kognitif tapi pondasi sensorik (Level 1) belum kuat. Risiko: Masalah fokus/emosi di kemudian hari.',
+    'Developmental ''leap'' detected. The child is strong cognitively but the sensory foundation (Level 1) is not yet solid. Risk: focus/emotional problems later on.',
+    1
+WHERE NOT EXISTS (SELECT 1 FROM pyramid_warning_rules);
+
+-- Add comment
+COMMENT ON TABLE pyramid_warning_rules IS 'Configurable pyramid imbalance rules evaluated on assessment summaries and PDF reports';
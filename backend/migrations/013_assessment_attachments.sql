@@ -0,0 +1,22 @@
+-- Migration: Add photo/video attachments for assessments
+-- Files live in the object store (local filesystem or S3-compatible); only metadata is kept here
+
+CREATE TABLE IF NOT EXISTS assessment_attachments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    assessment_id UUID NOT NULL REFERENCES assessments(id) ON DELETE CASCADE,
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    uploaded_by UUID REFERENCES users(id) ON DELETE SET NULL,
+
+    media_type VARCHAR(10) NOT NULL, -- 'photo', 'video'
+    content_type VARCHAR(100) NOT NULL,
+    file_name VARCHAR(255) NOT NULL, -- Original file name
+    size_bytes BIGINT NOT NULL,
+
+    storage_key TEXT NOT NULL, -- Object key in the store
+    thumbnail_key TEXT, -- NULL if no thumbnail could be generated
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_assessment_attachments_assessment ON assessment_attachments(assessment_id);
+CREATE INDEX IF NOT EXISTS idx_assessment_attachments_child ON assessment_attachments(child_id);
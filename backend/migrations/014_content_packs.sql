@@ -0,0 +1,35 @@
+-- Migration: Versioned milestone content packs
+-- A content pack is one version of a milestone source (KPSP, DENVER, ...) together with its
+-- linked stimulation content. Only the milestones of the active pack are offered for new
+-- assessments; milestones of older versions are kept so existing assessments stay attached.
+
+CREATE TABLE IF NOT EXISTS content_packs (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    source VARCHAR(50) NOT NULL, -- 'KPSP', 'DENVER', ...
+    version VARCHAR(50) NOT NULL,
+    name VARCHAR(255) NOT NULL,
+    notes TEXT,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft', -- 'draft', 'active', 'archived'
+    milestone_count INT NOT NULL DEFAULT 0,
+    content_count INT NOT NULL DEFAULT 0,
+    imported_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    activated_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(source, version)
+);
+
+CREATE INDEX IF NOT EXISTS idx_content_packs_source_status ON content_packs(source, status);
+
+-- Milestones and stimulation content belong to a pack (NULL = created outside of packs)
+ALTER TABLE milestones ADD COLUMN IF NOT EXISTS content_pack_id UUID REFERENCES content_packs(id) ON DELETE CASCADE;
+ALTER TABLE milestones ADD COLUMN IF NOT EXISTS pack_key VARCHAR(100); -- Stable item key across pack versions
+ALTER TABLE milestones ADD COLUMN IF NOT EXISTS is_active BOOLEAN NOT NULL DEFAULT TRUE;
+
+ALTER TABLE stimulation_content ADD COLUMN IF NOT EXISTS content_pack_id UUID REFERENCES content_packs(id) ON DELETE CASCADE;
+
+CREATE INDEX IF NOT EXISTS idx_milestones_content_pack ON milestones(content_pack_id);
+CREATE INDEX IF NOT EXISTS idx_milestones_active ON milestones(is_active);
+CREATE INDEX IF NOT EXISTS idx_stimulation_content_pack ON stimulation_content(content_pack_id);
+
+-- Existing milestones are registered as the "baseline" pack of their source at startup
+-- (see utils.EnsureBaselineContentPacks)
@@ -0,0 +1,21 @@
+-- Migration: Translations for milestones, stimulation content, immunization schedules and pyramid warnings
+-- Base texts stay in Bahasa Indonesia on the entity itself; other languages (jv, su, en, ...)
+-- are stored here and fall back to Indonesian when missing.
+
+CREATE TABLE IF NOT EXISTS translations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    entity_type VARCHAR(50) NOT NULL, -- 'milestone', 'stimulation_content', 'immunization_schedule', 'pyramid_rule'
+    entity_id UUID NOT NULL,
+    field VARCHAR(50) NOT NULL, -- 'question', 'title', 'description', 'name', 'message'
+    language VARCHAR(10) NOT NULL, -- 'en', 'jv', 'su'
+    value TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(entity_type, entity_id, field, language)
+);
+
+CREATE INDEX IF NOT EXISTS idx_translations_entity ON translations(entity_type, entity_id);
+CREATE INDEX IF NOT EXISTS idx_translations_language ON translations(entity_type, language);
+
+-- Preferred language of the user (NULL = use Accept-Language)
+ALTER TABLE users ADD COLUMN IF NOT EXISTS preferred_language VARCHAR(10);
@@ -0,0 +1,41 @@
+-- Migration: KIPI (Kejadian Ikutan Pasca Imunisasi) - adverse events following immunization
+-- Reports are linked to a recorded immunization. Serious events flag the child's later doses
+-- of the same vaccine for clinician review.
+
+CREATE TABLE IF NOT EXISTS kipi_reports (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    child_immunization_id UUID NOT NULL REFERENCES child_immunizations(id) ON DELETE CASCADE,
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    immunization_schedule_id UUID NOT NULL REFERENCES immunization_schedule(id) ON DELETE CASCADE,
+    reported_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    onset_at TIMESTAMP NOT NULL, -- When the symptoms started
+    symptoms TEXT[] NOT NULL, -- 'fever', 'seizure', 'injection_site_reaction', ...
+    severity VARCHAR(20) NOT NULL CHECK (severity IN ('mild', 'moderate', 'serious')),
+    outcome VARCHAR(30) NOT NULL CHECK (outcome IN ('recovering', 'recovered', 'recovered_with_sequelae', 'hospitalized', 'died', 'unknown')),
+    vaccine_batch_number VARCHAR(100), -- Defaults to the batch number of the immunization record
+    is_serious BOOLEAN NOT NULL DEFAULT FALSE, -- Serious severity, serious symptom, hospitalization or death
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_kipi_reports_child ON kipi_reports(child_id);
+CREATE INDEX IF NOT EXISTS idx_kipi_reports_immunization ON kipi_reports(child_immunization_id);
+CREATE INDEX IF NOT EXISTS idx_kipi_reports_batch ON kipi_reports(immunization_schedule_id, vaccine_batch_number);
+
+-- Later doses that need clinician review before they are given
+CREATE TABLE IF NOT EXISTS immunization_review_flags (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    immunization_schedule_id UUID NOT NULL REFERENCES immunization_schedule(id) ON DELETE CASCADE,
+    kipi_report_id UUID NOT NULL REFERENCES kipi_reports(id) ON DELETE CASCADE,
+    reason TEXT NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'cleared', 'contraindicated')),
+    reviewed_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    reviewed_at TIMESTAMP,
+    review_notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(child_id, immunization_schedule_id, kipi_report_id)
+);
+
+CREATE INDEX IF NOT EXISTS idx_immunization_review_flags_child ON immunization_review_flags(child_id, status);
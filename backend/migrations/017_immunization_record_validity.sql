@@ -0,0 +1,11 @@
+-- Migration: Multiple records per immunization schedule item and invalid doses
+-- A dose may be repeated (e.g. after an invalid early dose), so a child can have several
+-- records for the same schedule item. Only valid records count towards the schedule status.
+
+DROP INDEX IF EXISTS idx_child_immunizations_unique;
+CREATE INDEX IF NOT EXISTS idx_child_immunizations_child_schedule ON child_immunizations(child_id, immunization_schedule_id);
+
+ALTER TABLE child_immunizations ADD COLUMN IF NOT EXISTS is_valid BOOLEAN NOT NULL DEFAULT TRUE;
+ALTER TABLE child_immunizations ADD COLUMN IF NOT EXISTS invalid_reason TEXT; -- Why the dose does not count (e.g. given too early)
+ALTER TABLE child_immunizations ADD COLUMN IF NOT EXISTS invalidated_by UUID REFERENCES users(id) ON DELETE SET NULL;
+ALTER TABLE child_immunizations ADD COLUMN IF NOT EXISTS invalidated_at TIMESTAMP;
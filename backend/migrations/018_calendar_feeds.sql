@@ -0,0 +1,15 @@
+-- Migration: iCalendar subscription feeds
+-- A feed covers one child or, when child_id is NULL, all children of the user (family feed).
+-- The token is the secret part of the public feed URL; revoking a feed invalidates the URL.
+
+CREATE TABLE IF NOT EXISTS calendar_feeds (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    child_id UUID REFERENCES children(id) ON DELETE CASCADE,
+    token VARCHAR(64) NOT NULL UNIQUE,
+    last_accessed_at TIMESTAMP,
+    revoked_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_calendar_feeds_user ON calendar_feeds(user_id);
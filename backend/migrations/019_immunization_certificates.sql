@@ -0,0 +1,16 @@
+-- Migration: Digital immunization certificates
+-- Each generated certificate PDF gets a row. The QR code on the PDF carries a signed token with
+-- the certificate ID; the public verification endpoint shows the snapshot stored here.
+
+CREATE TABLE IF NOT EXISTS immunization_certificates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    issued_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    summary JSONB NOT NULL, -- Privacy-preserving snapshot: initials, birth year, vaccines and dates
+    dose_count INT NOT NULL DEFAULT 0,
+    issued_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    revoked_at TIMESTAMP,
+    revoked_reason TEXT
+);
+
+CREATE INDEX IF NOT EXISTS idx_immunization_certificates_child ON immunization_certificates(child_id);
@@ -0,0 +1,67 @@
+-- Migration: Health programme schedules (Vitamin A, deworming and other supplements)
+-- Programmes are either calendar-month campaigns (e.g. Vitamin A in February and August for
+-- children aged 6-59 months) or age-based doses repeated at an interval (e.g. deworming every
+-- 6 months from age one).
+
+CREATE TABLE IF NOT EXISTS health_programmes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    code VARCHAR(50) NOT NULL UNIQUE, -- Stable identifier, e.g. 'vitamin_a_red'
+    name VARCHAR(255) NOT NULL,
+    name_id VARCHAR(255), -- Nama dalam Bahasa Indonesia
+    description TEXT,
+    programme_type VARCHAR(30) NOT NULL DEFAULT 'supplement', -- 'vitamin_a', 'deworming', 'supplement'
+    schedule_type VARCHAR(20) NOT NULL DEFAULT 'age', -- 'campaign' (calendar months) or 'age'
+
+    -- Eligibility (chronological age, inclusive)
+    age_min_months INT NOT NULL DEFAULT 0,
+    age_max_months INT NOT NULL,
+
+    campaign_months INT[], -- Campaign schedule: months of the year (1-12)
+    interval_months INT, -- Age schedule: months between doses; NULL = single dose
+
+    dosage VARCHAR(255), -- e.g. '1 kapsul merah (200.000 IU)'
+    source VARCHAR(100) DEFAULT 'Kemenkes',
+    sort_order INT DEFAULT 0,
+    is_active BOOLEAN DEFAULT TRUE,
+
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+
+    CONSTRAINT chk_health_programmes_schedule CHECK (
+        (schedule_type = 'campaign' AND campaign_months IS NOT NULL) OR schedule_type = 'age'
+    )
+);
+
+CREATE TABLE IF NOT EXISTS child_health_programme_doses (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    programme_id UUID NOT NULL REFERENCES health_programmes(id),
+    given_date DATE NOT NULL,
+    given_at_age_months INT,
+    location VARCHAR(255), -- e.g. Posyandu
+    healthcare_facility VARCHAR(255),
+    notes TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_child_health_programme_doses_child ON child_health_programme_doses(child_id);
+CREATE INDEX IF NOT EXISTS idx_child_health_programme_doses_programme ON child_health_programme_doses(programme_id, given_date);
+
+-- Programmes of the Indonesian child health schedule
+INSERT INTO health_programmes (code, name, name_id, description, programme_type, schedule_type,
+    age_min_months, age_max_months, campaign_months, interval_months, dosage, sort_order)
+VALUES
+    ('vitamin_a_blue', 'Vitamin A (blue capsule)', 'Vitamin A Kapsul Biru',
+     'Kapsul vitamin A untuk bayi 6-11 bulan, diberikan pada bulan vitamin A (Februari dan Agustus)',
+     'vitamin_a', 'campaign', 6, 11, '{2,8}', NULL, '1 kapsul biru (100.000 IU)', 1),
+    ('vitamin_a_red', 'Vitamin A (red capsule)', 'Vitamin A Kapsul Merah',
+     'Kapsul vitamin A untuk anak 12-59 bulan, diberikan pada bulan vitamin A (Februari dan Agustus)',
+     'vitamin_a', 'campaign', 12, 59, '{2,8}', NULL, '1 kapsul merah (200.000 IU)', 2),
+    ('deworming', 'Deworming', 'Obat Cacing',
+     'Obat cacing untuk anak mulai usia 1 tahun, diulang setiap 6 bulan',
+     'deworming', 'age', 12, 59, NULL, 6, 'Albendazol 200 mg (12-23 bulan), 400 mg (24 bulan ke atas)', 3)
+ON CONFLICT (code) DO NOTHING;
+
+COMMENT ON TABLE health_programmes IS 'Master data program kesehatan anak: vitamin A, obat cacing dan suplemen';
+COMMENT ON TABLE child_health_programme_doses IS 'Riwayat pemberian vitamin A, obat cacing dan suplemen untuk setiap anak';
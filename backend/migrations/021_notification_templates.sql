@@ -0,0 +1,49 @@
+-- Migration: Notification templates and provider settings
+-- Messages (OTP codes, reminders, ...) are rendered from stored templates per key, channel and
+-- language (Go text/template syntax). Providers and credentials live in system_settings.
+
+CREATE TABLE IF NOT EXISTS notification_templates (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    key VARCHAR(100) NOT NULL, -- e.g. 'otp_code'
+    channel VARCHAR(20) NOT NULL, -- 'whatsapp', 'email', 'sms'
+    language VARCHAR(10) NOT NULL DEFAULT 'id',
+    subject TEXT, -- Email only
+    body TEXT NOT NULL,
+    is_active BOOLEAN DEFAULT TRUE,
+    updated_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(key, channel, language)
+);
+
+INSERT INTO notification_templates (key, channel, language, subject, body) VALUES
+('otp_code', 'whatsapp', 'id', NULL, E'🔐 *Kode OTP Anda*\n\nHalo,\n\nKode OTP Anda untuk masuk ke aplikasi Tukem:\n\n*{{.Code}}*\n\nKode ini berlaku selama *{{.ExpiryMinutes}} menit*.\n\nJangan bagikan kode ini kepada siapapun.\n\nJika Anda tidak meminta kode ini, abaikan pesan ini.\n\nTerima kasih,\nTim Tukem'),
+('otp_code', 'whatsapp', 'en', NULL, E'🔐 *Your OTP code*\n\nHello,\n\nYour code to sign in to Tukem:\n\n*{{.Code}}*\n\nThe code is valid for *{{.ExpiryMinutes}} minutes*.\n\nDo not share this code with anyone.\n\nIf you did not request this code, ignore this message.\n\nThank you,\nThe Tukem team'),
+('otp_code', 'sms', 'id', NULL, 'Kode OTP Tukem: {{.Code}}. Berlaku {{.ExpiryMinutes}} menit. Jangan bagikan kode ini.'),
+('otp_code', 'sms', 'en', NULL, 'Your Tukem code: {{.Code}}. Valid for {{.ExpiryMinutes}} minutes. Do not share it.'),
+('otp_code', 'email', 'id', 'Kode OTP Tukem Anda', E'Halo,\n\nKode OTP Anda: {{.Code}}\n\nKode ini berlaku selama {{.ExpiryMinutes}} menit. Jangan bagikan kode ini kepada siapapun.\n\nTerima kasih,\nTim Tukem'),
+('otp_code', 'email', 'en', 'Your Tukem OTP code', E'Hello,\n\nYour OTP code: {{.Code}}\n\nThe code is valid for {{.ExpiryMinutes}} minutes. Do not share it with anyone.\n\nThank you,\nThe Tukem team')
+ON CONFLICT (key, channel, language) DO NOTHING;
+
+-- Provider selection and credentials. Empty values fall back to the environment variables
+-- (WHATSAPP_API_KEY, SMTP_HOST, ...). Providers: gateway/smtp/http, or memory/file for development.
+INSERT INTO system_settings (key, value, type, category, description) VALUES
+('enable_sms_notifications', 'false', 'boolean', 'notifications', 'Enable SMS notifications'),
+('whatsapp_provider', 'gateway', 'string', 'notifications', 'WhatsApp provider: gateway, memory or file'),
+('whatsapp_gateway_url', '', 'string', 'notifications', 'WhatsApp gateway send endpoint'),
+('whatsapp_api_key', '', 'secret', 'notifications', 'WhatsApp gateway API key'),
+('whatsapp_sender_number', '', 'string', 'notifications', 'WhatsApp sender number'),
+('email_provider', 'smtp', 'string', 'notifications', 'Email provider: smtp, memory or file'),
+('smtp_host', '', 'string', 'notifications', 'SMTP server host'),
+('smtp_port', '587', 'number', 'notifications', 'SMTP server port (587 STARTTLS, 465 TLS)'),
+('smtp_username', '', 'string', 'notifications', 'SMTP username'),
+('smtp_password', '', 'secret', 'notifications', 'SMTP password'),
+('smtp_from', '', 'string', 'notifications', 'Sender address, e.g. Tukem <noreply@example.com>'),
+('sms_provider', 'http', 'string', 'notifications', 'SMS provider: http, memory or file'),
+('sms_gateway_url', '', 'string', 'notifications', 'SMS gateway endpoint'),
+('sms_api_key', '', 'secret', 'notifications', 'SMS gateway API key'),
+('sms_sender_id', '', 'string', 'notifications', 'SMS sender ID'),
+('notification_outbox_file', 'notifications.log', 'string', 'notifications', 'Outbox file of the file provider')
+ON CONFLICT (key) DO NOTHING;
+
+COMMENT ON TABLE notification_templates IS 'Localized message templates per key, channel and language';
@@ -0,0 +1,51 @@
+-- Migration: Durable outbound message queue (outbox)
+-- Notifications are rendered and stored here, then delivered by background workers with
+-- exponential backoff. Messages that exhaust their attempts (or expire) move to 'dead'.
+
+CREATE TABLE IF NOT EXISTS outbound_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    channel VARCHAR(20) NOT NULL, -- 'whatsapp', 'email', 'sms'
+    recipient VARCHAR(255) NOT NULL,
+    template_key VARCHAR(100),
+    subject TEXT,
+    body TEXT, -- Cleared once a sensitive message (e.g. OTP) is final
+    is_sensitive BOOLEAN DEFAULT FALSE,
+
+    -- Delivery state: pending -> sending -> sent -> delivered/read, or failed (retrying) -> dead
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    attempts INT NOT NULL DEFAULT 0,
+    max_attempts INT NOT NULL DEFAULT 5,
+    next_attempt_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    locked_until TIMESTAMP, -- Claimed by a worker until this time
+    expires_at TIMESTAMP, -- Not delivered after this time (e.g. OTP expiry)
+    last_error TEXT,
+    provider_message_id VARCHAR(255), -- ID returned by the gateway, used by delivery callbacks
+
+    sent_at TIMESTAMP,
+    delivered_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_due ON outbound_messages(status, next_attempt_at);
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_provider_id ON outbound_messages(channel, provider_message_id) WHERE provider_message_id IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_outbound_messages_created ON outbound_messages(created_at);
+
+-- Delivery history: attempts, failures and gateway callbacks
+CREATE TABLE IF NOT EXISTS outbound_message_events (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    message_id UUID NOT NULL REFERENCES outbound_messages(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL,
+    detail TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbound_message_events_message ON outbound_message_events(message_id, created_at);
+
+-- Shared secret for gateway delivery callbacks (/webhooks/notifications/:channel)
+INSERT INTO system_settings (key, value, type, category, description) VALUES
+('notification_webhook_secret', '', 'secret', 'notifications', 'Token gateways must send with delivery status callbacks'),
+('notification_worker_count', '4', 'number', 'notifications', 'Number of outbound message workers (applied on restart)')
+ON CONFLICT (key) DO NOTHING;
+
+COMMENT ON TABLE outbound_messages IS 'Outbox of notifications delivered by background workers';
@@ -0,0 +1,50 @@
+-- Migration: Scheduled immunization and measurement reminders
+-- A background scheduler sends WhatsApp reminders for doses due in 7 days, in 1 day or overdue,
+-- and for children not measured for 30 days. reminder_log makes it idempotent across restarts
+-- and replicas: a reminder is only sent by the process that inserts its log row.
+
+-- Per-parent notification preferences (missing row = defaults)
+CREATE TABLE IF NOT EXISTS user_notification_preferences (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    immunization_reminders BOOLEAN DEFAULT TRUE,
+    measurement_reminders BOOLEAN DEFAULT TRUE,
+    quiet_hours_start VARCHAR(5), -- 'HH:MM' local time; NULL = system default
+    quiet_hours_end VARCHAR(5),
+    timezone VARCHAR(50), -- IANA name, e.g. 'Asia/Makassar'; NULL = system default
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS reminder_log (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    reminder_type VARCHAR(50) NOT NULL, -- 'immunization_due_7d', 'immunization_due_1d', 'immunization_overdue', 'measurement_due'
+    reference_key VARCHAR(255) NOT NULL, -- e.g. '<schedule id>:<due date>' or '<last measurement date>:<period>'
+    outbound_message_id UUID REFERENCES outbound_messages(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(child_id, reminder_type, reference_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reminder_log_user ON reminder_log(user_id, created_at);
+
+INSERT INTO notification_templates (key, channel, language, subject, body) VALUES
+('immunization_reminder_due_7d', 'whatsapp', 'id', NULL, E'💉 *Pengingat Imunisasi*\n\nHalo {{.ParentName}},\n\nImunisasi {{.ChildName}} akan jatuh tempo dalam {{.DaysUntilDue}} hari ({{.DueDate}}):\n{{.Vaccines}}\n\nSilakan jadwalkan kunjungan ke posyandu, puskesmas atau dokter.\n\nTim Tukem'),
+('immunization_reminder_due_7d', 'whatsapp', 'en', NULL, E'💉 *Immunization reminder*\n\nHello {{.ParentName}},\n\n{{.ChildName}}''s immunization is due in {{.DaysUntilDue}} days ({{.DueDate}}):\n{{.Vaccines}}\n\nPlease plan a visit to the posyandu, health centre or doctor.\n\nThe Tukem team'),
+('immunization_reminder_due_1d', 'whatsapp', 'id', NULL, E'💉 *Pengingat Imunisasi*\n\nHalo {{.ParentName}},\n\nImunisasi {{.ChildName}} jatuh tempo {{if eq .DaysUntilDue 0}}hari ini{{else}}besok{{end}} ({{.DueDate}}):\n{{.Vaccines}}\n\nJangan lupa membawa buku KIA.\n\nTim Tukem'),
+('immunization_reminder_due_1d', 'whatsapp', 'en', NULL, E'💉 *Immunization reminder*\n\nHello {{.ParentName}},\n\n{{.ChildName}}''s immunization is due {{if eq .DaysUntilDue 0}}today{{else}}tomorrow{{end}} ({{.DueDate}}):\n{{.Vaccines}}\n\nRemember to bring the KIA book.\n\nThe Tukem team'),
+('immunization_reminder_overdue', 'whatsapp', 'id', NULL, E'⚠️ *Imunisasi Terlambat*\n\nHalo {{.ParentName}},\n\nImunisasi berikut untuk {{.ChildName}} sudah melewati jadwal:\n{{.Vaccines}}\n\nImunisasi kejar masih dapat diberikan. Silakan konsultasikan dengan bidan atau dokter.\n\nTim Tukem'),
+('immunization_reminder_overdue', 'whatsapp', 'en', NULL, E'⚠️ *Overdue immunization*\n\nHello {{.ParentName}},\n\nThe following immunizations for {{.ChildName}} are past their schedule:\n{{.Vaccines}}\n\nCatch-up doses can still be given. Please consult a midwife or doctor.\n\nThe Tukem team'),
+('measurement_reminder', 'whatsapp', 'id', NULL, E'📏 *Pengingat Penimbangan*\n\nHalo {{.ParentName}},\n\n{{.ChildName}} belum ditimbang dan diukur selama {{.DaysSinceMeasurement}} hari. Penimbangan rutin setiap bulan membantu memantau pertumbuhan.\n\nCatat hasil penimbangan berikutnya di aplikasi Tukem.\n\nTim Tukem'),
+('measurement_reminder', 'whatsapp', 'en', NULL, E'📏 *Measurement reminder*\n\nHello {{.ParentName}},\n\n{{.ChildName}} has not been weighed and measured for {{.DaysSinceMeasurement}} days. Monthly measurements help track growth.\n\nRecord the next measurement in the Tukem app.\n\nThe Tukem team')
+ON CONFLICT (key, channel, language) DO NOTHING;
+
+INSERT INTO system_settings (key, value, type, category, description) VALUES
+('enable_scheduled_reminders', 'true', 'boolean', 'notifications', 'Send scheduled immunization and measurement reminders'),
+('reminder_interval_minutes', '60', 'number', 'notifications', 'How often the reminder scheduler runs'),
+('reminder_quiet_hours_start', '21:00', 'string', 'notifications', 'Default start of quiet hours (HH:MM, local time)'),
+('reminder_quiet_hours_end', '07:00', 'string', 'notifications', 'Default end of quiet hours (HH:MM, local time)'),
+('reminder_timezone', 'Asia/Jakarta', 'string', 'notifications', 'Default time zone for quiet hours'),
+('measurement_reminder_days', '30', 'number', 'notifications', 'Remind parents when a child has not been measured for this many days')
+ON CONFLICT (key) DO NOTHING;
+
+COMMENT ON TABLE reminder_log IS 'Scheduled reminders already sent, one row per child, type and reference';
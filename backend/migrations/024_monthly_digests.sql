@@ -0,0 +1,43 @@
+-- Migration: Monthly progress digest for parents
+-- A background job builds one digest per family per month (growth, milestones, overdue vaccines
+-- and recommended activities), stores it for the app and sends it by WhatsApp or email (HTML
+-- with a PDF attachment).
+
+ALTER TABLE user_notification_preferences ADD COLUMN IF NOT EXISTS monthly_digest BOOLEAN DEFAULT TRUE;
+ALTER TABLE user_notification_preferences ADD COLUMN IF NOT EXISTS digest_channel VARCHAR(20) DEFAULT 'whatsapp'; -- 'whatsapp' or 'email'
+
+-- Email bodies and attachments of outbound messages (attachments live in the object store)
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS html_body TEXT;
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS attachment_key VARCHAR(500);
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS attachment_filename VARCHAR(255);
+ALTER TABLE outbound_messages ADD COLUMN IF NOT EXISTS attachment_content_type VARCHAR(100);
+
+CREATE TABLE IF NOT EXISTS monthly_digests (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    period VARCHAR(7) NOT NULL, -- 'YYYY-MM'
+    language VARCHAR(10) NOT NULL DEFAULT 'id',
+    content JSONB NOT NULL, -- Snapshot of the digest as generated
+    pdf_key VARCHAR(500), -- Object store key of the PDF
+    channel VARCHAR(20), -- 'whatsapp', 'email' or NULL (in-app only)
+    outbound_message_id UUID REFERENCES outbound_messages(id) ON DELETE SET NULL,
+    read_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE(user_id, period)
+);
+
+CREATE INDEX IF NOT EXISTS idx_monthly_digests_user ON monthly_digests(user_id, period DESC);
+
+INSERT INTO notification_templates (key, channel, language, subject, body) VALUES
+('monthly_digest', 'whatsapp', 'id', NULL, E'📊 *Ringkasan Bulanan {{.Period}}*\n\nHalo {{.ParentName}},\n{{range .Children}}\n*{{.Name}}* ({{.AgeMonths}} bulan)\n{{if .WeightChange}}• Berat badan: {{.Weight}} kg ({{.WeightChange}} kg)\n{{else if .Weight}}• Berat badan: {{.Weight}} kg\n{{else}}• Belum ada penimbangan bulan ini\n{{end}}{{range .ZScores}}• {{.}}\n{{end}}{{if .Milestones}}• Milestone baru: {{.Milestones}}\n{{end}}{{if .OverdueVaccines}}• Imunisasi terlambat: {{.OverdueVaccines}}\n{{end}}{{if .Activities}}• Aktivitas: {{.Activities}}\n{{end}}{{end}}\nLihat ringkasan lengkap di aplikasi Tukem.\n\nTim Tukem'),
+('monthly_digest', 'whatsapp', 'en', NULL, E'📊 *Monthly summary {{.Period}}*\n\nHello {{.ParentName}},\n{{range .Children}}\n*{{.Name}}* ({{.AgeMonths}} months)\n{{if .WeightChange}}• Weight: {{.Weight}} kg ({{.WeightChange}} kg)\n{{else if .Weight}}• Weight: {{.Weight}} kg\n{{else}}• No measurement this month\n{{end}}{{range .ZScores}}• {{.}}\n{{end}}{{if .Milestones}}• New milestones: {{.Milestones}}\n{{end}}{{if .OverdueVaccines}}• Overdue immunizations: {{.OverdueVaccines}}\n{{end}}{{if .Activities}}• Activities: {{.Activities}}\n{{end}}{{end}}\nSee the full summary in the Tukem app.\n\nThe Tukem team'),
+('monthly_digest', 'email', 'id', 'Ringkasan Bulanan Tukem {{.Period}}', E'Halo {{.ParentName}},\n\nBerikut ringkasan tumbuh kembang anak Anda bulan {{.Period}}. Laporan lengkap terlampir dalam PDF.\n{{range .Children}}\n{{.Name}} ({{.AgeMonths}} bulan)\n{{if .WeightChange}}- Berat badan: {{.Weight}} kg ({{.WeightChange}} kg)\n{{else if .Weight}}- Berat badan: {{.Weight}} kg\n{{else}}- Belum ada penimbangan bulan ini\n{{end}}{{range .ZScores}}- {{.}}\n{{end}}{{if .Milestones}}- Milestone baru: {{.Milestones}}\n{{end}}{{if .OverdueVaccines}}- Imunisasi terlambat: {{.OverdueVaccines}}\n{{end}}{{if .Activities}}- Aktivitas: {{.Activities}}\n{{end}}{{end}}\nTerima kasih,\nTim Tukem'),
+('monthly_digest', 'email', 'en', 'Your Tukem monthly summary {{.Period}}', E'Hello {{.ParentName}},\n\nHere is your children''s growth and development summary for {{.Period}}. The full report is attached as a PDF.\n{{range .Children}}\n{{.Name}} ({{.AgeMonths}} months)\n{{if .WeightChange}}- Weight: {{.Weight}} kg ({{.WeightChange}} kg)\n{{else if .Weight}}- Weight: {{.Weight}} kg\n{{else}}- No measurement this month\n{{end}}{{range .ZScores}}- {{.}}\n{{end}}{{if .Milestones}}- New milestones: {{.Milestones}}\n{{end}}{{if .OverdueVaccines}}- Overdue immunizations: {{.OverdueVaccines}}\n{{end}}{{if .Activities}}- Activities: {{.Activities}}\n{{end}}{{end}}\nThank you,\nThe Tukem team')
+ON CONFLICT (key, channel, language) DO NOTHING;
+
+INSERT INTO system_settings (key, value, type, category, description) VALUES
+('enable_monthly_digest', 'true', 'boolean', 'notifications', 'Generate and send the monthly progress digest'),
+('monthly_digest_day', '1', 'number', 'notifications', 'Day of the month the digest for the previous month is sent')
+ON CONFLICT (key) DO NOTHING;
+
+COMMENT ON TABLE monthly_digests IS 'Monthly progress digests per family, readable in the app';
@@ -0,0 +1,34 @@
+-- Migration: WhatsApp chat commands
+-- Parents with a verified phone number can log measurements ("BB 8.2 TB 70 Budi") and ask for
+-- their children's immunization schedule by WhatsApp. The gateway posts incoming messages to
+-- /webhooks/whatsapp/inbound; replies go through the outbox.
+
+CREATE TABLE IF NOT EXISTS whatsapp_conversations (
+    phone_number VARCHAR(20) PRIMARY KEY,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    state VARCHAR(30) NOT NULL DEFAULT 'idle', -- 'idle', 'awaiting_child'
+    pending JSONB, -- Command waiting for the parent to choose a child, and the children offered
+    expires_at TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS whatsapp_inbound_messages (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    provider_message_id VARCHAR(255) UNIQUE, -- Gateway message ID, to ignore redelivered webhooks
+    phone_number VARCHAR(20) NOT NULL,
+    user_id UUID REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    command VARCHAR(30), -- Parsed command type
+    reply TEXT,
+    reply_message_id UUID REFERENCES outbound_messages(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_whatsapp_inbound_phone ON whatsapp_inbound_messages(phone_number, created_at DESC);
+
+INSERT INTO system_settings (key, value, type, category, description) VALUES
+('enable_whatsapp_commands', 'true', 'boolean', 'notifications', 'Let parents log measurements and check schedules by WhatsApp chat')
+ON CONFLICT (key) DO NOTHING;
+
+COMMENT ON TABLE whatsapp_conversations IS 'State of WhatsApp chat conversations, e.g. waiting for the parent to choose a child';
+COMMENT ON TABLE whatsapp_inbound_messages IS 'Incoming WhatsApp messages and the replies sent';
@@ -0,0 +1,25 @@
+-- Migration: User sessions
+-- Logins now return a short-lived access token and a refresh token. Each login is a session
+-- stored server-side so it can be listed and revoked; access tokens carry the session ID and
+-- are rejected once the session is revoked. Refresh tokens rotate on every use and only their
+-- SHA-256 hash is stored.
+
+CREATE TABLE IF NOT EXISTS user_sessions (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    refresh_token_hash VARCHAR(64) NOT NULL UNIQUE,
+    previous_token_hash VARCHAR(64), -- Hash of the rotated-out refresh token, to detect reuse
+    is_admin BOOLEAN NOT NULL DEFAULT false, -- Started from the admin login
+    user_agent TEXT,
+    ip_address VARCHAR(45),
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    last_used_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    expires_at TIMESTAMP NOT NULL,
+    revoked_at TIMESTAMP,
+    revoked_reason VARCHAR(50) -- 'logout', 'logout_all', 'revoked_by_user', 'revoked_by_admin', 'password_reset', 'refresh_token_reuse'
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_sessions_user ON user_sessions(user_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_user_sessions_previous_token ON user_sessions(previous_token_hash);
+
+COMMENT ON TABLE user_sessions IS 'Login sessions with their refresh tokens, for listing and server-side revocation';
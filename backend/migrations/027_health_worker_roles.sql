@@ -0,0 +1,97 @@
+-- Migration: Health-worker roles
+-- Adds roles for kader (posyandu volunteers), bidan (midwives), puskesmas nutritionists and district
+-- viewers. Each role has a set of permissions (e.g. measurement:create); users get a role through an
+-- assignment to a facility or area, which limits the children they can see to those registered at
+-- the facilities in it. Admins keep every permission everywhere.
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM pg_constraint WHERE conname = 'check_role') THEN
+        ALTER TABLE users DROP CONSTRAINT check_role;
+    END IF;
+END $$;
+
+ALTER TABLE users
+ADD CONSTRAINT check_role CHECK (role IN ('parent', 'admin', 'kader', 'bidan', 'nutritionist', 'district_viewer'));
+
+-- Administrative areas, e.g. a district containing subdistricts and villages
+CREATE TABLE IF NOT EXISTS areas (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    level VARCHAR(20) NOT NULL CHECK (level IN ('province', 'district', 'subdistrict', 'village')),
+    parent_id UUID REFERENCES areas(id) ON DELETE RESTRICT,
+    code VARCHAR(20) UNIQUE, -- Kemendagri area code
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_areas_parent ON areas(parent_id);
+
+-- Health facilities; a posyandu belongs to the puskesmas that supervises it
+CREATE TABLE IF NOT EXISTS facilities (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name VARCHAR(255) NOT NULL,
+    type VARCHAR(20) NOT NULL CHECK (type IN ('posyandu', 'puskesmas', 'clinic', 'hospital')),
+    area_id UUID REFERENCES areas(id) ON DELETE SET NULL,
+    parent_id UUID REFERENCES facilities(id) ON DELETE SET NULL,
+    address TEXT,
+    is_active BOOLEAN DEFAULT true,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_facilities_area ON facilities(area_id);
+CREATE INDEX IF NOT EXISTS idx_facilities_parent ON facilities(parent_id);
+
+-- Facility where the child is followed up, chosen by the parent or set by an admin
+ALTER TABLE children ADD COLUMN IF NOT EXISTS facility_id UUID REFERENCES facilities(id) ON DELETE SET NULL;
+CREATE INDEX IF NOT EXISTS idx_children_facility ON children(facility_id);
+
+-- Permissions of each role (the admin role implicitly has all of them)
+CREATE TABLE IF NOT EXISTS role_permissions (
+    role VARCHAR(30) NOT NULL,
+    permission VARCHAR(50) NOT NULL,
+    PRIMARY KEY (role, permission)
+);
+
+-- Roles held by users, for a facility, an area, or everywhere when both are NULL
+CREATE TABLE IF NOT EXISTS role_assignments (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(30) NOT NULL CHECK (role IN ('kader', 'bidan', 'nutritionist', 'district_viewer')),
+    facility_id UUID REFERENCES facilities(id) ON DELETE CASCADE,
+    area_id UUID REFERENCES areas(id) ON DELETE CASCADE,
+    assigned_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    CHECK (facility_id IS NULL OR area_id IS NULL)
+);
+
+CREATE INDEX IF NOT EXISTS idx_role_assignments_user ON role_assignments(user_id);
+
+INSERT INTO role_permissions (role, permission) VALUES
+('kader', 'child:view'),
+('kader', 'measurement:view'),
+('kader', 'measurement:create'),
+('kader', 'assessment:view'),
+('kader', 'immunization:view'),
+('bidan', 'child:view'),
+('bidan', 'measurement:view'),
+('bidan', 'measurement:create'),
+('bidan', 'assessment:view'),
+('bidan', 'immunization:view'),
+('bidan', 'report:view'),
+('nutritionist', 'child:view'),
+('nutritionist', 'measurement:view'),
+('nutritionist', 'measurement:create'),
+('nutritionist', 'assessment:view'),
+('nutritionist', 'report:view'),
+('district_viewer', 'child:view'),
+('district_viewer', 'measurement:view'),
+('district_viewer', 'assessment:view'),
+('district_viewer', 'immunization:view'),
+('district_viewer', 'report:view')
+ON CONFLICT (role, permission) DO NOTHING;
+
+COMMENT ON TABLE areas IS 'Administrative areas (province, district, subdistrict, village)';
+COMMENT ON TABLE facilities IS 'Posyandu, puskesmas and other health facilities';
+COMMENT ON TABLE role_permissions IS 'Permissions granted to each health-worker role';
+COMMENT ON TABLE role_assignments IS 'Health-worker roles held by users, scoped to a facility or area';
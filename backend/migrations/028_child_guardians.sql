@@ -0,0 +1,59 @@
+-- Migration: Shared child access
+-- A child can be followed by several caregivers (mother, father, a grandmother doing daycare).
+-- Access goes through child_guardians: the owner manages access and can delete the child, editors
+-- record data, viewers only read. children.parent_id stays the owner. Owners invite caregivers by
+-- phone number; the invitee receives a code on WhatsApp and accepts it while logged in with that
+-- number. Scheduled reminders go to every guardian.
+
+CREATE TABLE IF NOT EXISTS child_guardians (
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('owner', 'editor', 'viewer')),
+    relationship VARCHAR(50), -- e.g. 'ibu', 'ayah', 'nenek'
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (child_id, user_id)
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_child_guardians_owner ON child_guardians(child_id) WHERE role = 'owner';
+CREATE INDEX IF NOT EXISTS idx_child_guardians_user ON child_guardians(user_id);
+
+-- Existing parents own their children
+INSERT INTO child_guardians (child_id, user_id, role)
+SELECT id, parent_id, 'owner' FROM children WHERE parent_id IS NOT NULL
+ON CONFLICT (child_id, user_id) DO NOTHING;
+
+CREATE TABLE IF NOT EXISTS child_invitations (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    child_id UUID NOT NULL REFERENCES children(id) ON DELETE CASCADE,
+    phone_number VARCHAR(20) NOT NULL,
+    role VARCHAR(10) NOT NULL CHECK (role IN ('editor', 'viewer')),
+    relationship VARCHAR(50),
+    code_hash VARCHAR(64) NOT NULL, -- HMAC-SHA256 of the code sent by WhatsApp (OTP_HASH_KEY)
+    attempt_count INTEGER NOT NULL DEFAULT 0,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'accepted', 'revoked', 'expired')),
+    invited_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    accepted_by UUID REFERENCES users(id) ON DELETE SET NULL,
+    expires_at TIMESTAMP NOT NULL,
+    accepted_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_child_invitations_child ON child_invitations(child_id, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_child_invitations_phone ON child_invitations(phone_number, status);
+
+-- Scheduled reminders go to every guardian, each of them once
+ALTER TABLE reminder_log DROP CONSTRAINT IF EXISTS reminder_log_child_id_reminder_type_reference_key_key;
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_reminder_log_recipient
+    ON reminder_log(child_id, user_id, reminder_type, reference_key);
+
+INSERT INTO notification_templates (key, channel, language, subject, body) VALUES
+('child_invitation', 'whatsapp', 'id', NULL, E'👨‍👩‍👧 *Undangan Tukem*\n\n{{.InviterName}} mengundang Anda untuk ikut memantau tumbuh kembang {{.ChildName}} sebagai {{.Role}}.\n\nKode undangan: *{{.Code}}*\nBerlaku {{.ExpiryHours}} jam.\n\nMasuk ke aplikasi Tukem dengan nomor WhatsApp ini, lalu masukkan kode di menu Undangan. Abaikan pesan ini jika Anda tidak mengenal pengirimnya.\n\nTim Tukem'),
+('child_invitation', 'whatsapp', 'en', NULL, E'👨‍👩‍👧 *Tukem invitation*\n\n{{.InviterName}} invited you to follow {{.ChildName}}''s growth and development as {{.Role}}.\n\nInvitation code: *{{.Code}}*\nValid for {{.ExpiryHours}} hours.\n\nLog in to the Tukem app with this WhatsApp number and enter the code under Invitations. Ignore this message if you don''t know the sender.\n\nThe Tukem team')
+ON CONFLICT (key, channel, language) DO NOTHING;
+
+COMMENT ON TABLE child_guardians IS 'Caregivers with access to a child and their role (owner, editor, viewer)';
+COMMENT ON TABLE child_invitations IS 'Invitations to share a child, accepted with a code sent by WhatsApp';
+COMMENT ON TABLE reminder_log IS 'Scheduled reminders already sent, one row per child, recipient, type and reference';
@@ -0,0 +1,48 @@
+-- Migration: Two-factor authentication for admins
+-- Admins confirm every login with a TOTP code (RFC 6238) from an authenticator app, or with a
+-- one-time recovery code. A login by an admin first returns a short-lived challenge; the session
+-- is only started once the challenge is answered. Admins without two-factor authentication enroll
+-- through the challenge of their next login. Permissions of the admin role require a session that
+-- passed two-factor authentication.
+
+CREATE TABLE IF NOT EXISTS user_two_factor (
+    user_id UUID PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    secret_encrypted TEXT NOT NULL, -- AES-GCM encrypted base32 TOTP secret
+    enabled_at TIMESTAMP, -- NULL while the enrollment is not confirmed
+    last_used_step BIGINT NOT NULL DEFAULT 0, -- Time step of the last accepted code, to reject replays
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- One-time recovery codes, only their SHA-256 hash is stored
+CREATE TABLE IF NOT EXISTS user_recovery_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_user_recovery_codes_user ON user_recovery_codes(user_id);
+
+-- Pending second step of a login
+CREATE TABLE IF NOT EXISTS two_factor_challenges (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    token_hash VARCHAR(64) NOT NULL UNIQUE,
+    login_method VARCHAR(20) NOT NULL, -- 'password', 'otp', 'admin_otp', 'google'
+    is_admin BOOLEAN NOT NULL DEFAULT false, -- Starts an admin session (admin OTP login)
+    attempt_count INTEGER NOT NULL DEFAULT 0,
+    expires_at TIMESTAMP NOT NULL,
+    completed_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_two_factor_challenges_user ON two_factor_challenges(user_id, created_at DESC);
+
+ALTER TABLE user_sessions ADD COLUMN IF NOT EXISTS two_factor_verified BOOLEAN NOT NULL DEFAULT false;
+
+COMMENT ON TABLE user_two_factor IS 'TOTP two-factor authentication of admins';
+COMMENT ON TABLE user_recovery_codes IS 'One-time recovery codes for when the authenticator app is lost';
+COMMENT ON TABLE two_factor_challenges IS 'Logins waiting for the two-factor code';
+COMMENT ON COLUMN user_sessions.two_factor_verified IS 'Whether the login was confirmed with a second factor';
@@ -0,0 +1,45 @@
+-- Migration: Hashed OTP codes and OTP lockout
+-- OTP codes are stored as an HMAC-SHA256 of the phone number, purpose and code instead of in
+-- plaintext. Codes already issued cannot be converted and are dropped (they expire within
+-- minutes anyway). Request limits are counted from otp_codes, so otp_rate_limits is no longer
+-- used. Failed verifications are recorded per phone number and IP address; too many of them
+-- lock both out for a while. Expiry, attempt limits and lockout come from system_settings.
+
+DO $$
+BEGIN
+    IF EXISTS (SELECT 1 FROM information_schema.columns
+               WHERE table_name = 'otp_codes' AND column_name = 'otp_code') THEN
+        DELETE FROM otp_codes;
+        ALTER TABLE otp_codes DROP COLUMN otp_code;
+    END IF;
+END $$;
+
+ALTER TABLE otp_codes ADD COLUMN IF NOT EXISTS code_hash VARCHAR(64) NOT NULL;
+
+CREATE INDEX IF NOT EXISTS idx_otp_codes_lookup ON otp_codes(phone_number, purpose, created_at DESC);
+CREATE INDEX IF NOT EXISTS idx_otp_codes_ip ON otp_codes(ip_address, created_at);
+
+DROP TABLE IF EXISTS otp_rate_limits;
+
+CREATE TABLE IF NOT EXISTS otp_failures (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    phone_number VARCHAR(20) NOT NULL,
+    ip_address VARCHAR(45),
+    purpose VARCHAR(50) NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_otp_failures_phone ON otp_failures(phone_number, created_at);
+CREATE INDEX IF NOT EXISTS idx_otp_failures_ip ON otp_failures(ip_address, created_at);
+
+INSERT INTO system_settings (key, value, type, category, description) VALUES
+('otp_request_limit', '3', 'number', 'security', 'OTP codes a phone number can request per request window'),
+('otp_ip_request_limit', '20', 'number', 'security', 'OTP codes an IP address can request per request window'),
+('otp_request_window_minutes', '15', 'number', 'security', 'Window of the OTP request limits in minutes'),
+('otp_lockout_phone_failures', '10', 'number', 'security', 'Failed OTP verifications that lock a phone number out'),
+('otp_lockout_ip_failures', '30', 'number', 'security', 'Failed OTP verifications that lock an IP address out'),
+('otp_lockout_minutes', '30', 'number', 'security', 'How long failed OTP verifications count towards a lockout')
+ON CONFLICT (key) DO NOTHING;
+
+COMMENT ON COLUMN otp_codes.code_hash IS 'HMAC-SHA256 of phone number, purpose and code';
+COMMENT ON TABLE otp_failures IS 'Failed OTP verifications, counted for the per-phone and per-IP lockout';
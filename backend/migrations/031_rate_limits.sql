@@ -0,0 +1,46 @@
+-- Migration: API-wide rate limiting
+-- Requests are limited with token buckets per route class (auth, public, api, admin) and per
+-- client IP address or user. Buckets are kept in memory by each replica, or in rate_limit_buckets
+-- when the replicas must share them. Clients that keep exceeding their limits are banned for a
+-- while; bans are shared by all replicas and written to the audit log.
+
+CREATE TABLE IF NOT EXISTS rate_limit_buckets (
+    key VARCHAR(255) PRIMARY KEY, -- '<class>:<ip|user>:<subject>', or 'violations:<ip|user>:<subject>'
+    tokens DOUBLE PRECISION NOT NULL,
+    allowed BOOLEAN NOT NULL DEFAULT true, -- Whether the last request took a token
+    updated_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_buckets_updated ON rate_limit_buckets(updated_at);
+
+CREATE TABLE IF NOT EXISTS rate_limit_bans (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    subject_type VARCHAR(10) NOT NULL, -- 'ip' or 'user'
+    subject VARCHAR(255) NOT NULL, -- IP address or user ID
+    route_class VARCHAR(20) NOT NULL, -- Class of the request that triggered the ban
+    ip_address VARCHAR(45),
+    user_agent TEXT,
+    banned_until TIMESTAMP NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_rate_limit_bans_active ON rate_limit_bans(banned_until);
+CREATE INDEX IF NOT EXISTS idx_rate_limit_bans_subject ON rate_limit_bans(subject_type, subject);
+
+INSERT INTO system_settings (key, value, type, category, description) VALUES
+('rate_limit_enabled', 'true', 'boolean', 'rate_limit', 'Limit API requests per route class, IP address and user'),
+('rate_limit_store', 'memory', 'string', 'rate_limit', 'Where request counts are kept: memory (per replica) or postgres (shared by replicas)'),
+('rate_limit_auth_ip_per_minute', '20', 'number', 'rate_limit', 'Login, registration and OTP requests per IP address per minute (0 = unlimited)'),
+('rate_limit_public_ip_per_minute', '60', 'number', 'rate_limit', 'Public file, calendar and certificate requests per IP address per minute (0 = unlimited)'),
+('rate_limit_api_ip_per_minute', '600', 'number', 'rate_limit', 'API requests per IP address per minute (0 = unlimited)'),
+('rate_limit_api_user_per_minute', '300', 'number', 'rate_limit', 'API requests per user per minute (0 = unlimited)'),
+('rate_limit_admin_ip_per_minute', '600', 'number', 'rate_limit', 'Admin API requests per IP address per minute (0 = unlimited)'),
+('rate_limit_admin_user_per_minute', '300', 'number', 'rate_limit', 'Admin API requests per user per minute (0 = unlimited)'),
+('rate_limit_ban_threshold', '100', 'number', 'rate_limit', 'Rejected requests within the ban window that ban an IP address or user (0 = never ban)'),
+('rate_limit_ban_window_minutes', '10', 'number', 'rate_limit', 'Window of the ban threshold in minutes'),
+('rate_limit_ban_minutes', '60', 'number', 'rate_limit', 'How long a ban lasts in minutes'),
+('rate_limit_exempt_ips', '', 'string', 'rate_limit', 'Comma-separated IP addresses that are never limited, e.g. monitoring')
+ON CONFLICT (key) DO NOTHING;
+
+COMMENT ON TABLE rate_limit_buckets IS 'Token buckets of the rate limiter when shared by replicas';
+COMMENT ON TABLE rate_limit_bans IS 'IP addresses and users banned for repeatedly exceeding rate limits';
@@ -0,0 +1,19 @@
+-- Migration: Google login exchange codes
+-- The Google OAuth callback no longer puts tokens in the frontend redirect, where they would end
+-- up in browser history, access logs and Referer headers. It redirects with a short-lived,
+-- single-use code instead, which the frontend exchanges by POST for the token pair (or the
+-- two-factor challenge). Only the SHA-256 hash of the code is stored.
+
+CREATE TABLE IF NOT EXISTS login_exchange_codes (
+    id UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    user_id UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    code_hash VARCHAR(64) NOT NULL UNIQUE,
+    login_method VARCHAR(20) NOT NULL, -- 'google'
+    expires_at TIMESTAMP NOT NULL,
+    used_at TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_login_exchange_codes_expires ON login_exchange_codes(expires_at);
+
+COMMENT ON TABLE login_exchange_codes IS 'Single-use codes that hand a Google login over to the frontend';
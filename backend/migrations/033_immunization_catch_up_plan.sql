@@ -0,0 +1,12 @@
+-- Migration: Catch-up plan origin of immunization records
+-- A record is a catch-up dose when it was given late, or when it was recorded from the catch-up
+-- plan. The plan origin is kept in its own column so editing the given date can recalculate the
+-- timing without losing it, or keeping a flag that only came from a mistyped date.
+
+ALTER TABLE child_immunizations ADD COLUMN IF NOT EXISTS from_catch_up_plan BOOLEAN NOT NULL DEFAULT FALSE;
+
+-- Timing never flags an on-schedule dose as catch-up, so those records came from the plan
+UPDATE child_immunizations SET from_catch_up_plan = TRUE
+WHERE is_catch_up = TRUE AND is_on_schedule = TRUE AND from_catch_up_plan = FALSE;
+
+COMMENT ON COLUMN child_immunizations.from_catch_up_plan IS 'Recorded from the catch-up plan; is_catch_up is set regardless of the timing';
@@ -0,0 +1,37 @@
+-- Migration: WhatsApp chat reply templates
+-- Chat replies are rendered from notification templates like the other outbound messages, so
+-- admins can edit and translate them. Lists (children, doses) are passed to the templates as
+-- data; {{.Children}} is already numbered.
+
+INSERT INTO notification_templates (key, channel, language, subject, body) VALUES
+('chat_unregistered', 'whatsapp', 'id', NULL, 'Nomor ini belum terdaftar atau belum diverifikasi di Tukem. Masuk ke aplikasi Tukem dengan nomor WhatsApp ini untuk menggunakan fitur chat.'),
+('chat_unregistered', 'whatsapp', 'en', NULL, 'This number is not registered or verified on Tukem. Sign in to the Tukem app with this WhatsApp number to use chat commands.'),
+('chat_help', 'whatsapp', 'id', NULL, E'🤖 *Perintah Tukem*\n\n• *BB 8.2 TB 70 Budi* - catat berat badan (kg) dan tinggi badan (cm), tambahkan *LK 44* untuk lingkar kepala\n• *jadwal Budi* - jadwal imunisasi dan penimbangan berikutnya\n• *imunisasi Budi* - status imunisasi\n• *batal* - batalkan pertanyaan\n\nNama anak boleh dikosongkan jika Anda hanya memiliki satu anak.'),
+('chat_help', 'whatsapp', 'en', NULL, E'🤖 *Tukem commands*\n\n• *BB 8.2 TB 70 Budi* - record weight (kg) and height (cm), add *LK 44* for head circumference\n• *schedule Budi* - next immunizations and measurement\n• *immunization Budi* - immunization status\n• *cancel* - cancel a question\n\nThe child''s name can be left out if you have only one child.'),
+('chat_unknown', 'whatsapp', 'id', NULL, 'Maaf, pesan tidak dikenali. Ketik *bantuan* untuk melihat daftar perintah.'),
+('chat_unknown', 'whatsapp', 'en', NULL, 'Sorry, this message was not understood. Send *help* for the list of commands.'),
+('chat_cancelled', 'whatsapp', 'id', NULL, 'Baik, dibatalkan.'),
+('chat_cancelled', 'whatsapp', 'en', NULL, 'OK, cancelled.'),
+('chat_no_children', 'whatsapp', 'id', NULL, 'Belum ada data anak. Tambahkan anak di aplikasi Tukem terlebih dahulu.'),
+('chat_no_children', 'whatsapp', 'en', NULL, 'No children yet. Add your child in the Tukem app first.'),
+('chat_measurement_format', 'whatsapp', 'id', NULL, 'Kirim berat dan tinggi badan sekaligus, contoh: *BB 8.2 TB 70 Budi*'),
+('chat_measurement_format', 'whatsapp', 'en', NULL, 'Send weight and height together, e.g. *BB 8.2 TB 70 Budi*'),
+('chat_which_child', 'whatsapp', 'id', NULL, E'Untuk anak yang mana? Balas dengan nomornya:\n{{.Children}}'),
+('chat_which_child', 'whatsapp', 'en', NULL, E'For which child? Reply with the number:\n{{.Children}}'),
+('chat_child_not_found', 'whatsapp', 'id', NULL, E'Anak bernama "{{.Name}}" tidak ditemukan. Balas dengan nomor anak:\n{{.Children}}'),
+('chat_child_not_found', 'whatsapp', 'en', NULL, E'No child named "{{.Name}}" was found. Reply with the number of the child:\n{{.Children}}'),
+('chat_choose_number', 'whatsapp', 'id', NULL, E'Balas dengan nomor 1 sampai {{.Count}}, atau *batal*.\n{{.Children}}'),
+('chat_choose_number', 'whatsapp', 'en', NULL, E'Reply with a number from 1 to {{.Count}}, or *cancel*.\n{{.Children}}'),
+('chat_measurement_saved', 'whatsapp', 'id', NULL, E'✅ Pengukuran {{.ChildName}} tanggal {{.Date}} tersimpan.\n• Berat badan: {{.Weight}} kg\n• Tinggi badan: {{.Height}} cm{{if .HeadCircumference}}\n• Lingkar kepala: {{.HeadCircumference}} cm{{end}}{{range .Statuses}}\n• {{.}}{{end}}'),
+('chat_measurement_saved', 'whatsapp', 'en', NULL, E'✅ Measurement of {{.ChildName}} on {{.Date}} saved.\n• Weight: {{.Weight}} kg\n• Height: {{.Height}} cm{{if .HeadCircumference}}\n• Head circumference: {{.HeadCircumference}} cm{{end}}{{range .Statuses}}\n• {{.}}{{end}}'),
+('chat_measurement_failed', 'whatsapp', 'id', NULL, 'Pengukuran tidak dapat disimpan: {{.Reason}}'),
+('chat_measurement_failed', 'whatsapp', 'en', NULL, 'The measurement could not be saved: {{.Reason}}'),
+('chat_viewer_only', 'whatsapp', 'id', NULL, 'Anda hanya dapat melihat data {{.ChildName}}. Minta pemilik data anak untuk mengubah akses Anda agar dapat mencatat pengukuran.'),
+('chat_viewer_only', 'whatsapp', 'en', NULL, 'You can only view the data of {{.ChildName}}. Ask the child''s owner to change your access to record measurements.'),
+('chat_schedule', 'whatsapp', 'id', NULL, E'📅 *Jadwal {{.ChildName}}*{{range .Items}}\n• {{.Name}} - {{.DueDate}}{{if .Overdue}} (terlambat){{end}}{{else}}\nTidak ada imunisasi yang dijadwalkan.{{end}}{{if .NextMeasurement}}\n\nPenimbangan berikutnya: {{.NextMeasurement}}{{end}}'),
+('chat_schedule', 'whatsapp', 'en', NULL, E'📅 *Schedule of {{.ChildName}}*{{range .Items}}\n• {{.Name}} - {{.DueDate}}{{if .Overdue}} (overdue){{end}}{{else}}\nNo immunizations scheduled.{{end}}{{if .NextMeasurement}}\n\nNext measurement: {{.NextMeasurement}}{{end}}'),
+('chat_immunization', 'whatsapp', 'id', NULL, E'💉 *Imunisasi {{.ChildName}}*\n{{.Completed}} dari {{.Total}} imunisasi sudah diberikan.{{if .Overdue}}\n\nTerlambat:{{range .Overdue}}\n• {{.}}{{end}}{{end}}{{if .NextDose}}\n\nBerikutnya: {{.NextDose}} ({{.NextDoseDate}}){{end}}{{if .AllComplete}}\n\nSemua imunisasi sudah lengkap. 🎉{{end}}'),
+('chat_immunization', 'whatsapp', 'en', NULL, E'💉 *Immunizations of {{.ChildName}}*\n{{.Completed}} of {{.Total}} immunizations given.{{if .Overdue}}\n\nOverdue:{{range .Overdue}}\n• {{.}}{{end}}{{end}}{{if .NextDose}}\n\nNext: {{.NextDose}} ({{.NextDoseDate}}){{end}}{{if .AllComplete}}\n\nAll immunizations are complete. 🎉{{end}}'),
+('chat_error', 'whatsapp', 'id', NULL, 'Maaf, terjadi kesalahan. Silakan coba lagi nanti.'),
+('chat_error', 'whatsapp', 'en', NULL, 'Sorry, something went wrong. Please try again later.')
+ON CONFLICT (key, channel, language) DO NOTHING;
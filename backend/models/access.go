@@ -0,0 +1,82 @@
+package models
+
+import "time"
+
+// AccessScope is where a permission is held. A global scope covers every child; otherwise only
+// children registered at the listed facilities are accessible.
+type AccessScope struct {
+	Global      bool     `json:"global"`
+	FacilityIDs []string `json:"facility_ids,omitempty"` // Includes facilities in assigned areas and under assigned facilities
+}
+
+// Area is an administrative area
+type Area struct {
+	ID        string    `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Level     string    `json:"level" db:"level"` // province, district, subdistrict, village
+	ParentID  *string   `json:"parent_id,omitempty" db:"parent_id"`
+	Code      *string   `json:"code,omitempty" db:"code"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// Facility is a posyandu, puskesmas or other health facility
+type Facility struct {
+	ID        string    `json:"id" db:"id"`
+	Name      string    `json:"name" db:"name"`
+	Type      string    `json:"type" db:"type"` // posyandu, puskesmas, clinic, hospital
+	AreaID    *string   `json:"area_id,omitempty" db:"area_id"`
+	ParentID  *string   `json:"parent_id,omitempty" db:"parent_id"` // Supervising puskesmas of a posyandu
+	Address   *string   `json:"address,omitempty" db:"address"`
+	IsActive  bool      `json:"is_active" db:"is_active"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// RoleAssignment is a health-worker role held by a user for a facility, an area, or everywhere
+type RoleAssignment struct {
+	ID           string    `json:"id" db:"id"`
+	UserID       string    `json:"user_id" db:"user_id"`
+	Role         string    `json:"role" db:"role"`
+	FacilityID   *string   `json:"facility_id,omitempty" db:"facility_id"`
+	FacilityName *string   `json:"facility_name,omitempty" db:"facility_name"`
+	AreaID       *string   `json:"area_id,omitempty" db:"area_id"`
+	AreaName     *string   `json:"area_name,omitempty" db:"area_name"`
+	AssignedBy   *string   `json:"assigned_by,omitempty" db:"assigned_by"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// CreateRoleAssignmentRequest assigns a role to a user; leave facility and area empty for everywhere
+type CreateRoleAssignmentRequest struct {
+	Role       string  `json:"role" validate:"required"`
+	FacilityID *string `json:"facility_id,omitempty"`
+	AreaID     *string `json:"area_id,omitempty"`
+}
+
+// RolePermissions is a role with its permissions
+type RolePermissions struct {
+	Role        string   `json:"role"`
+	Permissions []string `json:"permissions"`
+}
+
+// UpdateRolePermissionsRequest replaces the permissions of a role
+type UpdateRolePermissionsRequest struct {
+	Permissions []string `json:"permissions"`
+}
+
+// FacilityRequest creates or updates a facility
+type FacilityRequest struct {
+	Name     string  `json:"name" validate:"required"`
+	Type     string  `json:"type" validate:"required,oneof=posyandu puskesmas clinic hospital"`
+	AreaID   *string `json:"area_id,omitempty"`
+	ParentID *string `json:"parent_id,omitempty"`
+	Address  *string `json:"address,omitempty"`
+	IsActive *bool   `json:"is_active,omitempty"`
+}
+
+// AreaRequest creates an area
+type AreaRequest struct {
+	Name     string  `json:"name" validate:"required"`
+	Level    string  `json:"level" validate:"required,oneof=province district subdistrict village"`
+	ParentID *string `json:"parent_id,omitempty"`
+	Code     *string `json:"code,omitempty"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// AssessmentAttachment is a photo or video uploaded as evidence for an assessment
+type AssessmentAttachment struct {
+	ID           string    `json:"id" db:"id"`
+	AssessmentID string    `json:"assessment_id" db:"assessment_id"`
+	ChildID      string    `json:"child_id" db:"child_id"`
+	UploadedBy   *string   `json:"uploaded_by,omitempty" db:"uploaded_by"`
+	MediaType    string    `json:"media_type" db:"media_type"` // photo, video
+	ContentType  string    `json:"content_type" db:"content_type"`
+	FileName     string    `json:"file_name" db:"file_name"`
+	SizeBytes    int64     `json:"size_bytes" db:"size_bytes"`
+	StorageKey   string    `json:"-" db:"storage_key"`
+	ThumbnailKey *string   `json:"-" db:"thumbnail_key"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+
+	// Signed download URLs, filled in for API responses
+	URL          string  `json:"url" db:"-"`
+	ThumbnailURL *string `json:"thumbnail_url,omitempty" db:"-"`
+}
@@ -0,0 +1,24 @@
+package models
+
+import (
+	"time"
+)
+
+// CalendarFeed is a secret, revocable iCalendar subscription for one child or the whole family
+type CalendarFeed struct {
+	ID             string     `json:"id" db:"id"`
+	UserID         string     `json:"user_id" db:"user_id"`
+	ChildID        *string    `json:"child_id,omitempty" db:"child_id"` // NULL = all children of the user
+	Token          string     `json:"-" db:"token"`
+	LastAccessedAt *time.Time `json:"last_accessed_at,omitempty" db:"last_accessed_at"`
+	RevokedAt      *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+
+	// Subscription URL, filled in for API responses
+	URL string `json:"url" db:"-"`
+}
+
+// CreateCalendarFeedRequest creates a feed for a child, or a family feed when child_id is empty
+type CreateCalendarFeedRequest struct {
+	ChildID *string `json:"child_id,omitempty"`
+}
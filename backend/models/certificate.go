@@ -0,0 +1,49 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// ImmunizationCertificate is an issued immunization certificate. Summary is the
+// privacy-preserving snapshot shown on the public verification page.
+type ImmunizationCertificate struct {
+	ID            string          `json:"id" db:"id"`
+	ChildID       string          `json:"child_id" db:"child_id"`
+	IssuedBy      *string         `json:"issued_by,omitempty" db:"issued_by"`
+	Summary       json.RawMessage `json:"summary" db:"summary"`
+	DoseCount     int             `json:"dose_count" db:"dose_count"`
+	IssuedAt      time.Time       `json:"issued_at" db:"issued_at"`
+	RevokedAt     *time.Time      `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedReason *string         `json:"revoked_reason,omitempty" db:"revoked_reason"`
+}
+
+// CertificateSummary is the public content of a certificate: no full name, exact birth date,
+// facility or batch numbers
+type CertificateSummary struct {
+	ChildInitials string                   `json:"child_initials"`
+	BirthYear     int                      `json:"birth_year"`
+	Doses         []CertificateSummaryDose `json:"doses"`
+}
+
+// CertificateSummaryDose is a dose listed on the public verification page
+type CertificateSummaryDose struct {
+	Vaccine    string `json:"vaccine"`
+	DoseNumber int    `json:"dose_number"`
+	GivenDate  string `json:"given_date"` // YYYY-MM-DD
+}
+
+// CertificateVerification is the response of the public verification endpoint
+type CertificateVerification struct {
+	Valid         bool                `json:"valid"`
+	Status        string              `json:"status"` // valid, revoked
+	CertificateID string              `json:"certificate_id"`
+	IssuedAt      time.Time           `json:"issued_at"`
+	RevokedAt     *time.Time          `json:"revoked_at,omitempty"`
+	Summary       *CertificateSummary `json:"summary,omitempty"`
+}
+
+// RevokeCertificateRequest revokes an issued certificate
+type RevokeCertificateRequest struct {
+	Reason *string `json:"reason,omitempty"`
+}
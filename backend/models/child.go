@@ -14,6 +14,8 @@ type Child struct {
 	BirthHeight     float64   `json:"birth_height" db:"birth_height"` // cm
 	IsPremature     bool      `json:"is_premature" db:"is_premature"`
 	GestationalAge  *int      `json:"gestational_age,omitempty" db:"gestational_age"` // weeks (if premature)
+	FacilityID      *string   `json:"facility_id,omitempty" db:"facility_id"` // Posyandu or puskesmas where the child is followed up
+	AccessRole      string    `json:"access_role,omitempty" db:"-"` // Role of the requesting user: owner, editor or viewer
 	CreatedAt       time.Time `json:"created_at" db:"created_at"`
 }
 
@@ -25,6 +27,7 @@ type CreateChildRequest struct {
 	BirthHeight    float64 `json:"birth_height" validate:"required,gt=0"`
 	IsPremature    bool    `json:"is_premature"`
 	GestationalAge *int    `json:"gestational_age,omitempty"`
+	FacilityID     *string `json:"facility_id,omitempty"`
 }
 
 type UpdateChildRequest struct {
@@ -35,4 +38,5 @@ type UpdateChildRequest struct {
 	BirthHeight    float64 `json:"birth_height"`
 	IsPremature    bool    `json:"is_premature"`
 	GestationalAge *int    `json:"gestational_age,omitempty"`
+	FacilityID     *string `json:"facility_id,omitempty"` // Unchanged when omitted
 }
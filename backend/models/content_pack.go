@@ -0,0 +1,75 @@
+package models
+
+import (
+	"time"
+)
+
+// ContentPack is one imported version of a milestone source (KPSP, DENVER, ...)
+type ContentPack struct {
+	ID             string     `json:"id" db:"id"`
+	Source         string     `json:"source" db:"source"`
+	Version        string     `json:"version" db:"version"`
+	Name           string     `json:"name" db:"name"`
+	Notes          *string    `json:"notes,omitempty" db:"notes"`
+	Status         string     `json:"status" db:"status"` // draft, active, archived
+	MilestoneCount int        `json:"milestone_count" db:"milestone_count"`
+	ContentCount   int        `json:"content_count" db:"content_count"`
+	ImportedBy     *string    `json:"imported_by,omitempty" db:"imported_by"`
+	ActivatedAt    *time.Time `json:"activated_at,omitempty" db:"activated_at"`
+	CreatedAt      time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ContentPackFile is the import/export format of a content pack
+type ContentPackFile struct {
+	Source     string                 `json:"source"`
+	Version    string                 `json:"version"`
+	Name       string                 `json:"name"`
+	Notes      *string                `json:"notes,omitempty"`
+	Milestones []ContentPackMilestone `json:"milestones"`
+}
+
+// ContentPackMilestone is a milestone in a content pack file
+type ContentPackMilestone struct {
+	Key          string                   `json:"key,omitempty"` // Stable item key used to match items across versions
+	AgeMonths    int                      `json:"age_months"`
+	MinAgeRange  *int                     `json:"min_age_range,omitempty"`
+	MaxAgeRange  *int                     `json:"max_age_range,omitempty"`
+	Category     string                   `json:"category"`
+	Question     string                   `json:"question"`
+	QuestionEn   string                   `json:"question_en,omitempty"`
+	IsRedFlag    bool                     `json:"is_red_flag"`
+	PyramidLevel int                      `json:"pyramid_level"`
+	DenverDomain *string                  `json:"denver_domain,omitempty"`
+	Stimulation  []ContentPackStimulation `json:"stimulation,omitempty"`
+}
+
+// ContentPackStimulation is stimulation content linked to a milestone in a content pack file
+type ContentPackStimulation struct {
+	Category     string  `json:"category"`
+	Title        string  `json:"title"`
+	Description  string  `json:"description,omitempty"`
+	ContentType  string  `json:"content_type"` // video, article
+	URL          string  `json:"url"`
+	ThumbnailURL *string `json:"thumbnail_url,omitempty"`
+	AgeMinMonths *int    `json:"age_min_months,omitempty"`
+	AgeMaxMonths *int    `json:"age_max_months,omitempty"`
+}
+
+// ContentPackChange is a milestone that exists in both versions but differs
+type ContentPackChange struct {
+	Key    string               `json:"key"`
+	Fields []string             `json:"fields"`
+	Before ContentPackMilestone `json:"before"`
+	After  ContentPackMilestone `json:"after"`
+}
+
+// ContentPackDiff compares an incoming pack against the active version of the same source
+type ContentPackDiff struct {
+	Source         string                 `json:"source"`
+	CurrentVersion *string                `json:"current_version"` // nil if the source has no active pack
+	NewVersion     string                 `json:"new_version"`
+	Added          []ContentPackMilestone `json:"added"`
+	Removed        []ContentPackMilestone `json:"removed"`
+	Changed        []ContentPackChange    `json:"changed"`
+	UnchangedCount int                    `json:"unchanged_count"`
+}
@@ -0,0 +1,41 @@
+package models
+
+import (
+	"time"
+)
+
+// DomainDevelopment is the age-equivalent and DQ for one Denver II domain
+type DomainDevelopment struct {
+	Domain                string   `json:"domain"`                // PS, FM, L, GM
+	AgeEquivalentMonths   float64  `json:"age_equivalent_months"` // Highest age level passed with every younger level passed
+	DevelopmentalQuotient *float64 `json:"developmental_quotient,omitempty"`
+	ItemsAssessed         int      `json:"items_assessed"`
+	ItemsPassed           int      `json:"items_passed"`
+}
+
+// DevelopmentalProfile represents the API response for a child's developmental profile
+type DevelopmentalProfile struct {
+	ChildID                string              `json:"child_id"`
+	AssessedAt             string              `json:"assessed_at"` // YYYY-MM-DD
+	ChronologicalAgeMonths float64             `json:"chronological_age_months"`
+	ReferenceAgeMonths     float64             `json:"reference_age_months"`
+	AgeBasis               string              `json:"age_basis"` // chronological, corrected
+	Domains                []DomainDevelopment `json:"domains"`
+}
+
+// DevelopmentalSnapshot is a stored age-equivalent/DQ value for a child, domain and date
+type DevelopmentalSnapshot struct {
+	ID                     string    `json:"id" db:"id"`
+	ChildID                string    `json:"child_id" db:"child_id"`
+	Domain                 string    `json:"domain" db:"domain"`
+	SnapshotDate           string    `json:"snapshot_date" db:"snapshot_date"` // YYYY-MM-DD
+	ChronologicalAgeMonths float64   `json:"chronological_age_months" db:"chronological_age_months"`
+	ReferenceAgeMonths     float64   `json:"reference_age_months" db:"reference_age_months"`
+	AgeBasis               string    `json:"age_basis" db:"age_basis"`
+	AgeEquivalentMonths    float64   `json:"age_equivalent_months" db:"age_equivalent_months"`
+	DevelopmentalQuotient  *float64  `json:"developmental_quotient,omitempty" db:"developmental_quotient"`
+	ItemsAssessed          int       `json:"items_assessed" db:"items_assessed"`
+	ItemsPassed            int       `json:"items_passed" db:"items_passed"`
+	CreatedAt              time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt              time.Time `json:"updated_at" db:"updated_at"`
+}
@@ -0,0 +1,81 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// MonthlyDigest is a stored monthly progress digest of a family
+type MonthlyDigest struct {
+	ID                string          `json:"id" db:"id"`
+	UserID            string          `json:"user_id" db:"user_id"`
+	Period            string          `json:"period" db:"period"` // YYYY-MM
+	Language          string          `json:"language" db:"language"`
+	Content           json.RawMessage `json:"content,omitempty" db:"content"` // DigestContent
+	PDFKey            *string         `json:"-" db:"pdf_key"`
+	Channel           *string         `json:"channel,omitempty" db:"channel"` // whatsapp, email, or empty when in-app only
+	OutboundMessageID *string         `json:"outbound_message_id,omitempty" db:"outbound_message_id"`
+	ReadAt            *time.Time      `json:"read_at,omitempty" db:"read_at"`
+	CreatedAt         time.Time       `json:"created_at" db:"created_at"`
+}
+
+// DigestContent is the content of a monthly digest
+type DigestContent struct {
+	Period      string        `json:"period"`       // YYYY-MM
+	PeriodStart string        `json:"period_start"` // YYYY-MM-DD
+	PeriodEnd   string        `json:"period_end"`   // YYYY-MM-DD
+	ParentName  string        `json:"parent_name"`
+	GeneratedAt time.Time     `json:"generated_at"`
+	Children    []DigestChild `json:"children"`
+}
+
+// DigestChild is the part of a digest about one child
+type DigestChild struct {
+	ChildID   string `json:"child_id"`
+	Name      string `json:"name"`
+	AgeMonths int    `json:"age_months"`
+
+	// Latest measurement up to the end of the period, and the one before it
+	LatestMeasurement   *DigestMeasurement   `json:"latest_measurement,omitempty"`
+	PreviousMeasurement *DigestMeasurement   `json:"previous_measurement,omitempty"`
+	WeightChange        *float64             `json:"weight_change,omitempty"` // kg since the previous measurement
+	ZScoreChanges       []DigestZScoreChange `json:"zscore_changes"`
+
+	NewMilestones   []DigestMilestone `json:"new_milestones"`   // Achieved during the period
+	OverdueVaccines []string          `json:"overdue_vaccines"` // At the time the digest was generated
+	Activities      []DigestActivity  `json:"activities"`       // Recommended stimulation activities
+}
+
+// DigestMeasurement is a measurement summarized in a digest
+type DigestMeasurement struct {
+	Date                  string   `json:"date" db:"date"` // YYYY-MM-DD
+	Weight                float64  `json:"weight" db:"weight"`
+	Height                float64  `json:"height" db:"height"`
+	WeightForAgeZScore    *float64 `json:"weight_for_age_zscore,omitempty" db:"weight_for_age_zscore"`
+	HeightForAgeZScore    *float64 `json:"height_for_age_zscore,omitempty" db:"height_for_age_zscore"`
+	WeightForHeightZScore *float64 `json:"weight_for_height_zscore,omitempty" db:"weight_for_height_zscore"`
+	NutritionalStatus     *string  `json:"nutritional_status,omitempty" db:"nutritional_status"`
+}
+
+// DigestZScoreChange is the change of one z-score indicator between two measurements
+type DigestZScoreChange struct {
+	Indicator string   `json:"indicator"` // weight_for_age, height_for_age, weight_for_height
+	Previous  *float64 `json:"previous,omitempty"`
+	Current   float64  `json:"current"`
+	Change    *float64 `json:"change,omitempty"`
+}
+
+// DigestMilestone is a milestone achieved during the digest period
+type DigestMilestone struct {
+	Question string `json:"question"`
+	Category string `json:"category"`
+	Date     string `json:"date"` // YYYY-MM-DD
+}
+
+// DigestActivity is a recommended stimulation activity
+type DigestActivity struct {
+	Title    string `json:"title"`
+	Category string `json:"category"`
+	URL      string `json:"url,omitempty"`
+	Reason   string `json:"reason,omitempty"`
+}
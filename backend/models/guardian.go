@@ -0,0 +1,59 @@
+package models
+
+import (
+	"time"
+)
+
+// ChildGuardian is a caregiver with access to a child
+type ChildGuardian struct {
+	ChildID      string    `json:"child_id" db:"child_id"`
+	UserID       string    `json:"user_id" db:"user_id"`
+	FullName     string    `json:"full_name" db:"full_name"`
+	PhoneNumber  *string   `json:"phone_number,omitempty" db:"phone_number"`
+	Role         string    `json:"role" db:"role"` // 'owner', 'editor' or 'viewer'
+	Relationship *string   `json:"relationship,omitempty" db:"relationship"`
+	InvitedBy    *string   `json:"invited_by,omitempty" db:"invited_by"`
+	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+}
+
+// ChildInvitation invites a caregiver, identified by phone number, to a child
+type ChildInvitation struct {
+	ID           string     `json:"id" db:"id"`
+	ChildID      string     `json:"child_id" db:"child_id"`
+	ChildName    string     `json:"child_name" db:"child_name"`
+	PhoneNumber  string     `json:"phone_number" db:"phone_number"`
+	Role         string     `json:"role" db:"role"`
+	Relationship *string    `json:"relationship,omitempty" db:"relationship"`
+	CodeHash     string     `json:"-" db:"code_hash"`
+	AttemptCount int        `json:"-" db:"attempt_count"`
+	Status       string     `json:"status" db:"status"` // 'pending', 'accepted', 'revoked' or 'expired'
+	InvitedBy    *string    `json:"invited_by,omitempty" db:"invited_by"`
+	InviterName  *string    `json:"inviter_name,omitempty" db:"inviter_name"`
+	AcceptedBy   *string    `json:"accepted_by,omitempty" db:"accepted_by"`
+	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
+	AcceptedAt   *time.Time `json:"accepted_at,omitempty" db:"accepted_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// CreateChildInvitationRequest invites a phone number as editor or viewer
+type CreateChildInvitationRequest struct {
+	PhoneNumber  string  `json:"phone_number"`
+	Role         string  `json:"role"`
+	Relationship *string `json:"relationship,omitempty"`
+}
+
+// AcceptChildInvitationRequest accepts an invitation with the code received on WhatsApp
+type AcceptChildInvitationRequest struct {
+	Code string `json:"code"`
+}
+
+// UpdateChildGuardianRequest changes the role of a caregiver
+type UpdateChildGuardianRequest struct {
+	Role         string  `json:"role"`
+	Relationship *string `json:"relationship,omitempty"`
+}
+
+// TransferChildOwnershipRequest makes another caregiver the owner of the child
+type TransferChildOwnershipRequest struct {
+	UserID string `json:"user_id"`
+}
@@ -0,0 +1,117 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// HealthProgramme is a master health programme item: Vitamin A, deworming or another supplement.
+// Campaign programmes are given in fixed calendar months; age programmes at an age, optionally
+// repeated every IntervalMonths.
+type HealthProgramme struct {
+	ID             string        `json:"id" db:"id"`
+	Code           string        `json:"code" db:"code"`
+	Name           string        `json:"name" db:"name"`
+	NameID         *string       `json:"name_id,omitempty" db:"name_id"`
+	Description    *string       `json:"description,omitempty" db:"description"`
+	ProgrammeType  string        `json:"programme_type" db:"programme_type"` // vitamin_a, deworming, supplement
+	ScheduleType   string        `json:"schedule_type" db:"schedule_type"`   // campaign, age
+	AgeMinMonths   int           `json:"age_min_months" db:"age_min_months"`
+	AgeMaxMonths   int           `json:"age_max_months" db:"age_max_months"`
+	CampaignMonths pq.Int64Array `json:"campaign_months,omitempty" db:"campaign_months"`
+	IntervalMonths *int          `json:"interval_months,omitempty" db:"interval_months"`
+	Dosage         *string       `json:"dosage,omitempty" db:"dosage"`
+	Source         *string       `json:"source,omitempty" db:"source"`
+	SortOrder      int           `json:"sort_order" db:"sort_order"`
+	IsActive       bool          `json:"is_active" db:"is_active"`
+	CreatedAt      time.Time     `json:"created_at" db:"created_at"`
+	UpdatedAt      time.Time     `json:"updated_at" db:"updated_at"`
+}
+
+// HealthProgrammeRequest creates or updates a health programme (admin)
+type HealthProgrammeRequest struct {
+	Code           string  `json:"code"`
+	Name           string  `json:"name"`
+	NameID         *string `json:"name_id,omitempty"`
+	Description    *string `json:"description,omitempty"`
+	ProgrammeType  string  `json:"programme_type"`
+	ScheduleType   string  `json:"schedule_type"`
+	AgeMinMonths   int     `json:"age_min_months"`
+	AgeMaxMonths   int     `json:"age_max_months"`
+	CampaignMonths []int64 `json:"campaign_months,omitempty"`
+	IntervalMonths *int    `json:"interval_months,omitempty"`
+	Dosage         *string `json:"dosage,omitempty"`
+	Source         *string `json:"source,omitempty"`
+	SortOrder      int     `json:"sort_order"`
+	IsActive       *bool   `json:"is_active,omitempty"`
+}
+
+// ChildHealthProgrammeDose is a recorded Vitamin A, deworming or supplement dose
+type ChildHealthProgrammeDose struct {
+	ID                 string           `json:"id" db:"id"`
+	ChildID            string           `json:"child_id" db:"child_id"`
+	ProgrammeID        string           `json:"programme_id" db:"programme_id"`
+	GivenDate          string           `json:"given_date" db:"given_date"` // YYYY-MM-DD
+	GivenAtAgeMonths   *int             `json:"given_at_age_months,omitempty" db:"given_at_age_months"`
+	Location           *string          `json:"location,omitempty" db:"location"`
+	HealthcareFacility *string          `json:"healthcare_facility,omitempty" db:"healthcare_facility"`
+	Notes              *string          `json:"notes,omitempty" db:"notes"`
+	CreatedAt          time.Time        `json:"created_at" db:"created_at"`
+	UpdatedAt          time.Time        `json:"updated_at" db:"updated_at"`
+	Programme          *HealthProgramme `json:"programme,omitempty" db:"-"`
+}
+
+// HealthProgrammeDoseRequest records a health programme dose
+type HealthProgrammeDoseRequest struct {
+	ProgrammeID        string  `json:"programme_id"`
+	GivenDate          string  `json:"given_date"` // YYYY-MM-DD
+	Location           *string `json:"location,omitempty"`
+	HealthcareFacility *string `json:"healthcare_facility,omitempty"`
+	Notes              *string `json:"notes,omitempty"`
+}
+
+// HealthProgrammeSlot is one dose of a programme the child is eligible for: a campaign month
+// or an age window
+type HealthProgrammeSlot struct {
+	Number      int                       `json:"number"`
+	WindowStart string                    `json:"window_start"` // YYYY-MM-DD
+	WindowEnd   string                    `json:"window_end"`   // YYYY-MM-DD
+	Status      string                    `json:"status"`       // completed, due, upcoming, missed
+	Dose        *ChildHealthProgrammeDose `json:"dose,omitempty"`
+}
+
+// HealthProgrammeStatus is the schedule of one programme for a child
+type HealthProgrammeStatus struct {
+	Programme HealthProgramme       `json:"programme"`
+	Slots     []HealthProgrammeSlot `json:"slots"`
+	NextSlot  *HealthProgrammeSlot  `json:"next_slot,omitempty"`
+}
+
+// HealthProgrammeSummary counts the slots of all programmes by status
+type HealthProgrammeSummary struct {
+	Completed int `json:"completed"`
+	Due       int `json:"due"`
+	Upcoming  int `json:"upcoming"`
+	Missed    int `json:"missed"`
+}
+
+// HealthProgrammeScheduleResponse is the health programme schedule of a child
+type HealthProgrammeScheduleResponse struct {
+	ChildID    string                     `json:"child_id"`
+	AgeMonths  int                        `json:"age_months"`
+	Programmes []HealthProgrammeStatus    `json:"programmes"`
+	Records    []ChildHealthProgrammeDose `json:"records"`
+	Summary    HealthProgrammeSummary     `json:"summary"`
+}
+
+// HealthProgrammeCoverage is the coverage of a programme's latest round (admin analytics)
+type HealthProgrammeCoverage struct {
+	ProgrammeID   string  `json:"programme_id"`
+	Code          string  `json:"code"`
+	Name          string  `json:"name"`
+	Eligible      int     `json:"eligible"`
+	Covered       int     `json:"covered"`
+	CoverageRate  float64 `json:"coverage_rate"` // Percentage
+	DosesLast12Mo int     `json:"doses_last_12_months"`
+}
@@ -64,6 +64,13 @@ type ChildImmunization struct {
 	// Status
 	IsOnSchedule            *bool     `json:"is_on_schedule,omitempty" db:"is_on_schedule"`
 	IsCatchUp               bool      `json:"is_catch_up" db:"is_catch_up"`
+	FromCatchUpPlan         bool      `json:"from_catch_up_plan" db:"from_catch_up_plan"` // Recorded from the catch-up plan
+	
+	// Validity: invalid doses (e.g. given too early) do not count towards the schedule
+	IsValid                 bool      `json:"is_valid" db:"is_valid"`
+	InvalidReason           *string   `json:"invalid_reason,omitempty" db:"invalid_reason"`
+	InvalidatedBy           *string   `json:"invalidated_by,omitempty" db:"invalidated_by"`
+	InvalidatedAt           *time.Time `json:"invalidated_at,omitempty" db:"invalidated_at"`
 	
 	CreatedAt               time.Time `json:"created_at" db:"created_at"`
 	UpdatedAt               time.Time `json:"updated_at" db:"updated_at"`
@@ -81,6 +88,25 @@ type ImmunizationRecordRequest struct {
 	DoctorName             *string `json:"doctor_name,omitempty"`
 	VaccineBatchNumber     *string `json:"vaccine_batch_number,omitempty"`
 	Notes                  *string `json:"notes,omitempty"`
+	FromCatchUpPlan        bool    `json:"from_catch_up_plan,omitempty"` // Recorded from the catch-up plan
+}
+
+// ImmunizationRecordUpdateRequest is the payload for editing an immunization record.
+// Omitted fields keep their current value.
+type ImmunizationRecordUpdateRequest struct {
+	ImmunizationScheduleID *string `json:"immunization_schedule_id,omitempty"`
+	GivenDate              *string `json:"given_date,omitempty"` // YYYY-MM-DD
+	Location               *string `json:"location,omitempty"`
+	HealthcareFacility     *string `json:"healthcare_facility,omitempty"`
+	DoctorName             *string `json:"doctor_name,omitempty"`
+	VaccineBatchNumber     *string `json:"vaccine_batch_number,omitempty"`
+	Notes                  *string `json:"notes,omitempty"`
+}
+
+// ImmunizationValidityRequest marks an immunization record invalid (with a reason) or valid again
+type ImmunizationValidityRequest struct {
+	IsValid bool    `json:"is_valid"`
+	Reason  *string `json:"reason,omitempty"`
 }
 
 // ImmunizationStatus represents the status of an immunization for a child
@@ -92,6 +118,16 @@ type ImmunizationStatus struct {
 	DaysUntilDue            *int                 `json:"days_until_due,omitempty"`
 	DaysOverdue             *int                 `json:"days_overdue,omitempty"`
 	
+	// Why the dose is due on its date (optimal age, minimum age or interval after the previous dose)
+	EarliestDate            *string              `json:"earliest_date,omitempty"` // YYYY-MM-DD
+	DueReason               string               `json:"due_reason,omitempty"`
+	DueExplanation          string               `json:"due_explanation,omitempty"`
+	PreviousDoseDate        *string              `json:"previous_dose_date,omitempty"` // YYYY-MM-DD
+	WaitingForPreviousDose  bool                 `json:"waiting_for_previous_dose,omitempty"`
+	
+	// Set if a serious KIPI requires clinician review before this dose
+	ReviewFlag              *ImmunizationReviewFlag `json:"review_flag,omitempty"`
+	
 	// If completed
 	Record                  *ChildImmunization   `json:"record,omitempty"`
 }
@@ -103,6 +139,9 @@ type ImmunizationScheduleResponse struct {
 	AgeDays       int                  `json:"age_days"`
 	Immunizations []ImmunizationStatus `json:"immunizations"`
 	Summary       ImmunizationSummary  `json:"summary"`
+
+	// Vitamin A, deworming and supplement programmes
+	HealthProgrammes []HealthProgrammeStatus `json:"health_programmes"`
 }
 
 // ImmunizationSummary contains summary statistics
@@ -114,3 +153,42 @@ type ImmunizationSummary struct {
 	Upcoming  int `json:"upcoming"`
 }
 
+
+// CatchUpDose is a dose planned in a catch-up schedule
+type CatchUpDose struct {
+	Schedule     ImmunizationSchedule `json:"schedule"`
+	Date         string               `json:"date"`          // YYYY-MM-DD
+	EarliestDate string               `json:"earliest_date"` // Earliest valid date (minimum age and interval)
+	MaxDate      *string              `json:"max_date,omitempty"`
+	IsCatchUp    bool                 `json:"is_catch_up"`
+	Reason       string               `json:"reason"`
+	Explanation  string               `json:"explanation"`
+
+	// Set if a serious KIPI requires clinician review before this dose
+	ReviewFlag *ImmunizationReviewFlag `json:"review_flag,omitempty"`
+}
+
+// CatchUpVisit groups the doses that can be given together on the same visit
+type CatchUpVisit struct {
+	VisitNumber int           `json:"visit_number"`
+	Date        string        `json:"date"` // YYYY-MM-DD
+	Doses       []CatchUpDose `json:"doses"`
+}
+
+// CatchUpMissedDose is a dose that can no longer be given because the child is past its maximum age
+type CatchUpMissedDose struct {
+	Schedule    ImmunizationSchedule `json:"schedule"`
+	MaxDate     string               `json:"max_date"` // YYYY-MM-DD
+	Explanation string               `json:"explanation"`
+}
+
+// CatchUpPlan is the catch-up immunization plan of a child
+type CatchUpPlan struct {
+	ChildID   string              `json:"child_id"`
+	AgeMonths int                 `json:"age_months"`
+	AgeDays   int                 `json:"age_days"`
+	NextVisit *CatchUpVisit       `json:"next_visit,omitempty"`
+	Visits    []CatchUpVisit      `json:"visits"`
+	Missed    []CatchUpMissedDose `json:"missed"`
+	Notes     []string            `json:"notes"`
+}
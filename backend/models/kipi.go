@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// KIPIReport is an adverse event following immunization (Kejadian Ikutan Pasca Imunisasi)
+type KIPIReport struct {
+	ID                     string         `json:"id" db:"id"`
+	ChildImmunizationID    string         `json:"child_immunization_id" db:"child_immunization_id"`
+	ChildID                string         `json:"child_id" db:"child_id"`
+	ImmunizationScheduleID string         `json:"immunization_schedule_id" db:"immunization_schedule_id"`
+	ReportedBy             *string        `json:"reported_by,omitempty" db:"reported_by"`
+	OnsetAt                time.Time      `json:"onset_at" db:"onset_at"`
+	Symptoms               pq.StringArray `json:"symptoms" db:"symptoms"`
+	Severity               string         `json:"severity" db:"severity"` // mild, moderate, serious
+	Outcome                string         `json:"outcome" db:"outcome"`   // recovering, recovered, recovered_with_sequelae, hospitalized, died, unknown
+	VaccineBatchNumber     *string        `json:"vaccine_batch_number,omitempty" db:"vaccine_batch_number"`
+	IsSerious              bool           `json:"is_serious" db:"is_serious"`
+	Notes                  *string        `json:"notes,omitempty" db:"notes"`
+	CreatedAt              time.Time      `json:"created_at" db:"created_at"`
+	UpdatedAt              time.Time      `json:"updated_at" db:"updated_at"`
+}
+
+// KIPIReportRequest is the payload for reporting an adverse event
+type KIPIReportRequest struct {
+	OnsetAt            string   `json:"onset_at" validate:"required"` // RFC 3339 or YYYY-MM-DD
+	Symptoms           []string `json:"symptoms" validate:"required"`
+	Severity           string   `json:"severity" validate:"required"`
+	Outcome            string   `json:"outcome" validate:"required"`
+	VaccineBatchNumber *string  `json:"vaccine_batch_number,omitempty"`
+	Notes              *string  `json:"notes,omitempty"`
+}
+
+// ImmunizationReviewFlag marks a later dose that needs clinician review after a serious KIPI
+type ImmunizationReviewFlag struct {
+	ID                     string     `json:"id" db:"id"`
+	ChildID                string     `json:"child_id" db:"child_id"`
+	ImmunizationScheduleID string     `json:"immunization_schedule_id" db:"immunization_schedule_id"`
+	KIPIReportID           string     `json:"kipi_report_id" db:"kipi_report_id"`
+	Reason                 string     `json:"reason" db:"reason"`
+	Status                 string     `json:"status" db:"status"` // pending, cleared, contraindicated
+	ReviewedBy             *string    `json:"reviewed_by,omitempty" db:"reviewed_by"`
+	ReviewedAt             *time.Time `json:"reviewed_at,omitempty" db:"reviewed_at"`
+	ReviewNotes            *string    `json:"review_notes,omitempty" db:"review_notes"`
+	CreatedAt              time.Time  `json:"created_at" db:"created_at"`
+}
+
+// ReviewImmunizationFlagRequest is the clinician decision on a review flag
+type ReviewImmunizationFlagRequest struct {
+	Status      string  `json:"status" validate:"required"` // cleared, contraindicated
+	ReviewNotes *string `json:"review_notes,omitempty"`
+}
+
+// KIPICluster groups reports of the same vaccine and batch number
+type KIPICluster struct {
+	VaccineName        string    `json:"vaccine_name" db:"vaccine_name"`
+	VaccineBatchNumber *string   `json:"vaccine_batch_number" db:"vaccine_batch_number"`
+	ReportCount        int       `json:"report_count" db:"report_count"`
+	SeriousCount       int       `json:"serious_count" db:"serious_count"`
+	ChildCount         int       `json:"child_count" db:"child_count"`
+	FirstOnsetAt       time.Time `json:"first_onset_at" db:"first_onset_at"`
+	LastOnsetAt        time.Time `json:"last_onset_at" db:"last_onset_at"`
+}
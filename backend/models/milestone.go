@@ -6,18 +6,21 @@ import (
 
 // Milestone represents a developmental milestone checklist item
 type Milestone struct {
-	ID           string    `json:"id" db:"id"`
-	AgeMonths    int       `json:"age_months" db:"age_months"`
-	MinAgeRange  *int      `json:"min_age_range,omitempty" db:"min_age_range"`
-	MaxAgeRange  *int      `json:"max_age_range,omitempty" db:"max_age_range"`
-	Category     string    `json:"category" db:"category"` // sensory, motor, perception, cognitive
-	Question     string    `json:"question" db:"question"`
-	QuestionEn   string    `json:"question_en,omitempty" db:"question_en"`
-	Source       string    `json:"source" db:"source"`
-	IsRedFlag    bool      `json:"is_red_flag" db:"is_red_flag"`
-	PyramidLevel int       `json:"pyramid_level" db:"pyramid_level"` // 1-4
-	DenverDomain *string   `json:"denver_domain,omitempty" db:"denver_domain"` // PS, FM, L, GM (Denver II)
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
+	ID            string    `json:"id" db:"id"`
+	AgeMonths     int       `json:"age_months" db:"age_months"`
+	MinAgeRange   *int      `json:"min_age_range,omitempty" db:"min_age_range"`
+	MaxAgeRange   *int      `json:"max_age_range,omitempty" db:"max_age_range"`
+	Category      string    `json:"category" db:"category"` // sensory, motor, perception, cognitive
+	Question      string    `json:"question" db:"question"`
+	QuestionEn    string    `json:"question_en,omitempty" db:"question_en"`
+	Source        string    `json:"source" db:"source"`
+	IsRedFlag     bool      `json:"is_red_flag" db:"is_red_flag"`
+	PyramidLevel  int       `json:"pyramid_level" db:"pyramid_level"`               // 1-4
+	DenverDomain  *string   `json:"denver_domain,omitempty" db:"denver_domain"`     // PS, FM, L, GM (Denver II)
+	ContentPackID *string   `json:"content_pack_id,omitempty" db:"content_pack_id"` // Content pack version this milestone belongs to
+	PackKey       *string   `json:"pack_key,omitempty" db:"pack_key"`               // Stable item key across pack versions
+	IsActive      bool      `json:"is_active" db:"is_active"`                       // False for milestones of inactive pack versions
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
 }
 
 // Assessment represents a user's answer to a milestone
@@ -50,10 +53,13 @@ type BatchAssessmentRequest struct {
 
 // AssessmentSummary contains progress data for the dashboard
 type AssessmentSummary struct {
-	TotalMilestones    int                    `json:"total_milestones"`
-	CompletedMilestones int                   `json:"completed_milestones"`
-	ProgressByCategory map[string]float64     `json:"progress_by_category"` // category -> percentage
-	RedFlagsDetected   []Milestone            `json:"red_flags_detected"`
-	PyramidWarnings    []string               `json:"pyramid_warnings"`
-	NextMilestones     []Milestone            `json:"next_milestones"`
+	TotalMilestones       int                  `json:"total_milestones"`
+	CompletedMilestones   int                  `json:"completed_milestones"`
+	ProgressByCategory    map[string]float64   `json:"progress_by_category"` // category -> percentage
+	RedFlagsDetected      []Milestone          `json:"red_flags_detected"`
+	PyramidWarnings       []string             `json:"pyramid_warnings"`
+	PyramidWarningDetails []PyramidWarning     `json:"pyramid_warning_details"`
+	NextMilestones        []Milestone          `json:"next_milestones"`
+	AgeMonths             int                  `json:"age_months"`
+	AgeBasis              string               `json:"age_basis"` // chronological, corrected
 }
@@ -0,0 +1,63 @@
+package models
+
+import "time"
+
+// NotificationTemplate is a stored, localized message template. Subject and Body use
+// Go text/template syntax, e.g. "Kode OTP Anda: {{.Code}}".
+type NotificationTemplate struct {
+	ID        string    `json:"id" db:"id"`
+	Key       string    `json:"key" db:"key"`           // e.g. otp_code
+	Channel   string    `json:"channel" db:"channel"`   // whatsapp, email, sms
+	Language  string    `json:"language" db:"language"` // id, en, jv, su
+	Subject   *string   `json:"subject,omitempty" db:"subject"`
+	Body      string    `json:"body" db:"body"`
+	IsActive  bool      `json:"is_active" db:"is_active"`
+	UpdatedBy *string   `json:"updated_by,omitempty" db:"updated_by"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// NotificationTemplateRequest creates or replaces a template for a key, channel and language
+type NotificationTemplateRequest struct {
+	Key      string  `json:"key"`
+	Channel  string  `json:"channel"`
+	Language string  `json:"language"`
+	Subject  *string `json:"subject,omitempty"`
+	Body     string  `json:"body"`
+	IsActive *bool   `json:"is_active,omitempty"`
+}
+
+// TestNotificationRequest sends a rendered template to a recipient (admin)
+type TestNotificationRequest struct {
+	Channel  string                 `json:"channel"`
+	To       string                 `json:"to"`
+	Key      string                 `json:"key"`
+	Language string                 `json:"language"`
+	Data     map[string]interface{} `json:"data"`
+}
+
+// NotificationPreferences are a parent's reminder opt-outs and quiet hours. Empty quiet hours
+// and time zone use the system defaults.
+type NotificationPreferences struct {
+	UserID                string    `json:"user_id" db:"user_id"`
+	ImmunizationReminders bool      `json:"immunization_reminders" db:"immunization_reminders"`
+	MeasurementReminders  bool      `json:"measurement_reminders" db:"measurement_reminders"`
+	MonthlyDigest         bool      `json:"monthly_digest" db:"monthly_digest"`
+	DigestChannel         string    `json:"digest_channel" db:"digest_channel"`                 // whatsapp, email
+	QuietHoursStart       *string   `json:"quiet_hours_start,omitempty" db:"quiet_hours_start"` // HH:MM
+	QuietHoursEnd         *string   `json:"quiet_hours_end,omitempty" db:"quiet_hours_end"`     // HH:MM
+	Timezone              *string   `json:"timezone,omitempty" db:"timezone"`                   // e.g. Asia/Makassar
+	UpdatedAt             time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// NotificationPreferencesRequest updates notification preferences. Omitted fields keep their
+// current value; an empty string clears a quiet hours or time zone override.
+type NotificationPreferencesRequest struct {
+	ImmunizationReminders *bool   `json:"immunization_reminders,omitempty"`
+	MeasurementReminders  *bool   `json:"measurement_reminders,omitempty"`
+	MonthlyDigest         *bool   `json:"monthly_digest,omitempty"`
+	DigestChannel         *string `json:"digest_channel,omitempty"`
+	QuietHoursStart       *string `json:"quiet_hours_start,omitempty"`
+	QuietHoursEnd         *string `json:"quiet_hours_end,omitempty"`
+	Timezone              *string `json:"timezone,omitempty"`
+}
@@ -0,0 +1,49 @@
+package models
+
+import "time"
+
+// OutboundMessage is a notification in the outbox, delivered by the background workers
+type OutboundMessage struct {
+	ID                string     `json:"id" db:"id"`
+	Channel           string     `json:"channel" db:"channel"` // whatsapp, email, sms
+	Recipient         string     `json:"recipient" db:"recipient"`
+	TemplateKey       *string    `json:"template_key,omitempty" db:"template_key"`
+	Subject           *string    `json:"subject,omitempty" db:"subject"`
+	Body              *string    `json:"body,omitempty" db:"body"`           // Hidden for sensitive messages
+	HTMLBody          *string    `json:"html_body,omitempty" db:"html_body"` // Email only
+	IsSensitive       bool       `json:"is_sensitive" db:"is_sensitive"`
+	Status            string     `json:"status" db:"status"` // pending, sending, sent, delivered, read, failed, dead
+	Attempts          int        `json:"attempts" db:"attempts"`
+	MaxAttempts       int        `json:"max_attempts" db:"max_attempts"`
+	NextAttemptAt     *time.Time `json:"next_attempt_at,omitempty" db:"next_attempt_at"`
+	LockedUntil       *time.Time `json:"-" db:"locked_until"`
+	ExpiresAt         *time.Time `json:"expires_at,omitempty" db:"expires_at"`
+	LastError         *string    `json:"last_error,omitempty" db:"last_error"`
+	ProviderMessageID *string    `json:"provider_message_id,omitempty" db:"provider_message_id"`
+
+	// Email attachment, stored in the object store
+	AttachmentKey         *string `json:"-" db:"attachment_key"`
+	AttachmentFilename    *string `json:"attachment_filename,omitempty" db:"attachment_filename"`
+	AttachmentContentType *string `json:"attachment_content_type,omitempty" db:"attachment_content_type"`
+
+	SentAt      *time.Time `json:"sent_at,omitempty" db:"sent_at"`
+	DeliveredAt *time.Time `json:"delivered_at,omitempty" db:"delivered_at"`
+	CreatedAt   time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// OutboundMessageEvent is one entry of a message's delivery history
+type OutboundMessageEvent struct {
+	ID        string    `json:"id" db:"id"`
+	MessageID string    `json:"message_id" db:"message_id"`
+	Status    string    `json:"status" db:"status"`
+	Detail    *string   `json:"detail,omitempty" db:"detail"`
+	CreatedAt time.Time `json:"created_at" db:"created_at"`
+}
+
+// DeliveryStatusCallback is the payload gateways post to report a delivery status
+type DeliveryStatusCallback struct {
+	MessageID string `json:"message_id"` // Provider message ID, or the outbox ID if the provider echoes it
+	Status    string `json:"status"`     // sent, delivered, read, failed
+	Error     string `json:"error,omitempty"`
+}
@@ -0,0 +1,36 @@
+package models
+
+import (
+	"time"
+)
+
+// PyramidRuleCondition is a single comparison on a level or category score
+type PyramidRuleCondition struct {
+	Scope    string  `json:"scope"`    // level, category
+	Target   string  `json:"target"`   // "1"-"4" for level, category name for category
+	Operator string  `json:"operator"` // <, <=, >, >=
+	Value    float64 `json:"value"`    // percentage 0-100
+}
+
+// PyramidWarningRule represents a configurable pyramid imbalance rule
+type PyramidWarningRule struct {
+	ID          string                 `json:"id" db:"id"`
+	Name        string                 `json:"name" db:"name"`
+	Description *string                `json:"description,omitempty" db:"description"`
+	Conditions  []PyramidRuleCondition `json:"conditions" db:"-"`      // All conditions must match
+	Severity    string                 `json:"severity" db:"severity"` // info, warning, critical
+	Message     string                 `json:"message" db:"message"`
+	MessageEn   *string                `json:"message_en,omitempty" db:"message_en"`
+	IsActive    bool                   `json:"is_active" db:"is_active"`
+	SortOrder   int                    `json:"sort_order" db:"sort_order"`
+	CreatedAt   time.Time              `json:"created_at" db:"created_at"`
+	UpdatedAt   time.Time              `json:"updated_at" db:"updated_at"`
+}
+
+// PyramidWarning is a triggered rule in an assessment summary
+type PyramidWarning struct {
+	RuleID   string `json:"rule_id"`
+	Name     string `json:"name"`
+	Severity string `json:"severity"`
+	Message  string `json:"message"`
+}
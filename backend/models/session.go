@@ -0,0 +1,38 @@
+package models
+
+import "time"
+
+// UserSession is a login session of a user
+type UserSession struct {
+	ID                string     `json:"id" db:"id"`
+	UserID            string     `json:"user_id" db:"user_id"`
+	RefreshTokenHash  string     `json:"-" db:"refresh_token_hash"`
+	PreviousTokenHash *string    `json:"-" db:"previous_token_hash"`
+	IsAdmin           bool       `json:"is_admin" db:"is_admin"`
+	TwoFactorVerified bool       `json:"two_factor_verified" db:"two_factor_verified"`
+	UserAgent         *string    `json:"user_agent,omitempty" db:"user_agent"`
+	IPAddress         *string    `json:"ip_address,omitempty" db:"ip_address"` // Last seen
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
+	LastUsedAt        time.Time  `json:"last_used_at" db:"last_used_at"`
+	ExpiresAt         time.Time  `json:"expires_at" db:"expires_at"`
+	RevokedAt         *time.Time `json:"revoked_at,omitempty" db:"revoked_at"`
+	RevokedReason     *string    `json:"revoked_reason,omitempty" db:"revoked_reason"`
+	Current           bool       `json:"current" db:"-"` // Session of the requesting token
+}
+
+// RefreshTokenRequest exchanges a refresh token for a new access and refresh token
+type RefreshTokenRequest struct {
+	RefreshToken string `json:"refresh_token" validate:"required"`
+}
+
+// LoginExchangeRequest redeems the single-use code of a Google login redirect
+type LoginExchangeRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// TokenResponse is a refreshed token pair
+type TokenResponse struct {
+	Token        string `json:"token"`
+	RefreshToken string `json:"refresh_token"`
+	ExpiresIn    int    `json:"expires_in"`
+}
@@ -6,20 +6,21 @@ import (
 
 // StimulationContent represents a piece of stimulation content (video/article)
 type StimulationContent struct {
-	ID           string    `json:"id" db:"id"`
-	MilestoneID  *string   `json:"milestone_id,omitempty" db:"milestone_id"` // Optional: link to specific milestone
-	Category     string    `json:"category" db:"category"`                    // sensory, motor, perception, cognitive
-	Title        string    `json:"title" db:"title"`
-	Description  string    `json:"description,omitempty" db:"description"`
-	ContentType  string    `json:"content_type" db:"content_type"` // video, article
-	URL          string    `json:"url" db:"url"`
-	ThumbnailURL *string   `json:"thumbnail_url,omitempty" db:"thumbnail_url"`
-	AgeMinMonths *int      `json:"age_min_months,omitempty" db:"age_min_months"`
-	AgeMaxMonths *int      `json:"age_max_months,omitempty" db:"age_max_months"`
-	IsActive     bool      `json:"is_active" db:"is_active"`
-	CreatedAt    time.Time `json:"created_at" db:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at" db:"updated_at"`
-	
+	ID            string    `json:"id" db:"id"`
+	MilestoneID   *string   `json:"milestone_id,omitempty" db:"milestone_id"` // Optional: link to specific milestone
+	Category      string    `json:"category" db:"category"`                   // sensory, motor, perception, cognitive
+	Title         string    `json:"title" db:"title"`
+	Description   string    `json:"description,omitempty" db:"description"`
+	ContentType   string    `json:"content_type" db:"content_type"` // video, article
+	URL           string    `json:"url" db:"url"`
+	ThumbnailURL  *string   `json:"thumbnail_url,omitempty" db:"thumbnail_url"`
+	AgeMinMonths  *int      `json:"age_min_months,omitempty" db:"age_min_months"`
+	AgeMaxMonths  *int      `json:"age_max_months,omitempty" db:"age_max_months"`
+	IsActive      bool      `json:"is_active" db:"is_active"`
+	ContentPackID *string   `json:"content_pack_id,omitempty" db:"content_pack_id"` // Set for content imported with a content pack
+	CreatedAt     time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt     time.Time `json:"updated_at" db:"updated_at"`
+
 	// Joined fields for API responses
 	Milestone *Milestone `json:"milestone,omitempty" db:"-"`
 }
@@ -36,6 +37,7 @@ type Recommendation struct {
 type RecommendationsResponse struct {
 	ChildID       string          `json:"child_id"`
 	AgeMonths     int             `json:"age_months"`
+	AgeBasis      string          `json:"age_basis"` // chronological, corrected
 	Recommendations []Recommendation `json:"recommendations"`
 }
 
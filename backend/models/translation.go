@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+)
+
+// Translation is a translated text of a field of a milestone, stimulation content,
+// immunization schedule or pyramid warning rule
+type Translation struct {
+	ID         string    `json:"id" db:"id"`
+	EntityType string    `json:"entity_type" db:"entity_type"` // milestone, stimulation_content, immunization_schedule, pyramid_rule
+	EntityID   string    `json:"entity_id" db:"entity_id"`
+	Field      string    `json:"field" db:"field"`       // question, title, description, name, message
+	Language   string    `json:"language" db:"language"` // en, jv, su
+	Value      string    `json:"value" db:"value"`
+	CreatedAt  time.Time `json:"created_at" db:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at" db:"updated_at"`
+}
+
+// UpsertTranslationRequest creates or replaces a translation
+type UpsertTranslationRequest struct {
+	EntityType string `json:"entity_type" validate:"required"`
+	EntityID   string `json:"entity_id" validate:"required"`
+	Field      string `json:"field" validate:"required"`
+	Language   string `json:"language" validate:"required"`
+	Value      string `json:"value" validate:"required"`
+}
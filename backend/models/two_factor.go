@@ -0,0 +1,82 @@
+package models
+
+import (
+	"time"
+)
+
+// UserTwoFactor is the TOTP enrollment of a user
+type UserTwoFactor struct {
+	UserID          string     `json:"user_id" db:"user_id"`
+	SecretEncrypted string     `json:"-" db:"secret_encrypted"`
+	EnabledAt       *time.Time `json:"enabled_at,omitempty" db:"enabled_at"` // Nil until the enrollment is confirmed
+	LastUsedStep    int64      `json:"-" db:"last_used_step"`
+	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at" db:"updated_at"`
+}
+
+// TwoFactorChallenge is a login waiting for the two-factor code
+type TwoFactorChallenge struct {
+	ID           string     `json:"id" db:"id"`
+	UserID       string     `json:"user_id" db:"user_id"`
+	TokenHash    string     `json:"-" db:"token_hash"`
+	LoginMethod  string     `json:"login_method" db:"login_method"` // 'password', 'otp', 'admin_otp' or 'google'
+	IsAdmin      bool       `json:"is_admin" db:"is_admin"`
+	AttemptCount int        `json:"attempt_count" db:"attempt_count"`
+	ExpiresAt    time.Time  `json:"expires_at" db:"expires_at"`
+	CompletedAt  *time.Time `json:"completed_at,omitempty" db:"completed_at"`
+	CreatedAt    time.Time  `json:"created_at" db:"created_at"`
+}
+
+// TwoFactorChallengeResponse is returned instead of a token when the login needs a second factor.
+// With enrollment_required the user first sets up an authenticator app (/api/auth/2fa/setup).
+type TwoFactorChallengeResponse struct {
+	TwoFactorRequired  bool   `json:"two_factor_required"`
+	EnrollmentRequired bool   `json:"enrollment_required"`
+	ChallengeToken     string `json:"challenge_token"`
+	ExpiresIn          int    `json:"expires_in"` // Seconds until the challenge expires
+}
+
+// TwoFactorChallengeRequest identifies the challenge of a login
+type TwoFactorChallengeRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+}
+
+// TwoFactorVerifyRequest answers a challenge with a TOTP code or a recovery code
+type TwoFactorVerifyRequest struct {
+	ChallengeToken string `json:"challenge_token" validate:"required"`
+	Code           string `json:"code,omitempty"`
+	RecoveryCode   string `json:"recovery_code,omitempty"`
+}
+
+// TwoFactorSetupResponse holds the new secret of an enrollment, as text and as QR code
+type TwoFactorSetupResponse struct {
+	Secret     string `json:"secret"`
+	OTPAuthURL string `json:"otpauth_url"`
+	QRCode     string `json:"qr_code"` // PNG data URI of otpauth_url
+}
+
+// TwoFactorEnrollResponse completes the login that confirmed an enrollment. The recovery codes
+// are only shown once.
+type TwoFactorEnrollResponse struct {
+	AuthResponse
+	RecoveryCodes []string `json:"recovery_codes"`
+}
+
+// TwoFactorStatus is the two-factor authentication state of a user
+type TwoFactorStatus struct {
+	Enabled                bool       `json:"enabled"`
+	EnabledAt              *time.Time `json:"enabled_at,omitempty"`
+	RecoveryCodesRemaining int        `json:"recovery_codes_remaining"`
+}
+
+// RegenerateRecoveryCodesRequest confirms new recovery codes with a current TOTP code
+type RegenerateRecoveryCodesRequest struct {
+	Code string `json:"code" validate:"required"`
+}
+
+// ResetTwoFactorRequest removes another admin's two-factor authentication, e.g. after a lost
+// phone. The acting admin confirms with their own TOTP code.
+type ResetTwoFactorRequest struct {
+	Code   string `json:"code" validate:"required"`
+	Reason string `json:"reason" validate:"required"`
+}
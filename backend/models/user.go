@@ -5,17 +5,18 @@ import (
 )
 
 type User struct {
-	ID              string     `json:"id" db:"id"`
-	Email           string     `json:"email" db:"email"`
-	PhoneNumber     *string    `json:"phone_number,omitempty" db:"phone_number"`
-	PasswordHash    string     `json:"-" db:"password_hash"`
-	FullName        string     `json:"full_name" db:"full_name"`
-	Role            string     `json:"role" db:"role"`
-	GoogleID        *string    `json:"-" db:"google_id"`
-	AuthProvider    string     `json:"auth_provider" db:"auth_provider"`
-	PhoneVerified   bool       `json:"phone_verified" db:"phone_verified"`
-	PhoneVerifiedAt *time.Time `json:"phone_verified_at,omitempty" db:"phone_verified_at"`
-	CreatedAt       time.Time  `json:"created_at" db:"created_at"`
+	ID                string     `json:"id" db:"id"`
+	Email             string     `json:"email" db:"email"`
+	PhoneNumber       *string    `json:"phone_number,omitempty" db:"phone_number"`
+	PasswordHash      string     `json:"-" db:"password_hash"`
+	FullName          string     `json:"full_name" db:"full_name"`
+	Role              string     `json:"role" db:"role"`
+	GoogleID          *string    `json:"-" db:"google_id"`
+	AuthProvider      string     `json:"auth_provider" db:"auth_provider"`
+	PhoneVerified     bool       `json:"phone_verified" db:"phone_verified"`
+	PhoneVerifiedAt   *time.Time `json:"phone_verified_at,omitempty" db:"phone_verified_at"`
+	PreferredLanguage *string    `json:"preferred_language,omitempty" db:"preferred_language"` // id, en, jv, su
+	CreatedAt         time.Time  `json:"created_at" db:"created_at"`
 }
 
 type RegisterRequest struct {
@@ -29,9 +30,11 @@ type LoginRequest struct {
 }
 
 type AuthResponse struct {
-	Token     string `json:"token"`
-	User      User   `json:"user"`
-	IsNewUser bool   `json:"is_new_user,omitempty"`
+	Token        string `json:"token"`                   // Short-lived access token
+	RefreshToken string `json:"refresh_token,omitempty"` // Exchanged at /api/auth/refresh for a new token pair
+	ExpiresIn    int    `json:"expires_in,omitempty"`    // Seconds until the access token expires
+	User         User   `json:"user"`
+	IsNewUser    bool   `json:"is_new_user,omitempty"`
 }
 
 // OTP Request Models
@@ -54,8 +57,9 @@ type OTPResponse struct {
 
 // Profile Request Models
 type UpdateProfileRequest struct {
-	FullName string `json:"full_name,omitempty"`
-	Email    string `json:"email,omitempty"`
+	FullName          string `json:"full_name,omitempty"`
+	Email             string `json:"email,omitempty"`
+	PreferredLanguage string `json:"preferred_language,omitempty"` // id, en, jv, su
 }
 
 type VerifyPhoneRequest struct {
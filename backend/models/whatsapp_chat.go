@@ -0,0 +1,23 @@
+package models
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// WhatsAppInboundMessage is the payload the WhatsApp gateway posts for an incoming message
+type WhatsAppInboundMessage struct {
+	MessageID string `json:"message_id"` // Gateway message ID, used to ignore redelivered webhooks
+	From      string `json:"from"`       // Sender number, e.g. 6281234567890 or 6281234567890@c.us
+	Message   string `json:"message"`
+}
+
+// WhatsAppConversation is the state of a chat with a parent
+type WhatsAppConversation struct {
+	PhoneNumber string          `json:"phone_number" db:"phone_number"`
+	UserID      string          `json:"user_id" db:"user_id"`
+	State       string          `json:"state" db:"state"`     // idle, awaiting_child
+	Pending     json.RawMessage `json:"pending" db:"pending"` // Command waiting for a child to be chosen
+	ExpiresAt   *time.Time      `json:"expires_at,omitempty" db:"expires_at"`
+	UpdatedAt   time.Time       `json:"updated_at" db:"updated_at"`
+}
@@ -20,6 +20,30 @@ MIGRATIONS=(
     "008_immunization_tables.sql"
     "009_add_phone_otp_auth.sql"
     "010_add_admin_rbac.sql"
+    "011_developmental_quotients.sql"
+    "012_pyramid_warning_rules.sql"
+    "013_assessment_attachments.sql"
+    "014_content_packs.sql"
+    "015_translations.sql"
+    "016_kipi_reports.sql"
+    "017_immunization_record_validity.sql"
+    "018_calendar_feeds.sql"
+    "019_immunization_certificates.sql"
+    "020_health_programmes.sql"
+    "021_notification_templates.sql"
+    "022_outbound_messages.sql"
+    "023_scheduled_reminders.sql"
+    "024_monthly_digests.sql"
+    "025_whatsapp_chat.sql"
+    "026_user_sessions.sql"
+    "027_health_worker_roles.sql"
+    "028_child_guardians.sql"
+    "029_admin_two_factor.sql"
+    "030_otp_hashing.sql"
+    "031_rate_limits.sql"
+    "032_login_exchange_codes.sql"
+    "033_immunization_catch_up_plan.sql"
+    "034_whatsapp_chat_templates.sql"
 )
 
 # Database connection (adjust as needed)
@@ -0,0 +1,128 @@
+package services
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strings"
+	"text/template"
+	"time"
+	"tukem-backend/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// DefaultNotificationLanguage is the template language used when a translation is missing
+const DefaultNotificationLanguage = "id"
+
+// ErrTemplateNotFound is returned when no active template exists for a key and channel
+var ErrTemplateNotFound = errors.New("notification template not found")
+
+// NotificationService renders templates and delivers them through the configured notifiers.
+// Settings are read from system_settings on every send, so admin changes apply immediately.
+type NotificationService struct {
+	db *sqlx.DB
+}
+
+// NewNotificationService creates a notification service backed by the database
+func NewNotificationService(db *sqlx.DB) *NotificationService {
+	return &NotificationService{db: db}
+}
+
+// Settings loads the notification settings (category "notifications")
+func (s *NotificationService) Settings() (NotificationSettings, error) {
+	rows, err := s.db.Query(`SELECT key, COALESCE(value, '') FROM system_settings WHERE category = 'notifications'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load notification settings: %w", err)
+	}
+	defer rows.Close()
+
+	settings := NotificationSettings{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to load notification settings: %w", err)
+		}
+		settings[key] = value
+	}
+	return settings, rows.Err()
+}
+
+// Notifier returns the notifier configured for a channel, regardless of whether it is enabled
+func (s *NotificationService) Notifier(channel string) (Notifier, error) {
+	settings, err := s.Settings()
+	if err != nil {
+		return nil, err
+	}
+	return NewNotifier(channel, settings)
+}
+
+// Render builds a message from the template for key and channel in lang, falling back to
+// the default language
+func (s *NotificationService) Render(key, channel, lang string, data map[string]interface{}) (Message, error) {
+	var tmpl models.NotificationTemplate
+	err := s.db.Get(&tmpl, `SELECT * FROM notification_templates
+		WHERE key = $1 AND channel = $2 AND language IN ($3, $4) AND is_active = true
+		ORDER BY (language = $3) DESC LIMIT 1`, key, channel, lang, DefaultNotificationLanguage)
+	if err == sql.ErrNoRows {
+		return Message{}, fmt.Errorf("%w: %s/%s", ErrTemplateNotFound, key, channel)
+	}
+	if err != nil {
+		return Message{}, fmt.Errorf("failed to load notification template: %w", err)
+	}
+
+	msg := Message{Channel: channel}
+	if msg.Body, err = RenderTemplateText(tmpl.Body, data); err != nil {
+		return Message{}, fmt.Errorf("template %s/%s/%s: %w", key, channel, tmpl.Language, err)
+	}
+	if tmpl.Subject != nil {
+		if msg.Subject, err = RenderTemplateText(*tmpl.Subject, data); err != nil {
+			return Message{}, fmt.Errorf("template %s/%s/%s subject: %w", key, channel, tmpl.Language, err)
+		}
+	}
+	return msg, nil
+}
+
+// Notify renders a template and queues it for a recipient. Returns ErrChannelDisabled when the
+// channel is switched off by enable_<channel>_notifications.
+func (s *NotificationService) Notify(channel, to, key, lang string, data map[string]interface{}) error {
+	settings, err := s.Settings()
+	if err != nil {
+		return err
+	}
+	if !settings.Enabled(channel) {
+		return ErrChannelDisabled
+	}
+	_, err = s.Enqueue(channel, to, key, lang, data, EnqueueOptions{})
+	return err
+}
+
+// SendOTP queues a login or verification code over WhatsApp. Codes are transactional and are
+// sent even when WhatsApp notifications are disabled; they are not delivered after expiresAt.
+func (s *NotificationService) SendOTP(phoneNumber, code, lang string, expiresAt time.Time) error {
+	_, err := s.Enqueue(ChannelWhatsApp, phoneNumber, "otp_code", lang, map[string]interface{}{
+		"Code":          code,
+		"ExpiryMinutes": int(time.Until(expiresAt).Round(time.Minute).Minutes()),
+	}, EnqueueOptions{Sensitive: true, ExpiresAt: &expiresAt})
+	return err
+}
+
+// RenderTemplateText executes a template text with data. A missing key is an error instead of
+// rendering "<no value>" into the message.
+func RenderTemplateText(text string, data map[string]interface{}) (string, error) {
+	tmpl, err := ParseTemplateText(text)
+	if err != nil {
+		return "", err
+	}
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(b.String()), nil
+}
+
+// ParseTemplateText parses a template text without executing it
+func ParseTemplateText(text string) (*template.Template, error) {
+	return template.New("notification").Option("missingkey=error").Parse(text)
+}
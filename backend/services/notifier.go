@@ -0,0 +1,135 @@
+package services
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// Notification channels
+const (
+	ChannelWhatsApp = "whatsapp"
+	ChannelEmail    = "email"
+	ChannelSMS      = "sms"
+)
+
+// Notification providers shared by all channels
+const (
+	ProviderMemory = "memory" // Keeps messages in memory (tests)
+	ProviderFile   = "file"   // Appends messages to a JSON lines file (development)
+)
+
+// ErrChannelDisabled is returned when a channel is switched off in the system settings
+var ErrChannelDisabled = errors.New("notification channel is disabled")
+
+// ErrRecipientUnreachable is wrapped by notifier errors that retrying cannot fix, e.g. a WhatsApp
+// number that never started a chat with the gateway bot
+var ErrRecipientUnreachable = errors.New("recipient cannot be reached")
+
+// Message is a notification ready to be delivered
+type Message struct {
+	Channel     string       `json:"channel"`
+	To          string       `json:"to"`                // Phone number or email address
+	Subject     string       `json:"subject,omitempty"` // Email only
+	Body        string       `json:"body"`
+	HTML        string       `json:"html,omitempty"`        // Email only: HTML alternative of Body
+	Attachments []Attachment `json:"attachments,omitempty"` // Email only
+}
+
+// Attachment is a file sent with an email
+type Attachment struct {
+	Filename    string `json:"filename"`
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"-"`
+}
+
+// Notifier delivers messages over one channel
+type Notifier interface {
+	// Channel returns the channel the notifier delivers to (whatsapp, email, sms)
+	Channel() string
+	// Send delivers a message
+	Send(msg Message) error
+}
+
+// NotificationSettings are the notification keys of system_settings (category "notifications")
+type NotificationSettings map[string]string
+
+// Get returns a setting, falling back to an environment variable and then to def
+func (s NotificationSettings) Get(key, env, def string) string {
+	if value := strings.TrimSpace(s[key]); value != "" {
+		return value
+	}
+	if env != "" {
+		if value := os.Getenv(env); value != "" {
+			return value
+		}
+	}
+	return def
+}
+
+// Enabled reports whether a channel is switched on (enable_<channel>_notifications).
+// WhatsApp and email default to on, SMS to off.
+func (s NotificationSettings) Enabled(channel string) bool {
+	def := "true"
+	if channel == ChannelSMS {
+		def = "false"
+	}
+	return s.Get("enable_"+channel+"_notifications", "", def) == "true"
+}
+
+// NewNotifier creates the notifier configured for a channel by <channel>_provider
+func NewNotifier(channel string, settings NotificationSettings) (Notifier, error) {
+	switch channel {
+	case ChannelWhatsApp:
+		switch provider := settings.Get("whatsapp_provider", "WHATSAPP_PROVIDER", "gateway"); provider {
+		case "gateway":
+			return NewWhatsAppNotifier(
+				settings.Get("whatsapp_gateway_url", "WHATSAPP_GATEWAY_URL", ""),
+				settings.Get("whatsapp_api_key", "WHATSAPP_API_KEY", ""),
+				settings.Get("whatsapp_sender_number", "WHATSAPP_SENDER_NUMBER", ""),
+			), nil
+		default:
+			return newDevNotifier(channel, provider, settings)
+		}
+
+	case ChannelEmail:
+		switch provider := settings.Get("email_provider", "EMAIL_PROVIDER", "smtp"); provider {
+		case "smtp":
+			return NewSMTPNotifier(SMTPConfig{
+				Host:     settings.Get("smtp_host", "SMTP_HOST", ""),
+				Port:     settings.Get("smtp_port", "SMTP_PORT", "587"),
+				Username: settings.Get("smtp_username", "SMTP_USERNAME", ""),
+				Password: settings.Get("smtp_password", "SMTP_PASSWORD", ""),
+				From:     settings.Get("smtp_from", "SMTP_FROM", ""),
+			}), nil
+		default:
+			return newDevNotifier(channel, provider, settings)
+		}
+
+	case ChannelSMS:
+		switch provider := settings.Get("sms_provider", "SMS_PROVIDER", "http"); provider {
+		case "http":
+			return NewSMSNotifier(
+				settings.Get("sms_gateway_url", "SMS_GATEWAY_URL", ""),
+				settings.Get("sms_api_key", "SMS_API_KEY", ""),
+				settings.Get("sms_sender_id", "SMS_SENDER_ID", ""),
+			), nil
+		default:
+			return newDevNotifier(channel, provider, settings)
+		}
+	}
+
+	return nil, fmt.Errorf("unknown notification channel %q", channel)
+}
+
+// newDevNotifier creates the memory or file notifier for a channel
+func newDevNotifier(channel, provider string, settings NotificationSettings) (Notifier, error) {
+	switch provider {
+	case ProviderMemory:
+		return NewMemoryNotifier(channel), nil
+	case ProviderFile:
+		return NewFileNotifier(channel, settings.Get("notification_outbox_file", "NOTIFICATION_OUTBOX_FILE", "notifications.log")), nil
+	}
+	return nil, fmt.Errorf("unknown %s notification provider %q", channel, provider)
+}
@@ -0,0 +1,94 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	memoryOutboxMu sync.Mutex
+	memoryOutbox   []Message
+)
+
+// MemoryNotifier keeps sent messages in memory; read them with SentMessages
+type MemoryNotifier struct {
+	channel string
+}
+
+// NewMemoryNotifier creates an in-memory notifier for a channel
+func NewMemoryNotifier(channel string) *MemoryNotifier {
+	return &MemoryNotifier{channel: channel}
+}
+
+// Channel returns the channel the notifier stands in for
+func (n *MemoryNotifier) Channel() string {
+	return n.channel
+}
+
+// Send records the message
+func (n *MemoryNotifier) Send(msg Message) error {
+	msg.Channel = n.channel
+	memoryOutboxMu.Lock()
+	defer memoryOutboxMu.Unlock()
+	memoryOutbox = append(memoryOutbox, msg)
+	return nil
+}
+
+// SentMessages returns the messages sent through memory notifiers, oldest first
+func SentMessages() []Message {
+	memoryOutboxMu.Lock()
+	defer memoryOutboxMu.Unlock()
+	return append([]Message(nil), memoryOutbox...)
+}
+
+// ResetSentMessages clears the memory outbox
+func ResetSentMessages() {
+	memoryOutboxMu.Lock()
+	defer memoryOutboxMu.Unlock()
+	memoryOutbox = nil
+}
+
+var fileOutboxMu sync.Mutex
+
+// FileNotifier appends sent messages to a JSON lines file
+type FileNotifier struct {
+	channel string
+	path    string
+}
+
+// NewFileNotifier creates a notifier that writes messages for a channel to path
+func NewFileNotifier(channel, path string) *FileNotifier {
+	return &FileNotifier{channel: channel, path: path}
+}
+
+// Channel returns the channel the notifier stands in for
+func (n *FileNotifier) Channel() string {
+	return n.channel
+}
+
+// Send appends the message to the outbox file
+func (n *FileNotifier) Send(msg Message) error {
+	msg.Channel = n.channel
+	line, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now()})
+	if err != nil {
+		return fmt.Errorf("failed to encode message: %w", err)
+	}
+
+	fileOutboxMu.Lock()
+	defer fileOutboxMu.Unlock()
+	f, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open notification outbox: %w", err)
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return fmt.Errorf("failed to write notification outbox: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,174 @@
+package services
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"mime"
+	"mime/multipart"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"time"
+)
+
+// SMTPConfig holds the SMTP server settings
+type SMTPConfig struct {
+	Host     string
+	Port     string // 587 (STARTTLS), 465 (implicit TLS) or 25
+	Username string
+	Password string
+	From     string // Sender address, e.g. "Tukem <noreply@example.com>"
+}
+
+// SMTPNotifier sends email through an SMTP server
+type SMTPNotifier struct {
+	config SMTPConfig
+}
+
+// NewSMTPNotifier creates an SMTP email notifier
+func NewSMTPNotifier(config SMTPConfig) *SMTPNotifier {
+	if config.Port == "" {
+		config.Port = "587"
+	}
+	return &SMTPNotifier{config: config}
+}
+
+// Channel returns the email channel
+func (n *SMTPNotifier) Channel() string {
+	return ChannelEmail
+}
+
+// Send sends an email: plain text, or multipart with an HTML alternative and attachments
+func (n *SMTPNotifier) Send(msg Message) error {
+	if n.config.Host == "" || n.config.From == "" {
+		return fmt.Errorf("SMTP not configured: smtp_host and smtp_from are required")
+	}
+
+	fromAddress := n.config.From
+	if parsed, err := mail.ParseAddress(n.config.From); err == nil {
+		fromAddress = parsed.Address
+	}
+	body := buildEmail(n.config.From, msg)
+	addr := net.JoinHostPort(n.config.Host, n.config.Port)
+
+	var auth smtp.Auth
+	if n.config.Username != "" {
+		auth = smtp.PlainAuth("", n.config.Username, n.config.Password, n.config.Host)
+	}
+
+	if n.config.Port != "465" {
+		// smtp.SendMail upgrades to STARTTLS when the server supports it
+		if err := smtp.SendMail(addr, auth, fromAddress, []string{msg.To}, body); err != nil {
+			return fmt.Errorf("failed to send email: %w", err)
+		}
+		return nil
+	}
+
+	// Implicit TLS
+	dialer := &net.Dialer{Timeout: 30 * time.Second}
+	conn, err := tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: n.config.Host})
+	if err != nil {
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	client, err := smtp.NewClient(conn, n.config.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("failed to connect to SMTP server: %w", err)
+	}
+	defer client.Close()
+
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("SMTP authentication failed: %w", err)
+		}
+	}
+	if err := client.Mail(fromAddress); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := client.Rcpt(msg.To); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if _, err := w.Write(body); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("failed to send email: %w", err)
+	}
+	return client.Quit()
+}
+
+// buildEmail renders a UTF-8 email with base64 parts. Plain text messages are a single part;
+// an HTML alternative and attachments make it multipart/mixed.
+func buildEmail(from string, msg Message) []byte {
+	var b bytes.Buffer
+	b.WriteString("From: " + from + "\r\n")
+	b.WriteString("To: " + msg.To + "\r\n")
+	b.WriteString("Subject: " + mime.QEncoding.Encode("utf-8", msg.Subject) + "\r\n")
+	b.WriteString("Date: " + time.Now().Format(time.RFC1123Z) + "\r\n")
+	b.WriteString("MIME-Version: 1.0\r\n")
+
+	if msg.HTML == "" && len(msg.Attachments) == 0 {
+		b.WriteString("Content-Type: text/plain; charset=UTF-8\r\n")
+		b.WriteString("Content-Transfer-Encoding: base64\r\n\r\n")
+		writeBase64Lines(&b, []byte(msg.Body))
+		return b.Bytes()
+	}
+
+	mixed := multipart.NewWriter(&b)
+	b.WriteString("Content-Type: multipart/mixed; boundary=" + mixed.Boundary() + "\r\n\r\n")
+
+	// Text and HTML bodies as alternatives
+	var alternative bytes.Buffer
+	alt := multipart.NewWriter(&alternative)
+	writeEmailPart(alt, "text/plain; charset=UTF-8", "", []byte(msg.Body))
+	if msg.HTML != "" {
+		writeEmailPart(alt, "text/html; charset=UTF-8", "", []byte(msg.HTML))
+	}
+	alt.Close()
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", "multipart/alternative; boundary="+alt.Boundary())
+	if part, err := mixed.CreatePart(header); err == nil {
+		part.Write(alternative.Bytes())
+	}
+
+	for _, attachment := range msg.Attachments {
+		disposition := mime.FormatMediaType("attachment", map[string]string{"filename": attachment.Filename})
+		writeEmailPart(mixed, attachment.ContentType, disposition, attachment.Data)
+	}
+	mixed.Close()
+	return b.Bytes()
+}
+
+// writeEmailPart adds a base64 encoded part to a multipart email
+func writeEmailPart(w *multipart.Writer, contentType, disposition string, data []byte) {
+	header := textproto.MIMEHeader{}
+	header.Set("Content-Type", contentType)
+	header.Set("Content-Transfer-Encoding", "base64")
+	if disposition != "" {
+		header.Set("Content-Disposition", disposition)
+	}
+	part, err := w.CreatePart(header)
+	if err != nil {
+		return
+	}
+	var b bytes.Buffer
+	writeBase64Lines(&b, data)
+	part.Write(b.Bytes())
+}
+
+// writeBase64Lines writes data base64 encoded in 76 character lines
+func writeBase64Lines(b *bytes.Buffer, data []byte) {
+	encoded := base64.StdEncoding.EncodeToString(data)
+	for len(encoded) > 76 {
+		b.WriteString(encoded[:76] + "\r\n")
+		encoded = encoded[76:]
+	}
+	b.WriteString(encoded + "\r\n")
+}
@@ -0,0 +1,89 @@
+package services
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+// SMSNotifier sends SMS through an HTTP gateway that accepts a JSON payload
+// {"to", "message", "sender"} with a bearer API key
+type SMSNotifier struct {
+	gatewayURL string
+	apiKey     string
+	senderID   string
+	client     *http.Client
+}
+
+// NewSMSNotifier creates an HTTP SMS gateway notifier
+func NewSMSNotifier(gatewayURL, apiKey, senderID string) *SMSNotifier {
+	return &SMSNotifier{
+		gatewayURL: gatewayURL,
+		apiKey:     apiKey,
+		senderID:   senderID,
+		client: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Channel returns the SMS channel
+func (n *SMSNotifier) Channel() string {
+	return ChannelSMS
+}
+
+// Send sends a text message via the SMS gateway
+func (n *SMSNotifier) Send(msg Message) error {
+	_, err := n.SendTracked(msg)
+	return err
+}
+
+// SendTracked sends a text message and returns the gateway message ID ("id" or "message_id"
+// in the JSON response), if there is one
+func (n *SMSNotifier) SendTracked(msg Message) (string, error) {
+	if n.gatewayURL == "" || n.apiKey == "" {
+		return "", fmt.Errorf("SMS gateway not configured: sms_gateway_url and sms_api_key are required")
+	}
+
+	payload, err := json.Marshal(map[string]string{
+		"to":      msg.To,
+		"message": msg.Body,
+		"sender":  n.senderID,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode SMS: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", n.gatewayURL, bytes.NewReader(payload))
+	if err != nil {
+		return "", fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+n.apiKey)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to send request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return "", fmt.Errorf("SMS gateway returned status %d: %s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		ID        string `json:"id"`
+		MessageID string `json:"message_id"`
+	}
+	if err := json.Unmarshal(body, &result); err == nil {
+		if result.ID != "" {
+			return result.ID, nil
+		}
+		return result.MessageID, nil
+	}
+	return "", nil
+}
@@ -7,12 +7,12 @@ import (
 	"io"
 	"mime/multipart"
 	"net/http"
-	"os"
+	"strings"
 	"time"
 )
 
-// WhatsAppService handles sending messages via WhatsApp
-type WhatsAppService struct {
+// WhatsAppNotifier sends messages through the WhatsApp gateway
+type WhatsAppNotifier struct {
 	gatewayURL   string
 	apiKey       string
 	senderNumber string
@@ -28,72 +28,60 @@ type WhatsAppRequest struct {
 
 // WhatsAppResponse represents the response from WhatsApp gateway
 type WhatsAppResponse struct {
-	Success bool   `json:"success"`
-	Message string `json:"message,omitempty"`
-	Error   string `json:"error,omitempty"`
+	Success   bool   `json:"success"`
+	Message   string `json:"message,omitempty"`
+	Error     string `json:"error,omitempty"`
+	ID        string `json:"id,omitempty"`         // Message ID, if the gateway reports one
+	MessageID string `json:"message_id,omitempty"` // Alternative field name used by some gateways
 }
 
-// NewWhatsAppService creates a new WhatsApp service instance
-func NewWhatsAppService() *WhatsAppService {
-	gatewayURL := os.Getenv("WHATSAPP_GATEWAY_URL")
+// NewWhatsAppNotifier creates a WhatsApp gateway notifier
+func NewWhatsAppNotifier(gatewayURL, apiKey, senderNumber string) *WhatsAppNotifier {
 	if gatewayURL == "" {
 		// Default gateway URL - menggunakan send-file endpoint sesuai dengan kode PHP
 		gatewayURL = "https://anakhebat.web.id/services/wa-gateway/api/send-file"
 	}
 
-	return &WhatsAppService{
+	return &WhatsAppNotifier{
 		gatewayURL:   gatewayURL,
-		apiKey:       os.Getenv("WHATSAPP_API_KEY"),
-		senderNumber: os.Getenv("WHATSAPP_SENDER_NUMBER"),
+		apiKey:       apiKey,
+		senderNumber: senderNumber,
 		client: &http.Client{
 			Timeout: 30 * time.Second,
 		},
 	}
 }
 
-// SendOTP sends OTP code via WhatsApp
-func (s *WhatsAppService) SendOTP(phoneNumber string, otpCode string) error {
-	message := fmt.Sprintf(`🔐 *Kode OTP Anda*
-
-Halo,
-
-Kode OTP Anda untuk masuk ke aplikasi Tukem:
-
-*%s*
-
-Kode ini berlaku selama *5 menit*.
-
-Jangan bagikan kode ini kepada siapapun.
-
-Jika Anda tidak meminta kode ini, abaikan pesan ini.
-
-Terima kasih,
-Tim Tukem`, otpCode)
+// Channel returns the WhatsApp channel
+func (s *WhatsAppNotifier) Channel() string {
+	return ChannelWhatsApp
+}
 
-	// Log for development/testing
-	fmt.Printf("[WhatsApp OTP] Sending to %s: %s\n", phoneNumber, otpCode)
+// Send sends a text message via the WhatsApp gateway
+func (s *WhatsAppNotifier) Send(msg Message) error {
+	_, err := s.SendTracked(msg)
+	return err
+}
 
-	// If gateway is configured, send via API
-	if s.gatewayURL != "" && s.apiKey != "" {
-		// Gunakan send-file endpoint (tanpa attachment untuk text message)
-		err := s.sendViaGateway(phoneNumber, message)
-		if err != nil {
-			// Log error with details
-			fmt.Printf("[WhatsApp Error] Failed to send OTP to %s: %v\n", phoneNumber, err)
-			return err
-		}
-		return nil
+// SendTracked sends a text message and returns the gateway message ID, if the gateway reports one
+func (s *WhatsAppNotifier) SendTracked(msg Message) (string, error) {
+	if s.apiKey == "" {
+		fmt.Printf("[Warning] WhatsApp gateway not configured. Set whatsapp_api_key in system settings or WHATSAPP_API_KEY.\n")
+		return "", fmt.Errorf("WhatsApp gateway not configured: API key is required")
 	}
 
-	// For development: log and return error if gateway not configured
-	fmt.Printf("[WhatsApp Message]: %s\n", message)
-	fmt.Printf("[Warning] WhatsApp gateway not configured. Set WHATSAPP_GATEWAY_URL and WHATSAPP_API_KEY environment variables.\n")
-	return fmt.Errorf("WhatsApp gateway not configured: WHATSAPP_API_KEY is required")
+	// Gunakan send-file endpoint (tanpa attachment untuk text message)
+	messageID, err := s.sendViaGateway(msg.To, msg.Body)
+	if err != nil {
+		fmt.Printf("[WhatsApp Error] Failed to send message to %s: %v\n", msg.To, err)
+		return "", err
+	}
+	return messageID, nil
 }
 
 // sendViaGateway sends message via WhatsApp gateway API
 // Menggunakan multipart/form-data dengan hanya number dan message (tanpa file)
-func (s *WhatsAppService) sendViaGateway(phoneNumber string, message string) error {
+func (s *WhatsAppNotifier) sendViaGateway(phoneNumber string, message string) (string, error) {
 	// Gateway hanya perlu form-data dengan number dan message
 	// Nomor yang berawalan 0 akan otomatis di-format jadi 62 oleh gateway
 	// Format nomor: coba beberapa format untuk kompatibilitas
@@ -123,7 +111,7 @@ func (s *WhatsAppService) sendViaGateway(phoneNumber string, message string) err
 	
 	err := writer.Close()
 	if err != nil {
-		return fmt.Errorf("failed to close multipart writer: %w", err)
+		return "", fmt.Errorf("failed to close multipart writer: %w", err)
 	}
 
 	// Log request body size untuk debugging
@@ -132,7 +120,7 @@ func (s *WhatsAppService) sendViaGateway(phoneNumber string, message string) err
 	// Create HTTP request
 	req, err := http.NewRequest("POST", s.gatewayURL, &requestBody)
 	if err != nil {
-		return fmt.Errorf("failed to create request: %w", err)
+		return "", fmt.Errorf("failed to create request: %w", err)
 	}
 
 	// Set headers - multipart/form-data dengan boundary
@@ -145,14 +133,14 @@ func (s *WhatsAppService) sendViaGateway(phoneNumber string, message string) err
 	// Send request
 	resp, err := s.client.Do(req)
 	if err != nil {
-		return fmt.Errorf("failed to send request: %w", err)
+		return "", fmt.Errorf("failed to send request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	// Read response body
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		return fmt.Errorf("failed to read response: %w", err)
+		return "", fmt.Errorf("failed to read response: %w", err)
 	}
 
 	// Log response untuk debugging
@@ -160,21 +148,37 @@ func (s *WhatsAppService) sendViaGateway(phoneNumber string, message string) err
 
 	// Check status code
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("WhatsApp gateway returned status %d: %s", resp.StatusCode, string(body))
+		if isUnreachableWhatsAppError(string(body)) {
+			return "", fmt.Errorf("%w: WhatsApp gateway returned status %d: %s", ErrRecipientUnreachable, resp.StatusCode, string(body))
+		}
+		return "", fmt.Errorf("WhatsApp gateway returned status %d: %s", resp.StatusCode, string(body))
 	}
 
 	// Try to parse response
 	var waResp WhatsAppResponse
+	messageID := ""
 	if err := json.Unmarshal(body, &waResp); err == nil {
+		messageID = waResp.ID
+		if messageID == "" {
+			messageID = waResp.MessageID
+		}
 		if !waResp.Success {
 			if waResp.Error != "" {
-				return fmt.Errorf("WhatsApp gateway error: %s", waResp.Error)
+				if isUnreachableWhatsAppError(waResp.Error) {
+					return "", fmt.Errorf("%w: WhatsApp gateway error: %s", ErrRecipientUnreachable, waResp.Error)
+				}
+				return "", fmt.Errorf("WhatsApp gateway error: %s", waResp.Error)
 			}
-			return fmt.Errorf("WhatsApp gateway returned success=false: %s", string(body))
+			return "", fmt.Errorf("WhatsApp gateway returned success=false: %s", string(body))
 		}
 	}
 
 	fmt.Printf("[WhatsApp] Message sent successfully to %s. Response: %s\n", phoneNumber, string(body))
-	return nil
+	return messageID, nil
 }
 
+// isUnreachableWhatsAppError reports whether the gateway rejected a number that has not
+// chatted with the bot yet, which retrying cannot fix
+func isUnreachableWhatsAppError(message string) bool {
+	return strings.Contains(message, "chat table") || strings.Contains(message, "belum pernah")
+}
@@ -0,0 +1,339 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+	"tukem-backend/models"
+	"tukem-backend/utils"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// OTP purposes
+const (
+	OTPPurposeLogin       = "login"
+	OTPPurposeAdminLogin  = "admin_login"
+	OTPPurposeVerifyPhone = "verify_phone"
+
+	// Child invitation codes are issued by the guardian handlers, but hashed like OTP codes
+	OTPPurposeChildInvitation = "child_invitation"
+)
+
+// otpRetention is how long used and expired codes and failed verifications are kept, for the
+// request limits and lockout. Longer windows in system_settings are capped to it.
+const otpRetention = 24 * time.Hour
+
+var (
+	ErrOTPInvalid          = errors.New("invalid OTP")
+	ErrOTPExpired          = errors.New("OTP expired")
+	ErrOTPAttemptsExceeded = errors.New("too many OTP attempts")
+)
+
+// OTPLimitError is returned when a phone number or IP address has requested too many codes or
+// is locked out after failed verifications
+type OTPLimitError struct {
+	LockedOut  bool // Locked out after failed verifications, rather than over the request limit
+	RetryAfter time.Duration
+}
+
+func (e *OTPLimitError) Error() string {
+	if e.LockedOut {
+		return fmt.Sprintf("locked out after failed OTP verifications, retry after %s", e.RetryAfter)
+	}
+	return fmt.Sprintf("OTP request limit reached, retry after %s", e.RetryAfter)
+}
+
+// OTPSettings are the OTP keys of system_settings (category "security")
+type OTPSettings struct {
+	Expiry            time.Duration // otp_expiry_minutes
+	MaxAttempts       int           // max_otp_attempts, per code
+	RequestLimit      int           // otp_request_limit, codes per phone number per window
+	IPRequestLimit    int           // otp_ip_request_limit, codes per IP address per window
+	RequestWindow     time.Duration // otp_request_window_minutes
+	PhoneLockoutLimit int           // otp_lockout_phone_failures
+	IPLockoutLimit    int           // otp_lockout_ip_failures
+	LockoutWindow     time.Duration // otp_lockout_minutes
+}
+
+// OTPService issues and verifies the one-time codes of the login, admin login and phone
+// verification flows. Codes are stored as keyed hashes; settings are read on every call, so
+// admin changes apply immediately.
+type OTPService struct {
+	db *sqlx.DB
+}
+
+// NewOTPService creates an OTP service backed by the database
+func NewOTPService(db *sqlx.DB) *OTPService {
+	return &OTPService{db: db}
+}
+
+// Settings loads the OTP settings, with defaults for missing or invalid values
+func (s *OTPService) Settings() (OTPSettings, error) {
+	rows, err := s.db.Query(`SELECT key, COALESCE(value, '') FROM system_settings WHERE category = 'security'`)
+	if err != nil {
+		return OTPSettings{}, fmt.Errorf("failed to load OTP settings: %w", err)
+	}
+	defer rows.Close()
+
+	values := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return OTPSettings{}, fmt.Errorf("failed to load OTP settings: %w", err)
+		}
+		values[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return OTPSettings{}, fmt.Errorf("failed to load OTP settings: %w", err)
+	}
+
+	number := func(key string, def int) int {
+		n, err := strconv.Atoi(strings.TrimSpace(values[key]))
+		if err != nil || n <= 0 {
+			return def
+		}
+		return n
+	}
+	minutes := func(key string, def int) time.Duration {
+		d := time.Duration(number(key, def)) * time.Minute
+		if d > otpRetention {
+			return otpRetention
+		}
+		return d
+	}
+
+	return OTPSettings{
+		Expiry:            minutes("otp_expiry_minutes", 5),
+		MaxAttempts:       number("max_otp_attempts", 3),
+		RequestLimit:      number("otp_request_limit", 3),
+		IPRequestLimit:    number("otp_ip_request_limit", 20),
+		RequestWindow:     minutes("otp_request_window_minutes", 15),
+		PhoneLockoutLimit: number("otp_lockout_phone_failures", 10),
+		IPLockoutLimit:    number("otp_lockout_ip_failures", 30),
+		LockoutWindow:     minutes("otp_lockout_minutes", 30),
+	}, nil
+}
+
+// Issue creates a code for the phone number and purpose, replacing the unused codes issued
+// before, and returns it with its expiry. It returns an *OTPLimitError when the phone number or
+// IP address is over the request limit or locked out.
+func (s *OTPService) Issue(phoneNumber, purpose, ipAddress, userAgent string) (string, time.Time, error) {
+	settings, err := s.Settings()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err := s.checkLockout(settings, phoneNumber, ipAddress); err != nil {
+		return "", time.Time{}, err
+	}
+	if err := s.checkRequestLimit(settings, phoneNumber, ipAddress); err != nil {
+		return "", time.Time{}, err
+	}
+
+	code, err := utils.GenerateOTP()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(settings.Expiry)
+
+	tx, err := s.db.Beginx()
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	defer tx.Rollback()
+
+	// Only the latest code of a purpose is valid
+	_, err = tx.Exec(`UPDATE otp_codes SET is_used = true
+	                  WHERE phone_number = $1 AND purpose = $2 AND is_used = false`, phoneNumber, purpose)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	_, err = tx.Exec(`INSERT INTO otp_codes (phone_number, code_hash, purpose, expires_at, ip_address, user_agent, max_attempts)
+	                  VALUES ($1, $2, $3, $4, $5, $6, $7)`,
+		phoneNumber, HashOTP(phoneNumber, purpose, code), purpose, expiresAt, ipAddress, userAgent, settings.MaxAttempts)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", time.Time{}, err
+	}
+
+	return code, expiresAt, nil
+}
+
+// Verify checks a code against the latest code of the phone number and purpose and marks it as
+// used. Wrong codes count towards the attempt limit of the code and the lockout of the phone
+// number and IP address.
+func (s *OTPService) Verify(phoneNumber, purpose, code, ipAddress string) error {
+	settings, err := s.Settings()
+	if err != nil {
+		return err
+	}
+	if err := s.checkLockout(settings, phoneNumber, ipAddress); err != nil {
+		return err
+	}
+
+	var otp models.OTPCode
+	err = s.db.Get(&otp, `SELECT * FROM otp_codes
+	                      WHERE phone_number = $1 AND purpose = $2 AND is_used = false
+	                      ORDER BY created_at DESC LIMIT 1`, phoneNumber, purpose)
+	if err == sql.ErrNoRows {
+		s.recordFailure(phoneNumber, purpose, ipAddress)
+		return ErrOTPInvalid
+	} else if err != nil {
+		return err
+	}
+	if !time.Now().Before(otp.ExpiresAt) {
+		return ErrOTPExpired
+	}
+	if otp.AttemptCount >= otp.MaxAttempts {
+		return ErrOTPAttemptsExceeded
+	}
+
+	if !hmac.Equal([]byte(HashOTP(phoneNumber, purpose, strings.TrimSpace(code))), []byte(otp.CodeHash)) {
+		if _, err := s.db.Exec(`UPDATE otp_codes SET attempt_count = attempt_count + 1 WHERE id = $1`, otp.ID); err != nil {
+			return err
+		}
+		s.recordFailure(phoneNumber, purpose, ipAddress)
+		return ErrOTPInvalid
+	}
+
+	// Only the first of two concurrent verifications of the same code wins
+	res, err := s.db.Exec(`UPDATE otp_codes SET is_used = true, used_at = $1
+	                       WHERE id = $2 AND is_used = false`, time.Now(), otp.ID)
+	if err != nil {
+		return err
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		return ErrOTPInvalid
+	}
+	return nil
+}
+
+// checkRequestLimit counts the codes issued to the phone number and IP address in the request
+// window
+func (s *OTPService) checkRequestLimit(settings OTPSettings, phoneNumber, ipAddress string) error {
+	since := time.Now().Add(-settings.RequestWindow)
+	checks := []struct {
+		column, value string
+		limit         int
+	}{
+		{"phone_number", phoneNumber, settings.RequestLimit},
+		{"ip_address", ipAddress, settings.IPRequestLimit},
+	}
+	for _, check := range checks {
+		if check.value == "" {
+			continue
+		}
+		retryAfter, err := s.limitRetryAfter(`SELECT created_at FROM otp_codes WHERE `+check.column+` = $1 AND created_at > $2
+		                                      ORDER BY created_at DESC`, check.value, since, check.limit, settings.RequestWindow)
+		if err != nil {
+			return err
+		}
+		if retryAfter > 0 {
+			return &OTPLimitError{RetryAfter: retryAfter}
+		}
+	}
+	return nil
+}
+
+// checkLockout counts the failed verifications of the phone number and IP address in the
+// lockout window
+func (s *OTPService) checkLockout(settings OTPSettings, phoneNumber, ipAddress string) error {
+	since := time.Now().Add(-settings.LockoutWindow)
+	checks := []struct {
+		column, value string
+		limit         int
+	}{
+		{"phone_number", phoneNumber, settings.PhoneLockoutLimit},
+		{"ip_address", ipAddress, settings.IPLockoutLimit},
+	}
+	for _, check := range checks {
+		if check.value == "" {
+			continue
+		}
+		retryAfter, err := s.limitRetryAfter(`SELECT created_at FROM otp_failures WHERE `+check.column+` = $1 AND created_at > $2
+		                                      ORDER BY created_at DESC`, check.value, since, check.limit, settings.LockoutWindow)
+		if err != nil {
+			return err
+		}
+		if retryAfter > 0 {
+			return &OTPLimitError{LockedOut: true, RetryAfter: retryAfter}
+		}
+	}
+	return nil
+}
+
+// limitRetryAfter returns how long until fewer than limit of the events selected by query (newest
+// first) fall within window, or 0 if that is already the case
+func (s *OTPService) limitRetryAfter(query, value string, since time.Time, limit int, window time.Duration) (time.Duration, error) {
+	var times []time.Time
+	if err := s.db.Select(&times, query+fmt.Sprintf(" LIMIT %d", limit), value, since); err != nil {
+		return 0, err
+	}
+	if len(times) < limit {
+		return 0, nil
+	}
+	// The window must slide past the limit-th newest event
+	retryAfter := time.Until(times[limit-1].Add(window))
+	if retryAfter < time.Second {
+		retryAfter = time.Second
+	}
+	return retryAfter, nil
+}
+
+func (s *OTPService) recordFailure(phoneNumber, purpose, ipAddress string) {
+	_, err := s.db.Exec(`INSERT INTO otp_failures (phone_number, ip_address, purpose) VALUES ($1, NULLIF($2, ''), $3)`,
+		phoneNumber, ipAddress, purpose)
+	if err != nil {
+		log.Printf("OTP: failed to record failed verification: %v", err)
+	}
+}
+
+// Cleanup deletes expired codes and failed verifications older than the retention period
+func (s *OTPService) Cleanup() (int64, error) {
+	cutoff := time.Now().Add(-otpRetention)
+	var total int64
+	for _, query := range []string{
+		`DELETE FROM otp_codes WHERE expires_at < $1`,
+		`DELETE FROM otp_failures WHERE created_at < $1`,
+	} {
+		res, err := s.db.Exec(query, cutoff)
+		if err != nil {
+			return total, err
+		}
+		n, _ := res.RowsAffected()
+		total += n
+	}
+	return total, nil
+}
+
+// StartOTPCleanup deletes old OTP codes and failed verifications every hour
+func StartOTPCleanup(db *sqlx.DB) {
+	service := NewOTPService(db)
+	go func() {
+		for {
+			if n, err := service.Cleanup(); err != nil {
+				log.Printf("OTP cleanup: %v", err)
+			} else if n > 0 {
+				log.Printf("OTP cleanup: deleted %d row(s)", n)
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+// hashOTP returns the stored hash of a code. The phone number and purpose are part of the hash,
+// so a code only matches the request it was issued for.
+func HashOTP(phoneNumber, purpose, code string) string {
+	mac := hmac.New(sha256.New, utils.SecretKey(utils.SecretOTPHash))
+	mac.Write([]byte(phoneNumber + ":" + purpose + ":" + code))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,433 @@
+package services
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+	"tukem-backend/models"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Outbound message statuses
+const (
+	OutboxPending   = "pending"   // Waiting for its first attempt
+	OutboxSending   = "sending"   // Claimed by a worker
+	OutboxSent      = "sent"      // Accepted by the provider
+	OutboxDelivered = "delivered" // Reported delivered by a gateway callback
+	OutboxRead      = "read"      // Reported read by a gateway callback
+	OutboxFailed    = "failed"    // Last attempt failed, a retry is scheduled
+	OutboxDead      = "dead"      // Attempts exhausted or expired; only an admin retry sends it again
+)
+
+const (
+	defaultOutboxMaxAttempts = 5
+	outboxBaseBackoff        = 30 * time.Second
+	outboxMaxBackoff         = time.Hour
+	outboxLease              = 2 * time.Minute  // How long a claimed message stays locked to a worker
+	outboxPollInterval       = 5 * time.Second  // Idle workers look for due retries this often
+	outboxSettingsTTL        = 30 * time.Second // How long workers cache the notification settings
+)
+
+// ErrOutboundMessageNotFound is returned when a message or callback does not match the outbox
+var ErrOutboundMessageNotFound = errors.New("outbound message not found")
+
+// ErrOutboundMessageNotRetryable is returned when an admin retries a message that is not failed
+// or dead, has expired, or whose body was already cleared
+var ErrOutboundMessageNotRetryable = errors.New("outbound message cannot be retried")
+
+// TrackingNotifier is implemented by notifiers whose provider returns a message ID, which
+// delivery status callbacks refer to
+type TrackingNotifier interface {
+	Notifier
+	// SendTracked delivers a message and returns the provider message ID (may be empty)
+	SendTracked(msg Message) (string, error)
+}
+
+// EnqueueOptions controls how an outbound message is delivered
+type EnqueueOptions struct {
+	Sensitive   bool       // Clear the body once the message is final (e.g. OTP codes)
+	ExpiresAt   *time.Time // Give up after this time
+	NotBefore   *time.Time // Hold the first attempt until this time (e.g. the end of quiet hours)
+	MaxAttempts int        // Defaults to 5
+
+	// Attachment already stored in the object store (email only)
+	Attachment *StoredAttachment
+}
+
+// StoredAttachment refers to an email attachment kept in the object store, so the outbox does
+// not hold file contents
+type StoredAttachment struct {
+	Key         string
+	Filename    string
+	ContentType string
+}
+
+// outboxWake wakes an idle worker when a message is enqueued or retried
+var outboxWake = make(chan struct{}, 1)
+
+func wakeOutbox() {
+	select {
+	case outboxWake <- struct{}{}:
+	default:
+	}
+}
+
+// Enqueue renders a template and stores it in the outbox for delivery by the workers.
+// Returns the outbound message ID.
+func (s *NotificationService) Enqueue(channel, to, key, lang string, data map[string]interface{}, opts EnqueueOptions) (string, error) {
+	msg, err := s.Render(key, channel, lang, data)
+	if err != nil {
+		return "", err
+	}
+	msg.To = to
+	return s.EnqueueMessage(msg, key, opts)
+}
+
+// EnqueueMessage stores an already rendered message in the outbox
+func (s *NotificationService) EnqueueMessage(msg Message, key string, opts EnqueueOptions) (string, error) {
+	maxAttempts := opts.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultOutboxMaxAttempts
+	}
+	var subject, templateKey, html *string
+	if msg.Subject != "" {
+		subject = &msg.Subject
+	}
+	if key != "" {
+		templateKey = &key
+	}
+	if msg.HTML != "" {
+		html = &msg.HTML
+	}
+	var attachmentKey, attachmentFilename, attachmentContentType *string
+	if opts.Attachment != nil {
+		attachmentKey = &opts.Attachment.Key
+		attachmentFilename = &opts.Attachment.Filename
+		attachmentContentType = &opts.Attachment.ContentType
+	}
+
+	nextAttemptAt := time.Now()
+	if opts.NotBefore != nil && opts.NotBefore.After(nextAttemptAt) {
+		nextAttemptAt = *opts.NotBefore
+	}
+
+	var id string
+	err := s.db.QueryRow(`INSERT INTO outbound_messages (channel, recipient, template_key, subject, body, html_body,
+			is_sensitive, max_attempts, expires_at, next_attempt_at, attachment_key, attachment_filename, attachment_content_type)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)
+		RETURNING id`,
+		msg.Channel, msg.To, templateKey, subject, msg.Body, html, opts.Sensitive, maxAttempts, opts.ExpiresAt, nextAttemptAt,
+		attachmentKey, attachmentFilename, attachmentContentType).Scan(&id)
+	if err != nil {
+		return "", fmt.Errorf("failed to enqueue notification: %w", err)
+	}
+	s.recordEvent(id, OutboxPending, "")
+	wakeOutbox()
+	return id, nil
+}
+
+// RetryOutboundMessage sends a failed or dead message again with a fresh set of attempts
+func (s *NotificationService) RetryOutboundMessage(id string) (*models.OutboundMessage, error) {
+	var msg models.OutboundMessage
+	err := s.db.Get(&msg, `UPDATE outbound_messages SET
+			status = $2, attempts = 0, next_attempt_at = CURRENT_TIMESTAMP, locked_until = NULL,
+			last_error = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status IN ($3, $4) AND body IS NOT NULL
+			AND (expires_at IS NULL OR expires_at > CURRENT_TIMESTAMP)
+		RETURNING *`, id, OutboxPending, OutboxFailed, OutboxDead)
+	if err == sql.ErrNoRows {
+		var exists bool
+		if err := s.db.Get(&exists, `SELECT EXISTS(SELECT 1 FROM outbound_messages WHERE id = $1)`, id); err != nil {
+			return nil, err
+		}
+		if !exists {
+			return nil, ErrOutboundMessageNotFound
+		}
+		return nil, ErrOutboundMessageNotRetryable
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.recordEvent(msg.ID, OutboxPending, "retried by admin")
+	wakeOutbox()
+	return &msg, nil
+}
+
+// ApplyDeliveryStatus records a gateway delivery callback. The reference is matched against the
+// provider message ID of the channel, then against the outbox ID. Statuses never move backwards
+// (a late "sent" does not undo "read"); a reported failure schedules a retry like a send error.
+func (s *NotificationService) ApplyDeliveryStatus(channel, reference, status, detail string) (*models.OutboundMessage, error) {
+	var msg models.OutboundMessage
+	err := s.db.Get(&msg, `SELECT * FROM outbound_messages
+		WHERE channel = $1 AND (provider_message_id = $2 OR id::text = $2)
+		ORDER BY (provider_message_id = $2) DESC NULLS LAST LIMIT 1`, channel, reference)
+	if err == sql.ErrNoRows {
+		return nil, ErrOutboundMessageNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	switch status {
+	case OutboxSent, OutboxDelivered, OutboxRead:
+		if deliveryRank(status) <= deliveryRank(msg.Status) {
+			s.recordEvent(msg.ID, status, detail)
+			return &msg, nil
+		}
+		err = s.db.Get(&msg, `UPDATE outbound_messages SET status = $2,
+				delivered_at = CASE WHEN $2 IN ($3, $4) THEN COALESCE(delivered_at, CURRENT_TIMESTAMP) ELSE delivered_at END,
+				sent_at = COALESCE(sent_at, CURRENT_TIMESTAMP), updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1 RETURNING *`, msg.ID, status, OutboxDelivered, OutboxRead)
+		if err != nil {
+			return nil, err
+		}
+		s.recordEvent(msg.ID, status, detail)
+	case OutboxFailed:
+		if detail == "" {
+			detail = "reported failed by provider"
+		}
+		if err := s.recordFailure(&msg, detail); err != nil {
+			return nil, err
+		}
+	default:
+		return nil, fmt.Errorf("unknown delivery status %q", status)
+	}
+	return &msg, nil
+}
+
+// deliveryRank orders the success statuses so callbacks cannot move a message backwards
+func deliveryRank(status string) int {
+	switch status {
+	case OutboxSent:
+		return 1
+	case OutboxDelivered:
+		return 2
+	case OutboxRead:
+		return 3
+	}
+	return 0
+}
+
+// StartOutboxWorkers starts n background workers that deliver the outbox. Workers claim due
+// messages with SELECT ... FOR UPDATE SKIP LOCKED, so several API instances can share the queue.
+func StartOutboxWorkers(db *sqlx.DB, n int) {
+	if n <= 0 {
+		n = 1
+	}
+	service := NewNotificationService(db)
+	for i := 0; i < n; i++ {
+		go service.runOutboxWorker()
+	}
+	log.Printf("Outbox: started %d worker(s)", n)
+}
+
+// OutboxWorkerCount returns the configured number of workers (notification_worker_count)
+func (s *NotificationService) OutboxWorkerCount() int {
+	settings, err := s.Settings()
+	if err != nil {
+		return 4
+	}
+	var n int
+	if _, err := fmt.Sscan(settings.Get("notification_worker_count", "NOTIFICATION_WORKER_COUNT", "4"), &n); err != nil || n <= 0 {
+		return 4
+	}
+	return n
+}
+
+func (s *NotificationService) runOutboxWorker() {
+	var settings NotificationSettings
+	var loadedAt time.Time
+	for {
+		if time.Since(loadedAt) > outboxSettingsTTL {
+			if loaded, err := s.Settings(); err != nil {
+				log.Printf("Outbox: %v", err)
+			} else {
+				settings, loadedAt = loaded, time.Now()
+			}
+		}
+
+		processed, err := s.processNextOutboundMessage(settings)
+		if err != nil {
+			log.Printf("Outbox: %v", err)
+		}
+		if processed {
+			continue
+		}
+		select {
+		case <-outboxWake:
+		case <-time.After(outboxPollInterval):
+		}
+	}
+}
+
+// processNextOutboundMessage claims and delivers one due message. Returns false when the queue
+// has nothing due. Messages left in "sending" by a crashed worker are picked up after the lease.
+func (s *NotificationService) processNextOutboundMessage(settings NotificationSettings) (bool, error) {
+	var msg models.OutboundMessage
+	err := s.db.Get(&msg, `UPDATE outbound_messages SET status = $1, attempts = attempts + 1,
+			locked_until = $2, updated_at = CURRENT_TIMESTAMP
+		WHERE id = (
+			SELECT id FROM outbound_messages
+			WHERE (status IN ($3, $4) AND next_attempt_at <= CURRENT_TIMESTAMP)
+				OR (status = $1 AND locked_until < CURRENT_TIMESTAMP)
+			ORDER BY next_attempt_at
+			LIMIT 1
+			FOR UPDATE SKIP LOCKED
+		)
+		RETURNING *`, OutboxSending, time.Now().Add(outboxLease), OutboxPending, OutboxFailed)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to claim outbound message: %w", err)
+	}
+
+	if msg.ExpiresAt != nil && time.Now().After(*msg.ExpiresAt) {
+		return true, s.markDead(&msg, "expired before delivery")
+	}
+	if msg.Body == nil {
+		return true, s.markDead(&msg, "message body is no longer available")
+	}
+
+	providerID, sendErr := s.deliver(&msg, settings)
+	if errors.Is(sendErr, ErrRecipientUnreachable) {
+		return true, s.markDead(&msg, sendErr.Error())
+	}
+	if sendErr != nil {
+		return true, s.recordFailure(&msg, sendErr.Error())
+	}
+
+	var providerMessageID *string
+	if providerID != "" {
+		providerMessageID = &providerID
+	}
+	_, err = s.db.Exec(`UPDATE outbound_messages SET status = $2, sent_at = CURRENT_TIMESTAMP, locked_until = NULL,
+			last_error = NULL, provider_message_id = $3,
+			body = CASE WHEN is_sensitive THEN NULL ELSE body END, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1`, msg.ID, OutboxSent, providerMessageID)
+	if err != nil {
+		return true, fmt.Errorf("failed to mark outbound message %s sent: %w", msg.ID, err)
+	}
+	s.recordEvent(msg.ID, OutboxSent, providerID)
+	return true, nil
+}
+
+// deliver sends a claimed message through the configured notifier
+func (s *NotificationService) deliver(msg *models.OutboundMessage, settings NotificationSettings) (string, error) {
+	if settings == nil {
+		return "", errors.New("notification settings are not available")
+	}
+	notifier, err := NewNotifier(msg.Channel, settings)
+	if err != nil {
+		return "", err
+	}
+
+	out := Message{Channel: msg.Channel, To: msg.Recipient, Body: *msg.Body}
+	if msg.Subject != nil {
+		out.Subject = *msg.Subject
+	}
+	if msg.HTMLBody != nil {
+		out.HTML = *msg.HTMLBody
+	}
+	if msg.AttachmentKey != nil {
+		attachment, err := loadStoredAttachment(msg)
+		if err != nil {
+			return "", err
+		}
+		out.Attachments = []Attachment{attachment}
+	}
+	if tracking, ok := notifier.(TrackingNotifier); ok {
+		return tracking.SendTracked(out)
+	}
+	return "", notifier.Send(out)
+}
+
+// loadStoredAttachment reads the attachment of a message from the object store
+func loadStoredAttachment(msg *models.OutboundMessage) (Attachment, error) {
+	reader, err := NewObjectStore().Get(*msg.AttachmentKey)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("failed to open attachment: %w", err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return Attachment{}, fmt.Errorf("failed to read attachment: %w", err)
+	}
+
+	attachment := Attachment{Filename: "attachment", ContentType: "application/octet-stream", Data: data}
+	if msg.AttachmentFilename != nil {
+		attachment.Filename = *msg.AttachmentFilename
+	}
+	if msg.AttachmentContentType != nil {
+		attachment.ContentType = *msg.AttachmentContentType
+	}
+	return attachment, nil
+}
+
+// recordFailure schedules the next attempt with exponential backoff, or moves the message to
+// dead when its attempts are exhausted, it would expire first, or its body was cleared
+func (s *NotificationService) recordFailure(msg *models.OutboundMessage, reason string) error {
+	next := time.Now().Add(OutboxBackoff(msg.Attempts))
+	if msg.Attempts >= msg.MaxAttempts || msg.Body == nil || (msg.ExpiresAt != nil && next.After(*msg.ExpiresAt)) {
+		return s.markDead(msg, reason)
+	}
+
+	err := s.db.Get(msg, `UPDATE outbound_messages SET status = $2, next_attempt_at = $3, locked_until = NULL,
+			last_error = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 RETURNING *`, msg.ID, OutboxFailed, next, truncateOutboxError(reason))
+	if err != nil {
+		return fmt.Errorf("failed to reschedule outbound message %s: %w", msg.ID, err)
+	}
+	s.recordEvent(msg.ID, OutboxFailed, reason)
+	return nil
+}
+
+// markDead gives up on a message. Sensitive bodies are cleared since they can no longer be sent.
+func (s *NotificationService) markDead(msg *models.OutboundMessage, reason string) error {
+	err := s.db.Get(msg, `UPDATE outbound_messages SET status = $2, locked_until = NULL, last_error = $3,
+			body = CASE WHEN is_sensitive THEN NULL ELSE body END, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 RETURNING *`, msg.ID, OutboxDead, truncateOutboxError(reason))
+	if err != nil {
+		return fmt.Errorf("failed to mark outbound message %s dead: %w", msg.ID, err)
+	}
+	s.recordEvent(msg.ID, OutboxDead, reason)
+	return nil
+}
+
+func (s *NotificationService) recordEvent(messageID, status, detail string) {
+	var detailValue *string
+	if detail = strings.TrimSpace(detail); detail != "" {
+		detail = truncateOutboxError(detail)
+		detailValue = &detail
+	}
+	if _, err := s.db.Exec(`INSERT INTO outbound_message_events (message_id, status, detail) VALUES ($1, $2, $3)`,
+		messageID, status, detailValue); err != nil {
+		log.Printf("Outbox: failed to record event for %s: %v", messageID, err)
+	}
+}
+
+// OutboxBackoff returns the delay before the next attempt after the given number of attempts:
+// 30s, 1m, 2m, 4m, ... capped at one hour, with up to 20% jitter
+func OutboxBackoff(attempts int) time.Duration {
+	delay := outboxBaseBackoff
+	for i := 1; i < attempts && delay < outboxMaxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > outboxMaxBackoff {
+		delay = outboxMaxBackoff
+	}
+	return delay + time.Duration(rand.Int63n(int64(delay)/5+1))
+}
+
+func truncateOutboxError(s string) string {
+	if len(s) > 1000 {
+		return s[:1000]
+	}
+	return s
+}
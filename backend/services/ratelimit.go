@@ -0,0 +1,446 @@
+package services
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// Subjects of rate limit buckets
+const (
+	RateLimitByIP   = "ip"
+	RateLimitByUser = "user"
+)
+
+// Rate limit stores
+const (
+	RateLimitStoreMemory   = "memory"   // Per replica
+	RateLimitStorePostgres = "postgres" // Shared by replicas
+)
+
+// defaultRateLimits are the requests per minute of the "<class>_<subject>" buckets when
+// rate_limit_<class>_<subject>_per_minute is not set. Other buckets are unlimited.
+var defaultRateLimits = map[string]int{
+	"auth_ip":    20,
+	"public_ip":  60,
+	"api_ip":     600,
+	"api_user":   300,
+	"admin_ip":   600,
+	"admin_user": 300,
+}
+
+// rateLimitReload is how long the settings and the ban list are cached
+const rateLimitReload = 30 * time.Second
+
+// rateLimitBucketRetention is how long idle buckets are kept in Postgres; longer ban windows in
+// system_settings are capped to it
+const rateLimitBucketRetention = 24 * time.Hour
+
+// RateLimitSettings are the keys of system_settings (category "rate_limit")
+type RateLimitSettings struct {
+	Enabled      bool           // rate_limit_enabled
+	Store        string         // rate_limit_store
+	Limits       map[string]int // rate_limit_<class>_<subject>_per_minute, by "<class>_<subject>"
+	BanThreshold int            // rate_limit_ban_threshold, 0 never bans
+	BanWindow    time.Duration  // rate_limit_ban_window_minutes
+	BanDuration  time.Duration  // rate_limit_ban_minutes
+	ExemptIPs    map[string]bool
+}
+
+// Limit returns the requests per minute of a bucket, 0 if unlimited
+func (s RateLimitSettings) Limit(class, subjectType string) int {
+	return s.Limits[class+"_"+subjectType]
+}
+
+// RateLimitRequest identifies the bucket a request takes a token from
+type RateLimitRequest struct {
+	Class       string // Route class
+	SubjectType string // RateLimitByIP or RateLimitByUser
+	Subject     string // IP address or user ID
+	IPAddress   string
+	UserAgent   string
+}
+
+// RateLimitDecision is the outcome of a request, with the state of its bucket for the
+// RateLimit-* response headers
+type RateLimitDecision struct {
+	Allowed     bool
+	Limit       int           // Requests per minute, 0 if the request is not limited
+	Remaining   int           // Requests left in the bucket
+	Reset       time.Duration // Until the bucket is full again
+	RetryAfter  time.Duration // Until the next request is allowed, if not allowed
+	BannedUntil *time.Time    // Set if the subject is banned
+}
+
+// RateLimiter limits requests with token buckets. A bucket of n requests per minute holds n
+// tokens and refills at n per minute; each request takes one. Requests rejected by any bucket
+// of a subject count towards its ban. Settings and bans are reloaded every 30 seconds.
+type RateLimiter struct {
+	db     *sqlx.DB
+	memory *memoryRateLimitStore
+
+	mu               sync.Mutex
+	settings         RateLimitSettings
+	settingsLoadedAt time.Time
+	bans             map[string]time.Time // Ban expiry by "<subject type>:<subject>"
+	bansLoadedAt     time.Time
+}
+
+// NewRateLimiter creates a rate limiter with its ban list in the database
+func NewRateLimiter(db *sqlx.DB) *RateLimiter {
+	return &RateLimiter{
+		db:     db,
+		memory: &memoryRateLimitStore{buckets: map[string]*memoryBucket{}},
+		bans:   map[string]time.Time{},
+	}
+}
+
+// Allow takes a token for a request from its bucket. Store errors are returned with a decision
+// that allows the request, so an unavailable database does not take the API down.
+func (l *RateLimiter) Allow(req RateLimitRequest) (RateLimitDecision, error) {
+	settings := l.Settings()
+	if !settings.Enabled || settings.ExemptIPs[req.IPAddress] {
+		return RateLimitDecision{Allowed: true}, nil
+	}
+
+	subjectKey := req.SubjectType + ":" + req.Subject
+	if until, banned := l.bannedUntil(subjectKey); banned {
+		return RateLimitDecision{RetryAfter: time.Until(until), BannedUntil: &until}, nil
+	}
+
+	limit := settings.Limit(req.Class, req.SubjectType)
+	if limit <= 0 {
+		return RateLimitDecision{Allowed: true}, nil
+	}
+
+	store := l.store(settings)
+	capacity := float64(limit)
+	rate := capacity / 60
+	tokens, allowed, err := store.take(req.Class+":"+subjectKey, capacity, rate)
+	if err != nil {
+		return RateLimitDecision{Allowed: true}, err
+	}
+
+	decision := RateLimitDecision{
+		Allowed:   allowed,
+		Limit:     limit,
+		Remaining: int(math.Max(0, math.Floor(tokens))),
+		Reset:     secondsDuration((capacity - tokens) / rate),
+	}
+	if allowed {
+		return decision, nil
+	}
+	decision.RetryAfter = secondsDuration((1 - tokens) / rate)
+
+	// Every rejected request takes a token from the violation bucket of the subject; the subject
+	// is banned once it is empty
+	if settings.BanThreshold <= 0 {
+		return decision, nil
+	}
+	threshold := float64(settings.BanThreshold)
+	_, underThreshold, err := store.take("violations:"+subjectKey, threshold, threshold/settings.BanWindow.Seconds())
+	if err != nil {
+		return decision, err
+	}
+	if !underThreshold {
+		until, err := l.ban(req, settings.BanDuration)
+		decision.RetryAfter = time.Until(until)
+		decision.BannedUntil = &until
+		if err != nil {
+			return decision, err
+		}
+	}
+	return decision, nil
+}
+
+// Settings returns the rate limit settings, with defaults for missing or invalid values. They
+// are cached; if they cannot be loaded, the previous (or default) settings are kept.
+func (l *RateLimiter) Settings() RateLimitSettings {
+	l.mu.Lock()
+	if l.settings.Limits != nil && time.Since(l.settingsLoadedAt) < rateLimitReload {
+		settings := l.settings
+		l.mu.Unlock()
+		return settings
+	}
+	// Other requests keep using the cached settings while they are reloaded
+	l.settingsLoadedAt = time.Now()
+	l.mu.Unlock()
+
+	values, err := l.loadSettings()
+	if err != nil {
+		log.Printf("Rate limiter: %v", err)
+	}
+	settings := parseRateLimitSettings(values)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err != nil && l.settings.Limits != nil {
+		return l.settings
+	}
+	l.settings = settings
+	return settings
+}
+
+func (l *RateLimiter) loadSettings() (map[string]string, error) {
+	rows, err := l.db.Query(`SELECT key, COALESCE(value, '') FROM system_settings WHERE category = 'rate_limit'`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load rate limit settings: %w", err)
+	}
+	defer rows.Close()
+
+	values := map[string]string{}
+	for rows.Next() {
+		var key, value string
+		if err := rows.Scan(&key, &value); err != nil {
+			return nil, fmt.Errorf("failed to load rate limit settings: %w", err)
+		}
+		values[key] = value
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("failed to load rate limit settings: %w", err)
+	}
+	return values, nil
+}
+
+func parseRateLimitSettings(values map[string]string) RateLimitSettings {
+	number := func(key string, def int) int {
+		n, err := strconv.Atoi(strings.TrimSpace(values[key]))
+		if err != nil || n < 0 {
+			return def
+		}
+		return n
+	}
+	minutes := func(key string, def int) time.Duration {
+		n := number(key, def)
+		if n == 0 {
+			n = def
+		}
+		d := time.Duration(n) * time.Minute
+		if d > rateLimitBucketRetention {
+			return rateLimitBucketRetention
+		}
+		return d
+	}
+
+	settings := RateLimitSettings{
+		Enabled:      strings.TrimSpace(values["rate_limit_enabled"]) != "false",
+		Store:        RateLimitStoreMemory,
+		Limits:       map[string]int{},
+		BanThreshold: number("rate_limit_ban_threshold", 100),
+		BanWindow:    minutes("rate_limit_ban_window_minutes", 10),
+		BanDuration:  minutes("rate_limit_ban_minutes", 60),
+		ExemptIPs:    map[string]bool{},
+	}
+	if strings.TrimSpace(values["rate_limit_store"]) == RateLimitStorePostgres {
+		settings.Store = RateLimitStorePostgres
+	}
+	for bucket, def := range defaultRateLimits {
+		settings.Limits[bucket] = number("rate_limit_"+bucket+"_per_minute", def)
+	}
+	for _, ip := range strings.Split(values["rate_limit_exempt_ips"], ",") {
+		if ip = strings.TrimSpace(ip); ip != "" {
+			settings.ExemptIPs[ip] = true
+		}
+	}
+	return settings
+}
+
+// bannedUntil returns the expiry of an active ban of a subject
+func (l *RateLimiter) bannedUntil(subjectKey string) (time.Time, bool) {
+	l.mu.Lock()
+	reload := time.Since(l.bansLoadedAt) >= rateLimitReload
+	if reload {
+		l.bansLoadedAt = time.Now()
+	}
+	l.mu.Unlock()
+
+	// Bans of other replicas
+	if reload {
+		var rows []struct {
+			SubjectType string    `db:"subject_type"`
+			Subject     string    `db:"subject"`
+			BannedUntil time.Time `db:"banned_until"`
+		}
+		err := l.db.Select(&rows, `SELECT subject_type, subject, MAX(banned_until) AS banned_until
+		                           FROM rate_limit_bans WHERE banned_until > $1
+		                           GROUP BY subject_type, subject`, time.Now())
+		if err != nil {
+			log.Printf("Rate limiter: failed to load bans: %v", err)
+		} else {
+			bans := make(map[string]time.Time, len(rows))
+			for _, row := range rows {
+				bans[row.SubjectType+":"+row.Subject] = row.BannedUntil
+			}
+			l.mu.Lock()
+			// Keep the bans of this replica that could not be stored
+			for key, until := range l.bans {
+				if _, ok := bans[key]; !ok && time.Now().Before(until) {
+					bans[key] = until
+				}
+			}
+			l.bans = bans
+			l.mu.Unlock()
+		}
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	until, ok := l.bans[subjectKey]
+	if !ok || !time.Now().Before(until) {
+		return time.Time{}, false
+	}
+	return until, true
+}
+
+// ban bans the subject of a request and records the ban in the audit log. The ban applies to
+// this replica even if it cannot be stored.
+func (l *RateLimiter) ban(req RateLimitRequest, duration time.Duration) (time.Time, error) {
+	until := time.Now().Add(duration)
+	l.mu.Lock()
+	l.bans[req.SubjectType+":"+req.Subject] = until
+	l.mu.Unlock()
+	log.Printf("Rate limiter: banned %s %s until %s (%s requests)", req.SubjectType, req.Subject,
+		until.Format(time.RFC3339), req.Class)
+
+	var banID string
+	err := l.db.QueryRow(`INSERT INTO rate_limit_bans (subject_type, subject, route_class, ip_address, user_agent, banned_until)
+	                      VALUES ($1, $2, $3, NULLIF($4, ''), $5, $6) RETURNING id`,
+		req.SubjectType, req.Subject, req.Class, req.IPAddress, req.UserAgent, until).Scan(&banID)
+	if err != nil {
+		return until, fmt.Errorf("failed to store ban: %w", err)
+	}
+
+	var userID interface{}
+	if req.SubjectType == RateLimitByUser {
+		userID = req.Subject
+	}
+	afterData, _ := json.Marshal(map[string]interface{}{
+		"subject_type": req.SubjectType,
+		"subject":      req.Subject,
+		"route_class":  req.Class,
+		"banned_until": until,
+	})
+	_, err = l.db.Exec(`INSERT INTO audit_logs (user_id, action, resource_type, resource_id, after_data, ip_address, user_agent)
+	                    VALUES ($1, 'ban', 'rate_limit', $2, $3, $4, $5)`,
+		userID, banID, string(afterData), req.IPAddress, req.UserAgent)
+	if err != nil {
+		return until, fmt.Errorf("failed to log ban: %w", err)
+	}
+	return until, nil
+}
+
+func (l *RateLimiter) store(settings RateLimitSettings) rateLimitStore {
+	if settings.Store == RateLimitStorePostgres {
+		return postgresRateLimitStore{db: l.db}
+	}
+	return l.memory
+}
+
+// rateLimitStore keeps token buckets. take refills the bucket for the time since its last
+// request and takes a token if it has one; it returns the tokens left and whether one was taken.
+type rateLimitStore interface {
+	take(key string, capacity, rate float64) (float64, bool, error)
+}
+
+type memoryBucket struct {
+	tokens    float64
+	capacity  float64
+	rate      float64
+	updatedAt time.Time
+}
+
+type memoryRateLimitStore struct {
+	mu       sync.Mutex
+	buckets  map[string]*memoryBucket
+	prunedAt time.Time
+}
+
+func (s *memoryRateLimitStore) take(key string, capacity, rate float64) (float64, bool, error) {
+	now := time.Now()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	// Full buckets are the same as missing ones
+	if now.Sub(s.prunedAt) >= time.Minute {
+		for k, b := range s.buckets {
+			if b.tokens+now.Sub(b.updatedAt).Seconds()*b.rate >= b.capacity {
+				delete(s.buckets, k)
+			}
+		}
+		s.prunedAt = now
+	}
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &memoryBucket{tokens: capacity, updatedAt: now}
+		s.buckets[key] = b
+	}
+	b.capacity, b.rate = capacity, rate
+	b.tokens = math.Min(capacity, b.tokens+now.Sub(b.updatedAt).Seconds()*rate)
+	b.updatedAt = now
+	if b.tokens < 1 {
+		return b.tokens, false, nil
+	}
+	b.tokens--
+	return b.tokens, true, nil
+}
+
+type postgresRateLimitStore struct {
+	db *sqlx.DB
+}
+
+// The refilled bucket is LEAST($2, b.tokens + seconds since the last request * $3). It is
+// computed with the database clock so replicas with skewed clocks share buckets correctly.
+const takeRateLimitTokenQuery = `
+INSERT INTO rate_limit_buckets AS b (key, tokens, allowed, updated_at)
+VALUES ($1, $2::double precision - 1, true, NOW())
+ON CONFLICT (key) DO UPDATE SET
+    tokens = LEAST($2::double precision, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3::double precision)
+             - (LEAST($2::double precision, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3::double precision) >= 1)::int,
+    allowed = LEAST($2::double precision, b.tokens + EXTRACT(EPOCH FROM NOW() - b.updated_at) * $3::double precision) >= 1,
+    updated_at = NOW()
+RETURNING tokens, allowed`
+
+func (s postgresRateLimitStore) take(key string, capacity, rate float64) (float64, bool, error) {
+	var tokens float64
+	var allowed bool
+	if err := s.db.QueryRow(takeRateLimitTokenQuery, key, capacity, rate).Scan(&tokens, &allowed); err != nil {
+		return 0, true, fmt.Errorf("failed to take rate limit token: %w", err)
+	}
+	return tokens, allowed, nil
+}
+
+// CleanupRateLimits deletes the idle buckets of the Postgres store
+func CleanupRateLimits(db *sqlx.DB) (int64, error) {
+	res, err := db.Exec(`DELETE FROM rate_limit_buckets WHERE updated_at < NOW() - $1::interval`,
+		fmt.Sprintf("%d seconds", int(rateLimitBucketRetention.Seconds())))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// StartRateLimitCleanup deletes idle rate limit buckets every hour
+func StartRateLimitCleanup(db *sqlx.DB) {
+	go func() {
+		for {
+			if n, err := CleanupRateLimits(db); err != nil {
+				log.Printf("Rate limit cleanup: %v", err)
+			} else if n > 0 {
+				log.Printf("Rate limit cleanup: deleted %d idle bucket(s)", n)
+			}
+			time.Sleep(time.Hour)
+		}
+	}()
+}
+
+func secondsDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
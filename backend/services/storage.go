@@ -0,0 +1,33 @@
+package services
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ObjectStore is a pluggable store for uploaded files (assessment attachments, etc.)
+type ObjectStore interface {
+	// Put stores an object under key
+	Put(key string, body io.Reader, size int64, contentType string) error
+	// Get opens an object for reading
+	Get(key string) (io.ReadCloser, error)
+	// Delete removes an object. Deleting a missing object is not an error.
+	Delete(key string) error
+	// SignedURL returns a time-limited download URL for an object
+	SignedURL(key string, expiry time.Duration) (string, error)
+}
+
+// NewObjectStore creates the object store configured by STORAGE_DRIVER ("local" or "s3").
+// Falls back to the local driver if the S3 driver is not fully configured.
+func NewObjectStore() ObjectStore {
+	if os.Getenv("STORAGE_DRIVER") == "s3" {
+		store, err := NewS3Store()
+		if err == nil {
+			return store
+		}
+		fmt.Printf("[Storage Warning] %v, falling back to local storage\n", err)
+	}
+	return NewLocalStore()
+}
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+	"tukem-backend/utils"
+)
+
+// LocalStore stores objects on the local filesystem.
+// Downloads are served by the API at /files/<key> with an HMAC-signed query string.
+type LocalStore struct {
+	rootDir    string
+	signingKey []byte
+}
+
+// NewLocalStore creates a local filesystem store rooted at STORAGE_LOCAL_DIR (default "uploads")
+func NewLocalStore() *LocalStore {
+	rootDir := os.Getenv("STORAGE_LOCAL_DIR")
+	if rootDir == "" {
+		rootDir = "uploads"
+	}
+
+	return &LocalStore{
+		rootDir:    rootDir,
+		signingKey: utils.SecretKey(utils.SecretStorageSigning),
+	}
+}
+
+// path resolves a key to a file path, rejecting keys that escape the root directory
+func (s *LocalStore) path(key string) (string, error) {
+	cleaned := filepath.Clean("/" + key)
+	if cleaned == "/" || strings.Contains(key, "..") {
+		return "", fmt.Errorf("invalid object key")
+	}
+	return filepath.Join(s.rootDir, cleaned), nil
+}
+
+// Put stores an object on disk
+func (s *LocalStore) Put(key string, body io.Reader, size int64, contentType string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create directory: %w", err)
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("failed to create file: %w", err)
+	}
+	defer f.Close()
+
+	if _, err := io.Copy(f, body); err != nil {
+		os.Remove(path)
+		return fmt.Errorf("failed to write file: %w", err)
+	}
+	return nil
+}
+
+// Get opens an object on disk
+func (s *LocalStore) Get(key string) (io.ReadCloser, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(path)
+}
+
+// Delete removes an object from disk
+func (s *LocalStore) Delete(key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// SignedURL returns a download URL signed with HMAC-SHA256
+func (s *LocalStore) SignedURL(key string, expiry time.Duration) (string, error) {
+	expires := time.Now().Add(expiry).Unix()
+	signature := s.sign(key, expires)
+
+	query := url.Values{}
+	query.Set("expires", strconv.FormatInt(expires, 10))
+	query.Set("signature", signature)
+	return utils.PublicURL("/files/" + key + "?" + query.Encode()), nil
+}
+
+// VerifySignature checks a signature produced by SignedURL and that it has not expired
+func (s *LocalStore) VerifySignature(key string, expiresStr string, signature string) bool {
+	expires, err := strconv.ParseInt(expiresStr, 10, 64)
+	if err != nil || time.Now().Unix() > expires {
+		return false
+	}
+	expected := s.sign(key, expires)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+func (s *LocalStore) sign(key string, expires int64) string {
+	mac := hmac.New(sha256.New, s.signingKey)
+	mac.Write([]byte(key + "\n" + strconv.FormatInt(expires, 10)))
+	return hex.EncodeToString(mac.Sum(nil))
+}
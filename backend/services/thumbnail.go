@@ -0,0 +1,146 @@
+package services
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	_ "image/gif" // Register GIF decoder
+	"image/jpeg"
+	_ "image/png" // Register PNG decoder
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+)
+
+// ThumbnailMaxSize is the maximum width/height of generated thumbnails in pixels
+const ThumbnailMaxSize = 320
+
+// ThumbnailMaxSourcePixels is the largest image (width x height) that is decoded for a thumbnail.
+// A small compressed file can declare huge dimensions, and decoding allocates all of them.
+const ThumbnailMaxSourcePixels = 40_000_000
+
+// GenerateImageThumbnail decodes a JPEG/PNG/GIF image and returns a JPEG thumbnail
+// that fits within ThumbnailMaxSize x ThumbnailMaxSize
+func GenerateImageThumbnail(r io.Reader) ([]byte, error) {
+	// Check the declared dimensions before decoding; the header bytes read are replayed
+	var header bytes.Buffer
+	config, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+	if config.Width <= 0 || config.Height <= 0 || int64(config.Width)*int64(config.Height) > ThumbnailMaxSourcePixels {
+		return nil, fmt.Errorf("image of %dx%d pixels is too large for a thumbnail", config.Width, config.Height)
+	}
+
+	src, _, err := image.Decode(io.MultiReader(&header, r))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	thumb := resizeToFit(src, ThumbnailMaxSize)
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, thumb, &jpeg.Options{Quality: 80}); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// GenerateVideoThumbnail extracts the first frame of a video with ffmpeg and returns a JPEG thumbnail.
+// Returns an error if ffmpeg is not installed.
+func GenerateVideoThumbnail(r io.Reader) ([]byte, error) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		return nil, fmt.Errorf("ffmpeg not available: %w", err)
+	}
+
+	tmpDir, err := os.MkdirTemp("", "tukem-thumb-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// ffmpeg needs a seekable input for most containers, so copy the video to disk first
+	videoPath := filepath.Join(tmpDir, "video")
+	videoFile, err := os.Create(videoPath)
+	if err != nil {
+		return nil, err
+	}
+	_, err = io.Copy(videoFile, r)
+	videoFile.Close()
+	if err != nil {
+		return nil, err
+	}
+
+	framePath := filepath.Join(tmpDir, "frame.jpg")
+	cmd := exec.Command(ffmpeg, "-y", "-loglevel", "error", "-i", videoPath,
+		"-frames:v", "1", "-vf", fmt.Sprintf("scale='min(%d,iw)':-2", ThumbnailMaxSize), framePath)
+	if output, err := cmd.CombinedOutput(); err != nil {
+		return nil, fmt.Errorf("ffmpeg failed: %v: %s", err, string(output))
+	}
+
+	frame, err := os.Open(framePath)
+	if err != nil {
+		return nil, err
+	}
+	defer frame.Close()
+
+	return GenerateImageThumbnail(frame)
+}
+
+// resizeToFit scales an image down (never up) so both sides are at most maxSize,
+// averaging the source pixels covered by each destination pixel
+func resizeToFit(src image.Image, maxSize int) image.Image {
+	bounds := src.Bounds()
+	srcW, srcH := bounds.Dx(), bounds.Dy()
+	if srcW <= maxSize && srcH <= maxSize {
+		return src
+	}
+
+	dstW, dstH := maxSize, maxSize
+	if srcW > srcH {
+		dstH = srcH * maxSize / srcW
+	} else {
+		dstW = srcW * maxSize / srcH
+	}
+	if dstW < 1 {
+		dstW = 1
+	}
+	if dstH < 1 {
+		dstH = 1
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, dstW, dstH))
+	for y := 0; y < dstH; y++ {
+		y0 := bounds.Min.Y + y*srcH/dstH
+		y1 := bounds.Min.Y + (y+1)*srcH/dstH
+		for x := 0; x < dstW; x++ {
+			x0 := bounds.Min.X + x*srcW/dstW
+			x1 := bounds.Min.X + (x+1)*srcW/dstW
+
+			var r, g, b, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r += uint64(cr)
+					g += uint64(cg)
+					b += uint64(cb)
+					a += uint64(ca)
+					n++
+				}
+			}
+			if n == 0 {
+				continue
+			}
+			dst.Set(x, y, color.RGBA64{
+				R: uint16(r / n),
+				G: uint16(g / n),
+				B: uint16(b / n),
+				A: uint16(a / n),
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,146 @@
+package utils
+
+import (
+	"database/sql"
+	"sort"
+	"tukem-backend/db"
+	"tukem-backend/models"
+
+	"github.com/lib/pq"
+)
+
+// Permissions lists every permission and whether it can be held for a facility or area. Handlers
+// behind a scoped permission filter children by the scope; the other permissions only come from
+// the admin role or a role assigned everywhere.
+var Permissions = map[string]bool{
+	"user:view":           false,
+	"user:manage":         false,
+	"role:manage":         false,
+	"facility:manage":     false,
+	"analytics:view":      false,
+	"report:view":         true,
+	"settings:manage":     false,
+	"notification:manage": false,
+	"audit:view":          false,
+	"content:manage":      false, // Milestones, WHO standards, schedules, translations and other master data
+	"kipi:view":           false,
+	"kipi:review":         false,
+	"child:view":          true,
+	"measurement:view":    true,
+	"measurement:create":  true,
+	"assessment:view":     true,
+	"immunization:view":   true,
+}
+
+// HealthWorkerRoles are the roles that can be assigned to a facility or area
+var HealthWorkerRoles = []string{"kader", "bidan", "nutritionist", "district_viewer"}
+
+// IsValidRole reports whether role is a user role
+func IsValidRole(role string) bool {
+	return role == "parent" || role == "admin" || IsHealthWorkerRole(role)
+}
+
+// IsHealthWorkerRole reports whether role is a health-worker role
+func IsHealthWorkerRole(role string) bool {
+	for _, r := range HealthWorkerRoles {
+		if r == role {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether the user has the admin role
+func IsAdmin(userID string) (bool, error) {
+	var isAdmin bool
+	err := db.DB.QueryRow(`SELECT EXISTS(SELECT 1 FROM users WHERE id = $1 AND role = 'admin')`, userID).Scan(&isAdmin)
+	return isAdmin, err
+}
+
+// PermissionScope returns where the user holds the permission, or nil if they don't
+func PermissionScope(userID, permission string) (*models.AccessScope, error) {
+	var role string
+	err := db.DB.QueryRow(`SELECT role FROM users WHERE id = $1`, userID).Scan(&role)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	if role == "admin" {
+		return &models.AccessScope{Global: true}, nil
+	}
+
+	var assignments []struct {
+		FacilityID *string `db:"facility_id"`
+		AreaID     *string `db:"area_id"`
+	}
+	err = db.DB.Select(&assignments, `SELECT ra.facility_id, ra.area_id FROM role_assignments ra
+	                                  JOIN role_permissions rp ON rp.role = ra.role
+	                                  WHERE ra.user_id = $1 AND rp.permission = $2`, userID, permission)
+	if err != nil {
+		return nil, err
+	}
+	if len(assignments) == 0 {
+		return nil, nil
+	}
+
+	facilityIDs := []string{}
+	areaIDs := []string{}
+	for _, a := range assignments {
+		switch {
+		case a.FacilityID != nil:
+			facilityIDs = append(facilityIDs, *a.FacilityID)
+		case a.AreaID != nil:
+			areaIDs = append(areaIDs, *a.AreaID)
+		default:
+			return &models.AccessScope{Global: true}, nil
+		}
+	}
+	if !Permissions[permission] {
+		return nil, nil
+	}
+
+	scoped, err := expandScope(facilityIDs, areaIDs)
+	if err != nil {
+		return nil, err
+	}
+	return &models.AccessScope{FacilityIDs: scoped}, nil
+}
+
+// UserPermissions returns every permission the user holds with its scope
+func UserPermissions(userID string) (map[string]*models.AccessScope, error) {
+	permissions := map[string]*models.AccessScope{}
+	for permission := range Permissions {
+		scope, err := PermissionScope(userID, permission)
+		if err != nil {
+			return nil, err
+		}
+		if scope != nil {
+			permissions[permission] = scope
+		}
+	}
+	return permissions, nil
+}
+
+// expandScope returns the assigned facilities, the facilities under them (posyandu of a
+// puskesmas) and every facility in the assigned areas and their sub-areas
+func expandScope(facilityIDs, areaIDs []string) ([]string, error) {
+	query := `WITH RECURSIVE scoped_areas AS (
+	              SELECT id FROM areas WHERE id = ANY($2)
+	              UNION
+	              SELECT a.id FROM areas a JOIN scoped_areas s ON a.parent_id = s.id
+	          ), scoped_facilities AS (
+	              SELECT id FROM facilities
+	              WHERE id = ANY($1) OR area_id IN (SELECT id FROM scoped_areas)
+	              UNION
+	              SELECT f.id FROM facilities f JOIN scoped_facilities s ON f.parent_id = s.id
+	          )
+	          SELECT id FROM scoped_facilities`
+
+	ids := []string{}
+	if err := db.DB.Select(&ids, query, pq.Array(facilityIDs), pq.Array(areaIDs)); err != nil {
+		return nil, err
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -124,6 +124,36 @@ func CalculateCorrectedAge(dob string, measurementDate string, isPremature bool,
 	return correctedDays, correctedMonths, true, nil
 }
 
+// Age basis labels returned by the API to say which age was used
+const (
+	AgeBasisChronological = "chronological"
+	AgeBasisCorrected     = "corrected"
+)
+
+// CalculateReferenceAge returns the age used for developmental comparisons:
+// corrected age for premature children under 24 months, chronological age otherwise.
+// Returns: ageInDays, ageInMonths, ageBasis
+func CalculateReferenceAge(dob string, date string, isPremature bool, gestationalAgeWeeks *int) (int, int, string, error) {
+	days, months, useCorrected, err := CalculateCorrectedAge(dob, date, isPremature, gestationalAgeWeeks)
+	if err != nil {
+		return 0, 0, "", err
+	}
+	if useCorrected {
+		return days, months, AgeBasisCorrected, nil
+	}
+	return days, months, AgeBasisChronological, nil
+}
+
+// IsMilestoneDue reports whether a child of the given age is expected to have reached a milestone.
+// Uses max_age_range when set, otherwise the milestone target age.
+func IsMilestoneDue(milestoneAgeMonths int, maxAgeRange *int, ageMonths int) bool {
+	dueAge := milestoneAgeMonths
+	if maxAgeRange != nil {
+		dueAge = *maxAgeRange
+	}
+	return ageMonths >= dueAge
+}
+
 // FormatAgeDisplay formats age for display (e.g., "2 years 3 months")
 func FormatAgeDisplay(ageInMonths int) string {
 	if ageInMonths < 0 {
@@ -0,0 +1,43 @@
+package utils
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"strings"
+)
+
+func certificateSignature(certificateID string) string {
+	mac := hmac.New(sha256.New, SecretKey(SecretCertificateSigning))
+	mac.Write([]byte("immunization-certificate:" + certificateID))
+	// 128 bits is plenty and keeps the QR code small
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// SignCertificateToken returns the verification token of a certificate: "<id>.<signature>"
+func SignCertificateToken(certificateID string) string {
+	return certificateID + "." + certificateSignature(certificateID)
+}
+
+// VerifyCertificateToken checks a verification token and returns the certificate ID
+func VerifyCertificateToken(token string) (string, error) {
+	parts := strings.SplitN(token, ".", 2)
+	if len(parts) != 2 || parts[0] == "" {
+		return "", fmt.Errorf("malformed token")
+	}
+	if !hmac.Equal([]byte(parts[1]), []byte(certificateSignature(parts[0]))) {
+		return "", fmt.Errorf("invalid signature")
+	}
+	return parts[0], nil
+}
+
+// PublicURL returns an absolute URL on the public API host (API_PUBLIC_URL)
+func PublicURL(path string) string {
+	publicURL := os.Getenv("API_PUBLIC_URL")
+	if publicURL == "" {
+		publicURL = "http://localhost:8080"
+	}
+	return strings.TrimRight(publicURL, "/") + path
+}
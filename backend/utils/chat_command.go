@@ -0,0 +1,134 @@
+package utils
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Chat command types understood by the WhatsApp chat interface
+const (
+	ChatCommandMeasurement  = "measurement"  // "BB 8.2 TB 70 Budi"
+	ChatCommandSchedule     = "schedule"     // "jadwal [nama]"
+	ChatCommandImmunization = "immunization" // "imunisasi [nama]"
+	ChatCommandHelp         = "help"         // "bantuan"
+	ChatCommandCancel       = "cancel"       // "batal"
+	ChatCommandChoice       = "choice"       // "2" (answer to a numbered question)
+	ChatCommandUnknown      = "unknown"
+)
+
+// ChatCommand is a parsed chat message
+type ChatCommand struct {
+	Type              string   `json:"type"`
+	Weight            float64  `json:"weight,omitempty"`             // kg
+	Height            float64  `json:"height,omitempty"`             // cm
+	HeadCircumference *float64 `json:"head_circumference,omitempty"` // cm
+	ChildName         string   `json:"child_name,omitempty"`
+	Choice            int      `json:"choice,omitempty"`
+}
+
+var chatKeywords = map[string]string{
+	"jadwal":       ChatCommandSchedule,
+	"schedule":     ChatCommandSchedule,
+	"imunisasi":    ChatCommandImmunization,
+	"vaksin":       ChatCommandImmunization,
+	"immunization": ChatCommandImmunization,
+	"vaccines":     ChatCommandImmunization,
+	"bantuan":      ChatCommandHelp,
+	"help":         ChatCommandHelp,
+	"menu":         ChatCommandHelp,
+	"batal":        ChatCommandCancel,
+	"cancel":       ChatCommandCancel,
+}
+
+// chatMeasurementFields maps measurement keywords to the field they set
+var chatMeasurementFields = map[string]string{
+	"bb": "weight",
+	"tb": "height",
+	"pb": "height", // Panjang badan, for babies measured lying down
+	"lk": "head_circumference",
+}
+
+// ParseChatCommand parses a chat message. Measurements are keyword/value pairs in any order
+// (BB = weight in kg, TB or PB = height in cm, LK = head circumference in cm; "8,2" and "BB8.2"
+// are accepted) followed or preceded by the child's name. A message with only a number is the
+// answer to a numbered question.
+func ParseChatCommand(text string) ChatCommand {
+	words := strings.Fields(strings.TrimSpace(text))
+	if len(words) == 0 {
+		return ChatCommand{Type: ChatCommandUnknown}
+	}
+
+	if len(words) == 1 {
+		if n, err := strconv.Atoi(strings.TrimSuffix(words[0], ".")); err == nil && n > 0 {
+			return ChatCommand{Type: ChatCommandChoice, Choice: n}
+		}
+	}
+
+	if commandType, ok := chatKeywords[strings.ToLower(words[0])]; ok {
+		return ChatCommand{Type: commandType, ChildName: strings.Join(words[1:], " ")}
+	}
+
+	cmd := ChatCommand{Type: ChatCommandMeasurement}
+	found := false
+	name := []string{}
+	for i := 0; i < len(words); i++ {
+		keyword, value := splitChatMeasurement(words[i])
+		field, ok := chatMeasurementFields[keyword]
+		if !ok {
+			name = append(name, words[i])
+			continue
+		}
+		if value == "" && i+1 < len(words) {
+			i++
+			value = words[i]
+		}
+		number, err := parseChatNumber(value)
+		if err != nil {
+			return ChatCommand{Type: ChatCommandUnknown}
+		}
+		found = true
+		switch field {
+		case "weight":
+			cmd.Weight = number
+		case "height":
+			cmd.Height = number
+		case "head_circumference":
+			cmd.HeadCircumference = &number
+		}
+	}
+	if !found {
+		return ChatCommand{Type: ChatCommandUnknown}
+	}
+	cmd.ChildName = strings.Join(name, " ")
+	return cmd
+}
+
+// splitChatMeasurement splits "BB8.2" or "bb:8,2" into the keyword and value; a bare keyword
+// returns an empty value
+func splitChatMeasurement(word string) (string, string) {
+	lower := strings.ToLower(word)
+	for keyword := range chatMeasurementFields {
+		if lower == keyword {
+			return keyword, ""
+		}
+		if strings.HasPrefix(lower, keyword) {
+			value := strings.TrimLeft(lower[len(keyword):], ":=")
+			if value == "" {
+				return keyword, "" // "BB:" followed by the value
+			}
+			if value[0] >= '0' && value[0] <= '9' {
+				return keyword, value
+			}
+		}
+	}
+	return "", ""
+}
+
+// parseChatNumber parses a decimal number written with a dot or comma, with an optional unit
+func parseChatNumber(value string) (float64, error) {
+	value = strings.ToLower(value)
+	for _, unit := range []string{"kg", "cm"} {
+		value = strings.TrimSuffix(value, unit)
+	}
+	return strconv.ParseFloat(strings.ReplaceAll(value, ",", "."), 64)
+}
@@ -0,0 +1,62 @@
+package utils
+
+import (
+	"sort"
+	"time"
+)
+
+// MeasurementIntervalMonths returns the recommended interval between growth measurements:
+// monthly under 2 years, every 3 months until 5 years, every 6 months after that
+func MeasurementIntervalMonths(ageMonths int) int {
+	switch {
+	case ageMonths < 24:
+		return 1
+	case ageMonths < 60:
+		return 3
+	default:
+		return 6
+	}
+}
+
+// NextMeasurementDate returns the date of the next recommended growth measurement.
+// Without a previous measurement, or when it is overdue, the next measurement is due today.
+func NextMeasurementDate(lastMeasurement *time.Time, ageMonths int, today time.Time) time.Time {
+	if lastMeasurement == nil {
+		return today
+	}
+	next := lastMeasurement.AddDate(0, MeasurementIntervalMonths(ageMonths), 0)
+	if next.Before(today) {
+		return today
+	}
+	return next
+}
+
+// NextKPSPScreening returns the next KPSP screening age (in months) and its date.
+// screeningAges are the ages that have KPSP questionnaires; ages already screened are skipped.
+// correctionDays shifts the date for premature children assessed on corrected age.
+// Returns false if there is no screening left.
+func NextKPSPScreening(dob time.Time, screeningAges []int, screened map[int]bool, ageMonths, correctionDays int) (int, time.Time, bool) {
+	ages := append([]int(nil), screeningAges...)
+	sort.Ints(ages)
+
+	for _, age := range ages {
+		if age < ageMonths || screened[age] {
+			continue
+		}
+		return age, dob.AddDate(0, age, correctionDays), true
+	}
+	return 0, time.Time{}, false
+}
+
+// PrematureCorrectionDays returns the number of days corrected age lags behind chronological age
+// (40 weeks minus gestational age), or 0 when corrected age is not used
+func PrematureCorrectionDays(isPremature bool, gestationalAgeWeeks *int, chronologicalAgeDays int) int {
+	if !isPremature || gestationalAgeWeeks == nil || chronologicalAgeDays >= 730 {
+		return 0
+	}
+	weeksPremature := 40 - *gestationalAgeWeeks
+	if weeksPremature < 0 {
+		return 0
+	}
+	return weeksPremature * 7
+}
@@ -0,0 +1,77 @@
+package utils
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"tukem-backend/db"
+	"tukem-backend/models"
+)
+
+// Guardian roles of a child
+const (
+	GuardianOwner  = "owner"  // Manages access, transfers ownership and can delete the child
+	GuardianEditor = "editor" // Records and changes the child's data
+	GuardianViewer = "viewer" // Reads only
+)
+
+var guardianRoleRank = map[string]int{
+	GuardianViewer: 1,
+	GuardianEditor: 2,
+	GuardianOwner:  3,
+}
+
+var (
+	ErrChildNotFound     = errors.New("child not found")
+	ErrChildAccessDenied = errors.New("child access denied")
+)
+
+// IsGuardianRole reports whether role is a guardian role
+func IsGuardianRole(role string) bool {
+	_, ok := guardianRoleRank[role]
+	return ok
+}
+
+// HasGuardianRole reports whether role grants at least minRole. An empty role grants nothing.
+func HasGuardianRole(role, minRole string) bool {
+	return role != "" && guardianRoleRank[role] >= guardianRoleRank[minRole]
+}
+
+// AuthorizeChild loads the child with the user's guardian role in AccessRole and checks that the
+// role is at least minRole. Returns ErrChildNotFound or ErrChildAccessDenied when the user may not
+// use the child.
+func AuthorizeChild(childID, userID, minRole string) (*models.Child, error) {
+	if ValidateUUID(childID) != nil {
+		return nil, ErrChildNotFound
+	}
+
+	var child models.Child
+	err := db.DB.QueryRow(`SELECT c.id, c.parent_id, c.name, c.dob, c.gender, c.birth_weight, c.birth_height,
+	                       c.is_premature, c.gestational_age, c.facility_id, c.created_at,
+	                       COALESCE((SELECT g.role FROM child_guardians g WHERE g.child_id = c.id AND g.user_id = $2), '')
+	                       FROM children c WHERE c.id = $1`, childID, userID).Scan(
+		&child.ID, &child.ParentID, &child.Name, &child.DOB, &child.Gender, &child.BirthWeight, &child.BirthHeight,
+		&child.IsPremature, &child.GestationalAge, &child.FacilityID, &child.CreatedAt, &child.AccessRole)
+	if err == sql.ErrNoRows {
+		return nil, ErrChildNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !HasGuardianRole(child.AccessRole, minRole) {
+		return nil, ErrChildAccessDenied
+	}
+	return &child, nil
+}
+
+// ChildAccessStatus returns the HTTP status and error message for an AuthorizeChild error
+func ChildAccessStatus(err error) (int, string) {
+	switch {
+	case errors.Is(err, ErrChildNotFound):
+		return http.StatusNotFound, "Child not found"
+	case errors.Is(err, ErrChildAccessDenied):
+		return http.StatusForbidden, "Unauthorized"
+	default:
+		return http.StatusInternalServerError, "Failed to verify child access"
+	}
+}
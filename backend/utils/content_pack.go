@@ -0,0 +1,316 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/jmoiron/sqlx"
+	"tukem-backend/models"
+)
+
+// ValidContentPackSources are the milestone sources that can be managed with content packs
+var ValidContentPackSources = []string{"KPSP", "DENVER"}
+
+// CategoryForDenverDomain maps a Denver II domain to the pyramid category used for KPSP milestones
+func CategoryForDenverDomain(domain *string) string {
+	if domain == nil {
+		return "cognitive"
+	}
+	switch *domain {
+	case "PS":
+		return "sensory"
+	case "FM", "GM":
+		return "motor"
+	default:
+		return "cognitive"
+	}
+}
+
+// NormalizeContentPack trims fields and fills defaults (pack name, category from Denver domain)
+func NormalizeContentPack(pack *models.ContentPackFile) {
+	pack.Source = strings.ToUpper(strings.TrimSpace(pack.Source))
+	pack.Version = strings.TrimSpace(pack.Version)
+	pack.Name = strings.TrimSpace(pack.Name)
+	if pack.Name == "" {
+		pack.Name = pack.Source + " " + pack.Version
+	}
+
+	for i := range pack.Milestones {
+		m := &pack.Milestones[i]
+		m.Key = strings.TrimSpace(m.Key)
+		m.Question = strings.TrimSpace(m.Question)
+		if m.Category == "" {
+			m.Category = CategoryForDenverDomain(m.DenverDomain)
+		}
+		for j := range m.Stimulation {
+			if m.Stimulation[j].Category == "" {
+				m.Stimulation[j].Category = m.Category
+			}
+			if m.Stimulation[j].ContentType == "" {
+				m.Stimulation[j].ContentType = "article"
+			}
+		}
+	}
+}
+
+// ValidateContentPack validates a content pack file before import
+func ValidateContentPack(pack models.ContentPackFile) error {
+	validSource := false
+	for _, s := range ValidContentPackSources {
+		if pack.Source == s {
+			validSource = true
+			break
+		}
+	}
+	if !validSource {
+		return fmt.Errorf("source must be one of: %s", strings.Join(ValidContentPackSources, ", "))
+	}
+	if err := ValidateStringLength(pack.Version, 1, 50, "version"); err != nil {
+		return err
+	}
+	if err := ValidateStringLength(pack.Name, 1, 255, "name"); err != nil {
+		return err
+	}
+	if len(pack.Milestones) == 0 {
+		return fmt.Errorf("pack must contain at least one milestone")
+	}
+
+	validCategories := map[string]bool{"sensory": true, "motor": true, "perception": true, "cognitive": true}
+	validDomains := map[string]bool{"PS": true, "FM": true, "L": true, "GM": true}
+	keys := make(map[string]bool)
+
+	for i, m := range pack.Milestones {
+		if m.Key != "" {
+			if keys[m.Key] {
+				return fmt.Errorf("milestone %d: duplicate key %q", i+1, m.Key)
+			}
+			keys[m.Key] = true
+			if len(m.Key) > 100 {
+				return fmt.Errorf("milestone %d: key must be at most 100 characters", i+1)
+			}
+		}
+		if m.Question == "" {
+			return fmt.Errorf("milestone %d: question is required", i+1)
+		}
+		if m.AgeMonths < 0 || m.AgeMonths > 72 {
+			return fmt.Errorf("milestone %d: age_months must be between 0 and 72", i+1)
+		}
+		if m.MinAgeRange != nil && m.MaxAgeRange != nil && *m.MinAgeRange > *m.MaxAgeRange {
+			return fmt.Errorf("milestone %d: min_age_range must not exceed max_age_range", i+1)
+		}
+		if !validCategories[m.Category] {
+			return fmt.Errorf("milestone %d: invalid category %q", i+1, m.Category)
+		}
+		if m.PyramidLevel < 1 || m.PyramidLevel > 4 {
+			return fmt.Errorf("milestone %d: pyramid_level must be between 1 and 4", i+1)
+		}
+		if m.DenverDomain != nil && !validDomains[*m.DenverDomain] {
+			return fmt.Errorf("milestone %d: invalid denver_domain %q", i+1, *m.DenverDomain)
+		}
+		if pack.Source == "DENVER" && m.DenverDomain == nil {
+			return fmt.Errorf("milestone %d: denver_domain is required for DENVER packs", i+1)
+		}
+		for j, s := range m.Stimulation {
+			if s.Title == "" || s.URL == "" {
+				return fmt.Errorf("milestone %d, stimulation %d: title and url are required", i+1, j+1)
+			}
+			if s.ContentType != "video" && s.ContentType != "article" {
+				return fmt.Errorf("milestone %d, stimulation %d: content_type must be video or article", i+1, j+1)
+			}
+		}
+	}
+
+	return nil
+}
+
+// contentPackItemKey returns the key used to match a milestone across versions.
+// Items without an explicit key are matched on age and question text.
+func contentPackItemKey(m models.ContentPackMilestone) string {
+	if m.Key != "" {
+		return m.Key
+	}
+	return fmt.Sprintf("%d|%s", m.AgeMonths, strings.ToLower(strings.TrimSpace(m.Question)))
+}
+
+// DiffContentPacks compares an incoming pack against the current one (nil if the source has no active pack)
+func DiffContentPacks(current *models.ContentPackFile, incoming models.ContentPackFile) models.ContentPackDiff {
+	diff := models.ContentPackDiff{
+		Source:     incoming.Source,
+		NewVersion: incoming.Version,
+		Added:      []models.ContentPackMilestone{},
+		Removed:    []models.ContentPackMilestone{},
+		Changed:    []models.ContentPackChange{},
+	}
+
+	existing := make(map[string]models.ContentPackMilestone)
+	if current != nil {
+		diff.CurrentVersion = &current.Version
+		for _, m := range current.Milestones {
+			existing[contentPackItemKey(m)] = m
+		}
+	}
+
+	// Items without an explicit key may still match on age and question text
+	byText := make(map[string]string)
+	for key, m := range existing {
+		byText[contentPackItemKey(models.ContentPackMilestone{AgeMonths: m.AgeMonths, Question: m.Question})] = key
+	}
+
+	matched := make(map[string]bool)
+	for _, m := range incoming.Milestones {
+		key := contentPackItemKey(m)
+		before, ok := existing[key]
+		if !ok && m.Key == "" {
+			if k, found := byText[key]; found {
+				key, before, ok = k, existing[k], true
+			}
+		}
+		if !ok || matched[key] {
+			diff.Added = append(diff.Added, m)
+			continue
+		}
+		matched[key] = true
+
+		if fields := changedMilestoneFields(before, m); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, models.ContentPackChange{
+				Key:    key,
+				Fields: fields,
+				Before: before,
+				After:  m,
+			})
+		} else {
+			diff.UnchangedCount++
+		}
+	}
+
+	if current != nil {
+		for _, m := range current.Milestones {
+			if !matched[contentPackItemKey(m)] {
+				diff.Removed = append(diff.Removed, m)
+			}
+		}
+	}
+
+	return diff
+}
+
+// changedMilestoneFields lists the fields that differ between two versions of a milestone
+func changedMilestoneFields(a, b models.ContentPackMilestone) []string {
+	var fields []string
+	if a.AgeMonths != b.AgeMonths {
+		fields = append(fields, "age_months")
+	}
+	if !equalIntPtr(a.MinAgeRange, b.MinAgeRange) {
+		fields = append(fields, "min_age_range")
+	}
+	if !equalIntPtr(a.MaxAgeRange, b.MaxAgeRange) {
+		fields = append(fields, "max_age_range")
+	}
+	if a.Category != b.Category {
+		fields = append(fields, "category")
+	}
+	if a.Question != b.Question {
+		fields = append(fields, "question")
+	}
+	if a.QuestionEn != b.QuestionEn {
+		fields = append(fields, "question_en")
+	}
+	if a.IsRedFlag != b.IsRedFlag {
+		fields = append(fields, "is_red_flag")
+	}
+	if a.PyramidLevel != b.PyramidLevel {
+		fields = append(fields, "pyramid_level")
+	}
+	if !equalStringPtr(a.DenverDomain, b.DenverDomain) {
+		fields = append(fields, "denver_domain")
+	}
+	if len(a.Stimulation) != len(b.Stimulation) {
+		fields = append(fields, "stimulation")
+	} else {
+		for i := range a.Stimulation {
+			if a.Stimulation[i].Title != b.Stimulation[i].Title || a.Stimulation[i].URL != b.Stimulation[i].URL {
+				fields = append(fields, "stimulation")
+				break
+			}
+		}
+	}
+	return fields
+}
+
+func equalIntPtr(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+func equalStringPtr(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
+
+// EnsureBaselineContentPacks registers the seeded milestones of each source as its "baseline"
+// content pack, so later pack versions can be diffed against and rolled back to them
+func EnsureBaselineContentPacks(db *sqlx.DB) error {
+	var sources []string
+	err := db.Select(&sources, `
+		SELECT DISTINCT m.source FROM milestones m
+		WHERE m.content_pack_id IS NULL
+			AND m.source IS NOT NULL
+			AND NOT EXISTS (SELECT 1 FROM content_packs p WHERE p.source = m.source)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to check content packs: %v", err)
+	}
+
+	for _, source := range sources {
+		tx, err := db.Beginx()
+		if err != nil {
+			return fmt.Errorf("failed to begin transaction: %v", err)
+		}
+
+		var packID string
+		err = tx.QueryRow(`
+			INSERT INTO content_packs (source, version, name, notes, status, activated_at)
+			VALUES ($1, 'baseline', $2, 'Milestones seeded from data/ before content packs were introduced', 'active', NOW())
+			RETURNING id`, source, source+" baseline").Scan(&packID)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to create baseline pack for %s: %v", source, err)
+		}
+
+		result, err := tx.Exec(`UPDATE milestones SET content_pack_id = $1 WHERE source = $2 AND content_pack_id IS NULL`, packID, source)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to assign milestones to baseline pack for %s: %v", source, err)
+		}
+		milestoneCount, _ := result.RowsAffected()
+
+		// Stimulation content linked to these milestones becomes part of the pack
+		result, err = tx.Exec(`
+			UPDATE stimulation_content SET content_pack_id = $1
+			WHERE content_pack_id IS NULL
+				AND milestone_id IN (SELECT id FROM milestones WHERE content_pack_id = $1)`, packID)
+		if err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to assign content to baseline pack for %s: %v", source, err)
+		}
+		contentCount, _ := result.RowsAffected()
+
+		if _, err := tx.Exec(`UPDATE content_packs SET milestone_count = $1, content_count = $2 WHERE id = $3`,
+			milestoneCount, contentCount, packID); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("failed to update baseline pack for %s: %v", source, err)
+		}
+
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit baseline pack for %s: %v", source, err)
+		}
+		log.Printf("Registered %d %s milestones as baseline content pack", milestoneCount, source)
+	}
+
+	return nil
+}
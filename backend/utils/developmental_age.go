@@ -0,0 +1,94 @@
+package utils
+
+import (
+	"math"
+	"sort"
+)
+
+// DenverDomains lists the Denver II domains in chart order
+var DenverDomains = []string{"PS", "FM", "L", "GM"}
+
+// kpspCategoryDomains maps KPSP categories onto the closest Denver II domain.
+// KPSP milestones have no denver_domain, so this is used as a fallback.
+var kpspCategoryDomains = map[string]string{
+	"sensory":    "PS",
+	"perception": "FM",
+	"cognitive":  "L",
+	"motor":      "GM",
+}
+
+// DevelopmentItem is one assessed milestone used for the age-equivalent calculation
+type DevelopmentItem struct {
+	AgeMonths int
+	Status    string // yes, no, sometimes
+}
+
+// DenverDomainForMilestone returns the Denver II domain of a milestone,
+// falling back to the KPSP category mapping. Returns "" if unknown.
+func DenverDomainForMilestone(denverDomain *string, category string) string {
+	if denverDomain != nil && *denverDomain != "" {
+		return *denverDomain
+	}
+	return kpspCategoryDomains[category]
+}
+
+// CalculateAgeEquivalent returns the age-equivalent (in months) for a set of
+// answers within one domain, plus the number of items passed.
+// Items are grouped by target age; an age level counts as passed when its
+// score reaches 50% ("yes" = 1, "sometimes" = 0.5, "no" = 0).
+// As with a Denver II basal, the age-equivalent is the highest passed age level
+// below the first failed one: passing older items does not make up for failed
+// younger ones.
+func CalculateAgeEquivalent(items []DevelopmentItem) (float64, int) {
+	totalByAge := make(map[int]int)
+	scoreByAge := make(map[int]float64)
+	passed := 0
+
+	for _, item := range items {
+		totalByAge[item.AgeMonths]++
+		switch item.Status {
+		case "yes":
+			scoreByAge[item.AgeMonths] += 1.0
+			passed++
+		case "sometimes":
+			scoreByAge[item.AgeMonths] += 0.5
+		}
+	}
+
+	ages := make([]int, 0, len(totalByAge))
+	for age := range totalByAge {
+		ages = append(ages, age)
+	}
+	sort.Ints(ages)
+
+	ageEquivalent := 0.0
+	for _, age := range ages {
+		if scoreByAge[age]/float64(totalByAge[age]) < 0.5 {
+			break
+		}
+		ageEquivalent = float64(age)
+	}
+
+	return ageEquivalent, passed
+}
+
+// CalculateDQ calculates the developmental quotient: age-equivalent / age * 100.
+// Returns nil when the reference age is 0 (DQ is undefined for newborns).
+func CalculateDQ(ageEquivalentMonths, referenceAgeMonths float64) *float64 {
+	if referenceAgeMonths <= 0 {
+		return nil
+	}
+	dq := RoundTo(ageEquivalentMonths/referenceAgeMonths*100, 2)
+	return &dq
+}
+
+// DaysToMonths converts an age in days to fractional months (30.44 days per month)
+func DaysToMonths(days int) float64 {
+	return RoundTo(float64(days)/30.44, 2)
+}
+
+// RoundTo rounds a value to the given number of decimal places
+func RoundTo(value float64, places int) float64 {
+	factor := math.Pow(10, float64(places))
+	return math.Round(value*factor) / factor
+}
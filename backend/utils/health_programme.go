@@ -0,0 +1,147 @@
+package utils
+
+import (
+	"time"
+	"tukem-backend/models"
+)
+
+// Health programme schedule types
+const (
+	ProgrammeScheduleCampaign = "campaign" // Given in fixed calendar months, e.g. Vitamin A in February and August
+	ProgrammeScheduleAge      = "age"      // Given at an age, optionally repeated every interval
+)
+
+// Health programme slot statuses
+const (
+	ProgrammeSlotCompleted = "completed"
+	ProgrammeSlotDue       = "due"
+	ProgrammeSlotUpcoming  = "upcoming"
+	ProgrammeSlotMissed    = "missed"
+)
+
+// ValidProgrammeTypes are the allowed health programme types
+var ValidProgrammeTypes = []string{"vitamin_a", "deworming", "supplement"}
+
+// ProgrammeSlot is one dose window of a health programme for a child
+type ProgrammeSlot struct {
+	Number    int
+	Start     time.Time
+	End       time.Time
+	Status    string
+	DoseIndex int // Index of the matched dose, -1 when not given
+}
+
+// ProgrammeSlots returns the dose windows of a programme for a child born on dob, from the minimum
+// to the end of the maximum age month. Campaign programmes get one window per campaign month
+// (clipped to the eligible ages); age programmes get one window per interval, anchored on the
+// date of birth.
+func ProgrammeSlots(programme models.HealthProgramme, dob time.Time) []ProgrammeSlot {
+	eligibleFrom := dob.AddDate(0, programme.AgeMinMonths, 0)
+	eligibleUntil := dob.AddDate(0, programme.AgeMaxMonths+1, -1)
+	if eligibleUntil.Before(eligibleFrom) {
+		return nil
+	}
+
+	var slots []ProgrammeSlot
+	add := func(start, end time.Time) {
+		slots = append(slots, ProgrammeSlot{Number: len(slots) + 1, Start: start, End: end, DoseIndex: -1})
+	}
+
+	if programme.ScheduleType == ProgrammeScheduleCampaign {
+		campaign := make(map[time.Month]bool)
+		for _, m := range programme.CampaignMonths {
+			campaign[time.Month(m)] = true
+		}
+		month := time.Date(eligibleFrom.Year(), eligibleFrom.Month(), 1, 0, 0, 0, 0, eligibleFrom.Location())
+		for !month.After(eligibleUntil) {
+			if campaign[month.Month()] {
+				start, end := month, month.AddDate(0, 1, -1)
+				if start.Before(eligibleFrom) {
+					start = eligibleFrom
+				}
+				if end.After(eligibleUntil) {
+					end = eligibleUntil
+				}
+				add(start, end)
+			}
+			month = month.AddDate(0, 1, 0)
+		}
+		return slots
+	}
+
+	interval := 0
+	if programme.IntervalMonths != nil {
+		interval = *programme.IntervalMonths
+	}
+	if interval <= 0 {
+		add(eligibleFrom, eligibleUntil)
+		return slots
+	}
+	for n := 0; ; n++ {
+		// Offsets from the date of birth avoid drifting at month ends
+		start := dob.AddDate(0, programme.AgeMinMonths+n*interval, 0)
+		if start.After(eligibleUntil) {
+			break
+		}
+		end := dob.AddDate(0, programme.AgeMinMonths+(n+1)*interval, -1)
+		if end.After(eligibleUntil) {
+			end = eligibleUntil
+		}
+		add(start, end)
+	}
+	return slots
+}
+
+// EvaluateProgrammeSlots matches given doses (sorted by date) to the slots and sets each slot's
+// status. A dose counts for the slot whose window contains its date; each dose counts once.
+func EvaluateProgrammeSlots(slots []ProgrammeSlot, given []time.Time, today time.Time) {
+	used := make([]bool, len(given))
+	for i := range slots {
+		slot := &slots[i]
+		slot.DoseIndex = -1
+		for j, date := range given {
+			if !used[j] && !date.Before(slot.Start) && !date.After(slot.End) {
+				used[j] = true
+				slot.DoseIndex = j
+				break
+			}
+		}
+
+		switch {
+		case slot.DoseIndex >= 0:
+			slot.Status = ProgrammeSlotCompleted
+		case today.After(slot.End):
+			slot.Status = ProgrammeSlotMissed
+		case !today.Before(slot.Start):
+			slot.Status = ProgrammeSlotDue
+		default:
+			slot.Status = ProgrammeSlotUpcoming
+		}
+	}
+}
+
+// NextProgrammeSlot returns the first slot that is due or upcoming, or nil
+func NextProgrammeSlot(slots []ProgrammeSlot) *ProgrammeSlot {
+	for i := range slots {
+		if slots[i].Status == ProgrammeSlotDue || slots[i].Status == ProgrammeSlotUpcoming {
+			return &slots[i]
+		}
+	}
+	return nil
+}
+
+// CoverageProgrammeSlot returns the slot that counts for coverage analytics: the latest slot that
+// is completed or has closed, if it closed within the last 12 months. An open round that is not
+// given yet does not count against the child. Returns nil if the child is not in the denominator.
+func CoverageProgrammeSlot(slots []ProgrammeSlot, today time.Time) *ProgrammeSlot {
+	var latest *ProgrammeSlot
+	for i := range slots {
+		if slots[i].Status == ProgrammeSlotCompleted || slots[i].Status == ProgrammeSlotMissed {
+			latest = &slots[i]
+		}
+	}
+	if latest == nil || latest.End.Before(today.AddDate(-1, 0, 0)) {
+		return nil
+	}
+	return latest
+}
@@ -0,0 +1,90 @@
+package utils
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// DefaultLanguage is the language of the base texts stored on milestones, content, etc.
+const DefaultLanguage = "id"
+
+// SupportedLanguages are the languages texts can be translated to
+// (Bahasa Indonesia, English, Javanese, Sundanese)
+var SupportedLanguages = []string{"id", "en", "jv", "su"}
+
+// TranslatableFields lists the fields that can be translated per entity type
+var TranslatableFields = map[string][]string{
+	"milestone":             {"question"},
+	"stimulation_content":   {"title", "description"},
+	"immunization_schedule": {"name", "description"},
+	"pyramid_rule":          {"message"},
+}
+
+// NormalizeLanguage reduces a language tag ("jv-ID", "EN_us") to a supported base language.
+// Returns an empty string if the language is not supported.
+func NormalizeLanguage(tag string) string {
+	tag = strings.ToLower(strings.TrimSpace(tag))
+	if idx := strings.IndexAny(tag, "-_"); idx != -1 {
+		tag = tag[:idx]
+	}
+	// "in" is the deprecated ISO 639 code for Indonesian, still sent by some Android devices
+	if tag == "in" {
+		tag = "id"
+	}
+	for _, lang := range SupportedLanguages {
+		if tag == lang {
+			return lang
+		}
+	}
+	return ""
+}
+
+// ParseAcceptLanguage returns the supported language with the highest quality value
+// in an Accept-Language header, or an empty string if none is supported
+func ParseAcceptLanguage(header string) string {
+	type candidate struct {
+		lang    string
+		quality float64
+		order   int
+	}
+
+	var candidates []candidate
+	for i, part := range strings.Split(header, ",") {
+		fields := strings.Split(strings.TrimSpace(part), ";")
+		lang := NormalizeLanguage(fields[0])
+		if lang == "" {
+			continue
+		}
+		quality := 1.0
+		for _, param := range fields[1:] {
+			param = strings.TrimSpace(param)
+			if strings.HasPrefix(param, "q=") {
+				if q, err := strconv.ParseFloat(param[2:], 64); err == nil {
+					quality = q
+				}
+			}
+		}
+		if quality > 0 {
+			candidates = append(candidates, candidate{lang, quality, i})
+		}
+	}
+	if len(candidates) == 0 {
+		return ""
+	}
+
+	sort.SliceStable(candidates, func(a, b int) bool {
+		return candidates[a].quality > candidates[b].quality
+	})
+	return candidates[0].lang
+}
+
+// IsTranslatableField reports whether a field of an entity type can be translated
+func IsTranslatableField(entityType, field string) bool {
+	for _, f := range TranslatableFields[entityType] {
+		if f == field {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,84 @@
+package utils
+
+import (
+	"strings"
+	"time"
+)
+
+// CalendarEvent is an all-day event in an iCalendar feed
+type CalendarEvent struct {
+	UID         string // Stable across feed refreshes so calendar apps update the event in place
+	Date        time.Time
+	Summary     string
+	Description string
+	Alarms      []string // VALARM triggers relative to the start of the day, e.g. "-PT16H"
+}
+
+// Default reminders for all-day events: 08:00 one day before and 08:00 one week before
+const (
+	AlarmDayBefore  = "-PT16H"
+	AlarmWeekBefore = "-P6DT16H"
+)
+
+// BuildICS renders events as an iCalendar (RFC 5545) document
+func BuildICS(calendarName string, events []CalendarEvent, now time.Time) string {
+	var b strings.Builder
+	stamp := now.UTC().Format("20060102T150405Z")
+
+	writeICSLine(&b, "BEGIN:VCALENDAR")
+	writeICSLine(&b, "VERSION:2.0")
+	writeICSLine(&b, "PRODID:-//Tukem//Tumbuh Kembang Anak//ID")
+	writeICSLine(&b, "CALSCALE:GREGORIAN")
+	writeICSLine(&b, "METHOD:PUBLISH")
+	writeICSLine(&b, "X-WR-CALNAME:"+escapeICSText(calendarName))
+	writeICSLine(&b, "REFRESH-INTERVAL;VALUE=DURATION:PT12H")
+	writeICSLine(&b, "X-PUBLISHED-TTL:PT12H")
+
+	for _, event := range events {
+		writeICSLine(&b, "BEGIN:VEVENT")
+		writeICSLine(&b, "UID:"+event.UID)
+		writeICSLine(&b, "DTSTAMP:"+stamp)
+		writeICSLine(&b, "DTSTART;VALUE=DATE:"+event.Date.Format("20060102"))
+		writeICSLine(&b, "DTEND;VALUE=DATE:"+event.Date.AddDate(0, 0, 1).Format("20060102"))
+		writeICSLine(&b, "SUMMARY:"+escapeICSText(event.Summary))
+		if event.Description != "" {
+			writeICSLine(&b, "DESCRIPTION:"+escapeICSText(event.Description))
+		}
+		writeICSLine(&b, "TRANSP:TRANSPARENT")
+		for _, trigger := range event.Alarms {
+			writeICSLine(&b, "BEGIN:VALARM")
+			writeICSLine(&b, "ACTION:DISPLAY")
+			writeICSLine(&b, "DESCRIPTION:"+escapeICSText(event.Summary))
+			writeICSLine(&b, "TRIGGER:"+trigger)
+			writeICSLine(&b, "END:VALARM")
+		}
+		writeICSLine(&b, "END:VEVENT")
+	}
+
+	writeICSLine(&b, "END:VCALENDAR")
+	return b.String()
+}
+
+// escapeICSText escapes a TEXT value (backslash, semicolon, comma and newlines)
+func escapeICSText(text string) string {
+	replacer := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`)
+	return replacer.Replace(text)
+}
+
+// writeICSLine writes a content line, folded at 75 octets without splitting UTF-8 characters.
+// Continuation lines start with a space, which counts towards their 75 octets.
+func writeICSLine(b *strings.Builder, line string) {
+	maxOctets := 75
+	for len(line) > maxOctets {
+		cut := maxOctets
+		for cut > 0 && line[cut]&0xC0 == 0x80 {
+			cut--
+		}
+		b.WriteString(line[:cut])
+		b.WriteString("\r\n ")
+		line = line[cut:]
+		maxOctets = 74
+	}
+	b.WriteString(line)
+	b.WriteString("\r\n")
+}
@@ -0,0 +1,180 @@
+package utils
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"tukem-backend/models"
+)
+
+// CatchUpWindowDays is how many days after the optimal age a dose still counts as on schedule
+const CatchUpWindowDays = 7
+
+// catchUpSeries tracks the doses of one vaccine series while a plan is generated
+type catchUpSeries struct {
+	doses    []models.ImmunizationSchedule
+	next     int           // Index of the next dose to plan
+	previous *PreviousDose // Last dose given or planned
+	given    int           // Number of doses given or planned
+}
+
+// GenerateCatchUpPlan builds a catch-up plan from the active schedule and the doses already given
+// (schedule ID -> given date). Doses of different vaccines that fall on the same date are grouped
+// into one visit, as IDAI allows simultaneous administration. Within a series doses are planned in
+// order, at most one per visit, respecting minimum age, minimum interval, total doses and maximum age.
+func GenerateCatchUpPlan(dob, today time.Time, schedules []models.ImmunizationSchedule, given map[string]time.Time) ([]models.CatchUpVisit, []models.CatchUpMissedDose) {
+	visits := []models.CatchUpVisit{}
+	missed := []models.CatchUpMissedDose{}
+
+	// Group schedule items by series, ordered by dose number
+	seriesByName := make(map[string]*catchUpSeries)
+	names := []string{}
+	for _, schedule := range schedules {
+		series, ok := seriesByName[schedule.Name]
+		if !ok {
+			series = &catchUpSeries{}
+			seriesByName[schedule.Name] = series
+			names = append(names, schedule.Name)
+		}
+		series.doses = append(series.doses, schedule)
+	}
+
+	// Skip doses already given; the next dose follows the highest dose given
+	for _, name := range names {
+		series := seriesByName[name]
+		sort.SliceStable(series.doses, func(i, j int) bool {
+			return series.doses[i].DoseNumber < series.doses[j].DoseNumber
+		})
+		for i, dose := range series.doses {
+			if date, ok := given[dose.ID]; ok {
+				givenDate := date
+				series.previous = &PreviousDose{DoseNumber: dose.DoseNumber, Given: &givenDate}
+				series.next = i + 1
+				series.given++
+			}
+		}
+	}
+
+	// Each iteration plans one visit; every series advances by at most one dose per visit
+	for len(visits) <= len(schedules) {
+		type candidate struct {
+			series *catchUpSeries
+			dose   models.CatchUpDose
+			date   time.Time
+		}
+		var candidates []candidate
+
+		for _, name := range names {
+			series := seriesByName[name]
+			for series.next < len(series.doses) {
+				schedule := series.doses[series.next]
+
+				// Series limit: never plan more doses than the series has
+				if schedule.TotalDoses != nil && series.given >= *schedule.TotalDoses {
+					series.next = len(series.doses)
+					break
+				}
+
+				dose, date, ok := planCatchUpDose(dob, today, schedule, series.previous)
+				if !ok {
+					maxDate := dob.AddDate(0, 0, *schedule.AgeMaxDays)
+					missed = append(missed, models.CatchUpMissedDose{
+						Schedule: schedule,
+						MaxDate:  maxDate.Format("2006-01-02"),
+						Explanation: fmt.Sprintf("Batas usia pemberian dosis ini adalah %d hari (%s). Konsultasikan dengan dokter anak",
+							*schedule.AgeMaxDays, maxDate.Format("2006-01-02")),
+					})
+					series.next++
+					continue
+				}
+				candidates = append(candidates, candidate{series: series, dose: dose, date: date})
+				break
+			}
+		}
+
+		if len(candidates) == 0 {
+			break
+		}
+
+		// The visit is on the earliest date any pending dose can be given
+		visitDate := candidates[0].date
+		for _, cand := range candidates[1:] {
+			if cand.date.Before(visitDate) {
+				visitDate = cand.date
+			}
+		}
+
+		visit := models.CatchUpVisit{
+			VisitNumber: len(visits) + 1,
+			Date:        visitDate.Format("2006-01-02"),
+			Doses:       []models.CatchUpDose{},
+		}
+		for _, cand := range candidates {
+			if cand.date.After(visitDate) {
+				continue
+			}
+			visit.Doses = append(visit.Doses, cand.dose)
+
+			planned := visitDate
+			cand.series.previous = &PreviousDose{DoseNumber: cand.dose.Schedule.DoseNumber, Given: &planned, Planned: true}
+			cand.series.next++
+			cand.series.given++
+		}
+		visits = append(visits, visit)
+	}
+
+	return visits, missed
+}
+
+// planCatchUpDose returns the planned date of a dose given the previous dose of its series.
+// Overdue doses are planned on the earliest valid date from today; doses not yet due keep their
+// scheduled date. Returns false if the dose would be past its maximum age.
+func planCatchUpDose(dob, today time.Time, schedule models.ImmunizationSchedule, previous *PreviousDose) (models.CatchUpDose, time.Time, bool) {
+	due, ok := CalculateDoseDueDate(dob, DoseTiming{
+		DoseNumber:       schedule.DoseNumber,
+		AgeMinDays:       schedule.AgeMinDays,
+		AgeOptimalDays:   schedule.AgeOptimalDays,
+		IntervalFromPrev: schedule.IntervalFromPreviousDays,
+	}, previous)
+	if !ok {
+		// No timing information: plan on the next visit
+		due = DoseDueDate{DueDate: today, EarliestDate: today, Reason: DueReasonAgeSchedule}
+	}
+
+	date := due.DueDate
+	explanation := due.Explanation
+	if date.Before(today) {
+		date = due.EarliestDate
+		if date.Before(today) {
+			date = today
+		}
+		explanation = "Terlambat dari jadwal; dapat diberikan pada kunjungan berikutnya"
+		if date.After(today) {
+			explanation = fmt.Sprintf("Terlambat dari jadwal; paling cepat %s. %s", date.Format("2006-01-02"), due.Explanation)
+		}
+	}
+
+	dose := models.CatchUpDose{
+		Schedule:     schedule,
+		Date:         date.Format("2006-01-02"),
+		EarliestDate: due.EarliestDate.Format("2006-01-02"),
+		Reason:       due.Reason,
+		Explanation:  explanation,
+	}
+
+	if schedule.AgeMaxDays != nil {
+		maxDate := dob.AddDate(0, 0, *schedule.AgeMaxDays)
+		if date.After(maxDate) {
+			return dose, date, false
+		}
+		maxDateStr := maxDate.Format("2006-01-02")
+		dose.MaxDate = &maxDateStr
+	}
+
+	if schedule.AgeOptimalDays != nil {
+		dose.IsCatchUp = date.After(dob.AddDate(0, 0, *schedule.AgeOptimalDays+CatchUpWindowDays))
+	}
+
+	return dose, date, true
+}
@@ -0,0 +1,116 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// Reasons a dose is due on its date
+const (
+	DueReasonAgeSchedule         = "age_schedule"                 // Optimal age from the schedule
+	DueReasonMinimumAge          = "minimum_age"                  // Child must reach the minimum age first
+	DueReasonInterval            = "interval_after_previous_dose" // Minimum interval after the previous dose given
+	DueReasonPreviousDosePending = "previous_dose_pending"        // Previous dose not given yet, date is projected
+)
+
+// DoseTiming is the schedule timing of one dose of a vaccine series
+type DoseTiming struct {
+	DoseNumber       int
+	AgeMinDays       *int
+	AgeOptimalDays   *int
+	IntervalFromPrev *int // Minimum days after the previous dose of the same series
+}
+
+// PreviousDose describes the previous dose of the same series.
+// Given is set if it was recorded; otherwise ProjectedDue is its own calculated due date.
+type PreviousDose struct {
+	DoseNumber   int
+	Given        *time.Time
+	ProjectedDue *time.Time
+	Planned      bool // Given is a planned date (catch-up plan) rather than a recorded one
+}
+
+// DoseDueDate is the calculated due date of a dose and why it falls on that date
+type DoseDueDate struct {
+	DueDate                time.Time
+	EarliestDate           time.Time // Earliest valid date (minimum age and minimum interval)
+	Reason                 string
+	Explanation            string
+	WaitingForPreviousDose bool
+}
+
+// CalculateDoseDueDate calculates the due date of a dose from the child's date of birth,
+// the schedule timing and the previous dose of the same series (nil for the first dose).
+// The due date is the latest of: DOB + optimal age, DOB + minimum age and
+// previous dose date + minimum interval. Returns false if the schedule has no timing.
+func CalculateDoseDueDate(dob time.Time, timing DoseTiming, previous *PreviousDose) (DoseDueDate, bool) {
+	if timing.AgeOptimalDays == nil && timing.AgeMinDays == nil {
+		return DoseDueDate{}, false
+	}
+
+	result := DoseDueDate{Reason: DueReasonAgeSchedule}
+
+	if timing.AgeOptimalDays != nil {
+		result.DueDate = dob.AddDate(0, 0, *timing.AgeOptimalDays)
+		result.Explanation = fmt.Sprintf("Dijadwalkan pada usia optimal %d hari (%s)",
+			*timing.AgeOptimalDays, result.DueDate.Format("2006-01-02"))
+	}
+
+	result.EarliestDate = dob
+	if timing.AgeMinDays != nil {
+		minAgeDate := dob.AddDate(0, 0, *timing.AgeMinDays)
+		result.EarliestDate = minAgeDate
+		if minAgeDate.After(result.DueDate) {
+			result.DueDate = minAgeDate
+			result.Reason = DueReasonMinimumAge
+			result.Explanation = fmt.Sprintf("Usia minimal untuk dosis ini adalah %d hari (%s)",
+				*timing.AgeMinDays, minAgeDate.Format("2006-01-02"))
+		}
+	}
+
+	if previous == nil || timing.IntervalFromPrev == nil {
+		return result, true
+	}
+
+	interval := *timing.IntervalFromPrev
+	if previous.Given != nil {
+		intervalDate := previous.Given.AddDate(0, 0, interval)
+		if intervalDate.After(result.EarliestDate) {
+			result.EarliestDate = intervalDate
+		}
+		if intervalDate.After(result.DueDate) {
+			result.DueDate = intervalDate
+			result.Reason = DueReasonInterval
+			verb := "diberikan"
+			if previous.Planned {
+				verb = "direncanakan"
+			}
+			result.Explanation = fmt.Sprintf("Dosis %d %s pada %s; jarak minimal %d hari sehingga dosis ini paling cepat %s",
+				previous.DoseNumber, verb, previous.Given.Format("2006-01-02"), interval, intervalDate.Format("2006-01-02"))
+		}
+		return result, true
+	}
+
+	// Previous dose not given yet: this dose cannot be given before it,
+	// so project the date from the previous dose's own due date
+	result.WaitingForPreviousDose = true
+	if previous.ProjectedDue != nil {
+		intervalDate := previous.ProjectedDue.AddDate(0, 0, interval)
+		if intervalDate.After(result.DueDate) {
+			result.DueDate = intervalDate
+		}
+	}
+	result.Reason = DueReasonPreviousDosePending
+	result.Explanation = fmt.Sprintf("Menunggu dosis %d. Perkiraan %s jika dosis %d diberikan sesuai jadwal (jarak minimal %d hari)",
+		previous.DoseNumber, result.DueDate.Format("2006-01-02"), previous.DoseNumber, interval)
+
+	return result, true
+}
+
+// ParseDate parses a YYYY-MM-DD date, accepting ISO 8601 timestamps as returned for DATE columns
+func ParseDate(date string) (time.Time, error) {
+	if len(date) > 10 {
+		date = date[:10]
+	}
+	return time.Parse("2006-01-02", date)
+}
@@ -0,0 +1,93 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// ValidKIPISymptoms are the adverse event symptoms that can be reported
+var ValidKIPISymptoms = []string{
+	"fever",
+	"injection_site_reaction",
+	"swelling",
+	"rash",
+	"persistent_crying",
+	"vomiting",
+	"diarrhea",
+	"seizure",
+	"hypotonic_hyporesponsive",
+	"anaphylaxis",
+	"other",
+}
+
+// ValidKIPISeverities are the severity levels of an adverse event
+var ValidKIPISeverities = []string{"mild", "moderate", "serious"}
+
+// ValidKIPIOutcomes are the outcomes of an adverse event
+var ValidKIPIOutcomes = []string{"recovering", "recovered", "recovered_with_sequelae", "hospitalized", "died", "unknown"}
+
+// seriousKIPISymptoms make an event serious regardless of the reported severity
+var seriousKIPISymptoms = map[string]bool{
+	"seizure":                  true,
+	"hypotonic_hyporesponsive": true,
+	"anaphylaxis":              true,
+}
+
+// NormalizeKIPISymptoms lowercases, trims and de-duplicates symptoms and validates them
+func NormalizeKIPISymptoms(symptoms []string) ([]string, error) {
+	valid := make(map[string]bool)
+	for _, s := range ValidKIPISymptoms {
+		valid[s] = true
+	}
+
+	seen := make(map[string]bool)
+	result := []string{}
+	for _, s := range symptoms {
+		s = strings.ToLower(strings.TrimSpace(s))
+		if s == "" || seen[s] {
+			continue
+		}
+		if !valid[s] {
+			return nil, fmt.Errorf("invalid symptom %q. Allowed: %s", s, strings.Join(ValidKIPISymptoms, ", "))
+		}
+		seen[s] = true
+		result = append(result, s)
+	}
+	if len(result) == 0 {
+		return nil, fmt.Errorf("at least one symptom is required")
+	}
+	return result, nil
+}
+
+// IsValidKIPISeverity checks a severity level
+func IsValidKIPISeverity(severity string) bool {
+	return containsString(ValidKIPISeverities, severity)
+}
+
+// IsValidKIPIOutcome checks an outcome
+func IsValidKIPIOutcome(outcome string) bool {
+	return containsString(ValidKIPIOutcomes, outcome)
+}
+
+// IsSeriousKIPI reports whether an event is serious: reported as serious, a serious symptom
+// (seizure, hypotonic-hyporesponsive episode, anaphylaxis), hospitalization or death
+func IsSeriousKIPI(severity, outcome string, symptoms []string) bool {
+	if severity == "serious" || outcome == "hospitalized" || outcome == "died" {
+		return true
+	}
+	for _, s := range symptoms {
+		if seriousKIPISymptoms[s] {
+			return true
+		}
+	}
+	return false
+}
+
+func containsString(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -57,16 +57,6 @@ func ValidatePhoneNumber(phone string) (string, error) {
 	return cleaned, nil
 }
 
-// GetOTPExpiration returns the expiration time for OTP (5 minutes from now)
-func GetOTPExpiration() time.Time {
-	return time.Now().Add(5 * time.Minute)
-}
-
-// IsOTPExpired checks if OTP has expired
-func IsOTPExpired(expiresAt time.Time) bool {
-	return time.Now().After(expiresAt)
-}
-
 // IsValidEmail validates email format
 func IsValidEmail(email string) bool {
 	emailRegex := regexp.MustCompile(`^[a-zA-Z0-9._%+\-]+@[a-zA-Z0-9.\-]+\.[a-zA-Z]{2,}$`)
@@ -0,0 +1,108 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"tukem-backend/models"
+)
+
+var validPyramidCategories = map[string]bool{"sensory": true, "motor": true, "perception": true, "cognitive": true}
+
+var validPyramidOperators = map[string]bool{"<": true, "<=": true, ">": true, ">=": true}
+
+var validPyramidSeverities = map[string]bool{"info": true, "warning": true, "critical": true}
+
+// ValidatePyramidRuleConditions validates the conditions of a pyramid warning rule
+func ValidatePyramidRuleConditions(conditions []models.PyramidRuleCondition) error {
+	if len(conditions) == 0 {
+		return fmt.Errorf("conditions must contain at least 1 condition")
+	}
+	for i, cond := range conditions {
+		switch cond.Scope {
+		case "level":
+			level, err := strconv.Atoi(cond.Target)
+			if err != nil || level < 1 || level > 4 {
+				return fmt.Errorf("condition %d: level target must be between 1 and 4", i+1)
+			}
+		case "category":
+			if !validPyramidCategories[cond.Target] {
+				return fmt.Errorf("condition %d: invalid category target", i+1)
+			}
+		default:
+			return fmt.Errorf("condition %d: scope must be 'level' or 'category'", i+1)
+		}
+		if !validPyramidOperators[cond.Operator] {
+			return fmt.Errorf("condition %d: operator must be one of <, <=, >, >=", i+1)
+		}
+		if cond.Value < 0 || cond.Value > 100 {
+			return fmt.Errorf("condition %d: value must be between 0 and 100", i+1)
+		}
+	}
+	return nil
+}
+
+// ValidatePyramidRuleSeverity validates a pyramid warning rule severity
+func ValidatePyramidRuleSeverity(severity string) error {
+	if !validPyramidSeverities[severity] {
+		return fmt.Errorf("severity must be 'info', 'warning' or 'critical'")
+	}
+	return nil
+}
+
+// EvaluatePyramidRules returns the warnings for every rule whose conditions all match.
+// Scores are percentages; a level or category without assessed items scores 0.
+// lang selects the message language ("en" or default Bahasa Indonesia).
+func EvaluatePyramidRules(rules []models.PyramidWarningRule, levelScores map[int]float64, categoryScores map[string]float64, lang string) []models.PyramidWarning {
+	warnings := []models.PyramidWarning{}
+
+	for _, rule := range rules {
+		if len(rule.Conditions) == 0 {
+			continue
+		}
+
+		matched := true
+		for _, cond := range rule.Conditions {
+			var score float64
+			if cond.Scope == "level" {
+				level, _ := strconv.Atoi(cond.Target)
+				score = levelScores[level]
+			} else {
+				score = categoryScores[cond.Target]
+			}
+			if !compareScore(score, cond.Operator, cond.Value) {
+				matched = false
+				break
+			}
+		}
+		if !matched {
+			continue
+		}
+
+		message := rule.Message
+		if lang == "en" && rule.MessageEn != nil && *rule.MessageEn != "" {
+			message = *rule.MessageEn
+		}
+		warnings = append(warnings, models.PyramidWarning{
+			RuleID:   rule.ID,
+			Name:     rule.Name,
+			Severity: rule.Severity,
+			Message:  message,
+		})
+	}
+
+	return warnings
+}
+
+func compareScore(score float64, operator string, value float64) bool {
+	switch operator {
+	case "<":
+		return score < value
+	case "<=":
+		return score <= value
+	case ">":
+		return score > value
+	case ">=":
+		return score >= value
+	}
+	return false
+}
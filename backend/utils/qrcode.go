@@ -0,0 +1,511 @@
+package utils
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+)
+
+// QR code encoder (ISO/IEC 18004) for short texts such as verification URLs.
+// Byte mode, error correction level M, versions 1 to 10 (up to 213 bytes).
+
+// qrVersionInfo holds the error correction block structure of a version at level M
+type qrVersionInfo struct {
+	ecPerBlock int
+	blocks     []int // Data codewords per block
+	alignment  []int // Alignment pattern center coordinates
+}
+
+var qrVersions = []qrVersionInfo{
+	{}, // Versions are 1-based
+	{10, []int{16}, nil},
+	{16, []int{28}, []int{6, 18}},
+	{26, []int{44}, []int{6, 22}},
+	{18, []int{32, 32}, []int{6, 26}},
+	{24, []int{43, 43}, []int{6, 30}},
+	{16, []int{27, 27, 27, 27}, []int{6, 34}},
+	{18, []int{31, 31, 31, 31}, []int{6, 22, 38}},
+	{22, []int{38, 38, 39, 39}, []int{6, 24, 42}},
+	{22, []int{36, 36, 36, 37, 37}, []int{6, 26, 46}},
+	{26, []int{43, 43, 43, 43, 44}, []int{6, 28, 50}},
+}
+
+// qrCode is a QR symbol under construction
+type qrCode struct {
+	version    int
+	size       int
+	modules    [][]bool
+	isFunction [][]bool
+}
+
+// EncodeQR encodes text as a QR code and returns its modules (true = dark), without quiet zone
+func EncodeQR(text string) ([][]bool, error) {
+	data := []byte(text)
+
+	// Pick the smallest version that fits
+	version := 0
+	for v := 1; v < len(qrVersions); v++ {
+		countBits := 8
+		if v >= 10 {
+			countBits = 16
+		}
+		if 4+countBits+8*len(data) <= qrDataCodewords(v)*8 {
+			version = v
+			break
+		}
+	}
+	if version == 0 {
+		return nil, fmt.Errorf("text too long for QR code (%d bytes)", len(data))
+	}
+
+	codewords := qrAddErrorCorrection(version, qrEncodeData(version, data))
+
+	qr := newQRCode(version)
+	qr.drawFunctionPatterns()
+	qr.drawCodewords(codewords)
+
+	// Choose the mask with the lowest penalty
+	bestMask, bestPenalty := 0, -1
+	for mask := 0; mask < 8; mask++ {
+		qr.applyMask(mask)
+		qr.drawFormatBits(mask)
+		penalty := qr.penalty()
+		if bestPenalty < 0 || penalty < bestPenalty {
+			bestMask, bestPenalty = mask, penalty
+		}
+		qr.applyMask(mask) // XOR again to undo
+	}
+	qr.applyMask(bestMask)
+	qr.drawFormatBits(bestMask)
+
+	return qr.modules, nil
+}
+
+// EncodeQRPNG encodes text as a QR code PNG image with scale pixels per module and a 4-module
+// quiet zone
+func EncodeQRPNG(text string, scale int) ([]byte, error) {
+	modules, err := EncodeQR(text)
+	if err != nil {
+		return nil, err
+	}
+
+	const quietZone = 4
+	size := (len(modules) + 2*quietZone) * scale
+	img := image.NewGray(image.Rect(0, 0, size, size))
+	for i := range img.Pix {
+		img.Pix[i] = 0xff
+	}
+	for y, row := range modules {
+		for x, dark := range row {
+			if !dark {
+				continue
+			}
+			for dy := 0; dy < scale; dy++ {
+				for dx := 0; dx < scale; dx++ {
+					img.SetGray((x+quietZone)*scale+dx, (y+quietZone)*scale+dy, color.Gray{Y: 0})
+				}
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func qrDataCodewords(version int) int {
+	total := 0
+	for _, n := range qrVersions[version].blocks {
+		total += n
+	}
+	return total
+}
+
+// qrEncodeData builds the data codewords: mode, character count, data, terminator and padding
+func qrEncodeData(version int, data []byte) []byte {
+	capacity := qrDataCodewords(version) * 8
+	var bits []bool
+	appendBits := func(value, length int) {
+		for i := length - 1; i >= 0; i-- {
+			bits = append(bits, (value>>uint(i))&1 == 1)
+		}
+	}
+
+	countBits := 8
+	if version >= 10 {
+		countBits = 16
+	}
+	appendBits(0x4, 4) // Byte mode
+	appendBits(len(data), countBits)
+	for _, b := range data {
+		appendBits(int(b), 8)
+	}
+
+	// Terminator and bit padding to a byte boundary
+	terminator := capacity - len(bits)
+	if terminator > 4 {
+		terminator = 4
+	}
+	appendBits(0, terminator)
+	if rem := len(bits) % 8; rem != 0 {
+		appendBits(0, 8-rem)
+	}
+
+	result := make([]byte, 0, capacity/8)
+	for i := 0; i < len(bits); i += 8 {
+		var b byte
+		for j := 0; j < 8; j++ {
+			if bits[i+j] {
+				b |= 1 << uint(7-j)
+			}
+		}
+		result = append(result, b)
+	}
+
+	// Pad bytes
+	for pad := byte(0xEC); len(result) < capacity/8; pad ^= 0xEC ^ 0x11 {
+		result = append(result, pad)
+	}
+	return result
+}
+
+// qrAddErrorCorrection splits data into blocks, adds Reed-Solomon codewords and interleaves them
+func qrAddErrorCorrection(version int, data []byte) []byte {
+	info := qrVersions[version]
+	generator := qrReedSolomonGenerator(info.ecPerBlock)
+
+	var dataBlocks, ecBlocks [][]byte
+	offset := 0
+	for _, n := range info.blocks {
+		block := data[offset : offset+n]
+		offset += n
+		dataBlocks = append(dataBlocks, block)
+		ecBlocks = append(ecBlocks, qrReedSolomonRemainder(block, generator))
+	}
+
+	maxLen := info.blocks[len(info.blocks)-1]
+	result := make([]byte, 0, len(data)+len(info.blocks)*info.ecPerBlock)
+	for i := 0; i < maxLen; i++ {
+		for _, block := range dataBlocks {
+			if i < len(block) {
+				result = append(result, block[i])
+			}
+		}
+	}
+	for i := 0; i < info.ecPerBlock; i++ {
+		for _, block := range ecBlocks {
+			result = append(result, block[i])
+		}
+	}
+	return result
+}
+
+// qrGFMultiply multiplies two elements of GF(2^8) modulo x^8 + x^4 + x^3 + x^2 + 1
+func qrGFMultiply(x, y byte) byte {
+	var z int
+	for i := 7; i >= 0; i-- {
+		z = (z << 1) ^ ((z >> 7) * 0x11D)
+		z ^= int((y>>uint(i))&1) * int(x)
+	}
+	return byte(z)
+}
+
+// qrReedSolomonGenerator returns the coefficients of the generator polynomial of the given degree
+// (highest power first, leading 1 omitted)
+func qrReedSolomonGenerator(degree int) []byte {
+	result := make([]byte, degree)
+	result[degree-1] = 1
+	root := byte(1)
+	for i := 0; i < degree; i++ {
+		for j := 0; j < degree; j++ {
+			result[j] = qrGFMultiply(result[j], root)
+			if j+1 < degree {
+				result[j] ^= result[j+1]
+			}
+		}
+		root = qrGFMultiply(root, 0x02)
+	}
+	return result
+}
+
+func qrReedSolomonRemainder(data, generator []byte) []byte {
+	result := make([]byte, len(generator))
+	for _, b := range data {
+		factor := b ^ result[0]
+		copy(result, result[1:])
+		result[len(result)-1] = 0
+		for i, coef := range generator {
+			result[i] ^= qrGFMultiply(coef, factor)
+		}
+	}
+	return result
+}
+
+func newQRCode(version int) *qrCode {
+	size := version*4 + 17
+	qr := &qrCode{version: version, size: size}
+	qr.modules = make([][]bool, size)
+	qr.isFunction = make([][]bool, size)
+	for i := range qr.modules {
+		qr.modules[i] = make([]bool, size)
+		qr.isFunction[i] = make([]bool, size)
+	}
+	return qr
+}
+
+func (qr *qrCode) setFunction(x, y int, dark bool) {
+	qr.modules[y][x] = dark
+	qr.isFunction[y][x] = true
+}
+
+// drawFunctionPatterns draws finder, timing and alignment patterns and reserves the format
+// and version areas
+func (qr *qrCode) drawFunctionPatterns() {
+	for i := 0; i < qr.size; i++ {
+		qr.setFunction(6, i, i%2 == 0)
+		qr.setFunction(i, 6, i%2 == 0)
+	}
+
+	qr.drawFinder(3, 3)
+	qr.drawFinder(qr.size-4, 3)
+	qr.drawFinder(3, qr.size-4)
+
+	positions := qrVersions[qr.version].alignment
+	last := len(positions) - 1
+	for i, y := range positions {
+		for j, x := range positions {
+			// Skip the three corners occupied by finder patterns
+			if (i == 0 && j == 0) || (i == 0 && j == last) || (i == last && j == 0) {
+				continue
+			}
+			qr.drawAlignment(x, y)
+		}
+	}
+
+	qr.drawFormatBits(0) // Reserve the area, redrawn once the mask is chosen
+	qr.drawVersion()
+}
+
+func (qr *qrCode) drawFinder(cx, cy int) {
+	for dy := -4; dy <= 4; dy++ {
+		for dx := -4; dx <= 4; dx++ {
+			x, y := cx+dx, cy+dy
+			if x < 0 || x >= qr.size || y < 0 || y >= qr.size {
+				continue
+			}
+			dist := qrMax(qrAbs(dx), qrAbs(dy))
+			qr.setFunction(x, y, dist != 2 && dist != 4)
+		}
+	}
+}
+
+func (qr *qrCode) drawAlignment(cx, cy int) {
+	for dy := -2; dy <= 2; dy++ {
+		for dx := -2; dx <= 2; dx++ {
+			qr.setFunction(cx+dx, cy+dy, qrMax(qrAbs(dx), qrAbs(dy)) != 1)
+		}
+	}
+}
+
+// drawFormatBits draws both copies of the format information (level M and the mask)
+func (qr *qrCode) drawFormatBits(mask int) {
+	const levelM = 0
+	data := levelM<<3 | mask
+	rem := data
+	for i := 0; i < 10; i++ {
+		rem = (rem << 1) ^ ((rem >> 9) * 0x537)
+	}
+	bits := (data<<10 | rem) ^ 0x5412
+	bit := func(i int) bool { return (bits>>uint(i))&1 == 1 }
+
+	// First copy, around the top-left finder
+	for i := 0; i <= 5; i++ {
+		qr.setFunction(8, i, bit(i))
+	}
+	qr.setFunction(8, 7, bit(6))
+	qr.setFunction(8, 8, bit(7))
+	qr.setFunction(7, 8, bit(8))
+	for i := 9; i < 15; i++ {
+		qr.setFunction(14-i, 8, bit(i))
+	}
+
+	// Second copy, split between the top-right and bottom-left finders
+	for i := 0; i < 8; i++ {
+		qr.setFunction(qr.size-1-i, 8, bit(i))
+	}
+	for i := 8; i < 15; i++ {
+		qr.setFunction(8, qr.size-15+i, bit(i))
+	}
+	qr.setFunction(8, qr.size-8, true) // Dark module
+}
+
+// drawVersion draws the version information blocks (version 7 and up)
+func (qr *qrCode) drawVersion() {
+	if qr.version < 7 {
+		return
+	}
+	rem := qr.version
+	for i := 0; i < 12; i++ {
+		rem = (rem << 1) ^ ((rem >> 11) * 0x1F25)
+	}
+	bits := qr.version<<12 | rem
+	for i := 0; i < 18; i++ {
+		dark := (bits>>uint(i))&1 == 1
+		a := qr.size - 11 + i%3
+		b := i / 3
+		qr.setFunction(a, b, dark)
+		qr.setFunction(b, a, dark)
+	}
+}
+
+// drawCodewords places the codewords in the zigzag order, skipping function modules
+func (qr *qrCode) drawCodewords(codewords []byte) {
+	i := 0
+	total := len(codewords) * 8
+	for right := qr.size - 1; right >= 1; right -= 2 {
+		if right == 6 {
+			right = 5
+		}
+		upward := (right+1)&2 == 0
+		for vert := 0; vert < qr.size; vert++ {
+			y := vert
+			if upward {
+				y = qr.size - 1 - vert
+			}
+			for j := 0; j < 2; j++ {
+				x := right - j
+				if qr.isFunction[y][x] || i >= total {
+					continue
+				}
+				qr.modules[y][x] = (codewords[i>>3]>>uint(7-(i&7)))&1 == 1
+				i++
+			}
+		}
+	}
+}
+
+// applyMask XORs the data modules with a mask pattern
+func (qr *qrCode) applyMask(mask int) {
+	for y := 0; y < qr.size; y++ {
+		for x := 0; x < qr.size; x++ {
+			if qr.isFunction[y][x] {
+				continue
+			}
+			var invert bool
+			switch mask {
+			case 0:
+				invert = (x+y)%2 == 0
+			case 1:
+				invert = y%2 == 0
+			case 2:
+				invert = x%3 == 0
+			case 3:
+				invert = (x+y)%3 == 0
+			case 4:
+				invert = (x/3+y/2)%2 == 0
+			case 5:
+				invert = x*y%2+x*y%3 == 0
+			case 6:
+				invert = (x*y%2+x*y%3)%2 == 0
+			case 7:
+				invert = ((x+y)%2+x*y%3)%2 == 0
+			}
+			if invert {
+				qr.modules[y][x] = !qr.modules[y][x]
+			}
+		}
+	}
+}
+
+// penalty scores the symbol: long runs, 2x2 blocks, finder-like patterns and dark/light balance
+func (qr *qrCode) penalty() int {
+	size := qr.size
+	at := func(x, y int, transpose bool) bool {
+		if transpose {
+			return qr.modules[x][y]
+		}
+		return qr.modules[y][x]
+	}
+
+	result := 0
+	finderLike := []bool{true, false, true, true, true, false, true, false, false, false, false}
+	for _, transpose := range []bool{false, true} {
+		for y := 0; y < size; y++ {
+			// Runs of five or more modules of the same color
+			run := 1
+			for x := 1; x < size; x++ {
+				if at(x, y, transpose) == at(x-1, y, transpose) {
+					run++
+					continue
+				}
+				if run >= 5 {
+					result += run - 2
+				}
+				run = 1
+			}
+			if run >= 5 {
+				result += run - 2
+			}
+
+			// 1:1:3:1:1 finder-like patterns with four light modules on either side
+			for x := 0; x+len(finderLike) <= size; x++ {
+				forward, backward := true, true
+				for k, dark := range finderLike {
+					if at(x+k, y, transpose) != dark {
+						forward = false
+					}
+					if at(x+len(finderLike)-1-k, y, transpose) != dark {
+						backward = false
+					}
+				}
+				if forward {
+					result += 40
+				}
+				if backward {
+					result += 40
+				}
+			}
+		}
+	}
+
+	// 2x2 blocks of the same color
+	dark := 0
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			if qr.modules[y][x] {
+				dark++
+			}
+			if x+1 < size && y+1 < size {
+				c := qr.modules[y][x]
+				if c == qr.modules[y][x+1] && c == qr.modules[y+1][x] && c == qr.modules[y+1][x+1] {
+					result += 3
+				}
+			}
+		}
+	}
+
+	// Balance of dark modules, 10 points per 5% deviation from 50%
+	total := size * size
+	deviation := qrAbs(dark*20-total*10) / total
+	result += deviation * 10
+
+	return result
+}
+
+func qrAbs(x int) int {
+	if x < 0 {
+		return -x
+	}
+	return x
+}
+
+func qrMax(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
@@ -0,0 +1,50 @@
+package utils
+
+import (
+	"fmt"
+	"time"
+)
+
+// ParseClock parses a local time of day in HH:MM format and returns the minutes after midnight
+func ParseClock(value string) (int, error) {
+	t, err := time.Parse("15:04", value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, expected HH:MM", value)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// QuietHoursEnd reports whether now falls within the quiet hours start-end (HH:MM, local time in
+// loc) and, if so, returns when they end. Windows may cross midnight (e.g. 21:00-07:00); equal
+// start and end means no quiet hours.
+func QuietHoursEnd(now time.Time, start, end string, loc *time.Location) (time.Time, bool) {
+	startMin, err := ParseClock(start)
+	if err != nil {
+		return time.Time{}, false
+	}
+	endMin, err := ParseClock(end)
+	if err != nil || startMin == endMin {
+		return time.Time{}, false
+	}
+
+	local := now.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+	endToday := midnight.Add(time.Duration(endMin) * time.Minute)
+
+	if startMin < endMin {
+		if minute >= startMin && minute < endMin {
+			return endToday, true
+		}
+		return time.Time{}, false
+	}
+
+	// Window crosses midnight
+	switch {
+	case minute >= startMin:
+		return midnight.AddDate(0, 0, 1).Add(time.Duration(endMin) * time.Minute), true
+	case minute < endMin:
+		return endToday, true
+	}
+	return time.Time{}, false
+}
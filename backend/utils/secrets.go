@@ -0,0 +1,67 @@
+package utils
+
+import (
+	"fmt"
+	"log"
+	"os"
+)
+
+// Environment variables of the signing and hashing keys. Each falls back to JWT_SECRET.
+const (
+	SecretJWT                 = "JWT_SECRET"
+	SecretStorageSigning      = "STORAGE_SIGNING_KEY"
+	SecretCertificateSigning  = "CERTIFICATE_SIGNING_KEY"
+	SecretOTPHash             = "OTP_HASH_KEY"
+	SecretTwoFactorEncryption = "TWO_FACTOR_ENCRYPTION_KEY"
+)
+
+var secretKeyVars = []string{
+	SecretJWT,
+	SecretStorageSigning,
+	SecretCertificateSigning,
+	SecretOTPHash,
+	SecretTwoFactorEncryption,
+}
+
+// developmentSecret is the key used in development when no secret is set
+const developmentSecret = "secret"
+
+// IsDevelopment reports whether the API runs in development (ENV unset or "development")
+func IsDevelopment() bool {
+	env := os.Getenv("ENV")
+	return env == "" || env == "development"
+}
+
+// SecretKey returns the key of the environment variable envVar, falling back to JWT_SECRET, and
+// in development to a fixed key. CheckSecretKeys makes sure a key exists before the API starts.
+func SecretKey(envVar string) []byte {
+	key, err := secretKey(envVar)
+	if err != nil {
+		log.Fatal(err)
+	}
+	return key
+}
+
+// CheckSecretKeys returns an error outside development when a signing or hashing key is not set,
+// so the API refuses to start instead of signing with the development key
+func CheckSecretKeys() error {
+	for _, envVar := range secretKeyVars {
+		if _, err := secretKey(envVar); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func secretKey(envVar string) ([]byte, error) {
+	if key := os.Getenv(envVar); key != "" {
+		return []byte(key), nil
+	}
+	if key := os.Getenv(SecretJWT); key != "" {
+		return []byte(key), nil
+	}
+	if !IsDevelopment() {
+		return nil, fmt.Errorf("%s (or %s) must be set when ENV is %q", envVar, SecretJWT, os.Getenv("ENV"))
+	}
+	return []byte(developmentSecret), nil
+}
@@ -67,23 +67,7 @@ func SeedDenverIIMilestones(db *sqlx.DB) error {
 	for _, m := range milestones {
 		// Set category based on denver_domain if not set
 		if m.Category == "" {
-			switch m.DenverDomain {
-			case nil:
-				m.Category = "cognitive" // default
-			default:
-				switch *m.DenverDomain {
-				case "PS":
-					m.Category = "sensory"
-				case "FM":
-					m.Category = "motor"
-				case "L":
-					m.Category = "cognitive"
-				case "GM":
-					m.Category = "motor"
-				default:
-					m.Category = "cognitive"
-				}
-			}
+			m.Category = CategoryForDenverDomain(m.DenverDomain)
 		}
 
 		if _, err := stmt.Exec(m); err != nil {
@@ -0,0 +1,181 @@
+package utils
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"encoding/base32"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+// TOTP parameters (RFC 6238 defaults, which every authenticator app supports)
+const (
+	totpPeriod = 30 // Seconds per time step
+	totpDigits = 6
+	totpSkew   = 1 // Steps accepted before and after the current one, for clock drift
+)
+
+var totpEncoding = base32.StdEncoding.WithPadding(base32.NoPadding)
+
+// GenerateTOTPSecret returns a random 160-bit TOTP secret, base32 encoded
+func GenerateTOTPSecret() (string, error) {
+	b := make([]byte, 20)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return totpEncoding.EncodeToString(b), nil
+}
+
+// TOTPCode returns the code of a time step
+func TOTPCode(secret string, step int64) (string, error) {
+	key, err := totpEncoding.DecodeString(strings.ToUpper(secret))
+	if err != nil {
+		return "", fmt.Errorf("invalid TOTP secret: %w", err)
+	}
+
+	var msg [8]byte
+	binary.BigEndian.PutUint64(msg[:], uint64(step))
+	mac := hmac.New(sha1.New, key)
+	mac.Write(msg[:])
+	sum := mac.Sum(nil)
+
+	// Dynamic truncation (RFC 4226 section 5.3)
+	offset := sum[len(sum)-1] & 0x0f
+	value := binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff
+	return fmt.Sprintf("%0*d", totpDigits, value%1000000), nil
+}
+
+// TOTPStep returns the time step of t
+func TOTPStep(t time.Time) int64 {
+	return t.Unix() / totpPeriod
+}
+
+// ValidateTOTP checks a code against the steps around t. Steps up to lastUsedStep are rejected so
+// a code cannot be used twice. Returns the matched step.
+func ValidateTOTP(secret, code string, t time.Time, lastUsedStep int64) (int64, bool) {
+	code = strings.ReplaceAll(strings.TrimSpace(code), " ", "")
+	if len(code) != totpDigits {
+		return 0, false
+	}
+
+	current := TOTPStep(t)
+	for step := current - totpSkew; step <= current+totpSkew; step++ {
+		if step <= lastUsedStep {
+			continue
+		}
+		expected, err := TOTPCode(secret, step)
+		if err != nil {
+			return 0, false
+		}
+		if hmac.Equal([]byte(expected), []byte(code)) {
+			return step, true
+		}
+	}
+	return 0, false
+}
+
+// TOTPProvisioningURL returns the otpauth:// URL scanned by authenticator apps
+func TOTPProvisioningURL(account, secret string) string {
+	issuer := os.Getenv("TOTP_ISSUER")
+	if issuer == "" {
+		issuer = "Tukem"
+	}
+
+	params := url.Values{}
+	params.Set("secret", secret)
+	params.Set("issuer", issuer)
+	params.Set("algorithm", "SHA1")
+	params.Set("digits", fmt.Sprint(totpDigits))
+	params.Set("period", fmt.Sprint(totpPeriod))
+	label := url.PathEscape(issuer + ":" + account)
+	return "otpauth://totp/" + label + "?" + params.Encode()
+}
+
+// twoFactorEncryptionKey returns the AES-256 key that encrypts TOTP secrets at rest
+func twoFactorEncryptionKey() []byte {
+	sum := sha256.Sum256(append([]byte("two-factor:"), SecretKey(SecretTwoFactorEncryption)...))
+	return sum[:]
+}
+
+// EncryptTOTPSecret encrypts a TOTP secret for storage
+func EncryptTOTPSecret(secret string) (string, error) {
+	gcm, err := twoFactorCipher()
+	if err != nil {
+		return "", err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	sealed := gcm.Seal(nonce, nonce, []byte(secret), nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// DecryptTOTPSecret decrypts a TOTP secret encrypted by EncryptTOTPSecret
+func DecryptTOTPSecret(encrypted string) (string, error) {
+	gcm, err := twoFactorCipher()
+	if err != nil {
+		return "", err
+	}
+	sealed, err := base64.StdEncoding.DecodeString(encrypted)
+	if err != nil {
+		return "", err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return "", errors.New("encrypted TOTP secret too short")
+	}
+	secret, err := gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], nil)
+	if err != nil {
+		return "", err
+	}
+	return string(secret), nil
+}
+
+func twoFactorCipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(twoFactorEncryptionKey())
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// recoveryCodeAlphabet is Crockford's base32: no I, L, O or U, which are easily misread
+const recoveryCodeAlphabet = "0123456789abcdefghjkmnpqrstvwxyz"
+
+// recoveryCodeNormalizer undoes formatting and common misreadings of a typed recovery code
+var recoveryCodeNormalizer = strings.NewReplacer("-", "", " ", "", "o", "0", "i", "1", "l", "1")
+
+// GenerateRecoveryCodes returns n random one-time recovery codes formatted as "xxxxx-xxxxx"
+func GenerateRecoveryCodes(n int) ([]string, error) {
+	codes := make([]string, n)
+	for i := range codes {
+		b := make([]byte, 10)
+		if _, err := rand.Read(b); err != nil {
+			return nil, err
+		}
+		chars := make([]byte, len(b))
+		for j, v := range b {
+			chars[j] = recoveryCodeAlphabet[v&31]
+		}
+		codes[i] = string(chars[:5]) + "-" + string(chars[5:])
+	}
+	return codes, nil
+}
+
+// HashRecoveryCode returns the stored hash of a recovery code; case, spaces and dashes are ignored
+func HashRecoveryCode(code string) string {
+	normalized := recoveryCodeNormalizer.Replace(strings.ToLower(strings.TrimSpace(code)))
+	sum := sha256.Sum256([]byte(normalized))
+	return hex.EncodeToString(sum[:])
+}